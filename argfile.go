@@ -0,0 +1,65 @@
+package serpent
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expandArgFiles replaces every "@path" argument in args with the
+// arguments read from the file at path, one per line. Blank lines and lines
+// starting with "#" are skipped. A leading "@@" escapes a literal "@".
+//
+// Values of flags, which may use "@path" for indirection, arguments after
+// "--" and the arguments of RawArgs commands are left as is.
+func expandArgFiles(root *Command, args []string) ([]string, error) {
+	positional := make([]bool, len(args))
+	end := walkCommandArgs(root, args, func(_ *Command, i int, form string) bool {
+		positional[i] = form == ""
+		return true
+	})
+
+	expanded := make([]string, 0, len(args))
+	for i, arg := range args {
+		switch {
+		case i >= end || !positional[i]:
+			expanded = append(expanded, arg)
+		case strings.HasPrefix(arg, "@@"):
+			expanded = append(expanded, arg[1:])
+		case strings.HasPrefix(arg, "@") && len(arg) > 1:
+			fileArgs, err := readArgFile(arg[1:])
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, fileArgs...)
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+	return expanded, nil
+}
+
+// readArgFile reads the arguments in the arg file at path.
+func readArgFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read arg file: %w", err)
+	}
+	defer f.Close()
+
+	var args []string
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		args = append(args, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read arg file %s: %w", path, err)
+	}
+	return args, nil
+}
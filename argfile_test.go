@@ -0,0 +1,105 @@
+package serpent_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestCommand_ArgFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	argFile := filepath.Join(dir, "args.txt")
+	require.NoError(t, os.WriteFile(argFile, []byte("# hosts to deploy to\nweb1\r\n\n  # indented comment\nweb two\n--parallel\n"), 0o600))
+
+	type result struct {
+		args     []string
+		parallel bool
+		token    string
+		color    string
+	}
+	run := func(enable bool, args ...string) (result, error) {
+		var res result
+		root := &serpent.Command{
+			Use:            "app",
+			EnableArgFiles: enable,
+			Children: []*serpent.Command{
+				{
+					Use: "deploy",
+					Options: serpent.OptionSet{
+						{Name: "parallel", Flag: "parallel", Value: serpent.BoolOf(&res.parallel)},
+						{Name: "token", Flag: "token", FlagShorthand: "t", AllowIndirection: true, Value: serpent.StringOf(&res.token)},
+						{Name: "color", Flag: "color", FlagShorthand: "c", Value: serpent.ValueOf(&res.color, func(s string) (string, error) { return s, nil }, nil).WithNoOptDefValue("always")},
+					},
+					Handler: func(inv *serpent.Invocation) error {
+						res.args = inv.Args
+						return nil
+					},
+				},
+			},
+		}
+		err := root.Invoke(args...).Run()
+		return res, err
+	}
+
+	t.Run("Expand", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := run(true, "deploy", "@"+argFile, "web3")
+		require.NoError(t, err)
+		require.Equal(t, []string{"web1", "web two", "web3"}, res.args)
+		require.True(t, res.parallel)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := run(false, "deploy", "@"+argFile)
+		require.NoError(t, err)
+		require.Equal(t, []string{"@" + argFile}, res.args)
+	})
+
+	t.Run("Escape", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := run(true, "deploy", "@@handle", "--", "@"+argFile)
+		require.NoError(t, err)
+		require.Equal(t, []string{"@handle", "@" + argFile}, res.args)
+	})
+
+	t.Run("FlagValues", func(t *testing.T) {
+		t.Parallel()
+
+		tokenFile := filepath.Join(dir, "token")
+		require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+
+		// Flag values are left to option indirection.
+		res, err := run(true, "deploy", "-t", "@"+tokenFile, "@"+argFile)
+		require.NoError(t, err)
+		require.Equal(t, "secret", res.token)
+		require.Equal(t, []string{"web1", "web two"}, res.args)
+	})
+
+	t.Run("NoOptDefVal", func(t *testing.T) {
+		t.Parallel()
+
+		// --color takes no value unless given with "=", so the arg file
+		// after it is expanded.
+		res, err := run(true, "deploy", "--color", "@"+argFile, "-c", "@"+argFile)
+		require.NoError(t, err)
+		require.Equal(t, "always", res.color)
+		require.Equal(t, []string{"web1", "web two", "web1", "web two"}, res.args)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := run(true, "deploy", "@"+filepath.Join(dir, "missing"))
+		require.ErrorContains(t, err, "read arg file")
+	})
+}
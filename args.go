@@ -0,0 +1,196 @@
+package serpent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// argSpec is a positional argument declared in the Use of a command.
+type argSpec struct {
+	name     string
+	variadic bool
+}
+
+// Argument describes a positional argument of a command, see
+// Command.Arguments.
+type Argument struct {
+	// Name is used in the synopsis and with Invocation.Arg.
+	Name        string
+	Description string
+	// Optional arguments may be left out, after the required ones.
+	Optional bool
+	// Variadic is set for the last argument if it takes the remaining
+	// arguments.
+	Variadic bool
+	// CompletionHandler completes the argument when it's under the cursor,
+	// instead of the CompletionHandler of the command. See CompleteResource.
+	CompletionHandler CompletionHandlerFunc `json:"-"`
+}
+
+// CompleteResource returns a copy of a completed with the names of the
+// resource registered with RegisterResource, e.g.
+// Argument{Name: "workspace"}.CompleteResource("workspace").
+func (a Argument) CompleteResource(resource string) Argument {
+	a.CompletionHandler = ResourceCompletion(resource)
+	return a
+}
+
+// argumentAt returns the Argument of c at position i, or the last one if it's
+// variadic and i is past it. It returns nil if there's no such argument.
+func (c *Command) argumentAt(i int) *Argument {
+	if len(c.Arguments) == 0 || i < 0 {
+		return nil
+	}
+	if i < len(c.Arguments) {
+		return &c.Arguments[i]
+	}
+	if last := &c.Arguments[len(c.Arguments)-1]; last.Variadic {
+		return last
+	}
+	return nil
+}
+
+// usage returns the argument as shown in synopses, e.g. "<src>" or
+// "[files...]".
+func (a Argument) usage() string {
+	name := a.Name
+	if a.Variadic {
+		name += "..."
+	}
+	if a.Optional {
+		return "[" + name + "]"
+	}
+	return "<" + name + ">"
+}
+
+// Synopsis returns the usage of c without its parents, e.g.
+// "copy --mode <string> [flags] <src> <dst>". If Use holds only the name of
+// the command, the synopsis is generated from its required flags, whether it
+// has other visible flags, and its Arguments, so that it can't drift from
+// them. Otherwise, it's Use.
+func (c *Command) Synopsis() string {
+	if strings.Contains(strings.TrimSpace(c.Use), " ") {
+		return c.Use
+	}
+
+	words := []string{c.Name()}
+	var optional bool
+	for _, opt := range c.Options {
+		if opt.Hidden || opt.Flag == "" {
+			continue
+		}
+		if !opt.Required {
+			optional = true
+			continue
+		}
+		flag := "--" + opt.Flag
+		if opt.Value != nil && opt.Value.Type() != "bool" {
+			flag += " <" + typeHelp(&opt) + ">"
+		}
+		words = append(words, flag)
+	}
+	if optional {
+		words = append(words, "[flags]")
+	}
+	for _, arg := range c.Arguments {
+		words = append(words, arg.usage())
+	}
+	return strings.Join(words, " ")
+}
+
+// checkArguments returns an error if args don't match the Arguments of c,
+// if it declares any.
+func (c *Command) checkArguments(args []string) error {
+	if len(c.Arguments) == 0 {
+		return nil
+	}
+	for i, arg := range c.Arguments {
+		if i >= len(args) {
+			if arg.Optional {
+				return nil
+			}
+			return fmt.Errorf("missing argument %s", arg.usage())
+		}
+	}
+	if last := c.Arguments[len(c.Arguments)-1]; len(args) > len(c.Arguments) && !last.Variadic {
+		return fmt.Errorf("unexpected arguments %q, wanted %s",
+			args[len(c.Arguments):], c.Synopsis())
+	}
+	return nil
+}
+
+// argSpecs returns the positional arguments of c, its Arguments or else the
+// ones declared in its Use, e.g. "copy <src> <dst> [files...]". Flags such
+// as "[--shell <shell>]" and the "[flags]" placeholder are skipped.
+func (c *Command) argSpecs() []argSpec {
+	if len(c.Arguments) > 0 {
+		specs := make([]argSpec, len(c.Arguments))
+		for i, arg := range c.Arguments {
+			specs[i] = argSpec{name: arg.Name, variadic: arg.Variadic}
+		}
+		return specs
+	}
+
+	words := strings.Fields(c.Use)
+	if len(words) > 0 {
+		words = words[1:]
+	}
+
+	var (
+		specs  []argSpec
+		inFlag bool
+	)
+	for _, w := range words {
+		if inFlag {
+			inFlag = !strings.HasSuffix(w, "]")
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(w, "[<"), "-") {
+			// The value of the flag may follow in the same group.
+			inFlag = strings.HasPrefix(w, "[") && !strings.HasSuffix(w, "]")
+			continue
+		}
+		spec := argSpec{variadic: strings.Contains(w, "...")}
+		spec.name = strings.Trim(strings.ReplaceAll(w, "...", ""), "[]<>")
+		if spec.name == "" || spec.name == "flags" {
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// argIndex returns the position of the argument name in the Use of the
+// command. It panics if the command doesn't declare it, as that's a bug in
+// the command rather than a user error.
+func (inv *Invocation) argIndex(name string) int {
+	for i, spec := range inv.Command.argSpecs() {
+		if spec.name == name {
+			return i
+		}
+	}
+	panic(fmt.Sprintf("command %q has no argument %q in its Use %q", inv.Command.FullName(), name, inv.Command.Use))
+}
+
+// Arg returns the positional argument declared as name in the Use of the
+// command, e.g. "src" for "copy <src> <dst>", or "" if it wasn't given.
+// Handlers should use it rather than indexing inv.Args, which panics when
+// an optional argument is missing.
+func (inv *Invocation) Arg(name string) string {
+	i := inv.argIndex(name)
+	if i >= len(inv.Args) {
+		return ""
+	}
+	return inv.Args[i]
+}
+
+// ArgSlice returns the positional arguments from the one declared as name in
+// the Use of the command to the last, e.g. the files of
+// "copy <dst> [files...]", or nil if none were given.
+func (inv *Invocation) ArgSlice(name string) []string {
+	i := inv.argIndex(name)
+	if i >= len(inv.Args) {
+		return nil
+	}
+	return inv.Args[i:]
+}
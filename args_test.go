@@ -0,0 +1,101 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+)
+
+func TestInvocation_Arg(t *testing.T) {
+	t.Parallel()
+
+	run := func(use string, args ...string) *serpent.Invocation {
+		var got *serpent.Invocation
+		cmd := &serpent.Command{
+			Use: use,
+			Handler: func(inv *serpent.Invocation) error {
+				got = inv
+				return nil
+			},
+		}
+		require.NoError(t, cmd.Invoke(args...).Run())
+		return got
+	}
+
+	t.Run("Named", func(t *testing.T) {
+		t.Parallel()
+
+		inv := run("copy [flags] <src> <dst> [files...]", "a", "b", "c", "d")
+		require.Equal(t, "a", inv.Arg("src"))
+		require.Equal(t, "b", inv.Arg("dst"))
+		require.Equal(t, "c", inv.Arg("files"))
+		require.Equal(t, []string{"c", "d"}, inv.ArgSlice("files"))
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		t.Parallel()
+
+		inv := run("copy <src> [dst] <files>...", "a")
+		require.Equal(t, "a", inv.Arg("src"))
+		require.Empty(t, inv.Arg("dst"))
+		require.Nil(t, inv.ArgSlice("files"))
+	})
+
+	t.Run("SkipsFlags", func(t *testing.T) {
+		t.Parallel()
+
+		inv := run("completion [--shell <shell>] [-v] <name>", "a")
+		require.Equal(t, "a", inv.Arg("name"))
+	})
+
+	t.Run("Undeclared", func(t *testing.T) {
+		t.Parallel()
+
+		inv := run("copy <src>", "a")
+		require.Panics(t, func() { inv.Arg("dst") })
+	})
+}
+
+func TestCommand_Synopsis(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mode, region string
+		force        bool
+		args         []string
+	)
+	cmd := &serpent.Command{
+		Use: "copy",
+		Options: serpent.OptionSet{
+			{Name: "mode", Flag: "mode", Required: true, Value: serpent.EnumOf(&mode, "fast", "safe")},
+			{Name: "region", Flag: "region", Value: serpent.StringOf(&region)},
+			{Name: "force", Flag: "force", Required: true, Value: serpent.BoolOf(&force)},
+			{Name: "secret", Flag: "secret", Hidden: true, Value: serpent.StringOf(new(string))},
+		},
+		Arguments: []serpent.Argument{
+			{Name: "src"},
+			{Name: "dst"},
+			{Name: "files", Optional: true, Variadic: true},
+		},
+		Handler: func(inv *serpent.Invocation) error {
+			args = inv.ArgSlice("files")
+			return nil
+		},
+	}
+	require.Equal(t, "copy --mode <fast|safe> --force [flags] <src> <dst> [files...]", cmd.Synopsis())
+
+	// A Use with more than the name is kept.
+	require.Equal(t, "copy <a> <b>", (&serpent.Command{Use: "copy <a> <b>"}).Synopsis())
+
+	require.NoError(t, cmd.Invoke("--mode=fast", "--force", "a", "b", "c", "d").Run())
+	require.Equal(t, []string{"c", "d"}, args)
+
+	err := cmd.Invoke("--mode=fast", "--force", "a").Run()
+	require.ErrorContains(t, err, "missing argument <dst>")
+
+	cmd.Arguments = cmd.Arguments[:2]
+	err = cmd.Invoke("--mode=fast", "--force", "a", "b", "c").Run()
+	require.ErrorContains(t, err, `unexpected arguments ["c"]`)
+}
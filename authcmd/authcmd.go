@@ -0,0 +1,198 @@
+// Package authcmd provides login, logout and whoami commands for CLIs
+// talking to an API, storing the credentials obtained by an AuthProvider in
+// the keyring with the secret package, and middleware handing them to the
+// commands that need them.
+package authcmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/secret"
+)
+
+// ErrNotLoggedIn is returned when no credentials are stored.
+var ErrNotLoggedIn = errors.New("not logged in, see \"login\"")
+
+// credentialsKey is the keyring key credentials are stored under.
+const credentialsKey = "credentials"
+
+// Credentials are what an AuthProvider obtains when logging in.
+type Credentials struct {
+	Token string `json:"token"`
+	// Identity is who the token authenticates as, e.g. a user name or email,
+	// shown by whoami.
+	Identity string `json:"identity,omitempty"`
+	// Expiry is when the token expires, zero if it doesn't.
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// Expired reports whether the credentials have expired.
+func (c Credentials) Expired() bool {
+	return !c.Expiry.IsZero() && time.Now().After(c.Expiry)
+}
+
+// AuthProvider obtains credentials, e.g. with TokenPaste, DeviceCode or
+// BrowserCallback.
+type AuthProvider interface {
+	// Login obtains credentials, interacting with the user through the
+	// invocation's IO.
+	Login(inv *serpent.Invocation) (Credentials, error)
+}
+
+// Config configures the commands and middleware of the package.
+type Config struct {
+	// Service is the keyring service credentials are stored under, usually
+	// the name of the program.
+	Service  string
+	Provider AuthProvider
+	// Keyring stores the credentials. If nil, secret.DefaultKeyring is used.
+	Keyring secret.Keyring
+}
+
+func (c Config) keyring() secret.Keyring {
+	if c.Keyring != nil {
+		return c.Keyring
+	}
+	return secret.DefaultKeyring
+}
+
+// Load returns the stored credentials, or ErrNotLoggedIn.
+func (c Config) Load() (Credentials, error) {
+	var creds Credentials
+	raw, err := c.keyring().Get(c.Service, credentialsKey)
+	if errors.Is(err, secret.ErrNotFound) {
+		return creds, ErrNotLoggedIn
+	}
+	if err != nil {
+		return creds, fmt.Errorf("load credentials: %w", err)
+	}
+	if err := json.Unmarshal([]byte(raw), &creds); err != nil {
+		return creds, fmt.Errorf("decode credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// Save stores creds, replacing the previous ones.
+func (c Config) Save(creds Credentials) error {
+	byt, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	if err := c.keyring().Set(c.Service, credentialsKey, string(byt)); err != nil {
+		return fmt.Errorf("store credentials: %w", err)
+	}
+	return nil
+}
+
+// Commands returns the login, logout and whoami commands.
+func (c Config) Commands() []*serpent.Command {
+	return []*serpent.Command{c.LoginCommand(), c.LogoutCommand(), c.WhoAmICommand()}
+}
+
+// LoginCommand returns a "login" command obtaining credentials with the
+// Provider and storing them.
+func (c Config) LoginCommand() *serpent.Command {
+	return &serpent.Command{
+		Use:        "login",
+		Short:      "Log in and store the credentials in the keyring.",
+		Middleware: serpent.RequireNArgs(0),
+		Handler: func(inv *serpent.Invocation) error {
+			creds, err := c.Provider.Login(inv)
+			if err != nil {
+				return err
+			}
+			if creds.Token == "" {
+				return errors.New("login returned no token")
+			}
+			if err := c.Save(creds); err != nil {
+				return err
+			}
+			if creds.Identity != "" {
+				_, _ = fmt.Fprintf(inv.Stdout, "Logged in as %s.\n", serpent.Keyword(creds.Identity))
+			} else {
+				_, _ = fmt.Fprintln(inv.Stdout, "Logged in.")
+			}
+			return nil
+		},
+	}
+}
+
+// LogoutCommand returns a "logout" command deleting the stored credentials.
+func (c Config) LogoutCommand() *serpent.Command {
+	return &serpent.Command{
+		Use:        "logout",
+		Short:      "Delete the credentials stored in the keyring.",
+		Middleware: serpent.RequireNArgs(0),
+		Handler: func(inv *serpent.Invocation) error {
+			err := c.keyring().Delete(c.Service, credentialsKey)
+			if errors.Is(err, secret.ErrNotFound) {
+				_, _ = fmt.Fprintln(inv.Stdout, "Not logged in.")
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("delete credentials: %w", err)
+			}
+			_, _ = fmt.Fprintln(inv.Stdout, "Logged out.")
+			return nil
+		},
+	}
+}
+
+// WhoAmICommand returns a "whoami" command printing the identity of the
+// stored credentials.
+func (c Config) WhoAmICommand() *serpent.Command {
+	return &serpent.Command{
+		Use:        "whoami",
+		Short:      "Show who you're logged in as.",
+		Middleware: serpent.RequireNArgs(0),
+		Handler: func(inv *serpent.Invocation) error {
+			creds, err := c.Load()
+			if err != nil {
+				return err
+			}
+			identity := creds.Identity
+			if identity == "" {
+				identity = "unknown identity"
+			}
+			_, _ = fmt.Fprintln(inv.Stdout, identity)
+			switch {
+			case creds.Expired():
+				_, _ = fmt.Fprintf(inv.Stdout, "Expired %s.\n", creds.Expiry.Local().Format(time.RFC1123))
+			case !creds.Expiry.IsZero():
+				_, _ = fmt.Fprintf(inv.Stdout, "Expires %s.\n", creds.Expiry.Local().Format(time.RFC1123))
+			}
+			return nil
+		},
+	}
+}
+
+type contextKey struct{}
+
+// Middleware returns middleware loading the stored credentials into the
+// invocation's context, see FromContext. It fails with ErrNotLoggedIn if
+// there are none, and if they've expired.
+func (c Config) Middleware() serpent.MiddlewareFunc {
+	return func(next serpent.HandlerFunc) serpent.HandlerFunc {
+		return func(inv *serpent.Invocation) error {
+			creds, err := c.Load()
+			if err != nil {
+				return err
+			}
+			if creds.Expired() {
+				return fmt.Errorf("credentials expired: %w", ErrNotLoggedIn)
+			}
+			return next(inv.WithContext(context.WithValue(inv.Context(), contextKey{}, creds)))
+		}
+	}
+}
+
+// FromContext returns the credentials loaded by Middleware.
+func FromContext(ctx context.Context) (Credentials, bool) {
+	creds, ok := ctx.Value(contextKey{}).(Credentials)
+	return creds, ok
+}
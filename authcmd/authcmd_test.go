@@ -0,0 +1,194 @@
+package authcmd_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/authcmd"
+	"github.com/bketelsen/serpent/secret"
+	"github.com/bketelsen/serpent/ui"
+)
+
+// newApp returns a root command with the auth commands of cfg and an "api"
+// command requiring credentials, reporting them to got.
+func newApp(cfg authcmd.Config, got *authcmd.Credentials) *serpent.Command {
+	return &serpent.Command{
+		Use:     "app",
+		Options: serpent.OptionSet{ui.NoClipboardOption("")},
+		Children: append(cfg.Commands(), &serpent.Command{
+			Use:        "api",
+			Middleware: cfg.Middleware(),
+			Handler: func(inv *serpent.Invocation) error {
+				creds, ok := authcmd.FromContext(inv.Context())
+				if !ok {
+					return nil
+				}
+				*got = creds
+				return nil
+			},
+		}),
+	}
+}
+
+func run(t *testing.T, cmd *serpent.Command, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	inv := cmd.Invoke(args...)
+	inv.Stdin = strings.NewReader(stdin)
+	inv.Stdout = &stdout
+	inv.Stderr = &stderr
+	err := inv.Run()
+	return stdout.String(), err
+}
+
+func TestTokenPaste(t *testing.T) {
+	t.Parallel()
+
+	cfg := authcmd.Config{
+		Service: "app",
+		Keyring: secret.NewMemoryKeyring(),
+		Provider: authcmd.TokenPaste{
+			Verify: func(_ context.Context, token string) (authcmd.Credentials, error) {
+				return authcmd.Credentials{Identity: "alice@example.com"}, nil
+			},
+		},
+	}
+	var got authcmd.Credentials
+	app := newApp(cfg, &got)
+
+	_, err := run(t, app, "", "api")
+	require.ErrorIs(t, err, authcmd.ErrNotLoggedIn)
+
+	out, err := run(t, app, "tok-123\n", "login")
+	require.NoError(t, err)
+	require.Contains(t, out, "Logged in as alice@example.com.")
+
+	out, err = run(t, app, "", "whoami")
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.com\n", out)
+
+	_, err = run(t, app, "", "api")
+	require.NoError(t, err)
+	require.Equal(t, "tok-123", got.Token)
+
+	out, err = run(t, app, "", "logout")
+	require.NoError(t, err)
+	require.Contains(t, out, "Logged out.")
+	_, err = run(t, app, "", "whoami")
+	require.ErrorIs(t, err, authcmd.ErrNotLoggedIn)
+}
+
+func TestExpiredCredentials(t *testing.T) {
+	t.Parallel()
+
+	cfg := authcmd.Config{Service: "app", Keyring: secret.NewMemoryKeyring()}
+	require.NoError(t, cfg.Save(authcmd.Credentials{Token: "old", Expiry: time.Now().Add(-time.Hour)}))
+
+	var got authcmd.Credentials
+	_, err := run(t, newApp(cfg, &got), "", "api")
+	require.ErrorIs(t, err, authcmd.ErrNotLoggedIn)
+	require.ErrorContains(t, err, "expired")
+}
+
+func TestDeviceCode(t *testing.T) {
+	t.Parallel()
+
+	var polls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "cli", r.Form.Get("client_id"))
+		require.Equal(t, "read write", r.Form.Get("scope"))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "dev-1",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "dev-1", r.Form.Get("device_code"))
+		polls++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "tok-device",
+			"expires_in":   3600,
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cfg := authcmd.Config{
+		Service: "app",
+		Keyring: secret.NewMemoryKeyring(),
+		Provider: authcmd.DeviceCode{
+			ClientID:      "cli",
+			DeviceAuthURL: srv.URL + "/device",
+			TokenURL:      srv.URL + "/token",
+			Scopes:        []string{"read", "write"},
+			Identify: func(context.Context, string) (string, error) {
+				return "bob", nil
+			},
+		},
+	}
+	var got authcmd.Credentials
+	// The user code isn't copied to the clipboard of the machine running
+	// the tests.
+	out, err := run(t, newApp(cfg, &got), "", "login", "--no-clipboard")
+	require.NoError(t, err)
+	require.Contains(t, out, "Logged in as bob.")
+	require.Equal(t, 1, polls)
+
+	creds, err := cfg.Load()
+	require.NoError(t, err)
+	require.Equal(t, "tok-device", creds.Token)
+	require.WithinDuration(t, time.Now().Add(time.Hour), creds.Expiry, time.Minute)
+}
+
+func TestBrowserCallback(t *testing.T) {
+	t.Parallel()
+
+	cfg := authcmd.Config{
+		Service: "app",
+		Keyring: secret.NewMemoryKeyring(),
+		Provider: authcmd.BrowserCallback{
+			AuthURL: func(redirectURI, state string) string {
+				// The identity provider redirects straight back.
+				return redirectURI + "?" + url.Values{"code": {"code-1"}, "state": {state}}.Encode()
+			},
+			Open: func(u string) error {
+				go func() {
+					resp, err := http.Get(u) //nolint:noctx // Test browser.
+					if err == nil {
+						_ = resp.Body.Close()
+					}
+				}()
+				return nil
+			},
+			Exchange: func(_ context.Context, code, redirectURI string) (authcmd.Credentials, error) {
+				require.Equal(t, "code-1", code)
+				require.True(t, strings.HasPrefix(redirectURI, "http://127.0.0.1:"))
+				return authcmd.Credentials{Token: "tok-browser", Identity: "carol"}, nil
+			},
+		},
+	}
+	var got authcmd.Credentials
+	out, err := run(t, newApp(cfg, &got), "", "login")
+	require.NoError(t, err)
+	require.Contains(t, out, "Logged in as carol.")
+
+	creds, err := cfg.Load()
+	require.NoError(t, err)
+	require.Equal(t, "tok-browser", creds.Token)
+}
@@ -0,0 +1,311 @@
+package authcmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+// TokenPaste logs in with a token the user pastes, e.g. one created on the
+// settings page of the service.
+type TokenPaste struct {
+	// Prompt is shown before reading the token, e.g. "Paste a token from
+	// https://example.com/settings/tokens:".
+	Prompt string
+	// Verify, if set, checks the token, e.g. with a request to the API, and
+	// returns the credentials, with their identity.
+	Verify func(ctx context.Context, token string) (Credentials, error)
+}
+
+var _ AuthProvider = TokenPaste{}
+
+func (p TokenPaste) Login(inv *serpent.Invocation) (Credentials, error) {
+	prompt := p.Prompt
+	if prompt == "" {
+		prompt = "Paste your token:"
+	}
+	_, _ = fmt.Fprint(inv.Stderr, prompt+" ")
+
+	var token string
+	if f, ok := inv.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		// The token isn't echoed.
+		byt, err := term.ReadPassword(int(f.Fd()))
+		_, _ = fmt.Fprintln(inv.Stderr)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("read token: %w", err)
+		}
+		token = string(byt)
+	} else {
+		line, err := bufio.NewReader(inv.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return Credentials{}, fmt.Errorf("read token: %w", err)
+		}
+		token = line
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return Credentials{}, errors.New("no token given")
+	}
+
+	if p.Verify == nil {
+		return Credentials{Token: token}, nil
+	}
+	creds, err := p.Verify(inv.Context(), token)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("verify token: %w", err)
+	}
+	if creds.Token == "" {
+		creds.Token = token
+	}
+	return creds, nil
+}
+
+// DeviceCode logs in with the OAuth 2.0 device authorization grant (RFC
+// 8628): the user opens a URL on any device and enters a code, while the
+// command polls for the token. Requests are made with the invocation's
+// HTTPClient.
+type DeviceCode struct {
+	ClientID string
+	// DeviceAuthURL is the device authorization endpoint.
+	DeviceAuthURL string
+	// TokenURL is the token endpoint.
+	TokenURL string
+	Scopes   []string
+	// Identify, if set, returns the identity of the token.
+	Identify func(ctx context.Context, token string) (string, error)
+}
+
+var _ AuthProvider = DeviceCode{}
+
+// defaultDevicePollInterval is the polling interval when the server doesn't
+// set one, as RFC 8628 specifies.
+const defaultDevicePollInterval = 5 * time.Second
+
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (p DeviceCode) Login(inv *serpent.Invocation) (Credentials, error) {
+	ctx := inv.Context()
+	client, err := inv.HTTPClient()
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	form := url.Values{"client_id": {p.ClientID}}
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	var auth deviceAuthorization
+	if err := postForm(ctx, client, p.DeviceAuthURL, form, &auth); err != nil {
+		return Credentials{}, fmt.Errorf("device authorization: %w", err)
+	}
+	if auth.VerificationURIComplete != "" {
+		_, _ = fmt.Fprintf(inv.Stderr, "Open %s to log in, and check that it shows the code %s.\n", auth.VerificationURIComplete, serpent.Keyword(auth.UserCode))
+	} else {
+		copied := ""
+		if ui.CopyToClipboard(inv, auth.UserCode) == nil {
+			copied = " (copied to the clipboard)"
+		}
+		_, _ = fmt.Fprintf(inv.Stderr, "Open %s and enter the code %s%s to log in.\n", auth.VerificationURI, serpent.Keyword(auth.UserCode), copied)
+	}
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	form = url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {auth.DeviceCode},
+		"client_id":   {p.ClientID},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && inv.Context().Err() == nil {
+				return Credentials{}, errors.New("the device code expired, run login again")
+			}
+			return Credentials{}, serpent.Canceled
+		case <-time.After(interval):
+		}
+
+		var tok tokenResponse
+		err := postForm(ctx, client, p.TokenURL, form, &tok)
+		switch {
+		case err != nil && tok.Error == "":
+			return Credentials{}, fmt.Errorf("poll token: %w", err)
+		case tok.Error == "authorization_pending":
+			continue
+		case tok.Error == "slow_down":
+			interval += 5 * time.Second
+			continue
+		case tok.Error != "":
+			return Credentials{}, fmt.Errorf("login failed: %s", oauthError(tok.Error, tok.Description))
+		}
+
+		creds := Credentials{Token: tok.AccessToken}
+		if tok.ExpiresIn > 0 {
+			creds.Expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+		}
+		if p.Identify != nil {
+			creds.Identity, err = p.Identify(inv.Context(), creds.Token)
+			if err != nil {
+				return Credentials{}, fmt.Errorf("identify: %w", err)
+			}
+		}
+		return creds, nil
+	}
+}
+
+// postForm posts form to endpoint and decodes the JSON response into v. The
+// response is decoded even if the status is an error, as OAuth errors are
+// returned with status 400.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decodeErr := json.NewDecoder(resp.Body).Decode(v)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+	return decodeErr
+}
+
+func oauthError(code, description string) string {
+	if description != "" {
+		return code + ": " + description
+	}
+	return code
+}
+
+// BrowserCallback logs in with a browser redirecting to a local callback,
+// like the OAuth 2.0 authorization code flow for native apps (RFC 8252): a
+// server listens on a loopback port for the redirect carrying the
+// authorization code, which Exchange trades for credentials.
+type BrowserCallback struct {
+	// AuthURL returns the URL the user opens to log in, redirecting to
+	// redirectURI with the "code" and the given "state" query parameters.
+	AuthURL func(redirectURI, state string) string
+	// Exchange trades the authorization code for credentials.
+	Exchange func(ctx context.Context, code, redirectURI string) (Credentials, error)
+	// Open, if set, opens the URL in a browser instead of ui.OpenURL. The
+	// URL is printed either way, in case the browser doesn't open.
+	Open func(url string) error
+}
+
+var _ AuthProvider = BrowserCallback{}
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+func (p BrowserCallback) Login(inv *serpent.Invocation) (Credentials, error) {
+	ctx := inv.Context()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return Credentials{}, fmt.Errorf("listen for callback: %w", err)
+	}
+	defer ln.Close()
+
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return Credentials{}, err
+	}
+	state := hex.EncodeToString(b[:])
+	redirectURI := fmt.Sprintf("http://%s/callback", ln.Addr())
+
+	results := make(chan callbackResult, 1)
+	srv := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(w, r)
+				return
+			}
+			q := r.URL.Query()
+			var res callbackResult
+			switch {
+			case q.Get("state") != state:
+				http.Error(w, "Invalid state.", http.StatusBadRequest)
+				return
+			case q.Get("error") != "":
+				res.err = fmt.Errorf("login failed: %s", oauthError(q.Get("error"), q.Get("error_description")))
+				_, _ = fmt.Fprintln(w, "Login failed, see the terminal.")
+			default:
+				res.code = q.Get("code")
+				_, _ = fmt.Fprintln(w, "Logged in, you can close this window.")
+			}
+			select {
+			case results <- res:
+			default:
+			}
+		}),
+	}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	authURL := p.AuthURL(redirectURI, state)
+	if p.Open != nil {
+		_, _ = fmt.Fprintf(inv.Stderr, "Open %s to log in.\n", authURL)
+		if err := p.Open(authURL); err != nil {
+			inv.Warn("Failed to open the browser.", err.Error())
+		}
+	} else if err := ui.OpenURL(inv, authURL); err != nil {
+		return Credentials{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return Credentials{}, serpent.Canceled
+	case res := <-results:
+		if res.err != nil {
+			return Credentials{}, res.err
+		}
+		creds, err := p.Exchange(ctx, res.code, redirectURI)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("exchange code: %w", err)
+		}
+		return creds, nil
+	}
+}
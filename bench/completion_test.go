@@ -0,0 +1,75 @@
+package bench_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/bench"
+)
+
+func complete(b testing.TB, cmd *serpent.Command, args ...string) {
+	inv := cmd.Invoke(args...)
+	inv.Stdout = io.Discard
+	inv.Environ.Set(serpent.CompletionModeEnv, "1")
+	err := inv.Run()
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkCompletion(b *testing.B) {
+	for _, size := range []int{10, 100, 500} {
+		cmd := bench.Tree(size)
+		b.Run(fmt.Sprintf("Subcommands/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				complete(b, cmd, "")
+			}
+		})
+		b.Run(fmt.Sprintf("Flags/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				complete(b, cmd, "cmd1", "--verbose", "-")
+			}
+		})
+	}
+}
+
+func BenchmarkRun(b *testing.B) {
+	for _, size := range []int{10, 100, 500} {
+		cmd := bench.Tree(size)
+		b.Run(fmt.Sprintf("%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				err := cmd.Invoke("cmd1", "--verbose").Run()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestCompletionBudget guards against regressions that would make
+// completions noticeably slow on large trees. Wall-clock timing is flaky on
+// loaded machines, so it only runs with SERPENT_COMPLETION_BUDGET set, e.g.
+// from make bench.
+func TestCompletionBudget(t *testing.T) {
+	if os.Getenv("SERPENT_COMPLETION_BUDGET") == "" {
+		t.Skip("set SERPENT_COMPLETION_BUDGET to run the timing test")
+	}
+
+	cmd := bench.Tree(500)
+	// Warm up.
+	complete(t, cmd, "")
+
+	const runs = 20
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		complete(t, cmd, "cmd1", "--verbose", "-")
+	}
+	if avg := time.Since(start) / runs; avg > 5*time.Millisecond {
+		t.Fatalf("completion took %v on average, want < 5ms", avg)
+	}
+}
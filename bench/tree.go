@@ -0,0 +1,66 @@
+// Package bench contains benchmarks for serpent's hot paths, along with
+// helpers for building large synthetic command trees.
+package bench
+
+import (
+	"fmt"
+
+	"github.com/bketelsen/serpent"
+)
+
+// Tree returns a root command with n subcommands, each carrying a handful
+// of options that exercise env, YAML, and default handling.
+func Tree(n int) *serpent.Command {
+	root := &serpent.Command{
+		Use:   "root",
+		Short: "Root of a synthetic command tree.",
+	}
+	for i := 0; i < n; i++ {
+		var (
+			name    string
+			verbose bool
+			count   int64
+			tags    []string
+		)
+		root.AddSubcommands(&serpent.Command{
+			Use:   fmt.Sprintf("cmd%d [args...]", i),
+			Short: fmt.Sprintf("Synthetic command %d.", i),
+			Options: serpent.OptionSet{
+				{
+					Name:        "name",
+					Description: "The name to use.",
+					Flag:        "name",
+					Env:         fmt.Sprintf("CMD%d_NAME", i),
+					YAML:        "name",
+					Default:     "default",
+					Value:       serpent.StringOf(&name),
+				},
+				{
+					Name:        "verbose",
+					Description: "Enable verbose output.",
+					Flag:        "verbose",
+					Env:         fmt.Sprintf("CMD%d_VERBOSE", i),
+					Default:     "false",
+					Value:       serpent.BoolOf(&verbose),
+				},
+				{
+					Name:        "count",
+					Description: "The number of times to run.",
+					Flag:        "count",
+					Default:     "1",
+					Value:       serpent.Int64Of(&count),
+				},
+				{
+					Name:        "tags",
+					Description: "Tags to apply.",
+					Flag:        "tags",
+					Value:       serpent.StringArrayOf(&tags),
+				},
+			},
+			Handler: func(inv *serpent.Invocation) error {
+				return nil
+			},
+		})
+	}
+	return root
+}
@@ -0,0 +1,122 @@
+package serpent
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime"
+	"strings"
+
+	"github.com/bketelsen/serpent/internal/browser"
+)
+
+// BugReportCommand returns a "bug-report" command that opens a new issue
+// prefilled with the version, OS and the last command line recorded by
+// HistoryMiddleware in the default history file, sanitized as by
+// SanitizeArgs. The issue is created in the repository of the root's
+// ContactInfo, see ContactInfo.IssuesLink.
+func BugReportCommand() *Command {
+	var noOpen bool
+	return &Command{
+		Use:        "bug-report",
+		Short:      "Report a bug.",
+		Middleware: RequireNArgs(0),
+		Options: OptionSet{
+			{
+				Name:        "no-open",
+				Flag:        "no-open",
+				Description: "Print the issue URL instead of opening it in a browser.",
+				Value:       BoolOf(&noOpen),
+			},
+		},
+		Handler: func(inv *Invocation) error {
+			root := inv.Command
+			for root.Parent != nil {
+				root = root.Parent
+			}
+			contact := root.contactInfo()
+			if contact == nil || contact.IssuesLink() == "" {
+				return errors.New("no issue tracker configured")
+			}
+
+			body, err := bugReportBody(inv, root)
+			if err != nil {
+				return err
+			}
+			link, ok := newIssueURL(contact.IssuesLink(), body)
+			if !ok {
+				// The tracker doesn't support prefilled issues, the user
+				// has to paste the report themselves.
+				_, _ = fmt.Fprintf(inv.Stdout, "Report the bug at %s with the following details:\n\n%s", contact.IssuesLink(), body)
+				return nil
+			}
+
+			_, _ = fmt.Fprintln(inv.Stdout, link)
+			if noOpen {
+				return nil
+			}
+			if _, err := browser.Open(inv.Context(), inv.Environ.Get, link); err != nil {
+				inv.Warn("Failed to open a browser.", err.Error())
+			}
+			return nil
+		},
+	}
+}
+
+// bugReportBody returns the markdown body of a bug report for root.
+func bugReportBody(inv *Invocation, root *Command) (string, error) {
+	version := "unknown"
+	if root.Version != "" {
+		version = root.Version
+		if root.VersionCommit != "" {
+			version += " (" + root.VersionCommit + ")"
+		}
+	}
+
+	commandLine := "unknown"
+	entries, err := ReadHistory(historyPath(inv, ""))
+	if err != nil {
+		return "", fmt.Errorf("reading history: %w", err)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Command == inv.Command.FullName() {
+			continue
+		}
+		commandLine = strings.Join(append([]string{root.Name()}, sanitizeArgs(root, e.Args, false)...), " ")
+		break
+	}
+
+	var sb strings.Builder
+	_, _ = sb.WriteString("### Description\n\n<!-- What happened, and what did you expect to happen? -->\n\n")
+	_, _ = sb.WriteString("### Environment\n\n")
+	_, _ = fmt.Fprintf(&sb, "- Version: %s\n", version)
+	_, _ = fmt.Fprintf(&sb, "- OS: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	_, _ = fmt.Fprintf(&sb, "- Last command: `%s`\n", commandLine)
+	return sb.String(), nil
+}
+
+// newIssueURL returns the URL of a new issue with body in the GitHub or
+// GitLab issue tracker at issues.
+func newIssueURL(issues, body string) (string, bool) {
+	issues = strings.TrimSuffix(issues, "/")
+	u, err := url.Parse(issues)
+	if err != nil {
+		return "", false
+	}
+	q := url.Values{}
+	switch {
+	case u.Host == "github.com" && strings.HasSuffix(u.Path, "/issues"):
+		u.Path += "/new"
+		q.Set("body", body)
+	case u.Host == "github.com" && strings.HasSuffix(u.Path, "/issues/new"):
+		q.Set("body", body)
+	case u.Host == "gitlab.com" && strings.HasSuffix(u.Path, "/-/issues"):
+		u.Path += "/new"
+		q.Set("issue[description]", body)
+	default:
+		return "", false
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), true
+}
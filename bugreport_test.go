@@ -0,0 +1,118 @@
+package serpent_test
+
+import (
+	"net/url"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestBugReportCommand(t *testing.T) {
+	t.Parallel()
+
+	makeRoot := func(contact *serpent.ContactInfo) *serpent.Command {
+		root := &serpent.Command{
+			Use:           "app",
+			Version:       "1.2.3",
+			VersionCommit: "abc123",
+			ContactInfo:   contact,
+			Options: serpent.OptionSet{
+				{Name: "token", Flag: "token", Annotations: serpent.Annotations{}.Mark(serpent.AnnotationSecret, "true"), Value: serpent.StringOf(new(string))},
+				{Name: "format", Flag: "format", Value: serpent.StringOf(new(string))},
+				{Name: "debug", Flag: "debug", Value: serpent.BoolOf(new(bool))},
+			},
+			Children: []*serpent.Command{
+				{
+					Use: "login <url>",
+					Handler: func(inv *serpent.Invocation) error {
+						return nil
+					},
+				},
+				serpent.BugReportCommand(),
+			},
+		}
+		root.Walk(func(c *serpent.Command) {
+			c.Middleware = serpent.HistoryMiddleware("")
+		})
+		return root
+	}
+	run := func(stateDir string, contact *serpent.ContactInfo, args ...string) (string, error) {
+		inv := makeRoot(contact).Invoke(args...)
+		inv.Environ.Set("XDG_STATE_HOME", stateDir)
+		io := fakeIO(inv)
+		err := inv.Run()
+		return io.Stdout.String(), err
+	}
+
+	t.Run("GitHub", func(t *testing.T) {
+		t.Parallel()
+
+		contact := &serpent.ContactInfo{Repo: "https://github.com/example/app"}
+		stateDir := t.TempDir()
+		_, err := run(stateDir, contact, "--debug", "--token", "hunter2", "--format", "json", "login", "https://secret.example.com", "--token=hunter3")
+		require.NoError(t, err)
+
+		out, err := run(stateDir, contact, "bug-report", "--no-open")
+		require.NoError(t, err)
+		u, err := url.Parse(strings.TrimSpace(out))
+		require.NoError(t, err)
+		require.Equal(t, "https://github.com/example/app/issues/new", u.Scheme+"://"+u.Host+u.Path)
+
+		body := u.Query().Get("body")
+		require.Contains(t, body, "- Version: 1.2.3 (abc123)\n")
+		require.Contains(t, body, "- OS: "+runtime.GOOS+"/"+runtime.GOARCH+"\n")
+		require.Contains(t, body, "- Last command: `app --debug --token *** --format json login <arg> --token=***`\n")
+		require.NotContains(t, body, "hunter")
+		require.NotContains(t, body, "secret.example.com")
+
+		// The bug report itself isn't reported.
+		out, err = run(stateDir, contact, "bug-report", "--no-open")
+		require.NoError(t, err)
+		require.Contains(t, out, url.QueryEscape("app --debug --token *** --format json login"))
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		t.Parallel()
+
+		inv := makeRoot(&serpent.ContactInfo{Repo: "https://github.com/example/app"}).Invoke("bug-report")
+		inv.Environ.Set("XDG_STATE_HOME", t.TempDir())
+		_ = fakeIO(inv)
+		var opened string
+		inv = ui.WithURLOpener(inv, func(url string) error {
+			opened = url
+			return nil
+		})
+		require.NoError(t, inv.Run())
+		require.True(t, strings.HasPrefix(opened, "https://github.com/example/app/issues/new?body="), opened)
+	})
+
+	t.Run("OtherTracker", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := run(t.TempDir(), &serpent.ContactInfo{Issues: "https://bugs.example.com"}, "bug-report")
+		require.NoError(t, err)
+		require.Contains(t, out, "Report the bug at https://bugs.example.com with the following details:")
+		require.Contains(t, out, "- Last command: `unknown`")
+	})
+
+	t.Run("NoContactInfo", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := run(t.TempDir(), nil, "bug-report")
+		require.ErrorContains(t, err, "no issue tracker configured")
+	})
+}
+
+func TestContactInfo_IssuesLink(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "https://github.com/example/app/issues", (&serpent.ContactInfo{Repo: "https://github.com/example/app/"}).IssuesLink())
+	require.Equal(t, "https://gitlab.com/example/app/-/issues", (&serpent.ContactInfo{Repo: "https://gitlab.com/example/app"}).IssuesLink())
+	require.Equal(t, "https://bugs.example.com", (&serpent.ContactInfo{Repo: "https://github.com/example/app", Issues: "https://bugs.example.com"}).IssuesLink())
+	require.Empty(t, (&serpent.ContactInfo{Repo: "https://example.com/app"}).IssuesLink())
+}
@@ -0,0 +1,148 @@
+package serpent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bketelsen/serpent/files"
+)
+
+// AnnotationNoCache marks the option bypassing cached results, see
+// NoCacheOption.
+const AnnotationNoCache = "serpent.no_cache"
+
+// NoCacheOption returns a --no-cache option making commands using
+// CacheMiddleware run rather than reuse their cached results, which are then
+// replaced by the fresh ones.
+//
+// The option is usually added to the root command.
+func NoCacheOption() Option {
+	var disabled bool
+	return Option{
+		Name:        "no-cache",
+		Description: "Run the command rather than reuse its cached results.",
+		Flag:        "no-cache",
+		Value:       BoolOf(&disabled),
+		Annotations: Annotations{}.Mark(AnnotationNoCache, "true"),
+	}
+}
+
+// cachedResult is a cached run of a command.
+type cachedResult struct {
+	Created time.Time `json:"created"`
+	Stdout  []byte    `json:"stdout"`
+}
+
+// CacheMiddleware returns middleware caching the output of successful runs of
+// the command for ttl, for expensive read-only commands such as listing a
+// large remote inventory. While cached, the output is written to Stdout
+// without running the handler. Failed runs aren't cached, so that transient
+// errors are retried, and neither is Stderr.
+//
+// Runs with the same key share the cache. key returns the key of an
+// invocation, or "" to run it without the cache; a nil key uses the command
+// arguments and option values, so it must be set if the output depends on
+// anything else, such as a file. The NoCacheOption of the command or one of
+// its parents makes it run regardless. Results are kept in
+// $XDG_CACHE_HOME/<root command>/results.
+func CacheMiddleware(key func(inv *Invocation) string, ttl time.Duration) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			var k string
+			if key != nil {
+				if k = key(inv); k == "" {
+					return next(inv)
+				}
+			} else {
+				k = defaultCacheKey(inv)
+			}
+			// Only a hash of the key is stored, as it may hold secrets.
+			sum := sha256.Sum256([]byte(inv.Command.FullName() + "\x00" + k))
+			path := filepath.Join(cacheDir(inv), "results", hex.EncodeToString(sum[:])+".json")
+
+			if !inv.noCacheOption() {
+				if res, ok := readCachedResult(path); ok && time.Since(res.Created) < ttl {
+					_, err := inv.Stdout.Write(res.Stdout)
+					return err
+				}
+			}
+
+			var buf bytes.Buffer
+			err := next(inv.with(func(i *Invocation) {
+				i.Stdout = io.MultiWriter(inv.Stdout, &buf)
+			}))
+			if err != nil {
+				return err
+			}
+
+			byt, err := json.Marshal(cachedResult{Created: time.Now().UTC(), Stdout: buf.Bytes()})
+			if err == nil {
+				err = files.WriteFile(path, byt, files.PrivateFile)
+			}
+			if err != nil {
+				inv.Warn("Failed to cache the result.", err.Error())
+			}
+			return nil
+		}
+	}
+}
+
+// readCachedResult returns the result cached at path. Unreadable results are
+// ignored, as the command can run instead.
+func readCachedResult(path string) (cachedResult, bool) {
+	var res cachedResult
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		return res, false
+	}
+	if err := json.Unmarshal(byt, &res); err != nil {
+		return res, false
+	}
+	return res, true
+}
+
+// defaultCacheKey returns the arguments and the values of the options of the
+// invocation, bar the NoCacheOption, whether they're set by flags, the
+// environment or a config file.
+func defaultCacheKey(inv *Invocation) string {
+	parts := append([]string(nil), inv.Args...)
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Value == nil || opt.Annotations.IsSet(AnnotationNoCache) {
+				continue
+			}
+			parts = append(parts, opt.Name+"="+opt.Value.String())
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// cacheDir returns the XDG cache directory of the root command, e.g.
+// ~/.cache/app.
+func cacheDir(inv *Invocation) string {
+	root := inv.Command
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return files.CacheDir(inv.Environ.Get, root.Name())
+}
+
+// noCacheOption reports whether the closest option annotated with
+// AnnotationNoCache is set.
+func (inv *Invocation) noCacheOption() bool {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Annotations.IsSet(AnnotationNoCache) && opt.Value != nil {
+				return opt.Value.String() == "true"
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package serpent_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	t.Parallel()
+
+	type app struct {
+		cmd  *serpent.Command
+		runs int
+		fail bool
+	}
+	newApp := func(key func(*serpent.Invocation) string, ttl time.Duration) *app {
+		a := &app{}
+		var region string
+		a.cmd = &serpent.Command{
+			Use:     "app",
+			Options: serpent.OptionSet{serpent.NoCacheOption()},
+			Children: []*serpent.Command{{
+				Use:        "list",
+				Middleware: serpent.CacheMiddleware(key, ttl),
+				Options: serpent.OptionSet{{
+					Name:  "region",
+					Flag:  "region",
+					Value: serpent.StringOf(&region),
+				}},
+				Handler: func(inv *serpent.Invocation) error {
+					a.runs++
+					if a.fail {
+						return errors.New("unavailable")
+					}
+					_, _ = fmt.Fprintf(inv.Stdout, "run %d in %q\n", a.runs, region)
+					return nil
+				},
+			}},
+		}
+		return a
+	}
+	run := func(t *testing.T, a *app, cache string, args ...string) (string, error) {
+		t.Helper()
+		inv := a.cmd.Invoke(args...)
+		inv.Environ.Set("XDG_CACHE_HOME", cache)
+		io := fakeIO(inv)
+		err := inv.Run()
+		return io.Stdout.String(), err
+	}
+
+	t.Run("Cached", func(t *testing.T) {
+		t.Parallel()
+
+		cache := t.TempDir()
+		a := newApp(nil, time.Hour)
+		for i := 0; i < 2; i++ {
+			out, err := run(t, a, cache, "list", "--region", "eu")
+			require.NoError(t, err)
+			require.Equal(t, "run 1 in \"eu\"\n", out)
+		}
+		require.Equal(t, 1, a.runs)
+
+		// Other options are cached separately.
+		out, err := run(t, a, cache, "list", "--region", "us")
+		require.NoError(t, err)
+		require.Equal(t, "run 2 in \"us\"\n", out)
+
+		// --no-cache runs the command and refreshes the cache.
+		out, err = run(t, a, cache, "list", "--region", "eu", "--no-cache")
+		require.NoError(t, err)
+		require.Equal(t, "run 3 in \"eu\"\n", out)
+		require.Equal(t, 3, a.runs)
+		// Options keep their values across invocations of a command, so a
+		// new one is used.
+		b := newApp(nil, time.Hour)
+		out, err = run(t, b, cache, "list", "--region", "eu")
+		require.NoError(t, err)
+		require.Equal(t, "run 3 in \"eu\"\n", out)
+		require.Zero(t, b.runs)
+
+		entries, err := os.ReadDir(filepath.Join(cache, "app", "results"))
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		info, err := entries[0].Info()
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		t.Parallel()
+
+		cache := t.TempDir()
+		a := newApp(nil, time.Hour)
+		a.fail = true
+		_, err := run(t, a, cache, "list")
+		require.ErrorContains(t, err, "unavailable")
+
+		a.fail = false
+		out, err := run(t, a, cache, "list")
+		require.NoError(t, err)
+		require.Equal(t, "run 2 in \"\"\n", out)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		t.Parallel()
+
+		cache := t.TempDir()
+		a := newApp(nil, 0)
+		for i := 0; i < 2; i++ {
+			_, err := run(t, a, cache, "list")
+			require.NoError(t, err)
+		}
+		require.Equal(t, 2, a.runs)
+	})
+
+	t.Run("Key", func(t *testing.T) {
+		t.Parallel()
+
+		cache := t.TempDir()
+		// The key ignores the region, and an empty key bypasses the cache.
+		a := newApp(func(inv *serpent.Invocation) string {
+			if inv.Environ.Get("BYPASS") != "" {
+				return ""
+			}
+			return "all"
+		}, time.Hour)
+		_, err := run(t, a, cache, "list", "--region", "eu")
+		require.NoError(t, err)
+		out, err := run(t, a, cache, "list", "--region", "us")
+		require.NoError(t, err)
+		require.Equal(t, "run 1 in \"eu\"\n", out)
+
+		inv := a.cmd.Invoke("list")
+		inv.Environ.Set("XDG_CACHE_HOME", cache)
+		inv.Environ.Set("BYPASS", "1")
+		fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Equal(t, 2, a.runs)
+	})
+}
@@ -0,0 +1,64 @@
+package serpent
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encryptedTag is the YAML tag of the encrypted values of Sensitive options,
+// e.g. "token: !encrypted <base64>".
+const encryptedTag = "!encrypted"
+
+// ValueCipher encrypts and decrypts the values of Sensitive options in
+// config files, e.g. secret.Cipher.
+type ValueCipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// ConfigCipher encrypts the values of Sensitive options written by
+// OptionSet.MarshalYAML, and decrypts the encrypted values of config files.
+// It's nil by default, in which case writing Sensitive options and reading
+// encrypted values fails. Plaintext values of Sensitive options are read
+// as is, so users may write them by hand.
+var ConfigCipher ValueCipher
+
+// errNoConfigCipher is returned for Sensitive options when ConfigCipher is
+// nil.
+var errNoConfigCipher = errors.New("no ConfigCipher to encrypt sensitive values")
+
+// encryptedYAMLNode returns the value of the Sensitive option o encrypted
+// with ConfigCipher.
+func (o *Option) encryptedYAMLNode() (yaml.Node, error) {
+	if ConfigCipher == nil {
+		return yaml.Node{}, errNoConfigCipher
+	}
+	ciphertext, err := ConfigCipher.Encrypt([]byte(o.Value.String()))
+	if err != nil {
+		return yaml.Node{}, fmt.Errorf("encrypt: %w", err)
+	}
+	return yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   encryptedTag,
+		Value: base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// decryptYAMLNode returns the plaintext of the encrypted value n.
+func decryptYAMLNode(n *yaml.Node) (string, error) {
+	if ConfigCipher == nil {
+		return "", errNoConfigCipher
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(n.Value)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	plaintext, err := ConfigCipher.Decrypt(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -19,6 +19,7 @@ var (
 )
 
 type Styles struct {
+	Changed,
 	Code,
 	DateTimeStamp,
 	Error,
@@ -80,6 +81,11 @@ func Wrap(s string) string {
 	return DefaultStyles.Wrap.Render(s)
 }
 
+// Changed highlights text that changed, e.g. between two runs of a command.
+func Changed(s string) string {
+	return DefaultStyles.Changed.Render(s)
+}
+
 // Code formats code for display.
 func Code(s string) string {
 	return DefaultStyles.Code.Render(s)
@@ -102,6 +108,8 @@ func init() {
 	// Doing so would require a round-trip between the program and the terminal
 	// due to the OSC query and response.
 	DefaultStyles = Styles{
+		Changed: lipgloss.NewStyle().
+			Reverse(true),
 		Code: lipgloss.NewStyle().
 			PaddingLeft(1).
 			PaddingRight(1).
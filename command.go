@@ -10,7 +10,7 @@ import (
 	"os/signal"
 	"strings"
 	"testing"
-	"unicode"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/spf13/pflag"
@@ -21,6 +21,8 @@ import (
 
 var logger = log.New(os.Stderr)
 
+// ContactInfo tells users where to get help with a command. It's shown in
+// the help of the command and its subcommands.
 type ContactInfo struct {
 	Repo   string
 	Issues string
@@ -31,8 +33,20 @@ type ContactInfo struct {
 func (c *ContactInfo) RepoLink() string {
 	return c.Repo
 }
+
+// IssuesLink returns Issues, or the issues page of Repo if it's hosted on
+// GitHub or GitLab.
 func (c *ContactInfo) IssuesLink() string {
-	return c.Issues
+	if c.Issues != "" {
+		return c.Issues
+	}
+	switch repo := strings.TrimSuffix(c.Repo, "/"); {
+	case strings.HasPrefix(repo, "https://github.com/"):
+		return repo + "/issues"
+	case strings.HasPrefix(repo, "https://gitlab.com/"):
+		return repo + "/-/issues"
+	}
+	return ""
 }
 func (c *ContactInfo) ChatLink() string {
 	return c.Chat
@@ -41,6 +55,43 @@ func (c *ContactInfo) EmailLink() string {
 	return c.Email
 }
 
+// contactInfo returns the ContactInfo of c or its closest parent with one.
+func (c *Command) contactInfo() *ContactInfo {
+	for ; c != nil; c = c.Parent {
+		if c.ContactInfo != nil {
+			return c.ContactInfo
+		}
+	}
+	return nil
+}
+
+// Annotation returns the value of the annotation key of c, inherited from
+// the closest parent that sets it if c doesn't.
+func (c *Command) Annotation(key string) (string, bool) {
+	for ; c != nil; c = c.Parent {
+		if v, ok := c.Annotations.Get(key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// InheritedAnnotations returns the annotations of c merged with those of
+// its parents, where annotations of c override those of its parents.
+func (c *Command) InheritedAnnotations() Annotations {
+	var a Annotations
+	if c.Parent != nil {
+		a = c.Parent.InheritedAnnotations()
+	}
+	for k, v := range c.Annotations {
+		if a == nil {
+			a = make(Annotations)
+		}
+		a[k] = v
+	}
+	return a
+}
+
 // Command describes an executable command.
 type Command struct {
 	// Parent is the direct parent of the command.
@@ -51,9 +102,14 @@ type Command struct {
 	// Children is a list of direct descendants.
 	Children []*Command
 
-	// Use is provided in form "command [flags] [args...]".
+	// Use is provided in form "command [flags] [args...]". If it's only the
+	// name of the command, the synopsis is generated, see Synopsis.
 	Use string
 
+	// Arguments are the positional arguments of the command, used to
+	// generate its synopsis and checked before the handler runs.
+	Arguments []Argument
+
 	// Aliases is a list of alternative names for the command.
 	Aliases []string
 
@@ -68,22 +124,80 @@ type Command struct {
 	// If set, the value is used as the deprecation message.
 	Deprecated string `json:"deprecated,omitempty"`
 
+	// ReplacedBy is the path below the root command of the command
+	// replacing this one, e.g. []string{"workspace", "list"}. When set,
+	// invoking this command warns about the deprecation and runs the
+	// replacement with the same arguments instead, so the command needs no
+	// Handler.
+	ReplacedBy []string `json:"replaced_by,omitempty"`
+
+	// ReplacedFlags maps the flags of this command to the flags of its
+	// ReplacedBy command that were renamed, by long flag name. Other flags
+	// are passed on as they are.
+	ReplacedFlags map[string]string `json:"replaced_flags,omitempty"`
+
 	// RawArgs determines whether the command should receive unparsed arguments.
 	// No flags are parsed when set, and the command is responsible for parsing
 	// its own flags.
 	RawArgs bool
 
+	// TraverseChildren parses the flags of the command and its descendants
+	// only up to the name of the next subcommand, so parent flags must come
+	// before the subcommand and the subcommand's flags after it. By default
+	// the whole command line is parsed at every level, which can mistake a
+	// parent flag's value for a positional argument when a subcommand
+	// redefines the flag. It applies to all descendants.
+	TraverseChildren bool
+
+	// AllowPrefixMatching runs the subcommand whose name or alias starts with
+	// an unknown subcommand name, e.g. "myapp wo li" runs "myapp workspace
+	// list". When several subcommands match, the user picks one if stdin is
+	// a terminal, and the command fails otherwise. It applies to all
+	// descendants.
+	AllowPrefixMatching bool
+
+	// CaseInsensitive matches the names and aliases of subcommands, and the
+	// long names of flags, regardless of case, so "myapp Workspace LIST
+	// --Verbose" runs "myapp workspace list --verbose". It applies to
+	// dispatch, completion, help and all descendants.
+	CaseInsensitive bool
+
+	// CompleteAliases includes the aliases of subcommands in completions,
+	// next to their names. It applies to all descendants.
+	CompleteAliases bool
+
+	// EnableArgFiles expands "@path" arguments into the arguments listed in
+	// the file at path, one per line, before parsing. It works around the
+	// command line length limits of the OS for large batch operations, and
+	// only has an effect on the root command. Lines starting with "#" are
+	// comments, and "@@" escapes a literal "@".
+	EnableArgFiles bool
+
 	// Long is a detailed description of the command,
 	// presented on its help page. It may contain examples.
-	Long        string
-	Options     OptionSet
+	Long string
+	// Markdown indicates that Long is authored in Markdown. It is rendered
+	// with styling on terminals and as plain text otherwise.
+	Markdown bool
+	Options  OptionSet
+	// Annotations are inherited by children that don't set the same key,
+	// see Annotation and InheritedAnnotations.
 	Annotations Annotations
 
 	// Middleware is called before the Handler.
 	// Use Chain() to combine multiple middlewares.
-	Middleware  MiddlewareFunc
-	Handler     HandlerFunc
-	HelpHandler HandlerFunc
+	Middleware MiddlewareFunc
+	// PersistentMiddleware is called before the Middleware of the command
+	// and of all its descendants, outermost first. It's useful for
+	// middleware such as authentication or logging that applies to a whole
+	// command tree.
+	PersistentMiddleware MiddlewareFunc
+	Handler              HandlerFunc
+	HelpHandler          HandlerFunc
+	// HelpToStderr writes the default help of the command and its
+	// descendants to Stderr rather than Stdout, for commands whose output is
+	// parsed by scripts, so that an accidental --help doesn't pollute it.
+	HelpToStderr bool
 	// CompletionHandler is called when the command is run in completion
 	// mode. If nil, only the default completion handler is used.
 	//
@@ -94,10 +208,24 @@ type Command struct {
 	ContactInfo *ContactInfo
 
 	// Version defines the version for this command. If this value is non-empty and the command does not
-	// define a "version" flag, a "version" boolean flag will be added to the command and, if specified,
-	// will print content of the "Version" variable. A shorthand "v" flag will also be added if the
-	// command does not define one.
+	// define or inherit a "version" flag, a "version" boolean flag will be added to the command and, if
+	// specified on it or any of its subcommands, will print the version using VersionTemplate. A shorthand
+	// "v" flag will also be added unless the command, its parents or its subcommands already use one.
 	Version string
+	// VersionCommit and VersionDate are the commit and build date of Version,
+	// made available to VersionTemplate.
+	VersionCommit string
+	VersionDate   string
+	// VersionTemplate is a text/template executed with a VersionInfo to print
+	// the version. It's inherited by subcommands and defaults to
+	// DefaultVersionTemplate.
+	VersionTemplate string
+
+	// DefaultTimeout, if set, adds a --timeout option to the command with
+	// this default, and cancels the invocation's context once it elapses.
+	// Errors returned after the timeout are wrapped in a TimeoutError.
+	DefaultTimeout time.Duration
+	timeout        time.Duration
 }
 
 // AddSubcommands adds the given subcommands, setting their
@@ -128,7 +256,9 @@ func ascendingSortFn[T constraints.Ordered](a, b T) int {
 }
 
 // init performs initialization and linting on the command and all its children.
-func (c *Command) init() error {
+// Linting is skipped when lint is false, which keeps completion mode fast on
+// large command trees.
+func (c *Command) init(lint bool) error {
 	if c.Use == "" {
 		c.Use = "unnamed"
 	}
@@ -148,17 +278,23 @@ func (c *Command) init() error {
 				merr = errors.Join(merr, fmt.Errorf("option must have a Name, Flag, Env or YAML field"))
 			}
 		}
-		if opt.Description != "" {
-			// Enforce that description uses sentence form.
-			if unicode.IsLower(rune(opt.Description[0])) {
-				merr = errors.Join(merr, fmt.Errorf("option %q description should start with a capital letter", opt.Name))
-			}
-			if !strings.HasSuffix(opt.Description, ".") {
-				merr = errors.Join(merr, fmt.Errorf("option %q description should end with a period", opt.Name))
+		if lint {
+			for _, issue := range lintOption(*opt) {
+				merr = errors.Join(merr, errors.New(issue.Message))
 			}
 		}
 	}
 
+	if c.Version != "" {
+		c.addVersionOption()
+	}
+	if c.DefaultTimeout > 0 {
+		c.addTimeoutOption()
+	}
+	if c.Parent == nil && len(c.Children) > 0 {
+		c.addHelpCommand()
+	}
+
 	slices.SortFunc(c.Options, func(a, b Option) int {
 		return ascendingSortFn(a.Name, b.Name)
 	})
@@ -167,34 +303,11 @@ func (c *Command) init() error {
 	})
 	for _, child := range c.Children {
 		child.Parent = c
-		err := child.init()
+		err := child.init(lint)
 		if err != nil {
 			merr = errors.Join(merr, fmt.Errorf("command %v: %w", child.Name(), err))
 		}
 	}
-	hasVersion := false
-	if c.Parent == nil {
-		if c.Version != "" {
-			nameVersion := c.Options.ByName("version")
-			if nameVersion != nil {
-				hasVersion = true
-			}
-			flagVersion := c.Options.ByFlag("version")
-			if flagVersion != nil {
-				hasVersion = true
-			}
-
-			if !hasVersion {
-				var val bool
-				c.Options.Add(Option{
-					Flag:  "version",
-					Value: BoolOf(&val),
-					Name:  "version",
-				})
-			}
-
-		}
-	}
 	return merr
 }
 
@@ -214,6 +327,41 @@ func (c *Command) FullName() string {
 	return strings.Join(names, " ")
 }
 
+// Find returns the descendant of c at path, a list of subcommand names or
+// aliases, or nil if there's none. Find() returns c.
+func (c *Command) Find(path ...string) *Command {
+	cmd := c
+	for _, name := range path {
+		child := cmd.child(name)
+		if child == nil {
+			return nil
+		}
+		child.Parent = cmd
+		cmd = child
+	}
+	return cmd
+}
+
+// InvokeSubcommand runs the command at path below the root command with
+// args, reusing the invocation's IO, environment and context. Options of
+// the parent commands keep their current values rather than being parsed
+// again, so handlers can delegate to other commands of the tree.
+func (inv *Invocation) InvokeSubcommand(path []string, args []string) error {
+	root := inv.Command
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	cmd := root.Find(path...)
+	if cmd == nil {
+		return fmt.Errorf("no command %q", strings.Join(append([]string{root.Name()}, path...), " "))
+	}
+	return inv.with(func(i *Invocation) {
+		i.Command = cmd
+		i.Args = args
+		i.parsedFlags = nil
+	}).Run()
+}
+
 // FullName returns usage of the command, preceded
 // by the usage of its parents.
 func (c *Command) FullUsage() string {
@@ -221,7 +369,7 @@ func (c *Command) FullUsage() string {
 	if c.Parent != nil {
 		uses = append(uses, c.Parent.FullName())
 	}
-	uses = append(uses, c.Use)
+	uses = append(uses, c.Synopsis())
 	return strings.Join(uses, " ")
 }
 
@@ -271,6 +419,29 @@ type Invocation struct {
 	// Deprecated
 	Net Net
 
+	outputHooks    *outputHooks
+	messageHandler MessageHandler
+	// logFields are added to emitted events, see WithLogFields.
+	logFields []any
+	// rawArgs are the arguments Run was called with, before parsing.
+	rawArgs []string
+	// curArgIndex is the index of the positional argument being completed.
+	curArgIndex int
+	// valuesResolved is set once resolveCompletionValues ran.
+	valuesResolved bool
+	// deferred and tempDir are created by Run, see Defer and TempDir.
+	deferred *deferStack
+	tempDir  *tempDir
+	// workDir is set by WorkDirMiddleware, see WorkDir.
+	workDir string
+	// events is set by Events.
+	events *eventStream
+	// strictConfig and strictEnvPrefix are set by WithStrictConfig.
+	strictConfig    bool
+	strictEnvPrefix string
+	// iteration is set by EveryMiddleware, see Iteration.
+	iteration Iteration
+
 	// testing
 	signalNotifyContext func(parent context.Context, signals ...os.Signal) (ctx context.Context, stop context.CancelFunc)
 }
@@ -306,19 +477,44 @@ func (inv *Invocation) PrintErrf(format string, i ...interface{}) {
 }
 
 // WithOS returns the invocation as a main package, filling in the invocation's unset
-// fields with OS defaults.
+// fields with OS defaults. On Windows, it also enables rendering of colored
+// output by the console.
 func (inv *Invocation) WithOS() *Invocation {
-	return inv.with(func(i *Invocation) {
-		i.Stdout = os.Stdout
-		i.Stderr = os.Stderr
-		i.Stdin = os.Stdin
+	return inv.WithOSNoArgs().with(func(i *Invocation) {
 		i.Args = os.Args[1:]
-		i.Environ = ParseEnviron(os.Environ(), "")
+	})
+}
+
+// WithOSNoArgs is like WithOS, but keeps the invocation's arguments, e.g.
+// for hosts that run commands from their own input rather than os.Args.
+func (inv *Invocation) WithOSNoArgs() *Invocation {
+	enableConsole()
+	return inv.WithIO(os.Stdin, os.Stdout, os.Stderr).WithEnvPrefix("").with(func(i *Invocation) {
 		i.Net = osNet{}
 		log.SetOutput(i.Stderr)
 	})
 }
 
+// WithIO returns the invocation with the given standard streams, such as a
+// TUI's pipes or an HTTP request and response.
+func (inv *Invocation) WithIO(stdin io.Reader, stdout, stderr io.Writer) *Invocation {
+	return inv.with(func(i *Invocation) {
+		i.Stdin = stdin
+		i.Stdout = stdout
+		i.Stderr = stderr
+		i.outputHooks = nil
+		i.installOutputHooks()
+	})
+}
+
+// WithEnvPrefix returns the invocation with the OS environment variables
+// starting with prefix, without said prefix. See ParseEnviron.
+func (inv *Invocation) WithEnvPrefix(prefix string) *Invocation {
+	return inv.with(func(i *Invocation) {
+		i.Environ = ParseEnviron(os.Environ(), prefix)
+	})
+}
+
 // WithTestSignalNotifyContext allows overriding the default implementation of SignalNotifyContext.
 // This should only be used in testing.
 func (inv *Invocation) WithTestSignalNotifyContext(
@@ -365,13 +561,180 @@ func (inv *Invocation) ParsedFlags() *pflag.FlagSet {
 type runState struct {
 	allArgs      []string
 	commandDepth int
+	// traverseArgs are the arguments after the name of the current
+	// command, when traversing children.
+	traverseArgs []string
+	// commandArg is the argument that selected the current command, which
+	// may be an alias or a prefix of its name.
+	commandArg string
 
 	flagParseErr error
 }
 
+// warnDeprecated warns about c and its parents if they are deprecated,
+// root first.
+func (inv *Invocation) warnDeprecated(c *Command) {
+	if c == nil {
+		return
+	}
+	inv.warnDeprecated(c.Parent)
+	if c.Deprecated == "" {
+		return
+	}
+	inv.message(Message{
+		Level:  MessageWarn,
+		Kind:   MessageKindDeprecated,
+		Header: fmt.Sprintf("%q is deprecated!", c.FullName()),
+		Lines:  []string{c.Deprecated},
+		Text:   fmt.Sprintf("%s %q is deprecated!. %s\n", prettyHeader("warning"), c.FullName(), c.Deprecated),
+	})
+}
+
+// warnDeprecatedChoices warns about deprecated enum choices set by the user
+// in the options of the command and its parents.
+func (inv *Invocation) warnDeprecatedChoices() {
+	for c := inv.Command; c != nil; c = c.Parent {
+		for _, opt := range c.Options {
+			if opt.ValueSource == ValueSourceNone || opt.ValueSource == ValueSourceDefault {
+				continue
+			}
+			var values []string
+			switch v := opt.Value.(type) {
+			case *Enum:
+				values = []string{*v.Value}
+			case *EnumArray:
+				values = *v.Value
+			default:
+				continue
+			}
+			for _, d := range enumDetails(opt.Value) {
+				if d.Deprecated != "" && slices.Contains(values, d.Value) {
+					inv.message(Message{
+						Level:  MessageWarn,
+						Kind:   MessageKindDeprecated,
+						Header: fmt.Sprintf("%q is deprecated for option %q!", d.Value, opt.Name),
+						Lines:  []string{d.Deprecated},
+						Text:   fmt.Sprintf("%s %q is deprecated for option %q!. %s\n", prettyHeader("warning"), d.Value, opt.Name, d.Deprecated),
+					})
+				}
+			}
+		}
+	}
+}
+
+// completeAliases reports whether c or one of its parents sets
+// CompleteAliases.
+func (c *Command) completeAliases() bool {
+	for ; c != nil; c = c.Parent {
+		if c.CompleteAliases {
+			return true
+		}
+	}
+	return false
+}
+
+// allowPrefixMatching reports whether c or one of its parents sets
+// AllowPrefixMatching.
+func (c *Command) allowPrefixMatching() bool {
+	for ; c != nil; c = c.Parent {
+		if c.AllowPrefixMatching {
+			return true
+		}
+	}
+	return false
+}
+
+// caseInsensitive reports whether c or one of its parents sets
+// CaseInsensitive.
+func (c *Command) caseInsensitive() bool {
+	for ; c != nil; c = c.Parent {
+		if c.CaseInsensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// namesEqual reports whether the command or flag names a and b are equal,
+// ignoring case if fold is set.
+func namesEqual(a, b string, fold bool) bool {
+	if fold {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// lookupChild returns the child in children named name, ignoring case if
+// fold is set.
+func lookupChild(children map[string]*Command, name string, fold bool) *Command {
+	if child, ok := children[name]; ok || !fold {
+		return child
+	}
+	for n, child := range children {
+		if strings.EqualFold(n, name) {
+			return child
+		}
+	}
+	return nil
+}
+
+// foldFlagName is the pflag normalization of the flag names of
+// case-insensitive commands.
+func foldFlagName(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	return pflag.NormalizedName(strings.ToLower(name))
+}
+
+// traverseChildren reports whether c or one of its parents sets
+// TraverseChildren.
+func (c *Command) traverseChildren() bool {
+	for ; c != nil; c = c.Parent {
+		if c.TraverseChildren {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTraverse parses the flags in the arguments of the current command up
+// to the name of a child, if any, and returns the positional arguments
+// preceded by commandDepth placeholders for the parent commands, as a flat
+// parse would.
+func (inv *Invocation) parseTraverse(state *runState, children map[string]*Command) []string {
+	placeholders := make([]string, state.commandDepth)
+	if len(children) > 0 {
+		inv.resetAccumulatedFlags(state.traverseArgs)
+		inv.parsedFlags.SetInterspersed(false)
+		err := inv.parsedFlags.Parse(state.traverseArgs)
+		inv.parsedFlags.SetInterspersed(true)
+		rest := inv.parsedFlags.Args()
+		fold := inv.Command.caseInsensitive()
+		if err == nil && len(rest) > 0 && (lookupChild(children, rest[0], fold) != nil ||
+			inv.Command.allowPrefixMatching() && len(prefixChildren(children, rest[0], fold)) > 0) {
+			// The remaining arguments are the child's to parse.
+			return append(placeholders, rest...)
+		}
+	}
+	// The arguments may have been parsed above already.
+	inv.resetAccumulatedFlags(state.traverseArgs)
+	state.flagParseErr = inv.parsedFlags.Parse(state.traverseArgs)
+	return append(placeholders, inv.parsedFlags.Args()...)
+}
+
+// resetAccumulatedFlags resets the values accumulating repeated flags that
+// are used in args, before args are parsed.
+func (inv *Invocation) resetAccumulatedFlags(args []string) {
+	fold := inv.Command.caseInsensitive()
+	inv.parsedFlags.VisitAll(func(f *pflag.Flag) {
+		if containsFlag(args, inv.parsedFlags, f, fold) {
+			resetAccumulated(f.Value)
+		}
+	})
+}
+
 func copyFlagSetWithout(fs *pflag.FlagSet, without string) *pflag.FlagSet {
 	fs2 := pflag.NewFlagSet("", pflag.ContinueOnError)
 	fs2.Usage = func() {}
+	fs2.SetNormalizeFunc(fs.GetNormalizeFunc())
 	fs.VisitAll(func(f *pflag.Flag) {
 		if f.Name == without {
 			return
@@ -402,17 +765,18 @@ func (inv *Invocation) CurWords() (prev string, cur string) {
 // allArgs is wired through the stack so that global flags can be accepted
 // anywhere in the command invocation.
 func (inv *Invocation) run(state *runState) error {
-	if inv.Command.Deprecated != "" {
-		fmt.Fprintf(inv.Stderr, "%s %q is deprecated!. %s\n",
-			prettyHeader("warning"),
-			inv.Command.FullName(),
-			inv.Command.Deprecated,
-		)
-	}
-
-	err := inv.Command.Options.ParseEnv(inv.Environ)
-	if err != nil {
-		return fmt.Errorf("parsing env: %w", err)
+	// Completion mode only needs to know which flags were supplied on the
+	// command line, so environment, YAML, and default processing is deferred
+	// until a completion handler needs the values, see
+	// resolveCompletionValues.
+	completionMode := inv.IsCompletionMode()
+
+	var err error
+	if !completionMode {
+		err = inv.Command.Options.ParseEnv(inv.Environ)
+		if err != nil {
+			return fmt.Errorf("parsing env: %w", err)
+		}
 	}
 
 	// Now the fun part, argument parsing!
@@ -436,17 +800,34 @@ func (inv *Invocation) run(state *runState) error {
 
 	// If we find a duplicate flag, we want the deeper command's flag to override
 	// the shallow one. Unfortunately, pflag has no way to remove a flag, so we
-	// have to create a copy of the flagset without a value.
+	// have to create a copy of the flagset without a value. Lint reports
+	// duplicates that aren't marked with Option.Override.
 	inv.Command.Options.FlagSet().VisitAll(func(f *pflag.Flag) {
 		if inv.parsedFlags.Lookup(f.Name) != nil {
 			inv.parsedFlags = copyFlagSetWithout(inv.parsedFlags, f.Name)
 		}
 		inv.parsedFlags.AddFlag(f)
 	})
+	fold := inv.Command.caseInsensitive()
+	if fold {
+		inv.parsedFlags.SetNormalizeFunc(foldFlagName)
+	}
 
 	var parsedArgs []string
 
-	if !inv.Command.RawArgs {
+	// The values before parsing are passed to Option.OnChange.
+	before := make([]string, len(inv.Command.Options))
+	for i := range inv.Command.Options {
+		before[i] = inv.Command.Options[i].valueString()
+	}
+
+	if !inv.Command.RawArgs && inv.Command.traverseChildren() {
+		parsedArgs = inv.parseTraverse(state, children)
+	} else if !inv.Command.RawArgs {
+		// The arguments are parsed again at every depth, so values that
+		// accumulate repeated flags are reset first. This also drops values
+		// from the environment in favor of the flags.
+		inv.resetAccumulatedFlags(state.allArgs)
 		// Flag parsing will fail on intermediate commands in the command tree,
 		// so we check the error after looking for a child command.
 		state.flagParseErr = inv.parsedFlags.Parse(state.allArgs)
@@ -455,62 +836,102 @@ func (inv *Invocation) run(state *runState) error {
 
 	// Set value sources for flags.
 	for i, opt := range inv.Command.Options {
-		if fl := inv.parsedFlags.Lookup(opt.Flag); fl != nil && fl.Changed {
-			inv.Command.Options[i].ValueSource = ValueSourceFlag
+		if flagChanged(inv.parsedFlags, opt) {
+			inv.Command.Options[i].setOrigin(ValueOrigin{
+				Source: ValueSourceFlag,
+				Detail: flagForm(state.allArgs, inv.parsedFlags, opt, fold),
+			})
+			inv.Command.Options[i].changed(before[i])
 		}
 	}
 
-	// Read YAML configs, if any.
-	for _, opt := range inv.Command.Options {
-		path, ok := opt.Value.(*YAMLConfigPath)
-		if !ok || path.String() == "" {
-			continue
-		}
-
-		byt, err := os.ReadFile(path.String())
+	if completionMode {
+		// Options sharing a value with a flag that was set should not be
+		// suggested either.
+		inv.Command.Options.shareValueSources()
+	} else {
+		err = inv.readYAMLConfigs()
 		if err != nil {
-			return fmt.Errorf("reading yaml: %w", err)
+			return err
 		}
 
-		var n yaml.Node
-		err = yaml.Unmarshal(byt, &n)
+		err = inv.Command.Options.checkGates(inv.Environ)
 		if err != nil {
-			return fmt.Errorf("decoding yaml: %w", err)
+			return err
 		}
 
-		err = inv.Command.Options.UnmarshalYAML(&n)
+		err = inv.Command.Options.SetDefaults()
 		if err != nil {
-			return fmt.Errorf("applying yaml: %w", err)
+			return fmt.Errorf("setting defaults: %w", err)
 		}
 	}
 
-	err = inv.Command.Options.SetDefaults()
-	if err != nil {
-		return fmt.Errorf("setting defaults: %w", err)
-	}
-
 	// Run child command if found (next child only)
 	// We must do subcommand detection after flag parsing so we don't mistake flag
 	// values for subcommand names.
 	if len(parsedArgs) > state.commandDepth {
 		nextArg := parsedArgs[state.commandDepth]
-		if child, ok := children[nextArg]; ok {
+		child := lookupChild(children, nextArg, fold)
+		ok := child != nil
+		if !ok && !completionMode && inv.Command.allowPrefixMatching() {
+			child, err = inv.selectPrefixChild(children, nextArg)
+			if err != nil {
+				return err
+			}
+			ok = child != nil
+		}
+		if ok {
 			child.Parent = inv.Command
 			inv.Command = child
 			state.commandDepth++
+			state.commandArg = nextArg
+			state.traverseArgs = parsedArgs[state.commandDepth:]
 			return inv.run(state)
 		}
 	}
 
+	if len(inv.Command.ReplacedBy) > 0 {
+		return inv.redirect(state)
+	}
+
 	// Outputted completions are not filtered based on the word under the cursor, as every shell we support does this already.
 	// We only look at the current word to figure out handler to run, or what directory to inspect.
-	if inv.IsCompletionMode() {
-		for _, e := range inv.complete() {
+	if completionMode {
+		if inv.Command.RawArgs {
+			inv.curArgIndex = len(inv.Args) - 1
+		} else {
+			inv.curArgIndex = len(parsedArgs) - state.commandDepth - 1
+		}
+		completions, err := inv.complete()
+		if err != nil {
+			return err
+		}
+		for _, e := range completions {
 			fmt.Fprintln(inv.Stdout, e)
 		}
 		return nil
 	}
 
+	// Stdin and indirect values are resolved once the final command is known,
+	// since parsing flags at each depth of the tree resets them. There's no
+	// need to resolve them when only help is shown.
+	if !errors.Is(state.flagParseErr, pflag.ErrHelp) {
+		err = inv.readStdinValues()
+		if err != nil {
+			return fmt.Errorf("reading values: %w", err)
+		}
+		for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+			err = cmd.Options.resolveIndirection(inv.Environ)
+			if err != nil {
+				return fmt.Errorf("resolving values: %w", err)
+			}
+			err = cmd.Options.expandTemplates(inv.Environ)
+			if err != nil {
+				return fmt.Errorf("expanding values: %w", err)
+			}
+		}
+	}
+
 	ignoreFlagParseErrors := inv.Command.RawArgs
 
 	// Flag parse errors are irrelevant for raw args commands.
@@ -518,7 +939,7 @@ func (inv *Invocation) run(state *runState) error {
 		return fmt.Errorf(
 			"parsing flags (%v) for %q: %w",
 			state.allArgs,
-			inv.Command.FullName(), state.flagParseErr,
+			inv.Command.FullName(), inv.flagError(state.flagParseErr),
 		)
 	}
 
@@ -539,6 +960,16 @@ func (inv *Invocation) run(state *runState) error {
 	if len(missing) > 0 && !inv.IsCompletionMode() && !errors.Is(state.flagParseErr, pflag.ErrHelp) {
 		return fmt.Errorf("missing values for the required flags: %s", strings.Join(missing, ", "))
 	}
+	if !inv.IsCompletionMode() && !errors.Is(state.flagParseErr, pflag.ErrHelp) {
+		if err := inv.Command.Options.checkCounts(); err != nil {
+			return err
+		}
+	}
+	if !errors.Is(state.flagParseErr, pflag.ErrHelp) {
+		if err := inv.checkStrictEnv(); err != nil {
+			return err
+		}
+	}
 
 	if inv.Command.RawArgs {
 		// If we're at the root command, then the name is omitted
@@ -546,7 +977,7 @@ func (inv *Invocation) run(state *runState) error {
 		if state.commandDepth == 0 {
 			inv.Args = state.allArgs
 		} else {
-			argPos, err := findArg(inv.Command.Name(), state.allArgs, inv.parsedFlags)
+			argPos, err := findArg(state.commandArg, state.allArgs, inv.parsedFlags)
 			if err != nil {
 				panic(err)
 			}
@@ -556,21 +987,15 @@ func (inv *Invocation) run(state *runState) error {
 		// In non-raw-arg mode, we want to skip over flags.
 		inv.Args = parsedArgs[state.commandDepth:]
 	}
-	if inv.Command.Version != "" {
-		vflag := inv.Command.Options.ByFlag("version")
-		if vflag != nil {
-			fl := inv.parsedFlags.Lookup(vflag.Flag)
-			if fl != nil && fl.Changed {
-				inv.Println(inv.Command.Name() + " " + inv.Command.Version)
-				return nil
-			}
+	// Deprecation warnings are written once flags are parsed, so that
+	// they respect machine mode.
+	inv.warnDeprecated(inv.Command)
+	inv.warnDeprecatedChoices()
 
-		}
-	}
-	mw := inv.Command.Middleware
-	if mw == nil {
-		mw = Chain()
+	if vc := inv.versionRequested(); vc != nil {
+		return inv.printVersion(vc)
 	}
+	mw := inv.Command.middleware()
 
 	ctx := inv.ctx
 	if ctx == nil {
@@ -579,7 +1004,9 @@ func (inv *Invocation) run(state *runState) error {
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	inv = inv.WithContext(ctx)
+	ctx, cancelTimeout, wrapTimeout := inv.withTimeout(ctx)
+	defer cancelTimeout()
+	inv = inv.WithContext(ctx).withMachineLogger()
 
 	if inv.Command.Handler == nil || errors.Is(state.flagParseErr, pflag.ErrHelp) {
 		if inv.Command.HelpHandler == nil {
@@ -588,7 +1015,13 @@ func (inv *Invocation) run(state *runState) error {
 		return inv.Command.HelpHandler(inv)
 	}
 
-	err = mw(inv.Command.Handler)(inv)
+	if !inv.Command.RawArgs {
+		if err := inv.Command.checkArguments(inv.Args); err != nil {
+			return err
+		}
+	}
+
+	err = wrapTimeout(mw(resolvePaths(inv.Command.Handler))(inv))
 	if err != nil {
 		return &RunCommandError{
 			Cmd: inv.Command,
@@ -598,6 +1031,151 @@ func (inv *Invocation) run(state *runState) error {
 	return nil
 }
 
+// flagForm returns the form of opt's flag used in args, "--flag",
+// "--no-flag" or "-f", preferring long forms.
+func flagForm(args []string, fs *pflag.FlagSet, opt Option, fold bool) string {
+	form := "--" + opt.Flag
+	shorthand := false
+	walkArgs(args, fs, func(_ int, f string) bool {
+		switch {
+		case f == "":
+		case namesEqual(f, "--"+opt.Flag, fold):
+			form, shorthand = "--"+opt.Flag, false
+			return false
+		case opt.negatable() && namesEqual(f, "--no-"+opt.Flag, fold):
+			form, shorthand = "--no-"+opt.Flag, false
+			return false
+		case opt.FlagShorthand != "" && f == "-"+opt.FlagShorthand:
+			shorthand = true
+		}
+		return true
+	})
+	if shorthand {
+		return "-" + opt.FlagShorthand
+	}
+	return form
+}
+
+// flagChanged reports whether the flag of opt, or its --no-<flag> negation,
+// was set in fs.
+func flagChanged(fs *pflag.FlagSet, opt Option) bool {
+	if fl := fs.Lookup(opt.Flag); fl != nil && fl.Changed {
+		return true
+	}
+	if !opt.negatable() {
+		return false
+	}
+	fl := fs.Lookup("no-" + opt.Flag)
+	return fl != nil && fl.Changed
+}
+
+// containsFlag reports whether f of fs is used in args, by name or
+// shorthand.
+func containsFlag(args []string, fs *pflag.FlagSet, f *pflag.Flag, fold bool) bool {
+	found := false
+	walkArgs(args, fs, func(_ int, form string) bool {
+		found = form != "" && (namesEqual(form, "--"+f.Name, fold) || (f.Shorthand != "" && form == "-"+f.Shorthand))
+		return !found
+	})
+	return found
+}
+
+// resetAccumulated resets values that accumulate repeated flags, such as
+// StringArray or a Struct of a slice.
+func resetAccumulated(v pflag.Value) {
+	switch v := v.(type) {
+	case pflag.SliceValue:
+		_ = v.Replace(nil)
+	case interface{ resetSlice() }:
+		v.resetSlice()
+	}
+}
+
+// readYAMLConfigs applies the project config file referenced by a
+// ProjectConfigPath option and every YAML config file referenced by a
+// YAMLConfigPath option to the command's options, in order of precedence.
+// The selected profile's section of the YAML config files precedes the rest
+// of them when profiles are enabled.
+func (inv *Invocation) readYAMLConfigs() error {
+	for _, opt := range inv.Command.Options {
+		project, ok := opt.Value.(*ProjectConfigPath)
+		if !ok {
+			continue
+		}
+		path := project.Path(inv.configWorkDir())
+		if path == "" {
+			continue
+		}
+
+		n, err := readYAMLFile(path)
+		if err != nil {
+			return err
+		}
+		err = inv.Command.Options.unmarshalYAML(n, ValueOrigin{Source: ValueSourceProject, Detail: path})
+		if err != nil {
+			return fmt.Errorf("applying project config %s: %w", path, err)
+		}
+	}
+
+	profile, explicitProfile, profilesEnabled := inv.profile()
+	var read, profileFound bool
+	for _, opt := range inv.Command.Options {
+		path, ok := opt.Value.(*YAMLConfigPath)
+		if !ok || path.String() == "" {
+			continue
+		}
+
+		n, err := readYAMLFile(path.String())
+		if err != nil {
+			return err
+		}
+		read = true
+
+		if profilesEnabled {
+			_, profiles, err := extractProfiles(n)
+			if err != nil {
+				return fmt.Errorf("decoding yaml: %w", err)
+			}
+			if p, ok := profiles[profile]; ok && profile != "" {
+				profileFound = true
+				err = inv.Command.Options.unmarshalYAML(p, ValueOrigin{
+					Source: ValueSourceProfile,
+					Detail: fmt.Sprintf("%s (%s)", profile, path),
+				})
+				if err != nil {
+					return fmt.Errorf("applying profile %q: %w", profile, err)
+				}
+			}
+		}
+
+		err = inv.Command.Options.unmarshalYAML(n, ValueOrigin{Source: ValueSourceYAML, Detail: path.String()})
+		if err != nil {
+			return fmt.Errorf("applying yaml %s: %w", path, err)
+		}
+	}
+	// A profile selected with "profile use" may have since been removed,
+	// which mustn't prevent selecting another one.
+	if read && explicitProfile && !profileFound {
+		return fmt.Errorf("profile %q not found in config", profile)
+	}
+	return nil
+}
+
+// readYAMLFile reads and decodes the YAML file at path.
+func readYAMLFile(path string) (*yaml.Node, error) {
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading yaml: %w", err)
+	}
+
+	var n yaml.Node
+	err = yaml.Unmarshal(byt, &n)
+	if err != nil {
+		return nil, fmt.Errorf("decoding yaml: %w", err)
+	}
+	return &n, nil
+}
+
 type RunCommandError struct {
 	Cmd *Command
 	Err error
@@ -611,43 +1189,120 @@ func (e *RunCommandError) Error() string {
 	return fmt.Sprintf("running command %q: %+v", e.Cmd.FullName(), e.Err)
 }
 
-// findArg returns the index of the first occurrence of arg in args, skipping
-// over all flags.
+// findArg returns the index of the first occurrence of want in args that
+// isn't a flag or the value of one.
 func findArg(want string, args []string, fs *pflag.FlagSet) (int, error) {
+	pos := -1
+	walkArgs(args, fs, func(i int, form string) bool {
+		if form == "" && args[i] == want {
+			pos = i
+			return false
+		}
+		return true
+	})
+	if pos < 0 {
+		return -1, fmt.Errorf("arg %s not found", want)
+	}
+	return pos, nil
+}
+
+// walkArgs calls fn for each argument of args up to "--", following the
+// grammar of pflag with the flags of fs:
+//
+//   - "--flag=value", and "--flag value" unless the flag has a NoOptDefVal
+//     such as boolean flags, which take "--flag" alone;
+//   - "-f=value", "-fvalue" and "-f value" alike for shorthands, and
+//     combined shorthands "-abc" where all but the last have a NoOptDefVal,
+//     so "-abn5" sets -a, -b and -n to 5;
+//   - "-" alone is a positional argument, such as the stdin of StringOrStdin.
+//
+// fn is called with the index of the argument and the form of each flag it
+// sets, e.g. "--num" or "-n", or with an empty form for positional
+// arguments. The values of flags are skipped. Unknown long flags are assumed
+// to take no value, and the shorthands after an unknown one are skipped. The
+// walk stops when fn returns false. walkArgs returns the index it stopped
+// at, that of "--" or of the argument fn returned false for, or len(args).
+func walkArgs(args []string, fs *pflag.FlagSet, fn func(i int, form string) bool) int {
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
-		if !strings.HasPrefix(arg, "-") {
-			if arg == want {
-				return i, nil
+		switch {
+		case arg == "--":
+			return i
+		case len(arg) < 2 || arg[0] != '-':
+			if !fn(i, "") {
+				return i
+			}
+		case arg[1] == '-':
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			if !fn(i, "--"+name) {
+				return i
+			}
+			if f := fs.Lookup(name); f != nil && !hasValue && f.NoOptDefVal == "" {
+				i++
+			}
+		default:
+			shorthands := arg[1:]
+			for j := 0; j < len(shorthands); j++ {
+				if !fn(i, "-"+shorthands[j:j+1]) {
+					return i
+				}
+				f := fs.ShorthandLookup(shorthands[j : j+1])
+				if f == nil || (j+1 < len(shorthands) && shorthands[j+1] == '=') {
+					break
+				}
+				if f.NoOptDefVal == "" {
+					if j+1 == len(shorthands) {
+						// The value is the next argument.
+						i++
+					}
+					break
+				}
 			}
-			continue
-		}
-
-		// This is a flag!
-		if strings.Contains(arg, "=") {
-			// The flag contains the value in the same arg, just skip.
-			continue
 		}
+	}
+	return len(args)
+}
 
-		// We need to check if NoOptValue is set, then we should not wait
-		// for the next arg to be the value.
-		f := fs.Lookup(strings.TrimLeft(arg, "-"))
-		if f == nil {
-			return -1, fmt.Errorf("unknown flag: %s", arg)
+// walkCommandArgs is like walkArgs for the arguments of the root command,
+// descending into the child commands named by positional arguments so that
+// each argument is scanned with the flags of its command and its parents. fn
+// is also called with that command. The walk stops at "--", or at the
+// arguments of a RawArgs command, whose index is returned.
+func walkCommandArgs(root *Command, args []string, fn func(cmd *Command, i int, form string) bool) int {
+	cmd := root
+	for start := 0; ; {
+		if cmd.RawArgs {
+			return start
 		}
-		if f.NoOptDefVal != "" {
-			continue
-		}
-
-		if i == len(args)-1 {
-			return -1, fmt.Errorf("flag %s requires a value", arg)
+		var child *Command
+		end := walkArgs(args[start:], cmd.flagSetWithParents(), func(i int, form string) bool {
+			i += start
+			if !fn(cmd, i, form) {
+				return false
+			}
+			if form == "" {
+				child = cmd.child(args[i])
+			}
+			return child == nil
+		}) + start
+		if child == nil {
+			return end
 		}
-
-		// Skip the value.
-		i++
+		cmd, start = child, end+1
 	}
+}
 
-	return -1, fmt.Errorf("arg %s not found", want)
+// flagSetWithParents returns the flags of c and its parents, those of deeper
+// commands taking precedence as when parsing.
+func (c *Command) flagSetWithParents() *pflag.FlagSet {
+	fs := pflag.NewFlagSet(c.Name(), pflag.ContinueOnError)
+	if c.caseInsensitive() {
+		fs.SetNormalizeFunc(foldFlagName)
+	}
+	for p := c; p != nil; p = p.Parent {
+		fs.AddFlagSet(p.Options.FlagSet())
+	}
+	return fs
 }
 
 // Run executes the command.
@@ -655,7 +1310,7 @@ func findArg(want string, args []string, fs *pflag.FlagSet) (int, error) {
 //
 //nolint:revive
 func (inv *Invocation) Run() (err error) {
-	err = inv.Command.init()
+	err = inv.Command.init(!inv.IsCompletionMode())
 	if err != nil {
 		return fmt.Errorf("initializing command: %w", err)
 	}
@@ -683,8 +1338,29 @@ func (inv *Invocation) Run() (err error) {
 		e := rc.Close()
 		err = errors.Join(err, e)
 	}()
+	inv.deferred = &deferStack{}
+	inv.tempDir = &tempDir{}
+	defer func() {
+		err = errors.Join(err, inv.deferred.run())
+		if inv.events != nil {
+			inv.events.finish(err)
+		}
+	}()
+	if inv.wantsOptionsDump() {
+		return inv.dumpOptions(inv.Args[1:])
+	}
+
+	if inv.Command.EnableArgFiles && !inv.IsCompletionMode() {
+		inv.Args, err = expandArgFiles(inv.Command, inv.Args)
+		if err != nil {
+			return err
+		}
+	}
+
+	inv.rawArgs = inv.Args
 	err = inv.run(&runState{
-		allArgs: inv.Args,
+		allArgs:      inv.Args,
+		traverseArgs: inv.Args,
 	})
 	return err
 }
@@ -703,7 +1379,7 @@ func (inv *Invocation) with(fn func(*Invocation)) *Invocation {
 	return &i2
 }
 
-func (inv *Invocation) complete() []string {
+func (inv *Invocation) complete() ([]string, error) {
 	prev, cur := inv.CurWords()
 
 	// If the current word is a flag
@@ -712,60 +1388,156 @@ func (inv *Invocation) complete() []string {
 		flagName := flagParts[0][2:]
 		// If it's an equals flag
 		if len(flagParts) == 2 {
-			if out := inv.completeFlag(flagName); out != nil {
+			out, err := inv.completeFlag(flagName)
+			if err != nil {
+				return nil, err
+			}
+			if out != nil {
 				for i, o := range out {
 					out[i] = fmt.Sprintf("--%s=%s", flagName, o)
 				}
-				return out
+				return out, nil
 			}
 		} else if out := inv.Command.Options.ByFlag(flagName); out != nil {
 			// If the current word is a valid flag, auto-complete it so the
 			// shell moves the cursor
-			return []string{cur}
+			return []string{cur}, nil
 		}
 	}
 	// If the previous word is a flag, then we're writing it's value
 	// and we should check it's handler
 	if strings.HasPrefix(prev, "--") {
 		word := prev[2:]
-		if out := inv.completeFlag(word); out != nil {
-			return out
+		out, err := inv.completeFlag(word)
+		if err != nil {
+			return nil, err
+		}
+		if out != nil {
+			return out, nil
 		}
 	}
 	// If the current word is the command, move the shell cursor
 	if inv.Command.Name() == cur {
-		return []string{inv.Command.Name()}
+		return []string{inv.Command.Name()}, nil
 	}
 	var completions []string
 
-	if inv.Command.CompletionHandler != nil {
-		completions = append(completions, inv.Command.CompletionHandler(inv)...)
+	handler := inv.Command.CompletionHandler
+	if arg := inv.Command.argumentAt(inv.CurArgIndex()); arg != nil && arg.CompletionHandler != nil {
+		handler = arg.CompletionHandler
+	}
+	if handler != nil {
+		if err := inv.resolveCompletionValues(); err != nil {
+			return nil, err
+		}
+		completions = append(completions, handler(inv)...)
 	}
 
 	completions = append(completions, DefaultCompletionHandler(inv)...)
 
-	return completions
+	return completions, nil
 }
 
-func (inv *Invocation) completeFlag(word string) []string {
+func (inv *Invocation) completeFlag(word string) ([]string, error) {
 	opt := inv.Command.Options.ByFlag(word)
+	if opt == nil && inv.Command.caseInsensitive() {
+		opt = inv.Command.Options.byFoldedFlag(word)
+	}
 	if opt == nil {
-		return nil
+		return nil, nil
 	}
 	if opt.CompletionHandler != nil {
-		return opt.CompletionHandler(inv)
+		if err := inv.resolveCompletionValues(); err != nil {
+			return nil, err
+		}
+		return opt.CompletionHandler(inv), nil
+	}
+	var choices []string
+	switch v := opt.Value.(type) {
+	case *Path:
+		_, cur := inv.CurWords()
+		return v.complete(cur), nil
+	case *Enum:
+		choices = v.Choices
+	case *EnumArray:
+		choices = v.Choices
+	case *EnumSet:
+		_, cur := inv.CurWords()
+		if strings.HasPrefix(cur, "--") {
+			_, cur, _ = strings.Cut(cur, "=")
+		}
+		return v.remaining(cur), nil
+	default:
+		return nil, nil
 	}
-	enum, ok := opt.Value.(*Enum)
-	if ok {
-		return enum.Choices
+	if _, ok := inv.Environ.Lookup(CompletionDescriptionsEnv); !ok {
+		return choices, nil
 	}
-	enumArr, ok := opt.Value.(*EnumArray)
-	if ok {
-		return enumArr.Choices
+	descriptions := make(map[string]string)
+	for _, d := range enumDetails(opt.Value) {
+		descriptions[d.Value] = d.Description
+	}
+	completions := make([]string, 0, len(choices))
+	for _, c := range choices {
+		if desc := descriptions[c]; desc != "" {
+			c += "\t" + desc
+		}
+		completions = append(completions, c)
+	}
+	return completions, nil
+}
+
+// resolveCompletionValues resolves the option values of the command being
+// completed and its parents from the environment, config files and defaults,
+// which completion mode skips unless a completion handler may read them.
+// Values set by flags are kept.
+func (inv *Invocation) resolveCompletionValues() error {
+	if inv.valuesResolved {
+		return nil
+	}
+	inv.valuesResolved = true
+
+	var cmds []*Command
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		cmds = append([]*Command{cmd}, cmds...)
+	}
+	// readYAMLConfigs reads the options of inv.Command, as it does at each
+	// depth of run.
+	leaf := inv.Command
+	defer func() { inv.Command = leaf }()
+	for _, cmd := range cmds {
+		inv.Command = cmd
+		err := cmd.Options.parseEnv(inv.Environ, true)
+		if err != nil {
+			return fmt.Errorf("parsing env: %w", err)
+		}
+		err = inv.readYAMLConfigs()
+		if err != nil {
+			return err
+		}
+		err = cmd.Options.SetDefaults()
+		if err != nil {
+			return fmt.Errorf("setting defaults: %w", err)
+		}
 	}
 	return nil
 }
 
+// middleware returns the PersistentMiddleware of c and its parents, root
+// first, chained with the Middleware of c.
+func (c *Command) middleware() MiddlewareFunc {
+	var ms []MiddlewareFunc
+	if c.Middleware != nil {
+		ms = append(ms, c.Middleware)
+	}
+	for p := c; p != nil; p = p.Parent {
+		if p.PersistentMiddleware != nil {
+			ms = append([]MiddlewareFunc{p.PersistentMiddleware}, ms...)
+		}
+	}
+	return Chain(ms...)
+}
+
 // MiddlewareFunc returns the next handler in the chain,
 // or nil if there are no more.
 type MiddlewareFunc func(next HandlerFunc) HandlerFunc
@@ -5,7 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -652,6 +655,38 @@ func TestCommand_Help(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("Stream", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range []struct {
+			name     string
+			toStderr bool
+		}{
+			{name: "Stdout"},
+			{name: "Stderr", toStderr: true},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				c := cmd()
+				c.HelpHandler = nil
+				c.HelpToStderr = tc.toStderr
+				c.Children = []*serpent.Command{{Use: "sub", Short: "A subcommand."}}
+				inv := c.Invoke("sub", "--help")
+				stdio := fakeIO(inv)
+				require.NoError(t, inv.Run())
+
+				help, other := stdio.Stdout.String(), stdio.Stderr.String()
+				if tc.toStderr {
+					help, other = other, help
+				}
+				require.Contains(t, help, "root sub", "help of the subcommand")
+				require.Empty(t, other)
+			})
+		}
+	})
+
 	t.Run("Long", func(t *testing.T) {
 		t.Parallel()
 
@@ -673,6 +708,216 @@ func TestCommand_Help(t *testing.T) {
 
 		require.Contains(t, stdio.Stdout.String(), "abdracadabra")
 	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		t.Parallel()
+
+		c := cmd()
+		c.HelpHandler = nil
+		c.Markdown = true
+		c.Long = "## Details\n\nUse **root** with `--help`."
+		inv := c.Invoke("--help")
+		stdio := fakeIO(inv)
+		err := inv.Run()
+		require.NoError(t, err)
+
+		// Help is rendered without styling in tests.
+		require.Contains(t, stdio.Stdout.String(), "  Details\n")
+		require.Contains(t, stdio.Stdout.String(), "  Use root with --help.")
+	})
+
+	t.Run("DefaultDescription", func(t *testing.T) {
+		t.Parallel()
+
+		var user, region string
+		c := cmd()
+		c.HelpHandler = nil
+		c.Options = serpent.OptionSet{
+			{
+				Name:               "user",
+				Flag:               "user",
+				Default:            "alice",
+				DefaultDescription: "current user",
+				Value:              serpent.StringOf(&user),
+			},
+			{
+				Name:    "region",
+				Flag:    "region",
+				Default: "us-east",
+				Value:   serpent.StringOf(&region),
+			},
+		}
+		inv := c.Invoke("--help")
+		stdio := fakeIO(inv)
+		err := inv.Run()
+		require.NoError(t, err)
+
+		require.Contains(t, stdio.Stdout.String(), "(default: current user)")
+		require.NotContains(t, stdio.Stdout.String(), "alice")
+		require.Contains(t, stdio.Stdout.String(), "(default: us-east)")
+		require.Equal(t, "current user", c.Options.FlagSet().Lookup("user").DefValue)
+	})
+
+	t.Run("ContactInfo", func(t *testing.T) {
+		t.Parallel()
+
+		c := cmd()
+		c.HelpHandler = nil
+		c.ContactInfo = &serpent.ContactInfo{
+			Issues: "https://example.com/issues",
+			Email:  "help@example.com",
+		}
+		inv := c.Invoke("--help")
+		stdio := fakeIO(inv)
+		err := inv.Run()
+		require.NoError(t, err)
+
+		// Hyperlinks are never emitted when output isn't a terminal.
+		require.Contains(t, stdio.Stdout.String(), "https://example.com/issues")
+		require.Contains(t, stdio.Stdout.String(), "help@example.com")
+		require.NotContains(t, stdio.Stdout.String(), "\x1b]8;;")
+
+		// Subcommands inherit the contact info.
+		c.AddSubcommands(&serpent.Command{Use: "sub"})
+		inv = c.Invoke("sub", "--help")
+		stdio = fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "https://example.com/issues")
+	})
+
+	t.Run("FormatMarkdown", func(t *testing.T) {
+		t.Parallel()
+
+		var region string
+		c := cmd()
+		c.HelpHandler = nil
+		c.Short = "Do things."
+		c.Options = serpent.OptionSet{
+			serpent.HelpFormatOption(),
+			{
+				Name:        "region",
+				Flag:        "region",
+				Env:         "REGION",
+				Default:     "us-east",
+				Description: "Region to use.",
+				Value:       serpent.StringOf(&region),
+			},
+		}
+		c.AddSubcommands(&serpent.Command{Use: "sub", Short: "A subcommand."})
+
+		// The format is found after --help too.
+		for _, args := range [][]string{
+			{"--help-format=markdown", "--help"},
+			{"--help", "--help-format", "markdown"},
+		} {
+			inv := c.Invoke(args...)
+			stdio := fakeIO(inv)
+			require.NoError(t, inv.Run(), args)
+
+			out := stdio.Stdout.String()
+			require.Contains(t, out, "# root\n\nDo things.\n\n## Usage\n\n```console\nroot [flags]\n```\n")
+			require.Contains(t, out, "| Type        | `ansi\\|plain\\|markdown` |\n")
+			require.Contains(t, out, "| `sub` | A subcommand. |\n")
+			require.Contains(t, out, "### --region\n\n")
+			require.Contains(t, out, "| Environment | `$REGION` |\n| Default     | `us-east` |\n\nRegion to use.\n")
+		}
+	})
+
+	t.Run("FormatPlain", func(t *testing.T) {
+		t.Parallel()
+
+		c := cmd()
+		c.HelpHandler = nil
+		c.Markdown = true
+		c.Long = "Use **root** with `--help`."
+		inv := c.Invoke("--help")
+		inv.Environ.Set(serpent.HelpFormatEnv, serpent.HelpFormatPlain)
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "USAGE:\n  root\n")
+		require.Contains(t, stdio.Stdout.String(), "  Use root with --help.")
+		require.NotContains(t, stdio.Stdout.String(), "\x1b[")
+	})
+
+	t.Run("OptionColumns", func(t *testing.T) {
+		t.Parallel()
+
+		var format, token string
+		c := cmd()
+		c.HelpHandler = nil
+		c.Options = serpent.OptionSet{
+			{
+				Name:          "format",
+				Flag:          "format",
+				FlagShorthand: "f",
+				Env:           "FORMAT",
+				Default:       "table",
+				Description:   "Output format of the command, which is long enough to wrap at the width of the terminal.",
+				Value:         serpent.EnumOf(&format, "json", "table"),
+			},
+			{
+				Name:        "token",
+				Env:         "TOKEN",
+				Description: "Token.",
+				Value:       serpent.StringOf(&token),
+			},
+		}
+
+		// Options are aligned in columns when descriptions fit next to
+		// them.
+		inv := c.Invoke("--help")
+		inv.Environ.Set("COLUMNS", "100")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "OPTIONS:\n"+
+			"  -f, --format  json|table  $FORMAT  (default: table)  Output format of the command, which is long\n"+
+			"                                                       enough to wrap at the width of the terminal.\n"+
+			"                string      $TOKEN                     Token.\n")
+
+		// Otherwise, they're stacked above their descriptions.
+		inv = c.Invoke("--help")
+		inv.Environ.Set("COLUMNS", "60")
+		stdio = fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "OPTIONS:\n"+
+			"  -f, --format json|table, $FORMAT (default: table)\n"+
+			"          Output format of the command, which is long enough\n"+
+			"          to wrap at the width of the terminal.\n"+
+			"\n"+
+			"      $TOKEN string\n"+
+			"          Token.\n")
+	})
+
+	t.Run("Aliases", func(t *testing.T) {
+		t.Parallel()
+
+		c := cmd()
+		c.HelpHandler = nil
+		c.AddSubcommands(
+			&serpent.Command{Use: "server", Short: "Manage servers.", Aliases: []string{"srv", "s"}},
+			&serpent.Command{Use: "status", Short: "Show the status."},
+		)
+		inv := c.Invoke("--help")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "    server (srv, s)    Manage servers.\n"+
+			"    status             Show the status.\n")
+
+		inv = c.Invoke("server", "--help")
+		stdio = fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "Aliases: srv, s")
+	})
+
+	t.Run("FormatInvalid", func(t *testing.T) {
+		t.Parallel()
+
+		c := cmd()
+		c.HelpHandler = nil
+		inv := c.Invoke("--help")
+		inv.Environ.Set(serpent.HelpFormatEnv, "html")
+		require.ErrorContains(t, inv.Run(), `unknown help format "html"`)
+	})
 }
 
 func TestCommand_SliceFlags(t *testing.T) {
@@ -925,3 +1170,1029 @@ func TestCommand_OptionsWithSharedValue(t *testing.T) {
 	err = makeCmd("def.com", "alt-def.com").Invoke().Run()
 	require.Error(t, err, "default values are different")
 }
+
+func TestCommand_Indirection(t *testing.T) {
+	t.Parallel()
+
+	var token string
+	var tokens []string
+	makeCmd := func(allow bool) *serpent.Command {
+		return &serpent.Command{
+			Use: "root",
+			Options: serpent.OptionSet{
+				{
+					Name:             "token",
+					Flag:             "token",
+					Env:              "TOKEN",
+					Value:            serpent.StringOf(&token),
+					AllowIndirection: allow,
+				},
+			},
+			Children: []*serpent.Command{{
+				Use:     "child",
+				Handler: func(i *serpent.Invocation) error { return nil },
+			}},
+			Handler: func(i *serpent.Invocation) error { return nil },
+		}
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0o600))
+
+	t.Run("File", func(t *testing.T) {
+		cmd := makeCmd(true)
+		err := cmd.Invoke("child", "--token", "@"+tokenFile).Run()
+		require.NoError(t, err)
+		require.Equal(t, "from-file", token)
+		require.Equal(t, "@"+tokenFile, cmd.Options.ByName("token").IndirectSource)
+
+		err = cmd.Invoke("child", "--token", "plain").Run()
+		require.NoError(t, err)
+		require.Equal(t, "plain", token)
+		require.Empty(t, cmd.Options.ByName("token").IndirectSource)
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		inv := makeCmd(true).Invoke()
+		inv.Environ.Set("TOKEN", "env:OTHER")
+		inv.Environ.Set("OTHER", "from-env")
+		require.NoError(t, inv.Run())
+		require.Equal(t, "from-env", token)
+	})
+
+	t.Run("Escaped", func(t *testing.T) {
+		err := makeCmd(true).Invoke("--token", "@@literal").Run()
+		require.NoError(t, err)
+		require.Equal(t, "@literal", token)
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		err := makeCmd(false).Invoke("--token", "@"+tokenFile).Run()
+		require.NoError(t, err)
+		require.Equal(t, "@"+tokenFile, token)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		err := makeCmd(true).Invoke("--token", "env:DOES_NOT_EXIST").Run()
+		require.ErrorContains(t, err, "DOES_NOT_EXIST")
+
+		err = makeCmd(true).Invoke("--token", "@"+filepath.Join(t.TempDir(), "missing")).Run()
+		require.Error(t, err)
+	})
+
+	t.Run("NotString", func(t *testing.T) {
+		cmd := makeCmd(true)
+		cmd.Options = append(cmd.Options, serpent.Option{
+			Name:             "tokens",
+			Flag:             "tokens",
+			Value:            serpent.StringArrayOf(&tokens),
+			AllowIndirection: true,
+		})
+		err := cmd.Invoke().Run()
+		require.ErrorContains(t, err, "allows indirection but is not a string")
+	})
+}
+
+func TestCommand_StringOrStdin(t *testing.T) {
+	t.Parallel()
+
+	makeCmd := func(got *string, maxSize int64) *serpent.Command {
+		val := serpent.StringOrStdinOf(got)
+		val.MaxSize = maxSize
+		return &serpent.Command{
+			Use: "root",
+			Options: serpent.OptionSet{{
+				Name:  "data",
+				Flag:  "data",
+				Value: val,
+			}},
+			Handler: func(i *serpent.Invocation) error { return nil },
+		}
+	}
+
+	t.Run("Stdin", func(t *testing.T) {
+		t.Parallel()
+
+		var got string
+		inv := makeCmd(&got, 0).Invoke("--data", "-")
+		inv.Stdin = strings.NewReader("piped data")
+		require.NoError(t, inv.Run())
+		require.Equal(t, "piped data", got)
+	})
+
+	t.Run("Literal", func(t *testing.T) {
+		t.Parallel()
+
+		var got string
+		inv := makeCmd(&got, 0).Invoke("--data", "literal")
+		inv.Stdin = strings.NewReader("ignored")
+		require.NoError(t, inv.Run())
+		require.Equal(t, "literal", got)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		t.Parallel()
+
+		var got string
+		inv := makeCmd(&got, 4).Invoke("--data", "-")
+		inv.Stdin = strings.NewReader("too much data")
+		err := inv.Run()
+		require.ErrorContains(t, err, "maximum size of 4 bytes")
+	})
+}
+
+func TestInvocation_ReadFileArg(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("file data"), 0o600))
+
+	inv := (&serpent.Command{}).Invoke()
+	inv.Stdin = strings.NewReader("stdin data")
+
+	byt, err := inv.ReadFileArg(path)
+	require.NoError(t, err)
+	require.Equal(t, "file data", string(byt))
+
+	byt, err = inv.ReadFileArg("-")
+	require.NoError(t, err)
+	require.Equal(t, "stdin data", string(byt))
+}
+
+func TestInvocation_EachStdinLine(t *testing.T) {
+	t.Parallel()
+
+	run := func(stdin io.Reader, fn func(string) error, args ...string) ([]string, error) {
+		var lines []string
+		cmd := &serpent.Command{
+			Use:     "root",
+			Options: serpent.OptionSet{serpent.NullOption()},
+			Handler: func(inv *serpent.Invocation) error {
+				return inv.EachStdinLine(func(line string) error {
+					lines = append(lines, line)
+					return fn(line)
+				})
+			},
+		}
+		inv := cmd.Invoke(args...)
+		inv.Stdin = stdin
+		err := inv.Run()
+		return lines, err
+	}
+	ok := func(string) error { return nil }
+
+	t.Run("Lines", func(t *testing.T) {
+		t.Parallel()
+
+		lines, err := run(strings.NewReader("a\r\n\nb c\nd"), ok)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b c", "d"}, lines)
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		t.Parallel()
+
+		lines, err := run(strings.NewReader("a\nb\x00c\x00"), ok, "-0")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a\nb", "c"}, lines)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+
+		lines, err := run(strings.NewReader("a\nb\nc\n"), func(line string) error {
+			if line == "b" {
+				return errors.New("bad item")
+			}
+			return nil
+		})
+		require.ErrorContains(t, err, "bad item")
+		require.Equal(t, []string{"a", "b"}, lines)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		t.Parallel()
+
+		// The pipe is never written to, so reading blocks until canceled.
+		r, w := io.Pipe()
+		defer w.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		cmd := &serpent.Command{
+			Use: "root",
+			Handler: func(inv *serpent.Invocation) error {
+				cancel()
+				return inv.EachStdinLine(ok)
+			},
+		}
+		inv := cmd.Invoke().WithContext(ctx)
+		inv.Stdin = r
+		require.ErrorIs(t, inv.Run(), context.Canceled)
+	})
+}
+
+func TestInvocation_AddOutputHook(t *testing.T) {
+	t.Parallel()
+
+	var (
+		observed []string
+		remove   func()
+	)
+	cmd := &serpent.Command{
+		Use: "root",
+		Middleware: func(next serpent.HandlerFunc) serpent.HandlerFunc {
+			return func(inv *serpent.Invocation) error {
+				remove = inv.AddOutputHook(func(stream serpent.OutputStream, p []byte) {
+					observed = append(observed, string(stream)+":"+string(p))
+				})
+				return next(inv)
+			}
+		},
+		Handler: func(inv *serpent.Invocation) error {
+			_, _ = fmt.Fprint(inv.Stdout, "out")
+			_, _ = fmt.Fprint(inv.Stderr, "err")
+			remove()
+			_, _ = fmt.Fprint(inv.Stdout, "unobserved")
+			return nil
+		},
+	}
+
+	inv := cmd.Invoke()
+	io := fakeIO(inv)
+	require.NoError(t, inv.Run())
+	require.Equal(t, []string{"stdout:out", "stderr:err"}, observed)
+	require.Equal(t, "outunobserved", io.Stdout.String())
+	require.Equal(t, "err", io.Stderr.String())
+}
+
+func TestCommand_GatedGroups(t *testing.T) {
+	t.Parallel()
+
+	cmd := func(tunnel *string) *serpent.Command {
+		experimental := &serpent.Group{
+			Name:        "Experimental Networking",
+			YAML:        "experimental",
+			Description: "Preview networking settings.",
+			Gate:        "APP_EXPERIMENTAL",
+		}
+		internal := &serpent.Group{Name: "Internal", Hidden: true}
+		return &serpent.Command{
+			Use: "server",
+			Options: serpent.OptionSet{
+				{
+					Name:        "tunnel",
+					Description: "Tunnel implementation.",
+					Flag:        "tunnel",
+					Env:         "APP_TUNNEL",
+					YAML:        "tunnel",
+					Group:       experimental,
+					Value:       serpent.StringOf(tunnel),
+				},
+				{
+					Name:        "debug-addr",
+					Description: "Debug listener address.",
+					Flag:        "debug-addr",
+					Group:       internal,
+					Value:       serpent.StringOf(new(string)),
+				},
+			},
+			Handler: func(inv *serpent.Invocation) error { return nil },
+		}
+	}
+
+	t.Run("HelpHidden", func(t *testing.T) {
+		t.Parallel()
+
+		inv := cmd(new(string)).Invoke("--help")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.NotContains(t, stdio.Stdout.String(), "--tunnel")
+		require.NotContains(t, stdio.Stdout.String(), "--debug-addr")
+	})
+
+	t.Run("HelpGateOpen", func(t *testing.T) {
+		t.Parallel()
+
+		inv := cmd(new(string)).Invoke("--help")
+		inv.Environ.Set("APP_EXPERIMENTAL", "1")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "--tunnel")
+		require.NotContains(t, stdio.Stdout.String(), "--debug-addr")
+	})
+
+	t.Run("FlagRejected", func(t *testing.T) {
+		t.Parallel()
+
+		err := cmd(new(string)).Invoke("--tunnel", "wg").Run()
+		require.ErrorContains(t, err, `option "tunnel" set by flag --tunnel is disabled, set APP_EXPERIMENTAL=1 to enable it`)
+	})
+
+	t.Run("EnvRejected", func(t *testing.T) {
+		t.Parallel()
+
+		inv := cmd(new(string)).Invoke()
+		inv.Environ.Set("APP_TUNNEL", "wg")
+		require.ErrorContains(t, inv.Run(), `option "tunnel" set by env APP_TUNNEL is disabled`)
+	})
+
+	t.Run("YAMLRejected", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("experimental:\n  tunnel: wg\n"), 0o600))
+
+		var config serpent.YAMLConfigPath
+		c := cmd(new(string))
+		c.Options = append(c.Options, serpent.Option{Name: "config", Flag: "config", Value: &config})
+		err := c.Invoke("--config", path).Run()
+		require.ErrorContains(t, err, `option "tunnel" set by yaml `+path+` is disabled`)
+	})
+
+	t.Run("GateOpen", func(t *testing.T) {
+		t.Parallel()
+
+		var tunnel string
+		inv := cmd(&tunnel).Invoke("--tunnel", "wg")
+		inv.Environ.Set("APP_EXPERIMENTAL", "true")
+		require.NoError(t, inv.Run())
+		require.Equal(t, "wg", tunnel)
+	})
+}
+
+func TestAnnotations(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Typed", func(t *testing.T) {
+		t.Parallel()
+
+		a := serpent.Annotations{}.Mark("enabled", "true").Mark("weight", "10").Mark("bad", "x")
+		b, ok := a.GetBool("enabled")
+		require.True(t, ok)
+		require.True(t, b)
+		_, ok = a.GetBool("bad")
+		require.False(t, ok)
+		i, ok := a.GetInt("weight")
+		require.True(t, ok)
+		require.Equal(t, 10, i)
+		_, ok = a.GetInt("missing")
+		require.False(t, ok)
+
+		var nilAnnotations serpent.Annotations
+		_, ok = nilAnnotations.GetInt("weight")
+		require.False(t, ok)
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		t.Parallel()
+
+		a := serpent.Annotations{}.
+			MarkNamespace("docs", "weight", "10").
+			MarkNamespace("docs", "group", "admin").
+			Mark("docsless", "x")
+		require.Equal(t, serpent.Annotations{"weight": "10", "group": "admin"}, a.Namespace("docs"))
+		require.Nil(t, a.Namespace("router"))
+	})
+
+	t.Run("Inherited", func(t *testing.T) {
+		t.Parallel()
+
+		var got serpent.Annotations
+		root := &serpent.Command{
+			Use:         "root",
+			Annotations: serpent.Annotations{"group": "admin", "weight": "1"},
+			Children: []*serpent.Command{
+				{
+					Use:         "child",
+					Annotations: serpent.Annotations{"weight": "2"},
+					Handler: func(inv *serpent.Invocation) error {
+						got = inv.Command.InheritedAnnotations()
+						v, ok := inv.Command.Annotation("group")
+						require.True(t, ok)
+						require.Equal(t, "admin", v)
+						_, ok = inv.Command.Annotation("missing")
+						require.False(t, ok)
+						return nil
+					},
+				},
+			},
+		}
+		require.NoError(t, root.Invoke("child").Run())
+		require.Equal(t, serpent.Annotations{"group": "admin", "weight": "2"}, got)
+		require.Equal(t, serpent.Annotations{"group": "admin", "weight": "1"}, root.Annotations)
+	})
+}
+
+func TestCommand_PersistentMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	record := func(name string) serpent.MiddlewareFunc {
+		return func(next serpent.HandlerFunc) serpent.HandlerFunc {
+			return func(inv *serpent.Invocation) error {
+				calls = append(calls, name)
+				return next(inv)
+			}
+		}
+	}
+	root := &serpent.Command{
+		Use:                  "root",
+		PersistentMiddleware: record("root"),
+		Children: []*serpent.Command{
+			{
+				Use:                  "parent",
+				PersistentMiddleware: record("parent"),
+				Children: []*serpent.Command{
+					{
+						Use:        "leaf",
+						Middleware: record("leaf"),
+						Handler: func(*serpent.Invocation) error {
+							calls = append(calls, "handler")
+							return nil
+						},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, root.Invoke("parent", "leaf").Run())
+	require.Equal(t, []string{"root", "parent", "leaf", "handler"}, calls)
+}
+
+func TestCommand_TraverseChildren(t *testing.T) {
+	t.Parallel()
+
+	type result struct {
+		name  string
+		force bool
+		args  []string
+	}
+	cmd := func(traverse bool, res *result) *serpent.Command {
+		return &serpent.Command{
+			Use:              "root",
+			TraverseChildren: traverse,
+			Options: serpent.OptionSet{
+				{Name: "name", Flag: "name", Value: serpent.StringOf(&res.name)},
+			},
+			Children: []*serpent.Command{
+				{
+					Use: "start",
+					Options: serpent.OptionSet{
+						{Name: "force", Flag: "name", Override: true, Value: serpent.BoolOf(&res.force)},
+					},
+					Handler: func(inv *serpent.Invocation) error {
+						res.args = inv.Args
+						return nil
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("Flat", func(t *testing.T) {
+		t.Parallel()
+
+		// Without traversal, the child's bool flag takes over and the
+		// parent's flag value is misrouted as an argument.
+		var res result
+		require.NoError(t, cmd(false, &res).Invoke("--name", "start", "start").Run())
+		require.True(t, res.force)
+		require.Equal(t, []string{"start"}, res.args)
+	})
+
+	t.Run("Traverse", func(t *testing.T) {
+		t.Parallel()
+
+		var res result
+		require.NoError(t, cmd(true, &res).Invoke("--name", "start", "start", "arg").Run())
+		require.Equal(t, "start", res.name)
+		require.False(t, res.force)
+		require.Equal(t, []string{"arg"}, res.args)
+	})
+
+	t.Run("ChildFlagsAfterName", func(t *testing.T) {
+		t.Parallel()
+
+		var res result
+		require.NoError(t, cmd(true, &res).Invoke("start", "arg", "--name").Run())
+		require.True(t, res.force)
+		require.Equal(t, []string{"arg"}, res.args)
+	})
+
+	t.Run("ChildFlagBeforeName", func(t *testing.T) {
+		t.Parallel()
+
+		var res result
+		err := cmd(true, &res).Invoke("--unknown", "start").Run()
+		require.ErrorContains(t, err, "unknown flag: --unknown")
+	})
+
+	t.Run("SliceFlagNoChild", func(t *testing.T) {
+		t.Parallel()
+
+		var tags []string
+		root := &serpent.Command{
+			Use:              "root",
+			TraverseChildren: true,
+			Options: serpent.OptionSet{
+				{Name: "tag", Flag: "tag", Value: serpent.StringArrayOf(&tags)},
+			},
+			Handler:  func(inv *serpent.Invocation) error { return nil },
+			Children: []*serpent.Command{{Use: "start"}},
+		}
+		require.NoError(t, root.Invoke("--tag", "a", "x").Run())
+		require.Equal(t, []string{"a"}, tags)
+		require.NoError(t, root.Invoke("--tag", "a", "--tag", "b", "x").Run())
+		require.Equal(t, []string{"a", "b"}, tags)
+	})
+}
+
+func TestInvocation_WithIO(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := &serpent.Command{
+		Use: "echo",
+		Handler: func(inv *serpent.Invocation) error {
+			in, err := io.ReadAll(inv.Stdin)
+			if err != nil {
+				return err
+			}
+			_, _ = inv.Stdout.Write(in)
+			_, _ = inv.Stderr.Write([]byte("done"))
+			return nil
+		},
+	}
+	err := cmd.Invoke().WithIO(strings.NewReader("hello"), &stdout, &stderr).Run()
+	require.NoError(t, err)
+	require.Equal(t, "hello", stdout.String())
+	require.Equal(t, "done", stderr.String())
+}
+
+//nolint:paralleltest // t.Setenv can't be used in parallel tests.
+func TestInvocation_WithEnvPrefix(t *testing.T) {
+	t.Setenv("SERPENT_TEST_TOKEN", "secret")
+
+	var token string
+	cmd := &serpent.Command{
+		Use: "app",
+		Options: serpent.OptionSet{
+			{Name: "token", Env: "TOKEN", Value: serpent.StringOf(&token)},
+		},
+		Handler: func(*serpent.Invocation) error { return nil },
+	}
+	inv := cmd.Invoke("arg").WithEnvPrefix("SERPENT_TEST_")
+	require.NoError(t, inv.Run())
+	require.Equal(t, "secret", token)
+
+	inv = cmd.Invoke("arg").WithOSNoArgs()
+	require.Equal(t, []string{"arg"}, inv.Args)
+	require.Equal(t, "secret", inv.Environ.Get("SERPENT_TEST_TOKEN"))
+}
+
+func TestInvocation_WithMessageHandler(t *testing.T) {
+	t.Parallel()
+
+	cmd := func() *serpent.Command {
+		return &serpent.Command{
+			Use:        "old",
+			Deprecated: "Use new instead.",
+			Options:    serpent.OptionSet{serpent.MachineModeOption("APP_MACHINE")},
+			Handler: func(inv *serpent.Invocation) error {
+				inv.Warn("careful")
+				return nil
+			},
+		}
+	}
+
+	t.Run("Filter", func(t *testing.T) {
+		t.Parallel()
+
+		var kinds []string
+		inv := cmd().Invoke().WithMessageHandler(func(inv *serpent.Invocation, msg serpent.Message) {
+			kinds = append(kinds, msg.Kind)
+			if msg.Kind != serpent.MessageKindDeprecated {
+				serpent.DefaultMessageHandler(inv, msg)
+			}
+		})
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Equal(t, []string{serpent.MessageKindDeprecated, ""}, kinds)
+		require.NotContains(t, stdio.Stderr.String(), "deprecated")
+		require.Contains(t, stdio.Stderr.String(), "WARNING: careful")
+	})
+
+	t.Run("MachineMode", func(t *testing.T) {
+		t.Parallel()
+
+		inv := cmd().Invoke("--json-log")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.NotContains(t, stdio.Stderr.String(), "WARNING")
+		require.Contains(t, stdio.Stderr.String(), `"msg":"\"old\" is deprecated!"`)
+	})
+}
+
+func TestCommand_Find(t *testing.T) {
+	t.Parallel()
+
+	var (
+		verbose bool
+		force   bool
+		created string
+	)
+	root := &serpent.Command{
+		Use: "app",
+		Options: serpent.OptionSet{
+			{Name: "verbose", Flag: "verbose", Value: serpent.BoolOf(&verbose)},
+		},
+		Children: []*serpent.Command{
+			{
+				Use:     "workspace",
+				Aliases: []string{"ws"},
+				Children: []*serpent.Command{
+					{
+						Use: "create <name>",
+						Options: serpent.OptionSet{
+							{Name: "force", Flag: "force", Value: serpent.BoolOf(&force)},
+						},
+						Handler: func(inv *serpent.Invocation) error {
+							created = inv.Args[0]
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Use: "quickstart",
+				Handler: func(inv *serpent.Invocation) error {
+					return inv.InvokeSubcommand([]string{"workspace", "create"}, []string{"demo", "--force"})
+				},
+			},
+			{
+				Use: "broken",
+				Handler: func(inv *serpent.Invocation) error {
+					return inv.InvokeSubcommand([]string{"workspace", "missing"}, nil)
+				},
+			},
+		},
+	}
+
+	// The subtests share the command tree, so they don't run in parallel.
+	t.Run("Find", func(t *testing.T) {
+		create := root.Find("ws", "create")
+		require.NotNil(t, create)
+		require.Equal(t, "app workspace create", create.FullName())
+		require.Same(t, root, root.Find())
+		require.Nil(t, root.Find("workspace", "missing"))
+	})
+
+	t.Run("InvokeSubcommand", func(t *testing.T) {
+		require.NoError(t, root.Invoke("--verbose", "quickstart").Run())
+		require.Equal(t, "demo", created)
+		require.True(t, force)
+		require.True(t, verbose)
+
+		err := root.Invoke("broken").Run()
+		require.ErrorContains(t, err, `no command "app workspace missing"`)
+	})
+}
+
+func TestCommand_AllowPrefixMatching(t *testing.T) {
+	t.Parallel()
+
+	cmd := func(allow bool) (*serpent.Command, *string) {
+		var ran string
+		handler := func(inv *serpent.Invocation) error {
+			ran = inv.Command.FullName() + " " + strings.Join(inv.Args, " ")
+			return nil
+		}
+		return &serpent.Command{
+			Use:                 "app",
+			AllowPrefixMatching: allow,
+			Children: []*serpent.Command{
+				{
+					Use: "workspace",
+					Children: []*serpent.Command{
+						{Use: "list", Handler: handler},
+						{Use: "delete", Handler: handler},
+					},
+				},
+				{Use: "worker", Handler: handler},
+				{Use: "exec", RawArgs: true, Handler: handler},
+			},
+		}, &ran
+	}
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		ran  string
+	}{
+		{name: "Nested", args: []string{"works", "li"}, ran: "app workspace list "},
+		{name: "Exact", args: []string{"worker"}, ran: "app worker "},
+		{name: "RawArgs", args: []string{"ex", "--foo", "bar"}, ran: "app exec --foo bar"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd, ran := cmd(true)
+			require.NoError(t, cmd.Invoke(tc.args...).Run())
+			require.Equal(t, tc.ran, *ran)
+		})
+	}
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		t.Parallel()
+
+		cmd, ran := cmd(true)
+		err := cmd.Invoke("wo", "list").Run()
+		require.ErrorContains(t, err, `ambiguous command "wo", it could be worker, workspace`)
+		require.Empty(t, *ran)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+
+		cmd, ran := cmd(false)
+		_ = cmd.Invoke("works", "li").Run()
+		require.Empty(t, *ran)
+	})
+}
+
+func TestCommand_CaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	cmd := func(fold bool) (*serpent.Command, *string, *bool) {
+		var (
+			ran     string
+			verbose bool
+			format  string
+		)
+		return &serpent.Command{
+			Use:             "app",
+			CaseInsensitive: fold,
+			Options: serpent.OptionSet{
+				{Name: "verbose", Flag: "verbose", Value: serpent.BoolOf(&verbose)},
+			},
+			Children: []*serpent.Command{{
+				Use:     "workspace",
+				Aliases: []string{"ws"},
+				Children: []*serpent.Command{{
+					Use:   "list",
+					Short: "List workspaces.",
+					Options: serpent.OptionSet{
+						{Name: "format", Flag: "format", Value: serpent.EnumOf(&format, "json", "table")},
+					},
+					Handler: func(inv *serpent.Invocation) error {
+						ran = inv.Command.FullName() + " " + format
+						return nil
+					},
+				}},
+			}},
+		}, &ran, &verbose
+	}
+
+	t.Run("Dispatch", func(t *testing.T) {
+		t.Parallel()
+
+		cmd, ran, verbose := cmd(true)
+		require.NoError(t, cmd.Invoke("Workspace", "LIST", "--Verbose", "--FORMAT=json").Run())
+		require.Equal(t, "app workspace list json", *ran)
+		require.True(t, *verbose)
+
+		require.NoError(t, cmd.Invoke("WS", "List", "--format", "table").Run())
+		require.Equal(t, "app workspace list table", *ran)
+	})
+
+	t.Run("Completion", func(t *testing.T) {
+		t.Parallel()
+
+		cmd, _, _ := cmd(true)
+		inv := cmd.Invoke("WORKSPACE", "List", "--Format", "")
+		inv.Environ.Set(serpent.CompletionModeEnv, "1")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Equal(t, "json\ntable\n", stdio.Stdout.String())
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		t.Parallel()
+
+		cmd, _, _ := cmd(true)
+		require.NotNil(t, cmd.Find("Workspace", "LIST"))
+		inv := cmd.Invoke("help", "Workspace", "LIST")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "List workspaces.")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+
+		cmd, ran, _ := cmd(false)
+		require.Error(t, cmd.Invoke("workspace", "list", "--Verbose").Run())
+		require.Nil(t, cmd.Find("Workspace"))
+		_ = cmd.Invoke("Workspace", "list").Run()
+		require.Empty(t, *ran)
+	})
+}
+
+// levelValue is a level that defaults to "1" when its flag has no value.
+type levelValue struct{ serpent.Int64 }
+
+func (*levelValue) NoOptDefValue() string { return "1" }
+
+func TestCommand_ShortFlags(t *testing.T) {
+	t.Parallel()
+
+	type result struct {
+		A, B    bool
+		N       int64
+		Level   int64
+		Values  []string
+		Origins map[string]string
+		Ran     string
+		Args    []string
+	}
+	run := func(t *testing.T, env map[string]string, args ...string) (result, error) {
+		t.Helper()
+		var (
+			r     = result{Origins: map[string]string{}}
+			level levelValue
+		)
+		cmd := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "a", Flag: "all", FlagShorthand: "a", Value: serpent.BoolOf(&r.A)},
+				{Name: "b", Flag: "bare", FlagShorthand: "b", Value: serpent.BoolOf(&r.B)},
+				{Name: "n", Flag: "num", FlagShorthand: "n", Value: serpent.Int64Of(&r.N)},
+				{Name: "level", Flag: "level", FlagShorthand: "l", Value: &level},
+				{Name: "values", Flag: "value", FlagShorthand: "5", Env: "APP_VALUES", Value: serpent.StringArrayOf(&r.Values)},
+			},
+			Handler: func(inv *serpent.Invocation) error {
+				r.Ran, r.Args = inv.Command.Name(), inv.Args
+				for _, opt := range inv.Command.Options {
+					if opt.ValueSource == serpent.ValueSourceFlag {
+						r.Origins[opt.Name] = opt.ValueSourceDetail
+					}
+				}
+				return nil
+			},
+			Children: []*serpent.Command{{
+				Use:     "exec",
+				RawArgs: true,
+				Handler: func(inv *serpent.Invocation) error {
+					r.Ran, r.Args = inv.Command.Name(), inv.Args
+					return nil
+				},
+			}},
+		}
+		inv := cmd.Invoke(args...)
+		for k, v := range env {
+			inv.Environ.Set(k, v)
+		}
+		err := inv.Run()
+		r.Level = level.Value()
+		return r, err
+	}
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want func(r result) bool
+	}{
+		{name: "Combined", args: []string{"-ab"}, want: func(r result) bool { return r.A && r.B }},
+		{name: "Attached", args: []string{"-n5"}, want: func(r result) bool { return r.N == 5 }},
+		{name: "Equals", args: []string{"-n=5"}, want: func(r result) bool { return r.N == 5 }},
+		{name: "Separate", args: []string{"-n", "5"}, want: func(r result) bool { return r.N == 5 }},
+		{name: "CombinedValue", args: []string{"-abn5"}, want: func(r result) bool { return r.A && r.B && r.N == 5 }},
+		{name: "CombinedSeparateValue", args: []string{"-abn", "5", "x"}, want: func(r result) bool {
+			return r.A && r.B && r.N == 5 && slices.Equal(r.Args, []string{"x"})
+		}},
+		{name: "BoolEquals", args: []string{"-a=false", "-b"}, want: func(r result) bool { return !r.A && r.B }},
+		{name: "NoOptDefValue", args: []string{"-l", "x"}, want: func(r result) bool {
+			return r.Level == 1 && slices.Equal(r.Args, []string{"x"})
+		}},
+		{name: "NoOptDefValueEquals", args: []string{"-l=3"}, want: func(r result) bool { return r.Level == 3 }},
+		{name: "NoOptDefValueCombined", args: []string{"-la"}, want: func(r result) bool { return r.Level == 1 && r.A }},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := run(t, nil, tc.args...)
+			require.NoError(t, err)
+			require.True(t, tc.want(r), "%+v", r)
+		})
+	}
+
+	t.Run("Origins", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := run(t, nil, "-abn5", "--all")
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"a": "--all", "b": "-b", "n": "-n"}, r.Origins)
+	})
+
+	t.Run("ValueNotShorthand", func(t *testing.T) {
+		t.Parallel()
+
+		// The 5 of -n5 is a value, not the -5 shorthand, so the values set
+		// by the environment are kept.
+		r, err := run(t, map[string]string{"APP_VALUES": "x,y"}, "-n5")
+		require.NoError(t, err)
+		require.Equal(t, []string{"x", "y"}, r.Values)
+		require.NotContains(t, r.Origins, "values")
+	})
+
+	t.Run("RawArgs", func(t *testing.T) {
+		t.Parallel()
+
+		for _, args := range [][]string{
+			{"-an5", "exec", "--", "x"},
+			{"-n", "5", "exec", "--", "x"},
+		} {
+			r, err := run(t, nil, args...)
+			require.NoError(t, err, args)
+			require.Equal(t, "exec", r.Ran, args)
+			require.Equal(t, []string{"--", "x"}, r.Args, args)
+		}
+	})
+}
+
+func TestCommand_ReplacedBy(t *testing.T) {
+	t.Parallel()
+
+	cmd := func() (*serpent.Command, *string) {
+		var (
+			ran   string
+			all   bool
+			owner string
+		)
+		handler := func(inv *serpent.Invocation) error {
+			ran = fmt.Sprintf("%s %v %v %s", inv.Command.FullName(), inv.Args, all, owner)
+			return nil
+		}
+		return &serpent.Command{
+			Use: "app",
+			Children: []*serpent.Command{
+				{
+					Use: "workspace",
+					Children: []*serpent.Command{
+						{
+							Use: "list",
+							Options: serpent.OptionSet{
+								{Name: "all", Flag: "all", FlagShorthand: "a", Value: serpent.BoolOf(&all)},
+								{Name: "owner", Flag: "owner", Value: serpent.StringOf(&owner)},
+							},
+							Handler: handler,
+						},
+					},
+				},
+				{
+					Use:           "ls",
+					Deprecated:    "Use workspace list instead.",
+					ReplacedBy:    []string{"workspace", "list"},
+					ReplacedFlags: map[string]string{"user": "owner"},
+					Options: serpent.OptionSet{
+						{Name: "user", Flag: "user", Value: serpent.StringOf(new(string))},
+					},
+				},
+			},
+		}, &ran
+	}
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		ran  string
+	}{
+		{name: "Plain", args: []string{"ls"}, ran: "app workspace list [] false "},
+		{name: "Args", args: []string{"ls", "-a", "foo"}, ran: "app workspace list [foo] true "},
+		{name: "RenamedFlag", args: []string{"ls", "--user", "me", "--all"}, ran: "app workspace list [] true me"},
+		{name: "RenamedFlagValue", args: []string{"ls", "--user=me", "-a"}, ran: "app workspace list [] true me"},
+		{name: "HyphenHyphen", args: []string{"ls", "--", "--user"}, ran: "app workspace list [--user] false "},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd, ran := cmd()
+			inv := cmd.Invoke(tc.args...)
+			io := fakeIO(inv)
+			require.NoError(t, inv.Run())
+			require.Equal(t, tc.ran, *ran)
+			require.Contains(t, io.Stderr.String(), `"app ls" is deprecated!. Use workspace list instead. Running "app workspace list" instead.`)
+		})
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		t.Parallel()
+
+		cmd, _ := cmd()
+		cmd.Children[1].ReplacedBy = []string{"gone"}
+		err := cmd.Invoke("ls").Run()
+		require.ErrorContains(t, err, `redirecting "app ls": no command "app gone"`)
+	})
+}
@@ -2,6 +2,7 @@ package serpent
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/spf13/pflag"
 )
@@ -10,15 +11,58 @@ import (
 // set when the command is being run in completion mode.
 const CompletionModeEnv = "COMPLETION_MODE"
 
+// CompletionDescriptionsEnv is set by completion scripts of shells that
+// show descriptions next to completions, such as fish. Completions may then
+// be followed by a tab and their description.
+const CompletionDescriptionsEnv = "COMPLETION_DESCRIPTIONS"
+
 // IsCompletionMode returns true if the command is being run in completion mode.
 func (inv *Invocation) IsCompletionMode() bool {
 	_, ok := inv.Environ.Lookup(CompletionModeEnv)
 	return ok
 }
 
-// DefaultCompletionHandler is a handler that prints all the subcommands, or
-// all the options that haven't been exhaustively set, if the current word
-// starts with a dash.
+// CurArgIndex returns the index of the positional argument being completed,
+// not counting flags and their values. It's only meaningful in completion
+// handlers.
+func (inv *Invocation) CurArgIndex() int {
+	return max(inv.curArgIndex, 0)
+}
+
+var resources = struct {
+	sync.RWMutex
+	listers map[string]CompletionHandlerFunc
+}{listers: make(map[string]CompletionHandlerFunc)}
+
+// RegisterResource makes lister complete arguments naming the given
+// resource, e.g. workspaces, see Argument.CompleteResource. Registering a
+// resource again replaces its lister.
+func RegisterResource(resource string, lister CompletionHandlerFunc) {
+	resources.Lock()
+	defer resources.Unlock()
+	resources.listers[resource] = lister
+}
+
+// ResourceCompletion returns a handler completing names of the registered
+// resource. The lister is looked up at completion time, so handlers may be
+// declared before the resource is registered. An unregistered resource
+// completes nothing.
+func ResourceCompletion(resource string) CompletionHandlerFunc {
+	return func(inv *Invocation) []string {
+		resources.RLock()
+		lister := resources.listers[resource]
+		resources.RUnlock()
+		if lister == nil {
+			return nil
+		}
+		return lister(inv)
+	}
+}
+
+// DefaultCompletionHandler is a handler that prints all the visible
+// subcommands, and their aliases if Command.CompleteAliases is set, or all
+// the options that haven't been exhaustively set, if the current word starts
+// with a dash.
 func DefaultCompletionHandler(inv *Invocation) []string {
 	_, cur := inv.CurWords()
 	var allResps []string
@@ -29,12 +73,21 @@ func DefaultCompletionHandler(inv *Invocation) []string {
 				opt.ValueSource == ValueSourceDefault ||
 				isSlice {
 				allResps = append(allResps, "--"+opt.Flag)
+				if opt.showsNegation() {
+					allResps = append(allResps, "--no-"+opt.Flag)
+				}
 			}
 		}
 		return allResps
 	}
 	for _, cmd := range inv.Command.Children {
+		if cmd.Hidden {
+			continue
+		}
 		allResps = append(allResps, cmd.Name())
+		if inv.Command.completeAliases() {
+			allResps = append(allResps, cmd.Aliases...)
+		}
 	}
 	return allResps
 }
@@ -0,0 +1,108 @@
+package completion
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	home "github.com/mitchellh/go-homedir"
+
+	"github.com/bketelsen/serpent"
+)
+
+// AnnotationAlias is the command annotation holding the name of a shell
+// alias for the command, e.g. "mwl" for "myapp workspace list".
+const AnnotationAlias = "completion.alias"
+
+const (
+	aliasesStartTemplate = `# ============ BEGIN {{.Name}} ALIASES ============`
+	aliasesEndTemplate   = `# ============ END {{.Name}} ALIASES ==============`
+)
+
+// Alias is a shell alias for a command.
+type Alias struct {
+	Name string
+	// Command is the command line the alias expands to.
+	Command string
+}
+
+// Aliases returns the aliases declared with AnnotationAlias on root and its
+// descendants, in depth-first order. Command lines start with programName,
+// or the name of root if it's empty.
+func Aliases(root *serpent.Command, programName string) []Alias {
+	if programName == "" {
+		programName = root.Name()
+	}
+	var aliases []Alias
+	root.Walk(func(cmd *serpent.Command) {
+		name, ok := cmd.Annotations.Get(AnnotationAlias)
+		if !ok || name == "" {
+			return
+		}
+		var path []string
+		for c := cmd; c != root && c != nil; c = c.Parent {
+			path = append([]string{c.Name()}, path...)
+		}
+		aliases = append(aliases, Alias{
+			Name:    name,
+			Command: strings.Join(append([]string{programName}, path...), " "),
+		})
+	})
+	return aliases
+}
+
+// WriteAliases writes the aliases declared on root and its descendants in
+// the syntax of shell: aliases for bash and zsh, abbreviations for fish and
+// functions for PowerShell.
+func WriteAliases(w io.Writer, shell Shell, root *serpent.Command) error {
+	for _, a := range Aliases(root, shell.ProgramName()) {
+		var line string
+		switch shell.Name() {
+		case ShellBash, ShellZsh:
+			line = fmt.Sprintf("alias %s='%s'", a.Name, a.Command)
+		case ShellFish:
+			line = fmt.Sprintf("abbr --add %s '%s'", a.Name, a.Command)
+		case ShellPowershell:
+			line = fmt.Sprintf("function %s { %s @args }", a.Name, a.Command)
+		default:
+			return fmt.Errorf("unsupported shell %q", shell.Name())
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AliasesInstallPath returns the file InstallShellAliases writes to. It's
+// the completion script for every shell but fish, whose completion scripts
+// are only loaded on demand, so abbreviations go to conf.d instead.
+func AliasesInstallPath(shell Shell) (string, error) {
+	if shell.Name() != ShellFish {
+		return shell.InstallPath()
+	}
+	homeDir, err := home.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config/fish/conf.d/", shell.ProgramName()+"-aliases.fish"), nil
+}
+
+// InstallShellAliases installs the aliases declared on root and its
+// descendants alongside the completion script, replacing any previously
+// installed aliases.
+func InstallShellAliases(shell Shell, root *serpent.Command) error {
+	path, err := AliasesInstallPath(shell)
+	if err != nil {
+		return fmt.Errorf("get install path: %w", err)
+	}
+	err = installSection(path, shell.ProgramName(), aliasesStartTemplate, aliasesEndTemplate, func(w io.Writer) error {
+		_, _ = fmt.Fprintln(w)
+		return WriteAliases(w, shell, root)
+	})
+	if err != nil {
+		return fmt.Errorf("install aliases: %w", err)
+	}
+	return nil
+}
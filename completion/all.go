@@ -115,14 +115,25 @@ func InstallShellCompletion(shell Shell) error {
 	if err != nil {
 		return fmt.Errorf("get install path: %w", err)
 	}
+	err = installSection(path, shell.ProgramName(), completionStartTemplate, completionEndTemplate, shell.WriteCompletion)
+	if err != nil {
+		return fmt.Errorf("install completion: %w", err)
+	}
+	return nil
+}
+
+// installSection writes the output of write to the file at path, between a
+// header and footer rendered from the given templates. An existing section
+// is replaced, leaving the rest of the file untouched.
+func installSection(path, programName, startTemplate, endTemplate string, write func(io.Writer) error) error {
 	var headerBuf bytes.Buffer
-	err = writeConfig(&headerBuf, completionStartTemplate, shell.ProgramName())
+	err := writeConfig(&headerBuf, startTemplate, programName)
 	if err != nil {
 		return fmt.Errorf("generate header: %w", err)
 	}
 
 	var footerBytes bytes.Buffer
-	err = writeConfig(&footerBytes, completionEndTemplate, shell.ProgramName())
+	err = writeConfig(&footerBytes, endTemplate, programName)
 	if err != nil {
 		return fmt.Errorf("generate footer: %w", err)
 	}
@@ -148,9 +159,9 @@ func InstallShellCompletion(shell Shell) error {
 		_, _ = outBuf.Write([]byte("\n"))
 	}
 	_, _ = outBuf.Write(headerBuf.Bytes())
-	err = shell.WriteCompletion(&outBuf)
+	err = write(&outBuf)
 	if err != nil {
-		return fmt.Errorf("generate completion: %w", err)
+		return fmt.Errorf("generate: %w", err)
 	}
 	_, _ = outBuf.Write(footerBytes.Bytes())
 	_, _ = outBuf.Write([]byte("\n"))
@@ -158,7 +169,7 @@ func InstallShellCompletion(shell Shell) error {
 
 	err = atomic.WriteFile(path, &outBuf)
 	if err != nil {
-		return fmt.Errorf("write completion: %w", err)
+		return fmt.Errorf("write: %w", err)
 	}
 
 	return nil
@@ -196,3 +207,31 @@ func templateConfigSplit(header, footer, data []byte) (before, after []byte, err
 	}
 	return data, nil, nil
 }
+
+// InstalledCheck returns a doctor check that verifies completion is
+// installed for the given shell.
+func InstalledCheck(shell Shell) serpent.Check {
+	return serpent.Check{
+		Name: "Completion installed",
+		Run: func(*serpent.Invocation) serpent.CheckResult {
+			path, err := shell.InstallPath()
+			if err != nil {
+				return serpent.CheckResult{Status: serpent.CheckWarn, Message: err.Error()}
+			}
+			var header bytes.Buffer
+			err = writeConfig(&header, completionStartTemplate, shell.ProgramName())
+			if err != nil {
+				return serpent.CheckResult{Status: serpent.CheckFail, Message: err.Error()}
+			}
+			f, err := os.ReadFile(path)
+			if err != nil || !bytes.Contains(f, header.Bytes()) {
+				return serpent.CheckResult{
+					Status:      serpent.CheckWarn,
+					Message:     fmt.Sprintf("%s completion not found in %s", shell.Name(), path),
+					Remediation: fmt.Sprintf("Install completion for %s.", shell.Name()),
+				}
+			}
+			return serpent.CheckResult{Status: serpent.CheckPass, Message: path}
+		},
+	}
+}
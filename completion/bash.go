@@ -10,6 +10,7 @@ import (
 type bash struct {
 	goos        string
 	programName string
+	compat      bool
 }
 
 var _ Shell = &bash{}
@@ -18,6 +19,13 @@ func Bash(goos string, programName string) Shell {
 	return &bash{goos: goos, programName: programName}
 }
 
+// BashCompat returns a bash shell whose completion script also works with
+// bash 3.2, the version shipped with macOS. It avoids mapfile, associative
+// arrays and compopt.
+func BashCompat(goos string, programName string) Shell {
+	return &bash{goos: goos, programName: programName, compat: true}
+}
+
 func (b *bash) Name() string {
 	return "bash"
 }
@@ -34,6 +42,9 @@ func (b *bash) InstallPath() (string, error) {
 }
 
 func (b *bash) WriteCompletion(w io.Writer) error {
+	if b.compat {
+		return writeConfig(w, bashCompatCompletionTemplate, b.programName)
+	}
 	return writeConfig(w, bashCompletionTemplate, b.programName)
 }
 
@@ -60,3 +71,29 @@ _generate_{{.Name}}_completions() {
 # Setup Bash to use the function for completions for '{{.Name}}'
 complete -F _generate_{{.Name}}_completions {{.Name}}
 `
+
+const bashCompatCompletionTemplate = `
+_generate_{{.Name}}_completions() {
+    local args=("${COMP_WORDS[@]:1:COMP_CWORD}")
+
+    # Lines are read one at a time to stay compatible with bash 3.2.
+    local line
+    local -a output=()
+    while IFS= read -r line; do
+        output+=("$line")
+    done < <(COMPLETION_MODE=1 "{{.Name}}" "${args[@]}")
+
+    local -a completions=()
+    while IFS= read -r line; do
+        completions+=("$line")
+    done < <( compgen -W "$(printf '%q ' "${output[@]}")" -- "$2" )
+
+    local comp
+    COMPREPLY=()
+    for comp in "${completions[@]}"; do
+        COMPREPLY+=("$(printf "%q" "$comp")")
+    done
+}
+# Setup Bash to use the function for completions for '{{.Name}}'
+complete -F _generate_{{.Name}}_completions {{.Name}}
+`
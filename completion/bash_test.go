@@ -0,0 +1,107 @@
+package completion_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent/completion"
+)
+
+// bash3Incompatible are constructs unavailable in bash 3.2.
+var bash3Incompatible = []string{"mapfile", "readarray", "declare -A", "local -A", "compopt", "${line,,}", "${line^^}"}
+
+func TestBashCompat(t *testing.T) {
+	t.Parallel()
+
+	var script bytes.Buffer
+	require.NoError(t, completion.BashCompat("darwin", "fake").WriteCompletion(&script))
+	for _, construct := range bash3Incompatible {
+		require.NotContains(t, script.String(), construct)
+	}
+}
+
+// TestBashCompletionMatrix runs the completion scripts with every bash found
+// on the machine, e.g. bash 3.2 at /bin/bash on macOS and a newer bash from
+// Homebrew.
+func TestBashCompletionMatrix(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a POSIX environment")
+	}
+
+	var shells []string
+	seen := make(map[string]bool)
+	for _, candidate := range []string{"bash", "/bin/bash", "/usr/local/bin/bash", "/opt/homebrew/bin/bash"} {
+		path, err := exec.LookPath(candidate)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		if !seen[path] {
+			seen[path] = true
+			shells = append(shells, path)
+		}
+	}
+	if len(shells) == 0 {
+		t.Skip("bash not found")
+	}
+
+	// The fake program prints its completions in completion mode.
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "fake"), []byte(
+		"#!/bin/sh\n[ -n \"$COMPLETION_MODE\" ] && printf 'foo\\nfoobar\\nbar baz\\n'\n",
+	), 0o755))
+
+	for _, tc := range []struct {
+		name  string
+		shell completion.Shell
+		// requiresBash4 is set for scripts using bash 4 features.
+		requiresBash4 bool
+	}{
+		{name: "Default", shell: completion.Bash("linux", "fake"), requiresBash4: true},
+		{name: "Compat", shell: completion.BashCompat("darwin", "fake")},
+	} {
+		var script bytes.Buffer
+		require.NoError(t, tc.shell.WriteCompletion(&script))
+		scriptPath := filepath.Join(t.TempDir(), "completion.bash")
+		require.NoError(t, os.WriteFile(scriptPath, script.Bytes(), 0o600))
+
+		for _, shell := range shells {
+			tc, shell := tc, shell
+			t.Run(tc.name+"/"+shell, func(t *testing.T) {
+				t.Parallel()
+
+				version, err := exec.Command(shell, "-c", "echo ${BASH_VERSINFO[0]}").Output()
+				require.NoError(t, err)
+				major, err := strconv.Atoi(strings.TrimSpace(string(version)))
+				require.NoError(t, err)
+				if tc.requiresBash4 && major < 4 {
+					t.Skip("script requires bash 4")
+				}
+
+				run := func(words string, cword int, cur string) string {
+					cmd := exec.Command(shell, "-c", `source "$1"; COMP_WORDS=(`+words+`); COMP_CWORD=$2; `+
+						`_generate_fake_completions fake "$3"; printf '%s\n' "${COMPREPLY[@]}"`,
+						"bash", scriptPath, strconv.Itoa(cword), cur)
+					cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+					out, err := cmd.CombinedOutput()
+					require.NoError(t, err, string(out))
+					return string(out)
+				}
+
+				require.Equal(t, "foo\nfoobar\nbar\\ baz\n", run(`fake ""`, 1, ""))
+				require.Equal(t, "foo\nfoobar\n", run(`fake fo`, 1, "fo"))
+			})
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package completion
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/bketelsen/serpent"
+)
+
+// EnvCommand returns an "env" command, to be added to the root command,
+// printing the resolved values of options as statements exporting their
+// environment variables, so that other tools started from the shell inherit
+// the configuration of the CLI, whether it comes from flags, the environment
+// or config files:
+//
+//	eval "$(myapp env)"
+//	myapp env --shell fish | source
+//	myapp env --shell powershell | Invoke-Expression
+//
+// names selects the options of the parent commands to export by name, in
+// order. With no names, every option with an environment variable is
+// exported, except hidden options and secrets. Options without a value are left out. The
+// shell defaults to the one in the SHELL variable, or bash.
+func EnvCommand(names ...string) *serpent.Command {
+	var shell string
+	return &serpent.Command{
+		Use:        "env",
+		Short:      "Print shell statements exporting the configuration to the environment.",
+		Middleware: serpent.RequireNArgs(0),
+		Options: serpent.OptionSet{
+			{
+				Name:        "shell",
+				Description: "The shell to print statements for.",
+				Flag:        "shell",
+				Value:       ShellOptions(&shell),
+			},
+		},
+		Handler: func(inv *serpent.Invocation) error {
+			if shell == "" {
+				shell = defaultEnvShell(inv)
+			}
+			opts, err := envOptions(inv.Command.Parent, names)
+			if err != nil {
+				return err
+			}
+			for _, opt := range opts {
+				v := opt.Value.String()
+				if v == "" {
+					continue
+				}
+				line, err := exportStatement(shell, opt.Env, v)
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintln(inv.Stdout, line); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// envOptions returns the options of cmd and its parents to export: those
+// named by names in order, or else the visible options that aren't secrets,
+// the closest command's first.
+func envOptions(cmd *serpent.Command, names []string) ([]serpent.Option, error) {
+	var all []serpent.Option
+	seen := make(map[string]bool)
+	for c := cmd; c != nil; c = c.Parent {
+		for _, opt := range c.Options {
+			if opt.Env == "" || opt.Value == nil || seen[opt.Env] {
+				continue
+			}
+			seen[opt.Env] = true
+			all = append(all, opt)
+		}
+	}
+	if len(names) == 0 {
+		return slices.DeleteFunc(all, func(opt serpent.Option) bool {
+			return opt.Hidden || opt.IsSecret()
+		}), nil
+	}
+
+	opts := make([]serpent.Option, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(all, func(opt serpent.Option) bool { return opt.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("no option %q with an environment variable", name)
+		}
+		opts = append(opts, all[i])
+	}
+	return opts, nil
+}
+
+// defaultEnvShell returns the shell of the SHELL variable if it's supported,
+// else PowerShell on Windows and bash elsewhere.
+func defaultEnvShell(inv *serpent.Invocation) string {
+	if shell := filepath.Base(inv.Environ.Get("SHELL")); shell != "" {
+		if _, err := ShellByName(shell, ""); err == nil {
+			return shell
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return ShellPowershell
+	}
+	return ShellBash
+}
+
+// exportStatement returns the statement of shell exporting the environment
+// variable name with value v.
+func exportStatement(shell, name, v string) (string, error) {
+	switch shell {
+	case ShellBash, ShellZsh:
+		return fmt.Sprintf("export %s='%s'", name, strings.ReplaceAll(v, `'`, `'\''`)), nil
+	case ShellFish:
+		return fmt.Sprintf("set -gx %s '%s'", name, strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v)), nil
+	case ShellPowershell:
+		return fmt.Sprintf("$env:%s = '%s'", name, strings.ReplaceAll(v, `'`, `''`)), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q", shell)
+	}
+}
@@ -0,0 +1,67 @@
+package completion_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/completion"
+)
+
+func TestEnvCommand(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, names []string, args ...string) (string, error) {
+		t.Helper()
+		var region, token, debug, verbose string
+		root := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "region", Flag: "region", Env: "APP_REGION", Default: "us", Value: serpent.StringOf(&region)},
+				{Name: "token", Flag: "token", Env: "APP_TOKEN", Value: serpent.StringOf(&token), Annotations: serpent.Annotations{}.Mark(serpent.AnnotationSecret, "true")},
+				{Name: "debug", Flag: "debug", Env: "APP_DEBUG", Value: serpent.StringOf(&debug), Hidden: true},
+				{Name: "verbose", Flag: "verbose", Value: serpent.StringOf(&verbose)},
+			},
+			Children: []*serpent.Command{completion.EnvCommand(names...)},
+		}
+		var stdout bytes.Buffer
+		inv := root.Invoke(append([]string{"env"}, args...)...)
+		inv.Stdout = &stdout
+		inv.Environ.Set("SHELL", "/usr/bin/zsh")
+		inv.Environ.Set("APP_TOKEN", "s3cret")
+		inv.Environ.Set("APP_DEBUG", "1")
+		err := inv.Run()
+		return stdout.String(), err
+	}
+
+	for _, tc := range []struct {
+		name  string
+		names []string
+		args  []string
+		want  string
+	}{
+		{"Default", nil, nil, "export APP_REGION='us'\n"},
+		{"Quoted", nil, []string{"--region", "it's"}, "export APP_REGION='it'\\''s'\n"},
+		{"Fish", nil, []string{"--shell", "fish", "--region", `it's\`}, "set -gx APP_REGION 'it\\'s\\\\'\n"},
+		{"Powershell", nil, []string{"--shell", "powershell", "--region", "it's"}, "$env:APP_REGION = 'it''s'\n"},
+		{"Names", []string{"token", "debug"}, nil, "export APP_TOKEN='s3cret'\nexport APP_DEBUG='1'\n"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			out, err := run(t, tc.names, tc.args...)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, out)
+		})
+	}
+
+	t.Run("NoEnv", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := run(t, []string{"verbose"})
+		require.ErrorContains(t, err, `no option "verbose" with an environment variable`)
+	})
+}
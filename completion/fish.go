@@ -43,7 +43,7 @@ function _{{.Name}}_completions
 	# Capture the full command line as an array
 	set -l args (commandline -opc)
 	set -l current (commandline -ct)
-    COMPLETION_MODE=1 $args $current
+    COMPLETION_MODE=1 COMPLETION_DESCRIPTIONS=1 $args $current
 end
 
 # Setup Fish to use the function for completions for '{{.Name}}'
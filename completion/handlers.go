@@ -1,55 +1,125 @@
 package completion
 
 import (
-	"fmt"
+	"bufio"
+	"context"
 	"os"
-	"path/filepath"
+	"os/exec"
 	"strings"
+	"time"
+
+	home "github.com/mitchellh/go-homedir"
 
 	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/internal/filecomplete"
 )
 
 // FileHandler returns a handler that completes file names, using the
 // given filter func, which may be nil.
 func FileHandler(filter func(info os.FileInfo) bool) serpent.CompletionHandlerFunc {
 	return func(inv *serpent.Invocation) []string {
-		var out []string
 		_, word := inv.CurWords()
+		return filecomplete.Complete(word, filter)
+	}
+}
 
-		dir, _ := filepath.Split(word)
-		if dir == "" {
-			dir = "."
-		}
-		f, err := os.Open(dir)
-		if err != nil {
-			return out
-		}
-		defer f.Close()
-		if dir == "." {
-			dir = ""
-		}
+// commandTimeout bounds how long FromCommandOutput waits, since completions
+// must stay responsive.
+const commandTimeout = 2 * time.Second
 
-		infos, err := f.Readdir(0)
-		if err != nil {
-			return out
-		}
+// FromFileLines returns a handler that completes the first field of every
+// line of the file at path, e.g. a list of servers. Blank lines and lines
+// starting with "#" are skipped. A leading "~" in path is expanded to the
+// home directory.
+func FromFileLines(path string) serpent.CompletionHandlerFunc {
+	return func(*serpent.Invocation) []string {
+		return readFileFields(path, func(field string) []string {
+			return []string{field}
+		})
+	}
+}
 
-		for _, info := range infos {
-			if filter != nil && !filter(info) {
-				continue
+// FromKnownHosts returns a handler that completes the host names in an SSH
+// known_hosts file, e.g. "~/.ssh/known_hosts". Hashed entries are skipped.
+func FromKnownHosts(path string) serpent.CompletionHandlerFunc {
+	return func(*serpent.Invocation) []string {
+		seen := make(map[string]bool)
+		return readFileFields(path, func(field string) []string {
+			var hosts []string
+			for _, host := range strings.Split(field, ",") {
+				// Entries are "host", "[host]:port", "|1|<hash>" or markers
+				// such as "@cert-authority".
+				if strings.HasPrefix(host, "|") || strings.HasPrefix(host, "@") {
+					continue
+				}
+				if strings.HasPrefix(host, "[") {
+					host, _, _ = strings.Cut(host[1:], "]")
+				}
+				if host != "" && !seen[host] {
+					seen[host] = true
+					hosts = append(hosts, host)
+				}
 			}
+			return hosts
+		})
+	}
+}
 
-			var cur string
-			if info.IsDir() {
-				cur = fmt.Sprintf("%s%s%c", dir, info.Name(), os.PathSeparator)
-			} else {
-				cur = fmt.Sprintf("%s%s", dir, info.Name())
+func readFileFields(path string, candidates func(field string) []string) []string {
+	path, err := home.Expand(path)
+	if err != nil {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var out []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		out = append(out, candidates(fields[0])...)
+	}
+	return out
+}
+
+// FromCommandOutput returns a handler that completes the non-empty lines
+// printed by running the command name with args, e.g. FromCommandOutput(
+// "git", "branch", "--format=%(refname:short)"). The command is killed if
+// it takes longer than two seconds, and completes nothing if it fails.
+func FromCommandOutput(name string, args ...string) serpent.CompletionHandlerFunc {
+	return func(inv *serpent.Invocation) []string {
+		ctx, cancel := context.WithTimeout(inv.Context(), commandTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, name, args...)
+		// The command mustn't run in completion mode itself, in case it's
+		// built with serpent too.
+		env := inv.Environ
+		if len(env) == 0 {
+			env = serpent.ParseEnviron(os.Environ(), "")
+		}
+		for _, e := range env {
+			if e.Name != serpent.CompletionModeEnv {
+				cmd.Env = append(cmd.Env, e.Name+"="+e.Value)
 			}
+		}
+		out, err := cmd.Output()
+		if err != nil {
+			return nil
+		}
 
-			if strings.HasPrefix(cur, word) {
-				out = append(out, cur)
+		var lines []string
+		for _, line := range strings.Split(string(out), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
 			}
 		}
-		return out
+		return lines
 	}
 }
@@ -0,0 +1,95 @@
+package completion
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/contexts"
+)
+
+// PromptCommandName is the name of the hidden command the prompt hook runs,
+// see PromptCommand.
+const PromptCommandName = "prompt-status"
+
+// PromptCommand returns a hidden command, to be added to the root command,
+// printing the current context of the contexts store at path, followed by
+// the selected config file profile if any, e.g. "work/staging". It prints
+// nothing rather than failing when neither is set, so it's safe to run from
+// a prompt. An empty path selects contexts.DefaultPath.
+func PromptCommand(path string) *serpent.Command {
+	return &serpent.Command{
+		Use:        PromptCommandName,
+		Short:      "Print the current context and profile for shell prompts.",
+		Hidden:     true,
+		Middleware: serpent.RequireNArgs(0),
+		Handler: func(inv *serpent.Invocation) error {
+			var status string
+			if c, err := contexts.Current(inv, path); err == nil {
+				status = c.Name
+			}
+			if profile, ok := inv.Profile(); ok && profile != "" {
+				if status != "" {
+					status += "/"
+				}
+				status += profile
+			}
+			if status == "" {
+				return nil
+			}
+			_, err := fmt.Fprintln(inv.Stdout, status)
+			return err
+		},
+	}
+}
+
+// PromptHook writes a shell function named "<program>_prompt" in the syntax
+// of shell, printing the output of the PromptCommand in parentheses, for
+// use in prompts:
+//
+//	PS1='$(myapp_prompt)'$PS1
+//
+// Prompts such as starship that run commands outside of the shell can run
+// "myapp prompt-status" instead.
+func PromptHook(w io.Writer, shell Shell) error {
+	var tpl string
+	switch shell.Name() {
+	case ShellBash, ShellZsh:
+		tpl = posixPromptTemplate
+	case ShellFish:
+		tpl = fishPromptTemplate
+	case ShellPowershell:
+		tpl = powershellPromptTemplate
+	default:
+		return fmt.Errorf("unsupported shell %q", shell.Name())
+	}
+	return writeConfig(w, tpl, shell.ProgramName())
+}
+
+const posixPromptTemplate = `
+{{.Name}}_prompt() {
+    local status
+    status=$("{{.Name}}" ` + PromptCommandName + ` 2>/dev/null) || return 0
+    if [ -n "$status" ]; then
+        printf '(%s) ' "$status"
+    fi
+}
+`
+
+const fishPromptTemplate = `
+function {{.Name}}_prompt
+    set -l prompt_status ("{{.Name}}" ` + PromptCommandName + ` 2>/dev/null); or return 0
+    if test -n "$prompt_status"
+        printf '(%s) ' $prompt_status
+    end
+end
+`
+
+const powershellPromptTemplate = `
+function {{.Name}}_prompt {
+    $promptStatus = & "{{.Name}}" ` + PromptCommandName + ` 2>$null
+    if ($LASTEXITCODE -eq 0 -and $promptStatus) {
+        "($promptStatus) "
+    }
+}
+`
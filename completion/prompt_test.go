@@ -0,0 +1,77 @@
+package completion_test
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/completion"
+	"github.com/bketelsen/serpent/contexts"
+)
+
+func TestPromptCommand(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "contexts.yaml")
+	run := func(args ...string) string {
+		root := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				contexts.Option("APP_CONTEXT"),
+				serpent.ProfileOption("APP_PROFILE"),
+			},
+			Children: []*serpent.Command{completion.PromptCommand(path)},
+		}
+		var stdout bytes.Buffer
+		inv := root.Invoke(append([]string{completion.PromptCommandName}, args...)...)
+		inv.Stdout = &stdout
+		inv.Environ.Set("HOME", t.TempDir())
+		inv.Environ.Set("XDG_CONFIG_HOME", t.TempDir())
+		require.NoError(t, inv.Run())
+		return stdout.String()
+	}
+
+	// Nothing is printed without a context.
+	require.Empty(t, run())
+
+	s, err := contexts.Load(path)
+	require.NoError(t, err)
+	s.Set(contexts.Context{Name: "work", Server: "https://example.com"})
+	require.NoError(t, s.Use("work"))
+	require.NoError(t, s.Save())
+
+	require.Equal(t, "work\n", run())
+	require.Equal(t, "work/staging\n", run("--profile", "staging"))
+}
+
+func TestPromptHook(t *testing.T) {
+	t.Parallel()
+
+	for _, shell := range []completion.Shell{
+		completion.Bash("linux", "myapp"),
+		completion.Zsh("linux", "myapp"),
+		completion.Fish("linux", "myapp"),
+		completion.Powershell("linux", "myapp"),
+	} {
+		var script bytes.Buffer
+		require.NoError(t, completion.PromptHook(&script, shell), shell.Name())
+		require.Contains(t, script.String(), "myapp_prompt", shell.Name())
+		require.Contains(t, script.String(), `"myapp" prompt-status`, shell.Name())
+
+		if shell.Name() != completion.ShellBash {
+			continue
+		}
+		bash, err := exec.LookPath("bash")
+		if err != nil {
+			continue
+		}
+		// The hook prints nothing when the program isn't installed.
+		out, err := exec.Command(bash, "-c", script.String()+"\nmyapp_prompt").CombinedOutput()
+		require.NoError(t, err, string(out))
+		require.Empty(t, string(out))
+	}
+}
@@ -0,0 +1,39 @@
+package completion
+
+import (
+	"github.com/bketelsen/serpent"
+)
+
+// ResourceLister lists the names of a kind of resource, e.g. workspaces, to
+// complete arguments with.
+type ResourceLister func(inv *serpent.Invocation) []string
+
+// Register makes lister complete arguments naming the given resource, see
+// serpent.Argument.CompleteResource. Registering a resource again replaces
+// its lister.
+func Register(resource string, lister ResourceLister) {
+	serpent.RegisterResource(resource, serpent.CompletionHandlerFunc(lister))
+}
+
+// Resource returns a handler completing names of the registered resource,
+// for use as the CompletionHandler of an option or a command. The lister is
+// looked up at completion time, so handlers may be declared before the
+// resource is registered. An unregistered resource completes nothing.
+func Resource(resource string) serpent.CompletionHandlerFunc {
+	return serpent.ResourceCompletion(resource)
+}
+
+// Args returns a command CompletionHandler that completes each positional
+// argument with the resource at the same position, e.g. Args("workspace",
+// "agent") for "ssh <workspace> <agent>". An empty resource name, or an
+// argument past the last resource, completes nothing. Commands declaring
+// Arguments can use Argument.CompleteResource instead.
+func Args(resources ...string) serpent.CompletionHandlerFunc {
+	return func(inv *serpent.Invocation) []string {
+		pos := inv.CurArgIndex()
+		if pos >= len(resources) || resources[pos] == "" {
+			return nil
+		}
+		return Resource(resources[pos])(inv)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -28,6 +29,94 @@ func TestCompletion(t *testing.T) {
 		require.Equal(t, "altfile\nfile\nrequired-flag\ntoupper\n", io.Stdout.String())
 	})
 
+	t.Run("SubcommandAliases", func(t *testing.T) {
+		t.Parallel()
+		c := &serpent.Command{
+			Use: "app",
+			Children: []*serpent.Command{
+				{Use: "server", Aliases: []string{"srv"}},
+				{Use: "status"},
+			},
+		}
+		i := c.Invoke("")
+		i.Environ.Set(serpent.CompletionModeEnv, "1")
+		io := fakeIO(i)
+		require.NoError(t, i.Run())
+		require.Equal(t, "server\nstatus\n", io.Stdout.String())
+
+		c.CompleteAliases = true
+		i = c.Invoke("")
+		i.Environ.Set(serpent.CompletionModeEnv, "1")
+		io = fakeIO(i)
+		require.NoError(t, i.Run())
+		require.Equal(t, "server\nsrv\nstatus\n", io.Stdout.String())
+	})
+
+	t.Run("OptionValues", func(t *testing.T) {
+		t.Parallel()
+		var server, profile string
+		c := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "server", Flag: "server", Env: "APP_SERVER", Value: serpent.StringOf(&server)},
+				{Name: "profile", Flag: "profile", Default: "default", Value: serpent.StringOf(&profile)},
+				{
+					Name:  "workspace",
+					Flag:  "workspace",
+					Value: serpent.StringOf(new(string)),
+					CompletionHandler: func(*serpent.Invocation) []string {
+						return []string{server + "/" + profile}
+					},
+				},
+			},
+			Handler: func(*serpent.Invocation) error { return nil },
+		}
+		i := c.Invoke("--workspace", "")
+		i.Environ.Set(serpent.CompletionModeEnv, "1")
+		i.Environ.Set("APP_SERVER", "https://example.com")
+		io := fakeIO(i)
+		require.NoError(t, i.Run())
+		require.Equal(t, "https://example.com/default\n", io.Stdout.String())
+	})
+
+	t.Run("LazyOptionValues", func(t *testing.T) {
+		t.Parallel()
+		var server string
+		var port int64
+		c := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "server", Flag: "server", Env: "APP_SERVER", Value: serpent.StringOf(&server)},
+				{Name: "port", Flag: "port", Env: "APP_PORT", Value: serpent.Int64Of(&port)},
+				{
+					Name:  "workspace",
+					Flag:  "workspace",
+					Value: serpent.StringOf(new(string)),
+					CompletionHandler: func(*serpent.Invocation) []string {
+						return []string{server}
+					},
+				},
+			},
+			Handler: func(*serpent.Invocation) error { return nil },
+		}
+
+		// Values aren't resolved without a completion handler reading them.
+		i := c.Invoke("--")
+		i.Environ.Set(serpent.CompletionModeEnv, "1")
+		i.Environ.Set("APP_PORT", "invalid")
+		io := fakeIO(i)
+		require.NoError(t, i.Run())
+		require.Contains(t, io.Stdout.String(), "--port\n")
+
+		// Flags take precedence over the environment once they are.
+		i = c.Invoke("--server", "https://flag.example.com", "--workspace", "")
+		i.Environ.Set(serpent.CompletionModeEnv, "1")
+		i.Environ.Set("APP_SERVER", "https://env.example.com")
+		io = fakeIO(i)
+		require.NoError(t, i.Run())
+		require.Equal(t, "https://flag.example.com\n", io.Stdout.String())
+	})
+
 	t.Run("SubcommandNoPartial", func(t *testing.T) {
 		t.Parallel()
 		i := cmd().Invoke("f")
@@ -235,6 +324,141 @@ func TestFileCompletion(t *testing.T) {
 	}
 }
 
+func TestResourceCompletion(t *testing.T) {
+	t.Parallel()
+
+	completion.Register("test-workspace", func(*serpent.Invocation) []string {
+		return []string{"dev", "demo", "prod"}
+	})
+	completion.Register("test-agent", func(*serpent.Invocation) []string {
+		return []string{"main", "gpu"}
+	})
+
+	cmd := func() *serpent.Command {
+		var org string
+		return &serpent.Command{
+			Use: "root",
+			Children: []*serpent.Command{
+				{
+					Use: "ssh <workspace> <agent>",
+					Options: serpent.OptionSet{
+						{
+							Name:              "org",
+							Flag:              "org",
+							Value:             serpent.StringOf(&org),
+							CompletionHandler: completion.Resource("test-workspace"),
+						},
+					},
+					CompletionHandler: completion.Args("test-workspace", "test-agent"),
+					Handler:           func(*serpent.Invocation) error { return nil },
+				},
+			},
+		}
+	}
+
+	complete := func(args ...string) string {
+		i := cmd().Invoke(args...)
+		i.Environ.Set(serpent.CompletionModeEnv, "1")
+		io := fakeIO(i)
+		require.NoError(t, i.Run())
+		return io.Stdout.String()
+	}
+
+	require.Equal(t, "dev\ndemo\nprod\n", complete("ssh", ""))
+	require.Equal(t, "dev\ndemo\nprod\n", complete("ssh", "--org", "acme", "de"))
+	require.Equal(t, "main\ngpu\n", complete("ssh", "dev", ""))
+	require.Equal(t, "", complete("ssh", "dev", "main", ""))
+	require.Equal(t, "dev\ndemo\nprod\n", complete("ssh", "--org", "p"))
+}
+
+func TestArgumentCompletion(t *testing.T) {
+	t.Parallel()
+
+	completion.Register("test-host", func(*serpent.Invocation) []string {
+		return []string{"web1", "web2"}
+	})
+
+	cmd := &serpent.Command{
+		Use: "root",
+		Children: []*serpent.Command{
+			{
+				Use: "copy",
+				Arguments: []serpent.Argument{
+					serpent.Argument{Name: "host"}.CompleteResource("test-host"),
+					{
+						Name:     "files",
+						Variadic: true,
+						CompletionHandler: func(*serpent.Invocation) []string {
+							return []string{"a.txt", "b.txt"}
+						},
+					},
+				},
+				CompletionHandler: func(*serpent.Invocation) []string {
+					return []string{"unused"}
+				},
+				Handler: func(*serpent.Invocation) error { return nil },
+			},
+		},
+	}
+
+	complete := func(args ...string) string {
+		i := cmd.Invoke(args...)
+		i.Environ.Set(serpent.CompletionModeEnv, "1")
+		io := fakeIO(i)
+		require.NoError(t, i.Run())
+		return io.Stdout.String()
+	}
+
+	require.Equal(t, "web1\nweb2\n", complete("copy", ""))
+	require.Equal(t, "a.txt\nb.txt\n", complete("copy", "web1", ""))
+	require.Equal(t, "a.txt\nb.txt\n", complete("copy", "web1", "a.txt", ""))
+}
+
+func TestCompletionFromFilesAndCommands(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	servers := filepath.Join(dir, "servers")
+	require.NoError(t, os.WriteFile(servers, []byte("# production\nweb1.example.com  primary\n\nweb2.example.com\n"), 0o600))
+	knownHosts := filepath.Join(dir, "known_hosts")
+	require.NoError(t, os.WriteFile(knownHosts, []byte(
+		"github.com,140.82.112.3 ssh-ed25519 AAAA\n"+
+			"[git.example.com]:2222 ssh-rsa AAAA\n"+
+			"|1|hash|hash= ssh-rsa AAAA\n"+
+			"@cert-authority *.example.com ssh-rsa AAAA\n"+
+			"github.com ssh-rsa AAAA\n",
+	), 0o600))
+
+	var host string
+	cmd := &serpent.Command{
+		Use: "root",
+		Options: serpent.OptionSet{
+			{
+				Name:              "host",
+				Flag:              "host",
+				Value:             serpent.StringOf(&host),
+				CompletionHandler: completion.FromFileLines(servers),
+			},
+		},
+		Handler: func(*serpent.Invocation) error { return nil },
+	}
+	i := cmd.Invoke("--host", "")
+	i.Environ.Set(serpent.CompletionModeEnv, "1")
+	io := fakeIO(i)
+	require.NoError(t, i.Run())
+	require.Equal(t, "web1.example.com\nweb2.example.com\n", io.Stdout.String())
+
+	inv := cmd.Invoke()
+	require.Equal(t, []string{"github.com", "140.82.112.3", "git.example.com"}, completion.FromKnownHosts(knownHosts)(inv))
+	require.Empty(t, completion.FromFileLines(filepath.Join(dir, "missing"))(inv))
+
+	if runtime.GOOS != "windows" {
+		inv.Environ.Set(serpent.CompletionModeEnv, "1")
+		require.Equal(t, []string{"a", "b"}, completion.FromCommandOutput("sh", "-c", `[ -z "$COMPLETION_MODE" ] && printf 'a\n\n b\n'`)(inv))
+		require.Empty(t, completion.FromCommandOutput("sh", "-c", "exit 1")(inv))
+	}
+}
+
 func TestCompletionInstall(t *testing.T) {
 	t.Parallel()
 
@@ -316,9 +540,64 @@ func TestCompletionInstall(t *testing.T) {
 	}
 }
 
+func TestShellAliases(t *testing.T) {
+	t.Parallel()
+
+	cmd := &serpent.Command{
+		Use: "root",
+		Children: []*serpent.Command{
+			{
+				Use: "workspace",
+				Children: []*serpent.Command{
+					{
+						Use:         "list",
+						Annotations: serpent.Annotations{}.Mark(completion.AnnotationAlias, "mwl"),
+					},
+					{Use: "delete"},
+				},
+			},
+			{
+				Use:         "login",
+				Annotations: serpent.Annotations{}.Mark(completion.AnnotationAlias, "ml"),
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		shell    completion.Shell
+		expected string
+	}{
+		{completion.Bash("linux", "myapp"), "alias mwl='myapp workspace list'\nalias ml='myapp login'\n"},
+		{completion.Fish("linux", "myapp"), "abbr --add mwl 'myapp workspace list'\nabbr --add ml 'myapp login'\n"},
+		{completion.Powershell("linux", "myapp"), "function mwl { myapp workspace list @args }\nfunction ml { myapp login @args }\n"},
+	} {
+		var out strings.Builder
+		require.NoError(t, completion.WriteAliases(&out, tc.shell, cmd))
+		require.Equal(t, tc.expected, out.String(), tc.shell.Name())
+	}
+
+	t.Run("Install", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		shell := &fakeShell{baseInstallDir: dir, programName: "fake", name: completion.ShellBash}
+		require.NoError(t, completion.InstallShellCompletion(shell))
+		require.NoError(t, completion.InstallShellAliases(shell, cmd))
+		// Reinstalling replaces the section.
+		require.NoError(t, completion.InstallShellAliases(shell, cmd))
+
+		contents, err := os.ReadFile(filepath.Join(dir, "fake.sh"))
+		require.NoError(t, err)
+		require.Equal(t, "# ============ BEGIN fake COMPLETION ============\nFAKE_COMPLETION\n# ============ END fake COMPLETION ==============\n"+
+			"\n# ============ BEGIN fake ALIASES ============\nalias mwl='fake workspace list'\nalias ml='fake login'\n# ============ END fake ALIASES ==============\n",
+			string(contents))
+	})
+}
+
 type fakeShell struct {
 	baseInstallDir string
 	programName    string
+	name           string
 }
 
 func (f *fakeShell) ProgramName() string {
@@ -332,6 +611,9 @@ func (f *fakeShell) InstallPath() (string, error) {
 }
 
 func (f *fakeShell) Name() string {
+	if f.name != "" {
+		return f.name
+	}
 	return "Fake"
 }
 
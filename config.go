@@ -0,0 +1,61 @@
+package serpent
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+)
+
+// ConfigCommand returns a "config" command with a show subcommand listing
+// the value of every option of its parent commands along with where the
+// value came from, to help debug precedence between flags, environment
+// variables and config files.
+func ConfigCommand() *Command {
+	return &Command{
+		Use:   "config",
+		Short: "Inspect the configuration.",
+		Children: []*Command{
+			configShowCommand(),
+		},
+	}
+}
+
+func configShowCommand() *Command {
+	return &Command{
+		Use:        "show",
+		Short:      "Show the value of every option and where it came from.",
+		Middleware: RequireNArgs(0),
+		Handler: func(inv *Invocation) error {
+			tw := tabwriter.NewWriter(inv.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "OPTION\tVALUE\tSOURCE")
+			for _, opt := range configOptions(inv.Command) {
+				source := opt.originText()
+				if source == "" {
+					source = "-"
+				}
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", opt.Name, opt.Value.String(), source)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+// configOptions returns the visible options of cmd and its ancestors, with
+// options sharing a value listed once.
+func configOptions(cmd *Command) OptionSet {
+	var (
+		opts OptionSet
+		seen = make(map[pflag.Value]bool)
+	)
+	for ; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Hidden || len(opt.UseInstead) > 0 || opt.Value == nil || seen[opt.Value] {
+				continue
+			}
+			seen[opt.Value] = true
+			opts = append(opts, opt)
+		}
+	}
+	return opts
+}
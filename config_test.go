@@ -0,0 +1,109 @@
+package serpent_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestValueOrigin(t *testing.T) {
+	t.Parallel()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+url: https://example.com
+region: us
+profiles:
+  prod:
+    region: eu
+`), 0o600))
+
+	makeRoot := func() *serpent.Command {
+		var config serpent.YAMLConfigPath
+		root := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "config", Flag: "config", Value: &config},
+				serpent.ProfileOption("APP_PROFILE"),
+				{Name: "url", Flag: "url", Env: "APP_URL", YAML: "url", Value: serpent.StringOf(new(string))},
+				{Name: "region", YAML: "region", Value: serpent.StringOf(new(string))},
+				{Name: "token", Flag: "token", FlagShorthand: "t", Env: "APP_TOKEN", Value: serpent.StringOf(new(string))},
+				{Name: "port", Flag: "port", Default: "8080", Value: serpent.Int64Of(new(int64))},
+				{Name: "verbose", Flag: "verbose", Value: serpent.BoolOf(new(bool))},
+			},
+			Handler: func(inv *serpent.Invocation) error { return nil },
+			Children: []*serpent.Command{
+				serpent.ConfigCommand(),
+			},
+		}
+		root.Options.EnvAliases(serpent.EnvPrefixAliases("HOMEBREW_"))
+		return root
+	}
+	run := func(env map[string]string, args ...string) (serpent.OptionSet, string) {
+		t.Helper()
+
+		root := makeRoot()
+		inv := root.Invoke(append([]string{"--config", configPath}, args...)...)
+		inv.Environ.Set("XDG_STATE_HOME", t.TempDir())
+		for k, v := range env {
+			inv.Environ.Set(k, v)
+		}
+		io := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		return root.Options, io.Stdout.String()
+	}
+
+	t.Run("Origins", func(t *testing.T) {
+		t.Parallel()
+
+		opts, _ := run(map[string]string{"HOMEBREW_APP_URL": "https://brew.example.com"}, "-t", "secret", "--profile", "prod")
+		require.Equal(t, serpent.ValueOrigin{Source: serpent.ValueSourceFlag, Detail: "--config"}, opts.ByName("config").Origin())
+		require.Equal(t, serpent.ValueOrigin{Source: serpent.ValueSourceFlag, Detail: "-t"}, opts.ByName("token").Origin())
+		require.Equal(t, serpent.ValueOrigin{Source: serpent.ValueSourceEnv, Detail: "HOMEBREW_APP_URL"}, opts.ByName("url").Origin())
+		require.Equal(t, serpent.ValueOrigin{Source: serpent.ValueSourceProfile, Detail: "prod (" + configPath + ")"}, opts.ByName("region").Origin())
+		require.Equal(t, serpent.ValueOrigin{Source: serpent.ValueSourceDefault}, opts.ByName("port").Origin())
+		require.Equal(t, serpent.ValueOrigin{}, opts.ByName("verbose").Origin())
+		require.Equal(t, "env HOMEBREW_APP_URL", opts.ByName("url").Origin().String())
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		t.Parallel()
+
+		opts, _ := run(nil, "--token=secret")
+		require.Equal(t, "yaml "+configPath, opts.ByName("url").Origin().String())
+		require.Equal(t, "flag --token", opts.ByName("token").Origin().String())
+	})
+
+	t.Run("ConfigShow", func(t *testing.T) {
+		t.Parallel()
+
+		_, stdout := run(map[string]string{"APP_URL": "https://env.example.com"}, "config", "show")
+		var rows [][]string
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+			rows = append(rows, strings.Fields(line))
+		}
+		require.Len(t, rows, 8)
+		require.Equal(t, []string{"OPTION", "VALUE", "SOURCE"}, rows[0])
+		require.Contains(t, rows, []string{"url", "https://env.example.com", "env", "APP_URL"})
+		require.Contains(t, rows, []string{"region", "us", "yaml", configPath})
+		require.Contains(t, rows, []string{"port", "8080", "default"})
+		require.Contains(t, rows, []string{"verbose", "false", "-"})
+	})
+
+	t.Run("EnvParseError", func(t *testing.T) {
+		t.Parallel()
+
+		inv := makeRoot().Invoke()
+		inv.Command.Options = append(inv.Command.Options, serpent.Option{
+			Name: "count", Env: "APP_COUNT", Value: serpent.Int64Of(new(int64)),
+		})
+		inv.Environ.Set("APP_COUNT", "many")
+		_ = fakeIO(inv)
+		require.ErrorContains(t, inv.Run(), `parse "count" from APP_COUNT`)
+	})
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package serpent
+
+// enableConsole does nothing, as terminals other than the Windows console
+// render escape sequences natively.
+func enableConsole() {}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package serpent
+
+import (
+	"os"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var enableConsoleOnce sync.Once
+
+// enableConsole enables virtual terminal processing of the standard output
+// and error consoles, so that conhost renders the escape sequences of colored
+// output instead of printing them. Windows Terminal has it enabled already.
+// Consoles too old to support it get colorless output. The mode is left
+// enabled on exit, as PowerShell does.
+func enableConsole() {
+	enableConsoleOnce.Do(func() {
+		for _, f := range []*os.File{os.Stdout, os.Stderr} {
+			if _, err := termenv.EnableVirtualTerminalProcessing(termenv.NewOutput(f)); err != nil {
+				consoleColorDisabled.Store(true)
+				lipgloss.SetColorProfile(termenv.Ascii)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package serpent_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+// TestWithOS_NotConsole checks that WithOS works when virtual terminal
+// processing can't be enabled because the output isn't a console.
+func TestWithOS_NotConsole(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+	})
+
+	cmd := &serpent.Command{
+		Use: "echo",
+		Handler: func(inv *serpent.Invocation) error {
+			_, err := io.WriteString(inv.Stdout, inv.Args[0]+"\n")
+			return err
+		},
+	}
+	err = cmd.Invoke("hello").WithOSNoArgs().Run()
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", string(out))
+}
@@ -0,0 +1,216 @@
+package contexts
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/secret"
+)
+
+// Command returns a "context" command with list, show, use, set and delete
+// subcommands managing the store at path. An empty path selects DefaultPath.
+func Command(path string) *serpent.Command {
+	return &serpent.Command{
+		Use:     "context",
+		Aliases: []string{"contexts"},
+		Short:   "Manage the contexts used to connect to servers.",
+		Children: []*serpent.Command{
+			listCommand(path),
+			showCommand(path),
+			useCommand(path),
+			setCommand(path),
+			deleteCommand(path),
+		},
+	}
+}
+
+func listCommand(path string) *serpent.Command {
+	return &serpent.Command{
+		Use:        "list",
+		Aliases:    []string{"ls"},
+		Short:      "List contexts.",
+		Middleware: serpent.RequireNArgs(0),
+		Handler: func(inv *serpent.Invocation) error {
+			s, err := Load(storePath(inv, path))
+			if err != nil {
+				return err
+			}
+			current := selected(inv)
+			if current == "" {
+				current = s.Current
+			}
+
+			tw := tabwriter.NewWriter(inv.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "CURRENT\tNAME\tSERVER\tORG")
+			for _, c := range s.Contexts {
+				marker := ""
+				if c.Name == current {
+					marker = "*"
+				}
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", marker, c.Name, c.Server, c.Org)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func showCommand(path string) *serpent.Command {
+	return &serpent.Command{
+		Use:        "show [name]",
+		Short:      "Show a context, the current one by default.",
+		Middleware: serpent.RequireRangeArgs(0, 1),
+		Handler: func(inv *serpent.Invocation) error {
+			var (
+				c   Context
+				err error
+			)
+			if len(inv.Args) > 0 {
+				var s *Store
+				s, err = Load(storePath(inv, path))
+				if err != nil {
+					return err
+				}
+				var ok bool
+				c, ok = s.Get(inv.Args[0])
+				if !ok {
+					return fmt.Errorf("context %q not found", inv.Args[0])
+				}
+			} else {
+				c, err = Current(inv, path)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, _ = fmt.Fprintf(inv.Stdout, "Name:   %s\n", c.Name)
+			_, _ = fmt.Fprintf(inv.Stdout, "Server: %s\n", c.Server)
+			if c.Org != "" {
+				_, _ = fmt.Fprintf(inv.Stdout, "Org:    %s\n", c.Org)
+			}
+			if c.TokenRef != "" {
+				// Only keyring references are shown, never literal tokens.
+				ref := "<set>"
+				if strings.HasPrefix(c.TokenRef, secret.ReferencePrefix) {
+					ref = c.TokenRef
+				}
+				_, _ = fmt.Fprintf(inv.Stdout, "Token:  %s\n", ref)
+			}
+			return nil
+		},
+	}
+}
+
+func useCommand(path string) *serpent.Command {
+	return &serpent.Command{
+		Use:        "use <name>",
+		Short:      "Make a context current.",
+		Middleware: serpent.RequireNArgs(1),
+		Handler: func(inv *serpent.Invocation) error {
+			s, err := Load(storePath(inv, path))
+			if err != nil {
+				return err
+			}
+			if err := s.Use(inv.Args[0]); err != nil {
+				return err
+			}
+			if err := s.Save(); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(inv.Stdout, "Switched to context %s.\n", serpent.Keyword(inv.Args[0]))
+			return nil
+		},
+	}
+}
+
+func setCommand(path string) *serpent.Command {
+	var server, tokenRef, org string
+	return &serpent.Command{
+		Use:   "set <name>",
+		Short: "Create or update a context.",
+		Long: "Flags that aren't given keep their current values. The first " +
+			"context created becomes current.",
+		Options: serpent.OptionSet{
+			{
+				Name:        "server",
+				Description: "URL of the server.",
+				Flag:        "server",
+				Value:       serpent.StringOf(&server),
+			},
+			{
+				Name:        "token-ref",
+				Description: "Reference to the API token, e.g. keyring:work.",
+				Flag:        "token-ref",
+				Value:       serpent.StringOf(&tokenRef),
+			},
+			{
+				Name:        "org",
+				Description: "Organization to act on.",
+				Flag:        "org",
+				Value:       serpent.StringOf(&org),
+			},
+		},
+		Middleware: serpent.RequireNArgs(1),
+		Handler: func(inv *serpent.Invocation) error {
+			s, err := Load(storePath(inv, path))
+			if err != nil {
+				return err
+			}
+
+			name := inv.Args[0]
+			c, exists := s.Get(name)
+			c.Name = name
+			for _, opt := range inv.Command.Options {
+				if opt.ValueSource != serpent.ValueSourceFlag {
+					continue
+				}
+				switch opt.Flag {
+				case "server":
+					c.Server = server
+				case "token-ref":
+					c.TokenRef = tokenRef
+				case "org":
+					c.Org = org
+				}
+			}
+			s.Set(c)
+			if s.Current == "" {
+				s.Current = name
+			}
+			if err := s.Save(); err != nil {
+				return err
+			}
+
+			verb := "Created"
+			if exists {
+				verb = "Updated"
+			}
+			_, _ = fmt.Fprintf(inv.Stdout, "%s context %s.\n", verb, serpent.Keyword(name))
+			return nil
+		},
+	}
+}
+
+func deleteCommand(path string) *serpent.Command {
+	return &serpent.Command{
+		Use:        "delete <name>",
+		Aliases:    []string{"rm"},
+		Short:      "Delete a context.",
+		Middleware: serpent.RequireNArgs(1),
+		Handler: func(inv *serpent.Invocation) error {
+			s, err := Load(storePath(inv, path))
+			if err != nil {
+				return err
+			}
+			if err := s.Delete(inv.Args[0]); err != nil {
+				return err
+			}
+			if err := s.Save(); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(inv.Stdout, "Deleted context %s.\n", serpent.Keyword(inv.Args[0]))
+			return nil
+		},
+	}
+}
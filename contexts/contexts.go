@@ -0,0 +1,194 @@
+// Package contexts provides a kubectl-style store of named contexts, each
+// holding the server, credentials reference and organization an API client
+// talks to, along with commands to manage them.
+package contexts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/files"
+	"github.com/bketelsen/serpent/secret"
+)
+
+// AnnotationContext marks the option that overrides the current context.
+const AnnotationContext = "serpent.context"
+
+// ErrNoContext is returned when no context is selected.
+var ErrNoContext = errors.New("no context selected, see \"context use\"")
+
+// Context is a named set of connection settings.
+type Context struct {
+	Name   string `yaml:"name" json:"name"`
+	Server string `yaml:"server" json:"server"`
+	// TokenRef references the API token, e.g. "keyring:work". A value
+	// without the keyring prefix is used verbatim.
+	TokenRef string `yaml:"token_ref,omitempty" json:"token_ref,omitempty"`
+	Org      string `yaml:"org,omitempty" json:"org,omitempty"`
+}
+
+// Token resolves TokenRef, loading it from the keyring service when it's a
+// keyring reference.
+func (c Context) Token(service string) (string, error) {
+	if c.TokenRef == "" {
+		return "", nil
+	}
+	var token string
+	if err := secret.Of(service, &token).Set(c.TokenRef); err != nil {
+		return "", fmt.Errorf("context %q: %w", c.Name, err)
+	}
+	return token, nil
+}
+
+// Store is a file of contexts and the name of the current one.
+type Store struct {
+	Current  string    `yaml:"current,omitempty"`
+	Contexts []Context `yaml:"contexts"`
+
+	path string
+}
+
+// Load reads the store at path. A missing file is an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+	byt, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(byt, s); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save atomically writes the store back to the path it was loaded from.
+func (s *Store) Save() error {
+	byt, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return files.WriteFile(s.path, byt, files.PrivateFile)
+}
+
+// Get returns the context with the given name.
+func (s *Store) Get(name string) (Context, bool) {
+	i := s.index(name)
+	if i < 0 {
+		return Context{}, false
+	}
+	return s.Contexts[i], true
+}
+
+// Set adds c, replacing any context with the same name.
+func (s *Store) Set(c Context) {
+	if i := s.index(c.Name); i >= 0 {
+		s.Contexts[i] = c
+		return
+	}
+	s.Contexts = append(s.Contexts, c)
+}
+
+// Delete removes the named context, deselecting it if it's current.
+func (s *Store) Delete(name string) error {
+	i := s.index(name)
+	if i < 0 {
+		return fmt.Errorf("context %q not found", name)
+	}
+	s.Contexts = slices.Delete(s.Contexts, i, i+1)
+	if s.Current == name {
+		s.Current = ""
+	}
+	return nil
+}
+
+// Use makes the named context current.
+func (s *Store) Use(name string) error {
+	if s.index(name) < 0 {
+		return fmt.Errorf("context %q not found", name)
+	}
+	s.Current = name
+	return nil
+}
+
+func (s *Store) index(name string) int {
+	return slices.IndexFunc(s.Contexts, func(c Context) bool {
+		return c.Name == name
+	})
+}
+
+// Option returns a --context option, also settable with the env environment
+// variable, that overrides the current context for a single invocation. It's
+// usually added to the root command.
+func Option(env string) serpent.Option {
+	var name string
+	return serpent.Option{
+		Name:        "context",
+		Description: "Name of the context to use instead of the current one.",
+		Flag:        "context",
+		Env:         env,
+		Value:       serpent.StringOf(&name),
+		Annotations: serpent.Annotations{}.Mark(AnnotationContext, "true"),
+	}
+}
+
+// Current returns the context selected by the Option on the command or its
+// parents, or else the store's current context. An empty path selects
+// DefaultPath.
+func Current(inv *serpent.Invocation, path string) (Context, error) {
+	s, err := Load(storePath(inv, path))
+	if err != nil {
+		return Context{}, err
+	}
+
+	name := selected(inv)
+	if name == "" {
+		name = s.Current
+	}
+	if name == "" {
+		return Context{}, ErrNoContext
+	}
+	c, ok := s.Get(name)
+	if !ok {
+		return Context{}, fmt.Errorf("context %q not found", name)
+	}
+	return c, nil
+}
+
+// selected returns the context named by the Option, if any.
+func selected(inv *serpent.Invocation) string {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Annotations.IsSet(AnnotationContext) {
+				return opt.Value.String()
+			}
+		}
+	}
+	return ""
+}
+
+// DefaultPath returns the store path used when none is given:
+// $XDG_CONFIG_HOME/<root command>/contexts.yaml.
+func DefaultPath(inv *serpent.Invocation) string {
+	root := inv.Command
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	return filepath.Join(files.ConfigDir(inv.Environ.Get, root.Name()), "contexts.yaml")
+}
+
+func storePath(inv *serpent.Invocation, path string) string {
+	if path != "" {
+		return path
+	}
+	return DefaultPath(inv)
+}
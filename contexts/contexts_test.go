@@ -0,0 +1,127 @@
+package contexts_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/contexts"
+	"github.com/bketelsen/serpent/secret"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "contexts.yaml")
+	s, err := contexts.Load(path)
+	require.NoError(t, err)
+	require.Empty(t, s.Contexts)
+
+	s.Set(contexts.Context{Name: "dev", Server: "http://localhost"})
+	s.Set(contexts.Context{Name: "prod", Server: "https://example.com", Org: "acme"})
+	s.Set(contexts.Context{Name: "dev", Server: "http://127.0.0.1"})
+	require.NoError(t, s.Use("prod"))
+	require.Error(t, s.Use("missing"))
+	require.NoError(t, s.Save())
+
+	s, err = contexts.Load(path)
+	require.NoError(t, err)
+	require.Equal(t, "prod", s.Current)
+	require.Len(t, s.Contexts, 2)
+	dev, ok := s.Get("dev")
+	require.True(t, ok)
+	require.Equal(t, "http://127.0.0.1", dev.Server)
+
+	require.NoError(t, s.Delete("prod"))
+	require.Empty(t, s.Current)
+	require.Error(t, s.Delete("prod"))
+}
+
+func TestContext_Token(t *testing.T) {
+	t.Parallel()
+
+	token, err := contexts.Context{TokenRef: "literal"}.Token("app")
+	require.NoError(t, err)
+	require.Equal(t, "literal", token)
+
+	_, err = contexts.Context{Name: "dev", TokenRef: secret.ReferencePrefix}.Token("app")
+	require.ErrorContains(t, err, `context "dev"`)
+}
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "contexts.yaml")
+
+	run := func(args ...string) (contexts.Context, string, error) {
+		var current contexts.Context
+		root := &serpent.Command{
+			Use:     "app",
+			Options: serpent.OptionSet{contexts.Option("APP_CONTEXT")},
+			Children: []*serpent.Command{
+				contexts.Command(path),
+				{
+					Use: "whoami",
+					Handler: func(inv *serpent.Invocation) error {
+						var err error
+						current, err = contexts.Current(inv, path)
+						return err
+					},
+				},
+			},
+		}
+		var stdout bytes.Buffer
+		inv := root.Invoke(args...)
+		inv.Stdout = &stdout
+		err := inv.Run()
+		return current, stdout.String(), err
+	}
+
+	_, _, err := run("whoami")
+	require.ErrorIs(t, err, contexts.ErrNoContext)
+
+	_, out, err := run("context", "set", "dev", "--server", "http://localhost")
+	require.NoError(t, err)
+	require.Contains(t, out, "Created context")
+
+	_, _, err = run("context", "set", "prod", "--server", "https://example.com", "--token-ref", "keyring:prod")
+	require.NoError(t, err)
+	_, out, err = run("context", "set", "prod", "--org", "acme")
+	require.NoError(t, err)
+	require.Contains(t, out, "Updated context")
+
+	c, _, err := run("whoami")
+	require.NoError(t, err)
+	require.Equal(t, "dev", c.Name)
+
+	_, _, err = run("context", "use", "prod")
+	require.NoError(t, err)
+	c, _, err = run("whoami")
+	require.NoError(t, err)
+	require.Equal(t, contexts.Context{
+		Name:     "prod",
+		Server:   "https://example.com",
+		TokenRef: "keyring:prod",
+		Org:      "acme",
+	}, c)
+
+	c, _, err = run("--context", "dev", "whoami")
+	require.NoError(t, err)
+	require.Equal(t, "dev", c.Name)
+
+	_, out, err = run("context", "list")
+	require.NoError(t, err)
+	require.Regexp(t, `\*\s+prod\s+https://example.com\s+acme`, out)
+
+	_, out, err = run("context", "show")
+	require.NoError(t, err)
+	require.Contains(t, out, "Token:  keyring:prod")
+
+	_, _, err = run("context", "delete", "prod")
+	require.NoError(t, err)
+	_, _, err = run("whoami")
+	require.ErrorIs(t, err, contexts.ErrNoContext)
+}
@@ -0,0 +1,104 @@
+package serpent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bketelsen/serpent/files"
+)
+
+// CooldownError is returned by CooldownMiddleware when a command is run again
+// before its cooldown has elapsed.
+type CooldownError struct {
+	Key string
+	// Remaining is the time left until the command may run again.
+	Remaining time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("%q ran recently, try again in %s", e.Key, e.Remaining.Round(time.Second))
+}
+
+// CooldownMiddleware returns middleware refusing to run the command with a
+// CooldownError until d has elapsed since it last succeeded, for expensive
+// operations such as "sync --full". Commands sharing key share the cooldown;
+// an empty key uses the full name of the command. The time of the last
+// successful run is kept in $XDG_STATE_HOME/<root command>/cooldowns.json.
+func CooldownMiddleware(key string, d time.Duration) MiddlewareFunc {
+	return cooldownMiddleware(key, d, false)
+}
+
+// CooldownWarnMiddleware is like CooldownMiddleware, but only warns when the
+// command is run again within the cooldown.
+func CooldownWarnMiddleware(key string, d time.Duration) MiddlewareFunc {
+	return cooldownMiddleware(key, d, true)
+}
+
+func cooldownMiddleware(key string, d time.Duration, warn bool) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			key := key
+			if key == "" {
+				key = inv.Command.FullName()
+			}
+			path := filepath.Join(stateDir(inv), "cooldowns.json")
+
+			now := time.Now()
+			runs, err := readCooldowns(path)
+			if err != nil {
+				return err
+			}
+			if last, ok := runs[key]; ok {
+				if remaining := d - now.Sub(last); remaining > 0 {
+					cerr := &CooldownError{Key: key, Remaining: remaining}
+					if !warn {
+						return cerr
+					}
+					inv.Warn(fmt.Sprintf("%s.", cerr.Error()))
+				}
+			}
+
+			if err := next(inv); err != nil {
+				return err
+			}
+
+			// The file is read again, as other commands may have run
+			// meanwhile.
+			runs, err = readCooldowns(path)
+			if err != nil {
+				inv.Warn("Failed to record the run for the cooldown.", err.Error())
+				return nil
+			}
+			runs[key] = now.UTC()
+			byt, err := json.MarshalIndent(runs, "", "  ")
+			if err == nil {
+				err = files.WriteFile(path, byt, files.PrivateFile)
+			}
+			if err != nil {
+				inv.Warn("Failed to record the run for the cooldown.", err.Error())
+			}
+			return nil
+		}
+	}
+}
+
+// readCooldowns returns the times of the last successful runs by key.
+func readCooldowns(path string) (map[string]time.Time, error) {
+	runs := make(map[string]time.Time)
+	byt, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return runs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cooldowns: %w", err)
+	}
+	if err := json.Unmarshal(byt, &runs); err != nil {
+		return nil, fmt.Errorf("decode cooldowns %s: %w", path, err)
+	}
+	return runs, nil
+}
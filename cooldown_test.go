@@ -0,0 +1,113 @@
+package serpent_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestCooldownMiddleware(t *testing.T) {
+	t.Parallel()
+
+	makeCmd := func(mw serpent.MiddlewareFunc, runs *int, fail *bool) *serpent.Command {
+		return &serpent.Command{
+			Use: "app",
+			Children: []*serpent.Command{{
+				Use:        "sync",
+				Middleware: mw,
+				Handler: func(*serpent.Invocation) error {
+					*runs++
+					if *fail {
+						return errors.New("sync failed")
+					}
+					return nil
+				},
+			}},
+		}
+	}
+	invoke := func(cmd *serpent.Command, stateDir string) (*serpent.Invocation, *ioBufs) {
+		inv := cmd.Invoke("sync")
+		inv.Environ.Set("XDG_STATE_HOME", stateDir)
+		return inv, fakeIO(inv)
+	}
+
+	t.Run("Refuse", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			runs     int
+			fail     bool
+			stateDir = t.TempDir()
+			cmd      = makeCmd(serpent.CooldownMiddleware("", time.Hour), &runs, &fail)
+		)
+		inv, _ := invoke(cmd, stateDir)
+		require.NoError(t, inv.Run())
+
+		inv, _ = invoke(cmd, stateDir)
+		err := inv.Run()
+		var cerr *serpent.CooldownError
+		require.ErrorAs(t, err, &cerr)
+		require.Equal(t, "app sync", cerr.Key)
+		require.Greater(t, cerr.Remaining, 59*time.Minute)
+		require.Equal(t, 1, runs)
+
+		// Another state directory has no cooldown.
+		inv, _ = invoke(cmd, t.TempDir())
+		require.NoError(t, inv.Run())
+		require.Equal(t, 2, runs)
+	})
+
+	t.Run("FailedRunsDontCount", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			runs     int
+			fail     = true
+			stateDir = t.TempDir()
+			cmd      = makeCmd(serpent.CooldownMiddleware("sync", time.Hour), &runs, &fail)
+		)
+		inv, _ := invoke(cmd, stateDir)
+		require.Error(t, inv.Run())
+		fail = false
+		inv, _ = invoke(cmd, stateDir)
+		require.NoError(t, inv.Run())
+		require.Equal(t, 2, runs)
+	})
+
+	t.Run("Elapsed", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			runs     int
+			fail     bool
+			stateDir = t.TempDir()
+			cmd      = makeCmd(serpent.CooldownMiddleware("sync", time.Nanosecond), &runs, &fail)
+		)
+		for i := 0; i < 2; i++ {
+			inv, _ := invoke(cmd, stateDir)
+			require.NoError(t, inv.Run())
+		}
+		require.Equal(t, 2, runs)
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			runs     int
+			fail     bool
+			stateDir = t.TempDir()
+			cmd      = makeCmd(serpent.CooldownWarnMiddleware("sync", time.Hour), &runs, &fail)
+		)
+		inv, _ := invoke(cmd, stateDir)
+		require.NoError(t, inv.Run())
+		inv, io := invoke(cmd, stateDir)
+		require.NoError(t, inv.Run())
+		require.Contains(t, io.Stderr.String(), `"sync" ran recently, try again in`)
+		require.Equal(t, 2, runs)
+	})
+}
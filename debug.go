@@ -0,0 +1,125 @@
+package serpent
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// AnnotationDebugServer marks the option holding the address of the debug
+// server, see DebugServerOption.
+const AnnotationDebugServer = "serpent.debug_server"
+
+// DebugServerOption returns a --debug-address option. When it's set,
+// DebugServerMiddleware serves pprof profiles at /debug/pprof/, expvar
+// variables at /debug/vars and the options of the command at /debug/config
+// on that address while the command runs.
+func DebugServerOption(addr *string) Option {
+	return Option{
+		Name:        "debug-address",
+		Description: "Address to serve pprof, expvar and config debug endpoints on, disabled if empty.",
+		Flag:        "debug-address",
+		Value:       StringOf(addr),
+		Annotations: Annotations{}.Mark(AnnotationDebugServer, "true"),
+	}
+}
+
+// DebugServerMiddleware returns middleware starting the debug server if the
+// DebugServerOption of the command or one of its parents is set. The server
+// is stopped when the handler returns or the invocation's context is
+// canceled.
+func DebugServerMiddleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			addr := inv.debugServerAddress()
+			if addr == "" {
+				return next(inv)
+			}
+
+			var (
+				ln  net.Listener
+				err error
+			)
+			if inv.Net != nil {
+				ln, err = inv.Net.Listen("tcp", addr)
+			} else {
+				ln, err = net.Listen("tcp", addr)
+			}
+			if err != nil {
+				return fmt.Errorf("listen for debug server on %s: %w", addr, err)
+			}
+			srv := &http.Server{
+				Handler:           debugHandler(inv),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			defer srv.Close()
+			go func() {
+				<-inv.Context().Done()
+				_ = srv.Close()
+			}()
+			go func() {
+				err := srv.Serve(ln)
+				if err != nil && !errors.Is(err, http.ErrServerClosed) {
+					inv.Warn("Debug server stopped.", err.Error())
+				}
+			}()
+			inv.Info(fmt.Sprintf("Debug server listening on http://%s/debug/", ln.Addr()))
+			return next(inv)
+		}
+	}
+}
+
+// debugServerAddress returns the value of the closest option annotated
+// with AnnotationDebugServer.
+func (inv *Invocation) debugServerAddress() string {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Annotations.IsSet(AnnotationDebugServer) && opt.Value != nil {
+				return opt.Value.String()
+			}
+		}
+	}
+	return ""
+}
+
+// debugConfigOption is an option as served at /debug/config.
+type debugConfigOption struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+// debugHandler returns the handler of the debug server of inv.
+func debugHandler(inv *Invocation) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, _ *http.Request) {
+		opts := []debugConfigOption{}
+		for _, opt := range configOptions(inv.Command) {
+			value := opt.Value.String()
+			if opt.IsSecret() && value != "" {
+				value = SanitizedSecret
+			}
+			opts = append(opts, debugConfigOption{
+				Name:   opt.Name,
+				Value:  value,
+				Source: opt.originText(),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(opts)
+	})
+	return mux
+}
@@ -0,0 +1,98 @@
+package serpent_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+)
+
+// recordingNet listens on a random local port and records the listener.
+type recordingNet struct {
+	ln chan net.Listener
+}
+
+func (n recordingNet) Listen(network, _ string) (net.Listener, error) {
+	ln, err := net.Listen(network, "127.0.0.1:0")
+	if err == nil {
+		n.ln <- ln
+	}
+	return ln, err
+}
+
+func TestDebugServer(t *testing.T) {
+	t.Parallel()
+
+	get := func(inv *serpent.Invocation, url string) (string, error) {
+		req, err := http.NewRequestWithContext(inv.Context(), http.MethodGet, url, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		byt, err := io.ReadAll(resp.Body)
+		return string(byt), err
+	}
+
+	cmd := func(handler serpent.HandlerFunc) *serpent.Command {
+		var addr, token string
+		return &serpent.Command{
+			Use: "serve",
+			Options: serpent.OptionSet{
+				serpent.DebugServerOption(&addr),
+				{
+					Name:        "token",
+					Flag:        "token",
+					Annotations: serpent.Annotations{}.Mark(serpent.AnnotationSecret, "true"),
+					Value:       serpent.StringOf(&token),
+				},
+			},
+			Middleware: serpent.DebugServerMiddleware(),
+			Handler:    handler,
+		}
+	}
+
+	t.Run("Enabled", func(t *testing.T) {
+		t.Parallel()
+
+		nt := recordingNet{ln: make(chan net.Listener, 1)}
+		var config, vars string
+		inv := cmd(func(inv *serpent.Invocation) error {
+			base := "http://" + (<-nt.ln).Addr().String()
+			var err error
+			config, err = get(inv, base+"/debug/config")
+			if err != nil {
+				return err
+			}
+			vars, err = get(inv, base+"/debug/vars")
+			return err
+		}).Invoke("--debug-address", "localhost:6060", "--token", "hunter2")
+		inv.Net = nt
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+
+		require.Contains(t, stdio.Stderr.String(), "Debug server listening on http://127.0.0.1:")
+		require.Contains(t, config, `"name": "token"`)
+		require.Contains(t, config, `"value": "***"`)
+		require.Contains(t, config, `"source": "flag --token"`)
+		require.NotContains(t, config, "hunter2")
+		require.Contains(t, vars, `"memstats"`)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+
+		nt := recordingNet{ln: make(chan net.Listener, 1)}
+		inv := cmd(func(*serpent.Invocation) error { return nil }).Invoke()
+		inv.Net = nt
+		require.NoError(t, inv.Run())
+		require.Empty(t, nt.ln)
+	})
+}
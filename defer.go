@@ -0,0 +1,47 @@
+package serpent
+
+import (
+	"errors"
+	"sync"
+)
+
+// deferStack holds the functions deferred by an invocation, shared by the
+// copies of the invocation made while it runs.
+type deferStack struct {
+	mu    sync.Mutex
+	funcs []func() error
+}
+
+// Defer registers fn to be called once Run returns, after the handler,
+// whether it succeeds, fails, is canceled or panics. Deferred functions are
+// called last in, first out, and their errors are joined into the error
+// returned by Run. Use it from middleware and handlers to release resources
+// of the invocation. It panics if the invocation isn't running.
+func (inv *Invocation) Defer(fn func() error) {
+	if inv.deferred == nil {
+		panic("Defer called outside of Run")
+	}
+	inv.deferred.mu.Lock()
+	defer inv.deferred.mu.Unlock()
+	inv.deferred.funcs = append(inv.deferred.funcs, fn)
+}
+
+// run calls the deferred functions, last in first out, including any
+// deferred while running them.
+func (s *deferStack) run() error {
+	var errs []error
+	for {
+		s.mu.Lock()
+		if len(s.funcs) == 0 {
+			s.mu.Unlock()
+			return errors.Join(errs...)
+		}
+		fn := s.funcs[len(s.funcs)-1]
+		s.funcs = s.funcs[:len(s.funcs)-1]
+		s.mu.Unlock()
+
+		if err := fn(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
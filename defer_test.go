@@ -0,0 +1,91 @@
+package serpent_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestInvocation_Defer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("LIFO", func(t *testing.T) {
+		t.Parallel()
+
+		var calls []string
+		cmd := &serpent.Command{
+			Use: "root",
+			Middleware: func(next serpent.HandlerFunc) serpent.HandlerFunc {
+				return func(inv *serpent.Invocation) error {
+					inv.Defer(func() error {
+						calls = append(calls, "middleware")
+						return nil
+					})
+					return next(inv)
+				}
+			},
+			Handler: func(inv *serpent.Invocation) error {
+				inv.Defer(func() error {
+					calls = append(calls, "handler")
+					return nil
+				})
+				calls = append(calls, "run")
+				return nil
+			},
+		}
+		require.NoError(t, cmd.Invoke().Run())
+		require.Equal(t, []string{"run", "handler", "middleware"}, calls)
+	})
+
+	t.Run("ErrorsJoined", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			errHandler = errors.New("handler failed")
+			errFirst   = errors.New("first cleanup failed")
+			errSecond  = errors.New("second cleanup failed")
+		)
+		cmd := &serpent.Command{
+			Use: "root",
+			Handler: func(inv *serpent.Invocation) error {
+				inv.Defer(func() error { return errFirst })
+				inv.Defer(func() error { return errSecond })
+				return errHandler
+			},
+		}
+		err := cmd.Invoke().Run()
+		require.ErrorIs(t, err, errHandler)
+		require.ErrorIs(t, err, errFirst)
+		require.ErrorIs(t, err, errSecond)
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		t.Parallel()
+
+		var called bool
+		cmd := &serpent.Command{
+			Use: "root",
+			Handler: func(inv *serpent.Invocation) error {
+				inv.Defer(func() error {
+					called = true
+					return nil
+				})
+				panic("boom")
+			},
+		}
+		require.Panics(t, func() { _ = cmd.Invoke().Run() })
+		require.True(t, called)
+	})
+
+	t.Run("OutsideRun", func(t *testing.T) {
+		t.Parallel()
+
+		cmd := &serpent.Command{Use: "root"}
+		require.Panics(t, func() {
+			cmd.Invoke().Defer(func() error { return nil })
+		})
+	})
+}
@@ -0,0 +1,197 @@
+package serpent
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// BreakingChangeKind is the kind of a BreakingChange.
+type BreakingChangeKind string
+
+const (
+	RemovedCommand BreakingChangeKind = "removed-command"
+	RemovedAlias   BreakingChangeKind = "removed-alias"
+	RemovedOption  BreakingChangeKind = "removed-option"
+	RenamedFlag    BreakingChangeKind = "renamed-flag"
+	RemovedEnv     BreakingChangeKind = "removed-env"
+	RemovedYAML    BreakingChangeKind = "removed-yaml"
+	ChangedType    BreakingChangeKind = "changed-type"
+	ChangedDefault BreakingChangeKind = "changed-default"
+	TightenedEnum  BreakingChangeKind = "tightened-enum"
+	NewlyRequired  BreakingChangeKind = "newly-required"
+)
+
+// BreakingChange is a change between two versions of a command tree that
+// may break scripts written against the old version, found by DiffTrees.
+type BreakingChange struct {
+	Kind BreakingChangeKind
+	// Command is the full name of the command in the old tree.
+	Command string
+	// Option is the name of the option in the old tree, if any.
+	Option  string
+	Message string
+}
+
+func (c BreakingChange) String() string {
+	return fmt.Sprintf("%s: %s: %s", c.Command, c.Kind, c.Message)
+}
+
+// DiffTrees returns the breaking changes from the old to the new version of
+// a command tree, such as removed commands and flags, changed defaults and
+// enum choices that are no longer accepted. Hidden commands and options
+// aren't part of the interface, so hiding one counts as removing it.
+// Options inherited from parent commands are taken into account, so moving
+// an option to a parent isn't a breaking change.
+func DiffTrees(old, updated *Command) []BreakingChange {
+	return DiffCommandTrees(old.Tree(), updated.Tree())
+}
+
+// DiffCommandTrees is like DiffTrees, but compares trees exported with
+// Command.Tree, e.g. the JSON tree of the previous release.
+func DiffCommandTrees(old, updated CommandTree) []BreakingChange {
+	return diffCommandTrees(old, updated, nil, nil)
+}
+
+func diffCommandTrees(old, updated CommandTree, oldInherited, newInherited []OptionSchema) []BreakingChange {
+	var changes []BreakingChange
+	change := func(kind BreakingChangeKind, opt, format string, args ...any) {
+		changes = append(changes, BreakingChange{
+			Kind:    kind,
+			Command: old.Name,
+			Option:  opt,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, alias := range old.Aliases {
+		if !slices.Contains(updated.Aliases, alias) && lastWord(updated.Name) != alias {
+			change(RemovedAlias, "", "alias %q was removed", alias)
+		}
+	}
+
+	// Inherited options are compared with the parent's.
+	oldOpts := effectiveOptions(old.Options, oldInherited)
+	newOpts := effectiveOptions(updated.Options, newInherited)
+	for _, o := range old.Options {
+		n, ok := findOptionSchema(newOpts, o)
+		if !ok {
+			change(RemovedOption, o.Name, "option %q was removed", o.Name)
+			continue
+		}
+		changes = append(changes, diffOptionSchemas(old.Name, o, n)...)
+	}
+
+	for _, oldChild := range old.Children {
+		newChild, ok := findCommandTree(updated.Children, lastWord(oldChild.Name))
+		if !ok {
+			change(RemovedCommand, "", "command %q was removed", oldChild.Name)
+			continue
+		}
+		changes = append(changes, diffCommandTrees(oldChild, newChild, oldOpts, newOpts)...)
+	}
+	return changes
+}
+
+func diffOptionSchemas(command string, o, n OptionSchema) []BreakingChange {
+	var changes []BreakingChange
+	change := func(kind BreakingChangeKind, format string, args ...any) {
+		changes = append(changes, BreakingChange{
+			Kind:    kind,
+			Command: command,
+			Option:  o.Name,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if o.Flag != "" && o.Flag != n.Flag {
+		if n.Flag == "" {
+			change(RenamedFlag, "option %q flag --%s was removed", o.Name, o.Flag)
+		} else {
+			change(RenamedFlag, "option %q flag --%s was renamed to --%s", o.Name, o.Flag, n.Flag)
+		}
+	}
+	if o.FlagShorthand != "" && o.FlagShorthand != n.FlagShorthand {
+		change(RenamedFlag, "option %q shorthand -%s was removed", o.Name, o.FlagShorthand)
+	}
+	if o.Env != "" && o.Env != n.Env {
+		change(RemovedEnv, "option %q environment variable %s was removed", o.Name, o.Env)
+	}
+	if o.YAML != "" && o.YAML != n.YAML {
+		change(RemovedYAML, "option %q YAML key %s was removed", o.Name, o.YAML)
+	}
+	// Enum types include their choices, which are compared below.
+	if baseType(o.Type) != baseType(n.Type) {
+		change(ChangedType, "option %q type changed from %s to %s", o.Name, o.Type, n.Type)
+	}
+	if o.Default != n.Default {
+		change(ChangedDefault, "option %q default changed from %q to %q", o.Name, o.Default, n.Default)
+	}
+	if len(n.Choices) > 0 {
+		var removed []string
+		for _, choice := range o.Choices {
+			if !containsFold(n.Choices, choice) {
+				removed = append(removed, choice)
+			}
+		}
+		if len(o.Choices) == 0 {
+			change(TightenedEnum, "option %q only accepts %s", o.Name, strings.Join(n.Choices, ", "))
+		} else if len(removed) > 0 {
+			change(TightenedEnum, "option %q no longer accepts %s", o.Name, strings.Join(removed, ", "))
+		}
+	}
+	if !o.Required && n.Required {
+		change(NewlyRequired, "option %q is now required", o.Name)
+	}
+	return changes
+}
+
+// effectiveOptions returns the options of a command, including the ones it
+// inherits that it doesn't override.
+func effectiveOptions(own, inherited []OptionSchema) []OptionSchema {
+	opts := append([]OptionSchema(nil), own...)
+	for _, opt := range inherited {
+		if opt.Flag != "" && slices.ContainsFunc(own, func(o OptionSchema) bool { return o.Flag == opt.Flag }) {
+			continue
+		}
+		opts = append(opts, opt)
+	}
+	return opts
+}
+
+// findOptionSchema returns the option of opts matching o by name, or else by
+// flag or environment variable, in case the option was renamed.
+func findOptionSchema(opts []OptionSchema, o OptionSchema) (OptionSchema, bool) {
+	for _, match := range []func(OptionSchema) bool{
+		func(n OptionSchema) bool { return n.Name == o.Name },
+		func(n OptionSchema) bool { return o.Flag != "" && n.Flag == o.Flag },
+		func(n OptionSchema) bool { return o.Env != "" && n.Env == o.Env },
+	} {
+		if i := slices.IndexFunc(opts, match); i >= 0 {
+			return opts[i], true
+		}
+	}
+	return OptionSchema{}, false
+}
+
+// findCommandTree returns the command of trees named or aliased name.
+func findCommandTree(trees []CommandTree, name string) (CommandTree, bool) {
+	for _, t := range trees {
+		if lastWord(t.Name) == name || slices.Contains(t.Aliases, name) {
+			return t, true
+		}
+	}
+	return CommandTree{}, false
+}
+
+// baseType returns typ without enum choices, e.g. "enum" for
+// "enum[json|yaml]".
+func baseType(typ string) string {
+	base, _, _ := strings.Cut(typ, "[")
+	return base
+}
+
+func lastWord(s string) string {
+	return s[strings.LastIndex(s, " ")+1:]
+}
@@ -0,0 +1,84 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+)
+
+func TestDiffTrees(t *testing.T) {
+	t.Parallel()
+
+	type version struct {
+		format   []string
+		level    string
+		required bool
+		moved    bool
+	}
+	cmd := func(v version) *serpent.Command {
+		format := serpent.EnumOf(new(string), v.format...)
+		level := serpent.Option{Name: "level", Flag: "level", Env: "APP_LEVEL", Default: v.level, Value: serpent.StringOf(new(string))}
+		root := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "format", Flag: "format", Default: "json", Required: v.required, Value: format},
+			},
+			Children: []*serpent.Command{
+				{Use: "server", Aliases: []string{"srv"}, Options: serpent.OptionSet{level}},
+				{Use: "legacy"},
+			},
+		}
+		if v.moved {
+			// The level option moved to the root, and legacy was removed.
+			root.Options = append(root.Options, level)
+			root.Children[0].Options = nil
+			root.Children[0].Aliases = nil
+			root.Children = root.Children[:1]
+		}
+		return root
+	}
+
+	old := version{format: []string{"json", "yaml", "table"}, level: "info"}
+
+	t.Run("Unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		require.Empty(t, serpent.DiffTrees(cmd(old), cmd(old)))
+	})
+
+	t.Run("Breaking", func(t *testing.T) {
+		t.Parallel()
+
+		changes := serpent.DiffTrees(cmd(old), cmd(version{
+			format:   []string{"json", "yaml"},
+			level:    "warn",
+			required: true,
+			moved:    true,
+		}))
+		var got []string
+		for _, c := range changes {
+			got = append(got, c.String())
+		}
+		require.ElementsMatch(t, []string{
+			`app: tightened-enum: option "format" no longer accepts table`,
+			`app: newly-required: option "format" is now required`,
+			`app server: removed-alias: alias "srv" was removed`,
+			`app server: changed-default: option "level" default changed from "info" to "warn"`,
+			`app: removed-command: command "app legacy" was removed`,
+		}, got)
+	})
+
+	t.Run("RenamedFlag", func(t *testing.T) {
+		t.Parallel()
+
+		renamed := cmd(old)
+		renamed.Options[0].Flag = "output"
+		changes := serpent.DiffTrees(cmd(old), renamed)
+		require.Len(t, changes, 1)
+		require.Equal(t, serpent.RenamedFlag, changes[0].Kind)
+		require.Equal(t, "format", changes[0].Option)
+		require.Equal(t, `option "format" flag --format was renamed to --output`, changes[0].Message)
+	})
+}
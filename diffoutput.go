@@ -0,0 +1,70 @@
+package serpent
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/bketelsen/serpent/internal/textdiff"
+)
+
+// AnnotationDiffOutput marks the option highlighting changes between the
+// outputs of repeated runs, see DiffOutputOption.
+const AnnotationDiffOutput = "serpent.diff_output"
+
+// DiffOutputOption returns a --differences option, like "watch -d",
+// highlighting what changed in the output of a command since its previous
+// run when it's repeated by WatchMiddleware or EveryMiddleware. The output of
+// each run is then buffered and written once the run ends.
+func DiffOutputOption() Option {
+	var enabled bool
+	return Option{
+		Name:        "differences",
+		Description: "Highlight changes in the output since the previous run of --watch or --every.",
+		Flag:        "differences",
+		Value:       BoolOf(&enabled),
+		Annotations: Annotations{}.Mark(AnnotationDiffOutput, "true"),
+	}
+}
+
+// outputDiffer runs a repeated handler, highlighting the changes in its
+// output since the previous run when the DiffOutputOption is set.
+type outputDiffer struct {
+	enabled bool
+	ran     bool
+	prev    string
+}
+
+func newOutputDiffer(inv *Invocation) *outputDiffer {
+	return &outputDiffer{enabled: inv.diffOutputOption()}
+}
+
+func (d *outputDiffer) run(next HandlerFunc, inv *Invocation) error {
+	if !d.enabled {
+		return next(inv)
+	}
+	var buf bytes.Buffer
+	err := next(inv.with(func(i *Invocation) {
+		i.Stdout = &buf
+	}))
+	out := buf.String()
+	rendered := out
+	if d.ran {
+		rendered = textdiff.Highlight(d.prev, out, Changed)
+	}
+	d.ran, d.prev = true, out
+	_, _ = io.WriteString(inv.Stdout, rendered)
+	return err
+}
+
+// diffOutputOption reports whether the closest option annotated with
+// AnnotationDiffOutput is set.
+func (inv *Invocation) diffOutputOption() bool {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Annotations.IsSet(AnnotationDiffOutput) && opt.Value != nil {
+				return opt.Value.String() == "true"
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package serpent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// CheckStatus is the outcome of a Check.
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "pass"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+)
+
+// CheckResult is the result of running a Check.
+type CheckResult struct {
+	Status  CheckStatus
+	Message string
+	// Remediation tells the user how to resolve a warning or failure.
+	Remediation string
+}
+
+// Check is a named self-check run by the doctor command.
+type Check struct {
+	Name string
+	Run  func(inv *Invocation) CheckResult
+}
+
+// DoctorCommand returns a "doctor" command that runs the given checks and
+// renders their results as a table, followed by remediation steps for any
+// that didn't pass. The command fails if any check fails.
+func DoctorCommand(checks ...Check) *Command {
+	return &Command{
+		Use:   "doctor",
+		Short: "Check the health of your installation.",
+		Handler: func(inv *Invocation) error {
+			results := make([]CheckResult, len(checks))
+			for i, check := range checks {
+				results[i] = check.Run(inv)
+			}
+
+			tw := tabwriter.NewWriter(inv.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "STATUS\tCHECK\tMESSAGE")
+			for i, check := range checks {
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", formatCheckStatus(results[i].Status), check.Name, results[i].Message)
+			}
+			err := tw.Flush()
+			if err != nil {
+				return err
+			}
+
+			var failed int
+			for i, check := range checks {
+				res := results[i]
+				if res.Status == CheckFail {
+					failed++
+				}
+				if res.Status == CheckPass || res.Remediation == "" {
+					continue
+				}
+				_, _ = fmt.Fprintf(inv.Stdout, "\n%s %s:\n  %s\n", formatCheckStatus(res.Status), check.Name, res.Remediation)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+			}
+			return nil
+		},
+	}
+}
+
+func formatCheckStatus(s CheckStatus) string {
+	txt := strings.ToUpper(string(s))
+	switch s {
+	case CheckPass:
+		return Keyword(txt)
+	case CheckWarn:
+		return DefaultStyles.Warn.Render(txt)
+	case CheckFail:
+		return DefaultStyles.Error.Render(txt)
+	}
+	return txt
+}
+
+// ConfigReadableCheck checks that the config file at path, if it exists, can
+// be read. A missing file is only a warning.
+func ConfigReadableCheck(path string) Check {
+	return Check{
+		Name: "Config readable",
+		Run: func(*Invocation) CheckResult {
+			f, err := os.Open(path)
+			if errors.Is(err, os.ErrNotExist) {
+				return CheckResult{
+					Status:      CheckWarn,
+					Message:     fmt.Sprintf("%s does not exist", path),
+					Remediation: fmt.Sprintf("Create %s to configure the application.", path),
+				}
+			}
+			if err != nil {
+				return CheckResult{
+					Status:      CheckFail,
+					Message:     err.Error(),
+					Remediation: fmt.Sprintf("Check the permissions of %s.", path),
+				}
+			}
+			_ = f.Close()
+			return CheckResult{Status: CheckPass, Message: path}
+		},
+	}
+}
+
+// VersionCheck compares current with the latest available version, as
+// reported by latest.
+func VersionCheck(current string, latest func(ctx context.Context) (string, error)) Check {
+	return Check{
+		Name: "Version current",
+		Run: func(inv *Invocation) CheckResult {
+			want, err := latest(inv.Context())
+			if err != nil {
+				return CheckResult{
+					Status:  CheckWarn,
+					Message: fmt.Sprintf("could not determine the latest version: %v", err),
+				}
+			}
+			if strings.TrimPrefix(want, "v") != strings.TrimPrefix(current, "v") {
+				return CheckResult{
+					Status:      CheckWarn,
+					Message:     fmt.Sprintf("%s is installed, %s is available", current, want),
+					Remediation: fmt.Sprintf("Upgrade to %s.", want),
+				}
+			}
+			return CheckResult{Status: CheckPass, Message: current}
+		},
+	}
+}
+
+// PathCheck checks that the program found as name in $PATH is the one
+// currently running, catching stale installs that shadow new ones.
+func PathCheck(name string) Check {
+	return Check{
+		Name: "PATH",
+		Run: func(inv *Invocation) CheckResult {
+			found, err := exec.LookPath(name)
+			if err != nil {
+				return CheckResult{
+					Status:      CheckWarn,
+					Message:     fmt.Sprintf("%s not found in $PATH", name),
+					Remediation: fmt.Sprintf("Add the directory containing %s to your $PATH.", name),
+				}
+			}
+			self, err := os.Executable()
+			if err != nil {
+				return CheckResult{Status: CheckWarn, Message: err.Error()}
+			}
+			if !sameFile(found, self) {
+				return CheckResult{
+					Status:      CheckWarn,
+					Message:     fmt.Sprintf("%s in $PATH is %s, but %s is running", name, found, self),
+					Remediation: fmt.Sprintf("Remove the stale install at %s or reorder your $PATH.", found),
+				}
+			}
+			return CheckResult{Status: CheckPass, Message: found}
+		},
+	}
+}
+
+func sameFile(a, b string) bool {
+	if ra, err := filepath.EvalSymlinks(a); err == nil {
+		a = ra
+	}
+	if rb, err := filepath.EvalSymlinks(b); err == nil {
+		b = rb
+	}
+	ia, errA := os.Stat(a)
+	ib, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return os.SameFile(ia, ib)
+}
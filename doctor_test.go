@@ -0,0 +1,66 @@
+package serpent_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestDoctorCommand(t *testing.T) {
+	t.Parallel()
+
+	cfg := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfg, []byte("a: b"), 0o600))
+
+	t.Run("OK", func(t *testing.T) {
+		t.Parallel()
+
+		cmd := serpent.DoctorCommand(
+			serpent.ConfigReadableCheck(cfg),
+			serpent.VersionCheck("v1.2.3", func(context.Context) (string, error) {
+				return "1.2.3", nil
+			}),
+		)
+		inv := cmd.Invoke()
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "PASS    Config readable")
+		require.Contains(t, stdio.Stdout.String(), "PASS    Version current")
+	})
+
+	t.Run("WarnAndFail", func(t *testing.T) {
+		t.Parallel()
+
+		cmd := serpent.DoctorCommand(
+			serpent.ConfigReadableCheck(filepath.Join(t.TempDir(), "missing.yaml")),
+			serpent.VersionCheck("v1.2.3", func(context.Context) (string, error) {
+				return "", errors.New("offline")
+			}),
+			serpent.Check{
+				Name: "Custom",
+				Run: func(*serpent.Invocation) serpent.CheckResult {
+					return serpent.CheckResult{
+						Status:      serpent.CheckFail,
+						Message:     "broken",
+						Remediation: "Fix it.",
+					}
+				},
+			},
+		)
+		inv := cmd.Invoke()
+		stdio := fakeIO(inv)
+		err := inv.Run()
+		require.ErrorContains(t, err, "1 of 3 checks failed")
+		out := stdio.Stdout.String()
+		require.Contains(t, out, "WARN    Config readable")
+		require.Contains(t, out, "WARN    Version current  could not determine the latest version: offline")
+		require.Contains(t, out, "FAIL    Custom")
+		require.Contains(t, out, "FAIL Custom:\n  Fix it.")
+	})
+}
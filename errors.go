@@ -0,0 +1,226 @@
+package serpent
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+var quotedRe = regexp.MustCompile(`"[^"]*"`)
+
+// PrettyError formats err for display to a human. Errors joined with
+// errors.Join or multierror, such as the ones returned when several options
+// fail to parse, are rendered as a bulleted list under their common context
+// instead of one long string, with the offending option names highlighted.
+func PrettyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	header, errs := splitJoined(err)
+	var items []string
+	for _, e := range errs {
+		items = append(items, flattenError(e, "")...)
+	}
+
+	var str strings.Builder
+	_, _ = str.WriteString(Bold("ERROR: "))
+	switch {
+	case len(items) == 0:
+		_, _ = str.WriteString(DefaultStyles.Error.Render(err.Error()))
+	case len(items) == 1:
+		_, _ = str.WriteString(DefaultStyles.Error.Render(header))
+		_, _ = str.WriteString(highlightQuoted(items[0]))
+	default:
+		header = strings.TrimSuffix(strings.TrimSpace(header), ":")
+		if header == "" {
+			header = fmt.Sprintf("%d errors occurred", len(items))
+		}
+		_, _ = str.WriteString(DefaultStyles.Error.Render(header + ":"))
+		for _, item := range items {
+			_, _ = fmt.Fprintf(&str, "\n  %s %s", DefaultStyles.Error.Render("•"), highlightQuoted(item))
+		}
+	}
+	_, _ = str.WriteString("\n")
+
+	var flagErr *FlagError
+	if errors.As(err, &flagErr) && flagErr.Option != nil {
+		_, _ = str.WriteString(flagErr.usage())
+	}
+	return str.String()
+}
+
+// splitJoined finds the first joined error in err's chain. It returns the
+// context wrapped around it and the errors it joins, or no errors if err
+// doesn't wrap a joined error.
+func splitJoined(err error) (context string, errs []error) {
+	for cur := err; cur != nil; cur = errors.Unwrap(cur) {
+		if errs := joinedErrors(cur); len(errs) > 0 {
+			return strings.TrimSuffix(err.Error(), cur.Error()), errs
+		}
+	}
+	return "", nil
+}
+
+// joinedErrors returns the errors joined by err itself, if any.
+func joinedErrors(err error) []error {
+	//nolint:errorlint // Only err itself, not its chain, is of interest.
+	if merr, ok := err.(*multierror.Error); ok {
+		return merr.Errors
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return nil
+}
+
+// flattenError returns the messages of the leaves of a tree of joined
+// errors, each prefixed with the context wrapped around it.
+func flattenError(err error, prefix string) []string {
+	context, errs := splitJoined(err)
+	if len(errs) == 0 {
+		return []string{prefix + err.Error()}
+	}
+	var items []string
+	for _, e := range errs {
+		items = append(items, flattenError(e, prefix+context)...)
+	}
+	return items
+}
+
+// highlightQuoted highlights the quoted names, usually of options, in s.
+func highlightQuoted(s string) string {
+	return quotedRe.ReplaceAllStringFunc(s, Keyword)
+}
+
+var (
+	unknownFlagRe      = regexp.MustCompile(`^unknown flag: (--\S+)$`)
+	unknownShorthandRe = regexp.MustCompile(`^unknown shorthand flag: '(.)' in -\S+$`)
+	needsArgumentRe    = regexp.MustCompile(`^flag needs an argument: (--\S+)$`)
+	needsShorthandRe   = regexp.MustCompile(`^flag needs an argument: '(.)' in -\S+$`)
+	invalidArgumentRe  = regexp.MustCompile(`^invalid argument ("(?:[^"\\]|\\.)*") for "(?:-., )?(--\S+)" flag: (.*)$`)
+)
+
+// FlagError is a usage error about a flag on the command line, such as an
+// unknown flag or an invalid value.
+type FlagError struct {
+	// Flag is the flag as written, e.g. "--count" or "-c".
+	Flag string
+	// Value is the invalid value, if any.
+	Value string
+	// Reason is what's wrong, e.g. "unknown flag" or why the value is
+	// invalid.
+	Reason string
+	// Option is the option of the flag, nil for unknown flags.
+	Option *Option
+	// Err is the error reported by the flag parser.
+	Err error
+}
+
+func (e *FlagError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FlagError) Unwrap() error {
+	return e.Err
+}
+
+// usage describes the option of the flag for PrettyError.
+func (e *FlagError) usage() string {
+	opt := e.Option
+	var str strings.Builder
+	_, _ = fmt.Fprintf(&str, "\n  %s", Keyword("--"+opt.Flag))
+	if opt.FlagShorthand != "" {
+		_, _ = fmt.Fprintf(&str, ", %s", Keyword("-"+opt.FlagShorthand))
+	}
+	if opt.Value != nil {
+		_, _ = fmt.Fprintf(&str, " %s", opt.Value.Type())
+	}
+	_, _ = str.WriteString("\n")
+	if opt.Description != "" {
+		_, _ = fmt.Fprintf(&str, "      %s\n", opt.Description)
+	}
+	_, _ = fmt.Fprintf(&str, "      Example: %s\n", flagExample(opt))
+	return str.String()
+}
+
+// flagExample returns an example use of the flag of opt.
+func flagExample(opt *Option) string {
+	example := "--" + opt.Flag
+	if opt.Value == nil || opt.Value.Type() == "bool" {
+		return example
+	}
+	value := opt.Default
+	if value == "" {
+		var choices []string
+		switch v := opt.Value.(type) {
+		case *Enum:
+			choices = v.Choices
+		case *EnumArray:
+			choices = v.Choices
+		}
+		switch typ := opt.Value.Type(); {
+		case len(choices) > 0:
+			value = choices[0]
+		case typ == "int" || typ == "int64" || typ == "float64":
+			value = "1"
+		case typ == "duration":
+			value = "30s"
+		default:
+			value = "<" + typ + ">"
+		}
+	}
+	return example + " " + value
+}
+
+// flagError converts err, a flag parsing error, to a *FlagError with the
+// option of the flag among the options of the command and its parents, if
+// it's recognized.
+func (inv *Invocation) flagError(err error) error {
+	msg := err.Error()
+	flagErr := &FlagError{Err: err}
+	if m := unknownFlagRe.FindStringSubmatch(msg); m != nil {
+		flagErr.Flag, flagErr.Reason = m[1], "unknown flag"
+	} else if m := unknownShorthandRe.FindStringSubmatch(msg); m != nil {
+		flagErr.Flag, flagErr.Reason = "-"+m[1], "unknown flag"
+	} else if m := needsArgumentRe.FindStringSubmatch(msg); m != nil {
+		flagErr.Flag, flagErr.Reason = m[1], "flag needs an argument"
+	} else if m := needsShorthandRe.FindStringSubmatch(msg); m != nil {
+		flagErr.Flag, flagErr.Reason = "-"+m[1], "flag needs an argument"
+	} else if m := invalidArgumentRe.FindStringSubmatch(msg); m != nil {
+		value, uerr := strconv.Unquote(m[1])
+		if uerr != nil {
+			return err
+		}
+		flagErr.Flag, flagErr.Value, flagErr.Reason = m[2], value, m[3]
+	} else {
+		return err
+	}
+
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		if opt := cmd.Options.byFlagForm(flagErr.Flag); opt != nil {
+			flagErr.Option = opt
+			break
+		}
+	}
+	return flagErr
+}
+
+// Canceled is returned by interactive prompts, such as the ui.Wizard, when
+// the user interrupts them or the invocation's context is canceled. Its exit
+// code is 130, as for commands interrupted by SIGINT in shells.
+var Canceled error = canceledError{}
+
+type canceledError struct{}
+
+func (canceledError) Error() string {
+	return "canceled"
+}
+
+func (canceledError) ExitCode() int {
+	return 130
+}
@@ -0,0 +1,132 @@
+package serpent_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestPrettyError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Nil", func(t *testing.T) {
+		t.Parallel()
+		require.Empty(t, serpent.PrettyError(nil))
+	})
+
+	t.Run("Single", func(t *testing.T) {
+		t.Parallel()
+		err := fmt.Errorf("running: %w", errors.New("boom"))
+		require.Equal(t, "ERROR: running: boom\n", serpent.PrettyError(err))
+	})
+
+	t.Run("OneJoined", func(t *testing.T) {
+		t.Parallel()
+		err := fmt.Errorf("reading values: %w", errors.Join(errors.New(`parse "port": invalid syntax`)))
+		require.Equal(t, "ERROR: reading values: parse \"port\": invalid syntax\n", serpent.PrettyError(err))
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		t.Parallel()
+
+		os := serpent.OptionSet{
+			{Name: "port", Env: "APP_PORT", Value: serpent.Int64Of(new(int64))},
+			{Name: "debug", Env: "APP_DEBUG", Value: serpent.BoolOf(new(bool))},
+		}
+		err := os.ParseEnv([]serpent.EnvVar{
+			{Name: "APP_PORT", Value: "http"},
+			{Name: "APP_DEBUG", Value: "maybe"},
+		})
+		require.Error(t, err)
+		require.Equal(t, `ERROR: parsing environment:
+  • parse "port" from APP_PORT: strconv.ParseInt: parsing "http": invalid syntax
+  • parse "debug" from APP_DEBUG: strconv.ParseBool: parsing "maybe": invalid syntax
+`, serpent.PrettyError(fmt.Errorf("parsing environment: %w", err)))
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		t.Parallel()
+		err := errors.Join(
+			errors.New("first"),
+			fmt.Errorf("command %v: %w", "sub", errors.Join(errors.New("second"), errors.New("third"))),
+		)
+		require.Equal(t, `ERROR: 3 errors occurred:
+  • first
+  • command sub: second
+  • command sub: third
+`, serpent.PrettyError(err))
+	})
+}
+
+func TestFlagError(t *testing.T) {
+	t.Parallel()
+
+	cmd := func() *serpent.Command {
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{
+					Name:          "retries",
+					Flag:          "retries",
+					FlagShorthand: "r",
+					Description:   "Number of retries.",
+					Value:         serpent.Int64Of(new(int64)),
+				},
+				{
+					Name:        "format",
+					Flag:        "format",
+					Description: "Output format.",
+					Value:       serpent.EnumOf(new(string), "json", "table"),
+				},
+			},
+			Handler: func(*serpent.Invocation) error { return nil },
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		flag   string
+		value  string
+		reason string
+		option string
+	}{
+		{name: "Unknown", args: []string{"--nope"}, flag: "--nope", reason: "unknown flag"},
+		{name: "UnknownShorthand", args: []string{"-x"}, flag: "-x", reason: "unknown flag"},
+		{name: "NeedsArgument", args: []string{"--retries"}, flag: "--retries", reason: "flag needs an argument", option: "retries"},
+		{name: "NeedsArgumentShorthand", args: []string{"-r"}, flag: "-r", reason: "flag needs an argument", option: "retries"},
+		{name: "Invalid", args: []string{"--retries", "many"}, flag: "--retries", value: "many", reason: `strconv.ParseInt: parsing "many": invalid syntax`, option: "retries"},
+		{name: "InvalidEnum", args: []string{"--format=xml"}, flag: "--format", value: "xml", reason: `invalid choice: xml, should be one of [json table]`, option: "format"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := cmd().Invoke(tc.args...).Run()
+			var flagErr *serpent.FlagError
+			require.ErrorAs(t, err, &flagErr)
+			require.Equal(t, tc.flag, flagErr.Flag)
+			require.Equal(t, tc.value, flagErr.Value)
+			require.Equal(t, tc.reason, flagErr.Reason)
+			if tc.option == "" {
+				require.Nil(t, flagErr.Option)
+			} else {
+				require.Equal(t, tc.option, flagErr.Option.Name)
+			}
+		})
+	}
+
+	t.Run("Pretty", func(t *testing.T) {
+		t.Parallel()
+
+		err := cmd().Invoke("--format", "xml").Run()
+		pretty := serpent.PrettyError(err)
+		require.Contains(t, pretty, "\n  --format enum[json\\|table]\n")
+		require.Contains(t, pretty, "      Output format.\n")
+		require.Contains(t, pretty, "      Example: --format json\n")
+	})
+}
@@ -0,0 +1,80 @@
+package serpent
+
+import (
+	"sync"
+	"time"
+)
+
+// eventStream is the Events channel of an invocation, shared by the copies
+// of the invocation made while it runs.
+type eventStream struct {
+	ch chan Event
+	// done is closed once the invocation returns, unblocking senders.
+	done chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Events returns a channel receiving the invocation's progress and log
+// events, for hosts such as GUIs and TUIs rendering native progress instead
+// of parsing text output. Steps, progress and messages reported with ui.Step,
+// ui.Progress, Parallel, Info, Warn and Error are sent as events rather than
+// written to Stderr, and an EventResult event ends the stream before the
+// channel is closed, when Run returns.
+//
+// Events must be called before Run, and the channel read until it's closed:
+// emitting an event blocks until the host receives it.
+func (inv *Invocation) Events() <-chan Event {
+	if inv.events == nil {
+		inv.events = &eventStream{
+			ch:   make(chan Event),
+			done: make(chan struct{}),
+		}
+	}
+	return inv.events.ch
+}
+
+// EventsEnabled reports whether progress and messages are reported as
+// events, in machine mode or to a host reading Events, rather than as text.
+func (inv *Invocation) EventsEnabled() bool {
+	return inv.events != nil || inv.MachineMode()
+}
+
+// send sends e unless the stream is finished.
+func (s *eventStream) send(e Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- e:
+	case <-s.done:
+	}
+}
+
+// finish sends the result event for err and closes the stream.
+func (s *eventStream) finish(err error) {
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return
+	}
+
+	result := Event{Time: time.Now(), Kind: EventResult, Level: "info", Msg: "done"}
+	if err != nil {
+		result.Level = "error"
+		result.Msg = "failed"
+		result.Error = err.Error()
+		result.ExitCode = exitCode(err)
+	}
+	s.send(result)
+
+	close(s.done)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.ch)
+}
@@ -0,0 +1,94 @@
+package serpent_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestInvocation_Events(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, handler serpent.HandlerFunc) ([]serpent.Event, string, error) {
+		t.Helper()
+		cmd := &serpent.Command{Use: "root", Handler: handler}
+		inv := cmd.Invoke()
+		io := fakeIO(inv)
+		events := inv.Events()
+
+		errc := make(chan error, 1)
+		go func() { errc <- inv.Run() }()
+		var got []serpent.Event
+		for e := range events {
+			got = append(got, e)
+		}
+		err := <-errc
+		return got, io.Stderr.String(), err
+	}
+
+	t.Run("Kinds", func(t *testing.T) {
+		t.Parallel()
+
+		events, stderr, err := run(t, func(inv *serpent.Invocation) error {
+			ui.Step(inv, "build", "compiling %d files", 3)
+			ui.Progress(inv, "upload", 1, 2)
+			inv.Warn("Careful.")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Empty(t, stderr, "events replace text output")
+
+		require.Len(t, events, 4)
+		require.Equal(t, serpent.EventStep, events[0].Kind)
+		require.Equal(t, "build", events[0].Step)
+		require.Equal(t, "compiling 3 files", events[0].Msg)
+		require.Equal(t, serpent.EventProgress, events[1].Kind)
+		require.Equal(t, 1, events[1].Completed)
+		require.Equal(t, 2, events[1].Total)
+		require.Equal(t, serpent.EventLog, events[2].Kind)
+		require.Equal(t, "warn", events[2].Level)
+		require.Equal(t, "Careful.", events[2].Msg)
+		require.Equal(t, serpent.EventResult, events[3].Kind)
+		require.Zero(t, events[3].ExitCode)
+		require.False(t, events[3].Time.IsZero())
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		t.Parallel()
+
+		events, _, err := run(t, func(inv *serpent.Invocation) error {
+			concurrency := serpent.ConcurrencyOption(1)
+			_ = concurrency.Value.Set("1")
+			return serpent.Parallel(inv, concurrency, []string{"a", "b"}, func(context.Context, string) error {
+				return nil
+			})
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		for _, e := range events[:2] {
+			require.Equal(t, serpent.EventProgress, e.Kind)
+			require.Equal(t, 2, e.Total)
+		}
+		require.ElementsMatch(t, []int{1, 2}, []int{events[0].Completed, events[1].Completed})
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+
+		events, _, err := run(t, func(inv *serpent.Invocation) error {
+			return errors.New("boom")
+		})
+		require.Error(t, err)
+		require.Len(t, events, 1)
+		result := events[0]
+		require.Equal(t, serpent.EventResult, result.Kind)
+		require.Equal(t, "error", result.Level)
+		require.Contains(t, result.Error, "boom")
+		require.Equal(t, 1, result.ExitCode)
+	})
+}
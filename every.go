@@ -0,0 +1,117 @@
+package serpent
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// AnnotationEvery marks the option holding the interval commands are
+// repeated at, see EveryOption.
+const AnnotationEvery = "serpent.every"
+
+// EveryOption returns an --every option repeating the command at the given
+// interval until the user interrupts it, e.g. "--every 30s". EveryMiddleware
+// implements it.
+func EveryOption() Option {
+	var every time.Duration
+	return Option{
+		Name:        "every",
+		Description: "Run the command again at this interval until interrupted, e.g. 30s.",
+		Flag:        "every",
+		Value:       DurationOf(&every),
+		Annotations: Annotations{}.Mark(AnnotationEvery, "true"),
+	}
+}
+
+// Iteration describes the current run of a command repeated by
+// EveryMiddleware.
+type Iteration struct {
+	// N is the number of the run, starting at 1.
+	N int
+	// Failed is the number of previous runs that failed.
+	Failed int
+	// Interval is the time between the starts of two runs.
+	Interval time.Duration
+}
+
+// EveryMiddleware returns middleware repeating the handler at the interval
+// set by the EveryOption of the command or one of its parents, until the
+// user interrupts it with Ctrl-C. Runs are started Interval apart, or right
+// after the previous run if it took longer. Failed runs don't stop the
+// repetition, and a status line with the result of each run is written to
+// Stderr. Handlers can tell runs apart with Iteration. Add a
+// DiffOutputOption to let users highlight what changed in the output.
+//
+// Without the option, the handler runs once.
+func EveryMiddleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			every := inv.everyOption()
+			if every <= 0 {
+				return next(inv)
+			}
+
+			ctx, stop := inv.SignalNotifyContext(inv.Context(), os.Interrupt)
+			defer stop()
+			inv = inv.WithContext(ctx)
+
+			differ := newOutputDiffer(inv)
+			iter := Iteration{Interval: every}
+			for {
+				iter.N++
+				start := time.Now()
+				err := differ.run(next, inv.with(func(i *Invocation) {
+					i.iteration = iter
+				}))
+				if ctx.Err() != nil {
+					return nil
+				}
+				status := fmt.Sprintf("run %d ok in %s", iter.N, time.Since(start).Round(time.Millisecond))
+				if err != nil {
+					iter.Failed++
+					status = fmt.Sprintf("run %d %s in %s: %v", iter.N, DefaultStyles.Error.Render("failed"), time.Since(start).Round(time.Millisecond), err)
+				}
+				wait := max(time.Until(start.Add(every)), 0)
+				shown := wait.Round(time.Millisecond)
+				if wait >= time.Second {
+					shown = wait.Round(time.Second)
+				}
+				if err != nil || !inv.Quiet() {
+					_, _ = fmt.Fprintf(inv.Stderr, "\n%s %s, next run in %s, press Ctrl-C to stop.\n",
+						Timestamp(time.Now()), status, shown)
+				}
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil
+				case <-timer.C:
+				}
+			}
+		}
+	}
+}
+
+// Iteration returns the current run of a command repeated by
+// EveryMiddleware. ok is false when the command isn't repeated.
+func (inv *Invocation) Iteration() (iter Iteration, ok bool) {
+	return inv.iteration, inv.iteration.N > 0
+}
+
+// everyOption returns the value of the closest option annotated with
+// AnnotationEvery.
+func (inv *Invocation) everyOption() time.Duration {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if !opt.Annotations.IsSet(AnnotationEvery) || opt.Value == nil {
+				continue
+			}
+			if d, ok := opt.Value.(*Duration); ok {
+				return time.Duration(*d)
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package serpent_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestEveryMiddleware(t *testing.T) {
+	t.Parallel()
+
+	makeCmd := func(iters chan<- serpent.Iteration) *serpent.Command {
+		return &serpent.Command{
+			Use:        "check",
+			Options:    serpent.OptionSet{serpent.EveryOption()},
+			Middleware: serpent.EveryMiddleware(),
+			Handler: func(inv *serpent.Invocation) error {
+				iter, ok := inv.Iteration()
+				if !ok {
+					close(iters)
+					return nil
+				}
+				iters <- iter
+				if iter.N == 1 {
+					return errors.New("unhealthy")
+				}
+				return nil
+			},
+		}
+	}
+
+	t.Run("Once", func(t *testing.T) {
+		t.Parallel()
+
+		iters := make(chan serpent.Iteration)
+		inv := makeCmd(iters).Invoke()
+		fakeIO(inv)
+		require.NoError(t, inv.Run())
+		_, ok := <-iters
+		require.False(t, ok)
+	})
+
+	t.Run("Repeat", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		iters := make(chan serpent.Iteration)
+		inv := makeCmd(iters).Invoke("--every", "50ms").WithContext(ctx)
+		io := fakeIO(inv)
+		errc := make(chan error, 1)
+		go func() { errc <- inv.Run() }()
+
+		start := time.Now()
+		require.Equal(t, serpent.Iteration{N: 1, Interval: 50 * time.Millisecond}, <-iters)
+		require.Equal(t, serpent.Iteration{N: 2, Failed: 1, Interval: 50 * time.Millisecond}, <-iters)
+		require.Equal(t, serpent.Iteration{N: 3, Failed: 1, Interval: 50 * time.Millisecond}, <-iters)
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-errc)
+		require.Contains(t, io.Stderr.String(), "run 1 failed in ")
+		require.Contains(t, io.Stderr.String(), ": unhealthy, next run in ")
+		require.Contains(t, io.Stderr.String(), "run 2 ok in ")
+	})
+}
+
+func TestDiffOutputOption(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &serpent.Command{
+		Use:        "pods",
+		Options:    serpent.OptionSet{serpent.EveryOption(), serpent.DiffOutputOption()},
+		Middleware: serpent.EveryMiddleware(),
+		Handler: func(inv *serpent.Invocation) error {
+			iter, _ := inv.Iteration()
+			_, _ = fmt.Fprintf(inv.Stdout, "%d pods ready\n", iter.N)
+			if iter.N == 3 {
+				cancel()
+			}
+			return nil
+		},
+	}
+	inv := cmd.Invoke("--every", "10ms", "--differences").WithContext(ctx)
+	io := fakeIO(inv)
+	require.NoError(t, inv.Run())
+	// The output of each run is written when it ends. Highlighting is
+	// rendered as plain text when the output isn't a terminal.
+	require.Equal(t, "1 pods ready\n2 pods ready\n3 pods ready\n", io.Stdout.String())
+}
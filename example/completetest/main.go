@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"runtime"
 
 	"github.com/bketelsen/serpent"
 	"github.com/bketelsen/serpent/completion"
@@ -11,16 +12,30 @@ import (
 // installCommand returns a serpent command that helps
 // a user configure their shell to use serpent's completion.
 func installCommand() *serpent.Command {
-	var shell string
+	var (
+		shell  string
+		compat bool
+	)
 	return &serpent.Command{
 		Use:   "completion [--shell <shell>]",
 		Short: "Generate completion scripts for the given shell.",
 		Handler: func(inv *serpent.Invocation) error {
-			defaultShell, err := completion.DetectUserShell(inv.Command.Parent.Name())
+			programName := inv.Command.Parent.Name()
+			if shell == "" {
+				defaultShell, err := completion.DetectUserShell(programName)
+				if err != nil {
+					return fmt.Errorf("Could not detect user shell, please specify a shell using `--shell`")
+				}
+				shell = defaultShell.Name()
+			}
+			if compat && shell == completion.ShellBash {
+				return completion.BashCompat(runtime.GOOS, programName).WriteCompletion(inv.Stdout)
+			}
+			sh, err := completion.ShellByName(shell, programName)
 			if err != nil {
-				return fmt.Errorf("Could not detect user shell, please specify a shell using `--shell`")
+				return err
 			}
-			return defaultShell.WriteCompletion(inv.Stdout)
+			return sh.WriteCompletion(inv.Stdout)
 		},
 		Options: serpent.OptionSet{
 			{
@@ -29,6 +44,11 @@ func installCommand() *serpent.Command {
 				Description:   "The shell to generate a completion script for.",
 				Value:         completion.ShellOptions(&shell),
 			},
+			{
+				Flag:        "compat",
+				Description: "Generate a bash completion script compatible with bash 3.2, as shipped with macOS.",
+				Value:       serpent.BoolOf(&compat),
+			},
 		},
 	}
 }
@@ -56,7 +56,7 @@ func main() {
 
 	err := cmd.Invoke().WithOS().Run()
 	if err != nil {
-		fmt.Println("Error:", err.Error())
-
+		_, _ = fmt.Fprint(os.Stderr, serpent.PrettyError(err))
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,95 @@
+// Package files writes user files, such as config, history and logs, with
+// private permissions, and resolves the XDG base directories they live in.
+//
+// Files are created readable by their owner only (PrivateFile) in
+// directories only their owner can list (PrivateDir), whatever the umask, as
+// they may hold credentials or command lines with secrets.
+package files
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/natefinch/atomic"
+)
+
+const (
+	// PrivateFile is the mode of the files created by this package.
+	PrivateFile os.FileMode = 0o600
+	// PrivateDir is the mode of the directories created by this package.
+	PrivateDir os.FileMode = 0o700
+)
+
+// MkdirAll creates the directory dir and its missing parents with
+// PrivateDir permissions. Existing directories are left untouched.
+func MkdirAll(dir string) error {
+	return os.MkdirAll(dir, PrivateDir)
+}
+
+// WriteFile atomically replaces the file at path with data, creating its
+// missing parent directories: readers see either the previous or the new
+// contents, never a partial write. The file gets perm, e.g. PrivateFile,
+// regardless of the umask.
+func WriteFile(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	if err := MkdirAll(dir); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := atomic.ReplaceFile(f.Name(), path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// Create creates or truncates the file at path for writing, with
+// PrivateFile permissions, creating its missing parent directories.
+func Create(path string) (*os.File, error) {
+	return open(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+}
+
+// Append opens the file at path for appending, creating it with PrivateFile
+// permissions and its missing parent directories if needed.
+func Append(path string) (*os.File, error) {
+	return open(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+}
+
+func open(path string, flag int) (*os.File, error) {
+	if err := MkdirAll(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, flag, PrivateFile)
+	if err != nil {
+		return nil, err
+	}
+	// The umask may have masked bits of a new file, and an existing file
+	// may be more permissive, so the mode is set explicitly.
+	if err := f.Chmod(PrivateFile); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
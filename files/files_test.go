@@ -0,0 +1,99 @@
+package files_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent/files"
+)
+
+func requireMode(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, want, info.Mode().Perm(), path)
+}
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "app")
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, files.WriteFile(path, []byte("one"), files.PrivateFile))
+	require.NoError(t, files.WriteFile(path, []byte("two"), files.PrivateFile))
+
+	byt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "two", string(byt))
+	requireMode(t, path, files.PrivateFile)
+	requireMode(t, dir, files.PrivateDir)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary files are left")
+}
+
+func TestAppend(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "history.jsonl")
+	for _, line := range []string{"a\n", "b\n"} {
+		f, err := files.Append(path)
+		require.NoError(t, err)
+		_, err = f.WriteString(line)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	byt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\n", string(byt))
+	requireMode(t, path, files.PrivateFile)
+}
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("old contents"), 0o644))
+
+	f, err := files.Create(path)
+	require.NoError(t, err)
+	_, err = f.WriteString("new")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	byt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "new", string(byt))
+	requireMode(t, path, files.PrivateFile)
+}
+
+func TestXDGDirs(t *testing.T) {
+	t.Parallel()
+
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+	home := filepath.FromSlash("/home/me")
+
+	defaults := env(map[string]string{"HOME": home})
+	require.Equal(t, filepath.Join(home, ".config", "app"), files.ConfigDir(defaults, "app"))
+	require.Equal(t, filepath.Join(home, ".cache", "app"), files.CacheDir(defaults, "app"))
+	require.Equal(t, filepath.Join(home, ".local", "state", "app"), files.StateDir(defaults, "app"))
+	require.Equal(t, filepath.Join(home, ".local", "share", "app"), files.DataDir(defaults, "app"))
+
+	xdg := filepath.Join(t.TempDir(), "xdg")
+	set := env(map[string]string{"HOME": home, "XDG_CACHE_HOME": xdg})
+	require.Equal(t, filepath.Join(xdg, "app"), files.CacheDir(set, "app"))
+
+	relative := env(map[string]string{"HOME": home, "XDG_CACHE_HOME": "relative"})
+	require.Equal(t, filepath.Join(home, ".cache", "app"), files.CacheDir(relative, "app"), "relative paths are ignored")
+}
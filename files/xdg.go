@@ -0,0 +1,48 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ConfigDir returns the directory of the config files of the program named
+// app: $XDG_CONFIG_HOME/app, ~/.config/app by default. getenv looks up
+// environment variables, e.g. the Get method of serpent.Environ or
+// os.Getenv.
+func ConfigDir(getenv func(string) string, app string) string {
+	return xdgDir(getenv, "XDG_CONFIG_HOME", app, ".config")
+}
+
+// CacheDir returns the directory of the cached files of the program named
+// app: $XDG_CACHE_HOME/app, ~/.cache/app by default.
+func CacheDir(getenv func(string) string, app string) string {
+	return xdgDir(getenv, "XDG_CACHE_HOME", app, ".cache")
+}
+
+// StateDir returns the directory of the state files, such as history and
+// logs, of the program named app: $XDG_STATE_HOME/app, ~/.local/state/app by
+// default.
+func StateDir(getenv func(string) string, app string) string {
+	return xdgDir(getenv, "XDG_STATE_HOME", app, ".local", "state")
+}
+
+// DataDir returns the directory of the data files of the program named app:
+// $XDG_DATA_HOME/app, ~/.local/share/app by default.
+func DataDir(getenv func(string) string, app string) string {
+	return xdgDir(getenv, "XDG_DATA_HOME", app, ".local", "share")
+}
+
+// xdgDir returns the app directory under the base directory set by env, or
+// under the default relative to the home directory. Relative values of env
+// are ignored, as the XDG specification requires.
+func xdgDir(getenv func(string) string, env, app string, def ...string) string {
+	dir := getenv(env)
+	if !filepath.IsAbs(dir) {
+		home := getenv("HOME")
+		if home == "" {
+			home, _ = os.UserHomeDir()
+		}
+		dir = filepath.Join(append([]string{home}, def...)...)
+	}
+	return filepath.Join(dir, app)
+}
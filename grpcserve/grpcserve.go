@@ -0,0 +1,198 @@
+// Package grpcserve exposes a command tree to tools such as IDEs through the
+// Commands service defined in serpent.proto: listing commands, describing
+// their options and running them with streaming output.
+//
+// Service implements the service with plain Go types mirroring the
+// messages of serpent.proto, and Register serves it on a gRPC server:
+//
+//	s := grpc.NewServer()
+//	grpcserve.Register(s, grpcserve.New(root))
+//	err := s.Serve(lis)
+//
+// Clients use the stubs generated in the serpentv1 package, or generate
+// their own from serpent.proto.
+package grpcserve
+
+//go:generate protoc --go_out=serpentv1 --go_opt=paths=source_relative --go-grpc_out=serpentv1 --go-grpc_opt=paths=source_relative serpent.proto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bketelsen/serpent"
+)
+
+// CommandInfo describes a command.
+type CommandInfo struct {
+	// Name is the full name, e.g. "app server start".
+	Name    string
+	Use     string
+	Aliases []string
+	Short   string
+	Long    string
+}
+
+// ListCommandsResponse lists the visible commands of the tree.
+type ListCommandsResponse struct {
+	Commands []CommandInfo
+}
+
+// GetOptionsRequest selects a command by its path below the root, e.g.
+// []string{"server", "start"}.
+type GetOptionsRequest struct {
+	Path []string
+}
+
+// GetOptionsResponse holds the visible options of a command, including
+// inherited ones.
+type GetOptionsResponse struct {
+	Options []serpent.OptionSchema
+}
+
+// RunCommandRequest runs the command line Args, the arguments after the name
+// of the root command.
+type RunCommandRequest struct {
+	Args    []string
+	Environ map[string]string
+	Stdin   []byte
+}
+
+// RunCommandResponse is a chunk of output, or the final message holding the
+// exit code and error of the command.
+type RunCommandResponse struct {
+	Stdout []byte
+	Stderr []byte
+	// Done is set on the final message.
+	Done     bool
+	ExitCode int32
+	Error    string
+}
+
+// Service implements the Commands service for a command tree.
+type Service struct {
+	root *serpent.Command
+
+	// mu serializes runs, since commands keep their option values in
+	// shared variables.
+	mu sync.Mutex
+}
+
+// New returns a Service for the tree of root.
+func New(root *serpent.Command) *Service {
+	return &Service{root: root}
+}
+
+// ListCommands returns the visible commands of the tree, depth first.
+func (s *Service) ListCommands(context.Context) (*ListCommandsResponse, error) {
+	var resp ListCommandsResponse
+	var walk func(t serpent.CommandTree)
+	walk = func(t serpent.CommandTree) {
+		resp.Commands = append(resp.Commands, CommandInfo{
+			Name:    t.Name,
+			Use:     t.Use,
+			Aliases: t.Aliases,
+			Short:   t.Short,
+			Long:    t.Long,
+		})
+		for _, child := range t.Children {
+			walk(child)
+		}
+	}
+	walk(s.root.Tree())
+	return &resp, nil
+}
+
+// GetOptions returns the visible options of the command at req.Path, and of
+// its parents marked as inherited.
+func (s *Service) GetOptions(_ context.Context, req *GetOptionsRequest) (*GetOptionsResponse, error) {
+	cmd := s.root.Find(req.Path...)
+	if cmd == nil {
+		return nil, fmt.Errorf("no command %q", strings.Join(append([]string{s.root.Name()}, req.Path...), " "))
+	}
+	var resp GetOptionsResponse
+	for c, inherited := cmd, false; c != nil; c, inherited = c.Parent, true {
+		for _, opt := range c.Tree().Options {
+			opt.Inherited = inherited
+			resp.Options = append(resp.Options, opt)
+		}
+	}
+	return &resp, nil
+}
+
+// RunCommand runs the command line of req, calling send with the output as
+// it's written and finally with the exit code. Runs are serialized.
+func (s *Service) RunCommand(ctx context.Context, req *RunCommandRequest, send func(*RunCommandResponse) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := &streamWriter{send: send}
+	inv := s.root.Invoke(req.Args...).WithContext(ctx)
+	inv.Stdin = bytes.NewReader(req.Stdin)
+	inv.Stdout = out.writer(func(p []byte) *RunCommandResponse { return &RunCommandResponse{Stdout: p} })
+	inv.Stderr = out.writer(func(p []byte) *RunCommandResponse { return &RunCommandResponse{Stderr: p} })
+	keys := make([]string, 0, len(req.Environ))
+	for k := range req.Environ {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		inv.Environ.Set(k, req.Environ[k])
+	}
+
+	runErr := inv.Run()
+	if out.err != nil {
+		// The client is gone.
+		return out.err
+	}
+	done := &RunCommandResponse{Done: true, ExitCode: exitCode(runErr)}
+	if runErr != nil {
+		done.Error = runErr.Error()
+	}
+	return send(done)
+}
+
+// exitCode returns the process exit code conventionally associated with err.
+func exitCode(err error) int32 {
+	if err == nil {
+		return 0
+	}
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) {
+		return int32(coder.ExitCode())
+	}
+	return 1
+}
+
+// streamWriter sends output to the client, keeping the first error so
+// that the command's writes fail once the client is gone.
+type streamWriter struct {
+	mu   sync.Mutex
+	send func(*RunCommandResponse) error
+	err  error
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func (w *streamWriter) writer(msg func(p []byte) *RunCommandResponse) writerFunc {
+	return func(p []byte) (int, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.err != nil {
+			return 0, w.err
+		}
+		// The message may outlive the write, so p is copied.
+		if w.err = w.send(msg(bytes.Clone(p))); w.err != nil {
+			return 0, w.err
+		}
+		return len(p), nil
+	}
+}
@@ -0,0 +1,90 @@
+package grpcserve_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/grpcserve"
+)
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	var verbose bool
+	var name string
+	root := &serpent.Command{
+		Use: "app",
+		Options: serpent.OptionSet{
+			{Name: "verbose", Flag: "verbose", Value: serpent.BoolOf(&verbose)},
+		},
+		Children: []*serpent.Command{
+			{
+				Use:   "greet",
+				Short: "Greet someone.",
+				Options: serpent.OptionSet{
+					{Name: "name", Flag: "name", Env: "NAME", Value: serpent.StringOf(&name)},
+				},
+				Handler: func(inv *serpent.Invocation) error {
+					_, _ = fmt.Fprintf(inv.Stdout, "hello %s\n", name)
+					_, _ = fmt.Fprintln(inv.Stderr, "done")
+					return nil
+				},
+			},
+			{
+				Use: "fail",
+				Handler: func(inv *serpent.Invocation) error {
+					return errors.New("boom")
+				},
+			},
+			{Use: "secret", Hidden: true},
+		},
+	}
+	svc := grpcserve.New(root)
+	ctx := context.Background()
+
+	cmds, err := svc.ListCommands(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, c := range cmds.Commands {
+		names = append(names, c.Name)
+	}
+	require.Equal(t, []string{"app", "app greet", "app fail"}, names)
+
+	opts, err := svc.GetOptions(ctx, &grpcserve.GetOptionsRequest{Path: []string{"greet"}})
+	require.NoError(t, err)
+	require.Len(t, opts.Options, 2)
+	require.Equal(t, "name", opts.Options[0].Name)
+	require.False(t, opts.Options[0].Inherited)
+	require.Equal(t, "verbose", opts.Options[1].Name)
+	require.True(t, opts.Options[1].Inherited)
+
+	_, err = svc.GetOptions(ctx, &grpcserve.GetOptionsRequest{Path: []string{"nope"}})
+	require.ErrorContains(t, err, `no command "app nope"`)
+
+	run := func(req *grpcserve.RunCommandRequest) []*grpcserve.RunCommandResponse {
+		var msgs []*grpcserve.RunCommandResponse
+		require.NoError(t, svc.RunCommand(ctx, req, func(msg *grpcserve.RunCommandResponse) error {
+			msgs = append(msgs, msg)
+			return nil
+		}))
+		return msgs
+	}
+
+	msgs := run(&grpcserve.RunCommandRequest{Args: []string{"greet"}, Environ: map[string]string{"NAME": "world"}})
+	require.Equal(t, []*grpcserve.RunCommandResponse{
+		{Stdout: []byte("hello world\n")},
+		{Stderr: []byte("done\n")},
+		{Done: true},
+	}, msgs)
+
+	msgs = run(&grpcserve.RunCommandRequest{Args: []string{"fail"}})
+	last := msgs[len(msgs)-1]
+	require.True(t, last.Done)
+	require.EqualValues(t, 1, last.ExitCode)
+	require.Contains(t, last.Error, "boom")
+}
@@ -0,0 +1,215 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.27.3
+// source: serpent.proto
+
+package serpentv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Commands_ListCommands_FullMethodName = "/serpent.v1.Commands/ListCommands"
+	Commands_GetOptions_FullMethodName   = "/serpent.v1.Commands/GetOptions"
+	Commands_RunCommand_FullMethodName   = "/serpent.v1.Commands/RunCommand"
+)
+
+// CommandsClient is the client API for Commands service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Commands exposes a serpent command tree, so tools such as IDEs can list
+// its commands and options and run them.
+type CommandsClient interface {
+	// ListCommands returns every visible command of the tree.
+	ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (*ListCommandsResponse, error)
+	// GetOptions returns the options of a command, including inherited ones.
+	GetOptions(ctx context.Context, in *GetOptionsRequest, opts ...grpc.CallOption) (*GetOptionsResponse, error)
+	// RunCommand runs a command, streaming its output. The last message holds
+	// the exit code.
+	RunCommand(ctx context.Context, in *RunCommandRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunCommandResponse], error)
+}
+
+type commandsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCommandsClient(cc grpc.ClientConnInterface) CommandsClient {
+	return &commandsClient{cc}
+}
+
+func (c *commandsClient) ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (*ListCommandsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCommandsResponse)
+	err := c.cc.Invoke(ctx, Commands_ListCommands_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) GetOptions(ctx context.Context, in *GetOptionsRequest, opts ...grpc.CallOption) (*GetOptionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOptionsResponse)
+	err := c.cc.Invoke(ctx, Commands_GetOptions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) RunCommand(ctx context.Context, in *RunCommandRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunCommandResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Commands_ServiceDesc.Streams[0], Commands_RunCommand_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunCommandRequest, RunCommandResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Commands_RunCommandClient = grpc.ServerStreamingClient[RunCommandResponse]
+
+// CommandsServer is the server API for Commands service.
+// All implementations must embed UnimplementedCommandsServer
+// for forward compatibility.
+//
+// Commands exposes a serpent command tree, so tools such as IDEs can list
+// its commands and options and run them.
+type CommandsServer interface {
+	// ListCommands returns every visible command of the tree.
+	ListCommands(context.Context, *ListCommandsRequest) (*ListCommandsResponse, error)
+	// GetOptions returns the options of a command, including inherited ones.
+	GetOptions(context.Context, *GetOptionsRequest) (*GetOptionsResponse, error)
+	// RunCommand runs a command, streaming its output. The last message holds
+	// the exit code.
+	RunCommand(*RunCommandRequest, grpc.ServerStreamingServer[RunCommandResponse]) error
+	mustEmbedUnimplementedCommandsServer()
+}
+
+// UnimplementedCommandsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCommandsServer struct{}
+
+func (UnimplementedCommandsServer) ListCommands(context.Context, *ListCommandsRequest) (*ListCommandsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCommands not implemented")
+}
+func (UnimplementedCommandsServer) GetOptions(context.Context, *GetOptionsRequest) (*GetOptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOptions not implemented")
+}
+func (UnimplementedCommandsServer) RunCommand(*RunCommandRequest, grpc.ServerStreamingServer[RunCommandResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method RunCommand not implemented")
+}
+func (UnimplementedCommandsServer) mustEmbedUnimplementedCommandsServer() {}
+func (UnimplementedCommandsServer) testEmbeddedByValue()                  {}
+
+// UnsafeCommandsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CommandsServer will
+// result in compilation errors.
+type UnsafeCommandsServer interface {
+	mustEmbedUnimplementedCommandsServer()
+}
+
+func RegisterCommandsServer(s grpc.ServiceRegistrar, srv CommandsServer) {
+	// If the following call pancis, it indicates UnimplementedCommandsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Commands_ServiceDesc, srv)
+}
+
+func _Commands_ListCommands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCommandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).ListCommands(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_ListCommands_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).ListCommands(ctx, req.(*ListCommandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_GetOptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).GetOptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_GetOptions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).GetOptions(ctx, req.(*GetOptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_RunCommand_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunCommandRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CommandsServer).RunCommand(m, &grpc.GenericServerStream[RunCommandRequest, RunCommandResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Commands_RunCommandServer = grpc.ServerStreamingServer[RunCommandResponse]
+
+// Commands_ServiceDesc is the grpc.ServiceDesc for Commands service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Commands_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "serpent.v1.Commands",
+	HandlerType: (*CommandsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListCommands",
+			Handler:    _Commands_ListCommands_Handler,
+		},
+		{
+			MethodName: "GetOptions",
+			Handler:    _Commands_GetOptions_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RunCommand",
+			Handler:       _Commands_RunCommand_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "serpent.proto",
+}
@@ -0,0 +1,84 @@
+package grpcserve
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bketelsen/serpent/grpcserve/serpentv1"
+)
+
+// Register registers svc as the Commands service of s, e.g. a *grpc.Server,
+// converting between the messages of serpent.proto and the types of this
+// package.
+func Register(s grpc.ServiceRegistrar, svc *Service) {
+	serpentv1.RegisterCommandsServer(s, &server{svc: svc})
+}
+
+// server adapts a Service to the generated CommandsServer interface.
+type server struct {
+	serpentv1.UnimplementedCommandsServer
+	svc *Service
+}
+
+func (s *server) ListCommands(ctx context.Context, _ *serpentv1.ListCommandsRequest) (*serpentv1.ListCommandsResponse, error) {
+	resp, err := s.svc.ListCommands(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := &serpentv1.ListCommandsResponse{}
+	for _, c := range resp.Commands {
+		out.Commands = append(out.Commands, &serpentv1.CommandInfo{
+			Name:    c.Name,
+			Use:     c.Use,
+			Aliases: c.Aliases,
+			Short:   c.Short,
+			Long:    c.Long,
+		})
+	}
+	return out, nil
+}
+
+func (s *server) GetOptions(ctx context.Context, req *serpentv1.GetOptionsRequest) (*serpentv1.GetOptionsResponse, error) {
+	resp, err := s.svc.GetOptions(ctx, &GetOptionsRequest{Path: req.GetPath()})
+	if err != nil {
+		// The only failure is an unknown command.
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	out := &serpentv1.GetOptionsResponse{}
+	for _, opt := range resp.Options {
+		out.Options = append(out.Options, &serpentv1.OptionInfo{
+			Name:          opt.Name,
+			Description:   opt.Description,
+			Flag:          opt.Flag,
+			FlagShorthand: opt.FlagShorthand,
+			Env:           opt.Env,
+			Yaml:          opt.YAML,
+			Type:          opt.Type,
+			Choices:       opt.Choices,
+			Default:       opt.Default,
+			Required:      opt.Required,
+			Deprecated:    opt.Deprecated,
+			Inherited:     opt.Inherited,
+		})
+	}
+	return out, nil
+}
+
+func (s *server) RunCommand(req *serpentv1.RunCommandRequest, stream serpentv1.Commands_RunCommandServer) error {
+	return s.svc.RunCommand(stream.Context(), &RunCommandRequest{
+		Args:    req.GetArgs(),
+		Environ: req.GetEnviron(),
+		Stdin:   req.GetStdin(),
+	}, func(msg *RunCommandResponse) error {
+		return stream.Send(&serpentv1.RunCommandResponse{
+			Stdout:   msg.Stdout,
+			Stderr:   msg.Stderr,
+			Done:     msg.Done,
+			ExitCode: msg.ExitCode,
+			Error:    msg.Error,
+		})
+	})
+}
@@ -0,0 +1,110 @@
+package grpcserve_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/grpcserve"
+	"github.com/bketelsen/serpent/grpcserve/serpentv1"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	var name string
+	root := &serpent.Command{
+		Use: "app",
+		Children: []*serpent.Command{
+			{
+				Use: "greet",
+				Options: serpent.OptionSet{
+					{Name: "name", Flag: "name", Env: "NAME", Value: serpent.StringOf(&name)},
+				},
+				Handler: func(inv *serpent.Invocation) error {
+					in, err := io.ReadAll(inv.Stdin)
+					if err != nil {
+						return err
+					}
+					_, _ = fmt.Fprintf(inv.Stdout, "hello %s%s\n", name, in)
+					return nil
+				},
+			},
+			{
+				Use: "fail",
+				Handler: func(inv *serpent.Invocation) error {
+					return errors.New("boom")
+				},
+			},
+		},
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	grpcserve.Register(srv, grpcserve.New(root))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := serpentv1.NewCommandsClient(conn)
+	ctx := context.Background()
+
+	cmds, err := client.ListCommands(ctx, &serpentv1.ListCommandsRequest{})
+	require.NoError(t, err)
+	var names []string
+	for _, c := range cmds.GetCommands() {
+		names = append(names, c.GetName())
+	}
+	require.Equal(t, []string{"app", "app greet", "app fail"}, names)
+
+	opts, err := client.GetOptions(ctx, &serpentv1.GetOptionsRequest{Path: []string{"greet"}})
+	require.NoError(t, err)
+	require.Len(t, opts.GetOptions(), 1)
+	require.Equal(t, "NAME", opts.GetOptions()[0].GetEnv())
+
+	_, err = client.GetOptions(ctx, &serpentv1.GetOptionsRequest{Path: []string{"nope"}})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	run := func(req *serpentv1.RunCommandRequest) (stdout string, done *serpentv1.RunCommandResponse) {
+		stream, err := client.RunCommand(ctx, req)
+		require.NoError(t, err)
+		for {
+			msg, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return stdout, done
+			}
+			require.NoError(t, err)
+			stdout += string(msg.GetStdout())
+			if msg.GetDone() {
+				done = msg
+			}
+		}
+	}
+
+	stdout, done := run(&serpentv1.RunCommandRequest{
+		Args:    []string{"greet"},
+		Environ: map[string]string{"NAME": "world"},
+		Stdin:   []byte("!"),
+	})
+	require.Equal(t, "hello world!\n", stdout)
+	require.NotNil(t, done)
+	require.Zero(t, done.GetExitCode())
+
+	_, done = run(&serpentv1.RunCommandRequest{Args: []string{"fail"}})
+	require.NotNil(t, done)
+	require.EqualValues(t, 1, done.GetExitCode())
+	require.Contains(t, done.GetError(), "boom")
+}
@@ -5,19 +5,24 @@ import (
 	_ "embed"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"text/tabwriter"
 	"text/template"
 
 	"github.com/mitchellh/go-wordwrap"
 	"github.com/muesli/termenv"
-	"golang.org/x/term"
 
 	"github.com/coder/pretty"
+
+	"github.com/bketelsen/serpent/internal/hyperlink"
+	"github.com/bketelsen/serpent/internal/markdown"
 )
 
 //go:embed help.tpl
@@ -29,13 +34,8 @@ type optionGroup struct {
 	Options     OptionSet
 }
 
-func ttyWidth() int {
-	width, _, err := term.GetSize(0)
-	if err != nil {
-		return 80
-	}
-	return width
-}
+// keywordColor is the color of keywords such as flags in help.
+const keywordColor = "#04A777"
 
 // wrapTTY wraps a string to the width of the terminal, or 80 no terminal
 // is detected.
@@ -46,10 +46,17 @@ func wrapTTY(s string) string {
 var (
 	helpColorProfile termenv.Profile
 	helpColorOnce    sync.Once
+
+	// consoleColorDisabled is set when the Windows console can't render
+	// escape sequences, see enableConsole.
+	consoleColorDisabled atomic.Bool
 )
 
-// Color returns a color for the given string.
-func helpColor(s string) termenv.Color {
+// helpProfile returns the color profile used for help output.
+func helpProfile() termenv.Profile {
+	if consoleColorDisabled.Load() {
+		return termenv.Ascii
+	}
 	helpColorOnce.Do(func() {
 		helpColorProfile = termenv.NewOutput(os.Stdout).ColorProfile()
 		if flag.Lookup("test.v") != nil {
@@ -58,7 +65,18 @@ func helpColor(s string) termenv.Color {
 			helpColorProfile = termenv.Ascii
 		}
 	})
-	return helpColorProfile.Color(s)
+	return helpColorProfile
+}
+
+// helpHyperlinks reports whether help output should contain OSC 8
+// hyperlinks.
+func helpHyperlinks() bool {
+	return hyperlink.Enabled(helpProfile() != termenv.Ascii, os.Getenv)
+}
+
+// Color returns a color for the given string.
+func helpColor(s string) termenv.Color {
+	return helpProfile().Color(s)
 }
 
 // prettyHeader formats a header string with consistent styling.
@@ -74,7 +92,7 @@ func prettyHeader(s string) string {
 var defaultHelpTemplate = func() *template.Template {
 	var (
 		optionFg = pretty.FgColor(
-			helpColor("#04A777"),
+			helpColor(keywordColor),
 		)
 	)
 	return template.Must(
@@ -92,16 +110,6 @@ var defaultHelpTemplate = func() *template.Template {
 					return txt.String()
 				},
 				"prettyHeader": prettyHeader,
-				"typeHelper": func(opt *Option) string {
-					switch v := opt.Value.(type) {
-					case *Enum:
-						return strings.Join(v.Choices, "|")
-					case *EnumArray:
-						return fmt.Sprintf("[%s]", strings.Join(v.Choices, "|"))
-					default:
-						return v.Type()
-					}
-				},
 				"joinStrings": func(s []string) string {
 					return strings.Join(s, ", ")
 				},
@@ -126,24 +134,46 @@ var defaultHelpTemplate = func() *template.Template {
 					}
 					return sb.String()
 				},
+				"long": func(cmd *Command) string {
+					if !cmd.Markdown {
+						return cmd.Long
+					}
+					opts := markdown.Options{
+						Styled: helpProfile() != termenv.Ascii,
+					}
+					if helpHyperlinks() {
+						opts.Link = hyperlink.Format
+					}
+					return markdown.Render(cmd.Long, opts)
+				},
+				"link": func(text, url string) string {
+					if helpHyperlinks() {
+						return hyperlink.Format(text, url)
+					}
+					return text
+				},
+				"contactInfo": func(cmd *Command) *ContactInfo {
+					return cmd.contactInfo()
+				},
 				"rootCommandName": func(cmd *Command) string {
 					return strings.Split(cmd.FullName(), " ")[0]
 				},
 				"formatSubcommand": func(cmd *Command) string {
 					// Minimize padding by finding the longest neighboring name.
-					maxNameLength := len(cmd.Name())
+					maxNameLength := len(subcommandLabel(cmd))
 					if parent := cmd.Parent; parent != nil {
 						for _, c := range parent.Children {
-							if len(c.Name()) > maxNameLength {
-								maxNameLength = len(c.Name())
+							if label := subcommandLabel(c); !c.Hidden && len(label) > maxNameLength {
+								maxNameLength = len(label)
 							}
 						}
 					}
 
 					var sb strings.Builder
+					label := subcommandLabel(cmd)
 					_, _ = fmt.Fprintf(
 						&sb, "%s%s%s",
-						strings.Repeat(" ", 4), cmd.Name(), strings.Repeat(" ", maxNameLength-len(cmd.Name())+4),
+						strings.Repeat(" ", 4), label, strings.Repeat(" ", maxNameLength-len(label)+4),
 					)
 
 					// This is the point at which indentation begins if there's a
@@ -164,43 +194,13 @@ var defaultHelpTemplate = func() *template.Template {
 
 					return sb.String()
 				},
-				"envName": func(opt Option) string {
-					if opt.Env == "" {
-						return ""
-					}
-					return opt.Env
-				},
-				"flagName": func(opt Option) string {
-					return opt.Flag
-				},
-
-				"isDeprecated": func(opt Option) bool {
-					return len(opt.UseInstead) > 0
-				},
-				"useInstead": func(opt Option) string {
-					var sb strings.Builder
-					for i, s := range opt.UseInstead {
-						if i > 0 {
-							if i == len(opt.UseInstead)-1 {
-								_, _ = sb.WriteString(" and ")
-							} else {
-								_, _ = sb.WriteString(", ")
-							}
-						}
-						if s.Flag != "" {
-							_, _ = sb.WriteString("--")
-							_, _ = sb.WriteString(s.Flag)
-						} else if s.FlagShorthand != "" {
-							_, _ = sb.WriteString("-")
-							_, _ = sb.WriteString(s.FlagShorthand)
-						} else if s.Env != "" {
-							_, _ = sb.WriteString("$")
-							_, _ = sb.WriteString(s.Env)
-						} else {
-							_, _ = sb.WriteString(s.Name)
-						}
-					}
-					return sb.String()
+				// Overridden by DefaultHelpFn with the width of the invocation.
+				"formatOptions": func(opts OptionSet, groups []optionGroup) string {
+					return formatOptions(opts, groups, ttyWidth(), func(s string) string {
+						txt := pretty.String(s)
+						optionFg.Format(txt)
+						return txt.String()
+					})
 				},
 				"formatGroupDescription": func(s string) string {
 					s = strings.ReplaceAll(s, "\n", "")
@@ -213,60 +213,25 @@ var defaultHelpTemplate = func() *template.Template {
 						return !c.Hidden
 					})
 				},
+				// Overridden by DefaultHelpFn to show gated groups enabled in
+				// the invocation's environment.
 				"optionGroups": func(cmd *Command) []optionGroup {
-					groups := []optionGroup{{
-						// Default group.
-						Name:        "",
-						Description: "",
-					}}
-
-					// Sort options lexicographically.
-					sort.Slice(cmd.Options, func(i, j int) bool {
-						return cmd.Options[i].Name < cmd.Options[j].Name
-					})
-
-				optionLoop:
-					for _, opt := range cmd.Options {
-						if opt.Hidden {
-							continue
-						}
-
-						if len(opt.Group.Ancestry()) == 0 {
-							// Just add option to default group.
-							groups[0].Options = append(groups[0].Options, opt)
-							continue
-						}
-
-						groupName := opt.Group.FullName()
-
-						for i, foundGroup := range groups {
-							if foundGroup.Name != groupName {
-								continue
-							}
-							groups[i].Options = append(groups[i].Options, opt)
-							continue optionLoop
-						}
-
-						groups = append(groups, optionGroup{
-							Name:        groupName,
-							Description: opt.Group.Description,
-							Options:     OptionSet{opt},
-						})
-					}
-					sort.Slice(groups, func(i, j int) bool {
-						// Sort groups lexicographically.
-						return groups[i].Name < groups[j].Name
-					})
-
-					return filterSlice(groups, func(g optionGroup) bool {
-						return len(g.Options) > 0
-					})
+					return optionGroups(cmd, nil)
 				},
 			},
 		).Parse(helpTemplateRaw),
 	)
 }()
 
+// subcommandLabel returns the name of cmd as listed in the help of its
+// parent, followed by its aliases, e.g. "server (srv)".
+func subcommandLabel(cmd *Command) string {
+	if len(cmd.Aliases) == 0 {
+		return cmd.Name()
+	}
+	return cmd.Name() + " (" + strings.Join(cmd.Aliases, ", ") + ")"
+}
+
 func filterSlice[T any](s []T, f func(T) bool) []T {
 	var r []T
 	for _, v := range s {
@@ -331,19 +296,193 @@ func (e *UnknownSubcommandError) Error() string {
 	return fmt.Sprintf("unknown subcommand %q", strings.Join(e.Args, " "))
 }
 
+// optionGroups returns the options of cmd shown in help, by group. Options
+// in gated groups are only shown when the gate is open in environ.
+func optionGroups(cmd *Command, environ Environ) []optionGroup {
+	groups := []optionGroup{{
+		// Default group.
+		Name:        "",
+		Description: "",
+	}}
+
+	// Sort options lexicographically.
+	sort.Slice(cmd.Options, func(i, j int) bool {
+		return cmd.Options[i].Name < cmd.Options[j].Name
+	})
+
+optionLoop:
+	for _, opt := range cmd.Options {
+		if opt.Hidden || opt.Group.hidden(environ) {
+			continue
+		}
+
+		if len(opt.Group.Ancestry()) == 0 {
+			// Just add option to default group.
+			groups[0].Options = append(groups[0].Options, opt)
+			continue
+		}
+
+		groupName := opt.Group.FullName()
+
+		for i, foundGroup := range groups {
+			if foundGroup.Name != groupName {
+				continue
+			}
+			groups[i].Options = append(groups[i].Options, opt)
+			continue optionLoop
+		}
+
+		groups = append(groups, optionGroup{
+			Name:        groupName,
+			Description: opt.Group.Description,
+			Options:     OptionSet{opt},
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		// Sort groups lexicographically.
+		return groups[i].Name < groups[j].Name
+	})
+
+	return filterSlice(groups, func(g optionGroup) bool {
+		return len(g.Options) > 0
+	})
+}
+
+// HelpFormatEnv is the environment variable selecting the format of the
+// help output, see HelpFormatOption.
+const HelpFormatEnv = "HELP_FORMAT"
+
+// Formats of the help output.
+const (
+	// HelpFormatANSI styles help with colors and hyperlinks if the terminal
+	// supports them. It's the default.
+	HelpFormatANSI = "ansi"
+	// HelpFormatPlain never styles help.
+	HelpFormatPlain = "plain"
+	// HelpFormatMarkdown writes help as a Markdown document, e.g. for docs.
+	HelpFormatMarkdown = "markdown"
+)
+
+// HelpFormatOption returns a --help-format option, usually added to the root
+// command, selecting the format DefaultHelpFn writes help in. The format can
+// also be set with $HELP_FORMAT.
+func HelpFormatOption() Option {
+	var format string
+	return Option{
+		Name:        "help-format",
+		Description: "Format of the help output.",
+		Flag:        "help-format",
+		Env:         HelpFormatEnv,
+		Default:     HelpFormatANSI,
+		Value:       EnumOf(&format, HelpFormatANSI, HelpFormatPlain, HelpFormatMarkdown),
+	}
+}
+
+// helpFormat returns the help format of the invocation. The arguments are
+// searched for --help-format since pflag stops parsing at --help.
+func (inv *Invocation) helpFormat() (string, error) {
+	format := inv.Environ.Get(HelpFormatEnv)
+	for i, arg := range inv.rawArgs {
+		if arg == "--" {
+			break
+		}
+		if v, ok := strings.CutPrefix(arg, "--help-format="); ok {
+			format = v
+		} else if arg == "--help-format" && i+1 < len(inv.rawArgs) {
+			format = inv.rawArgs[i+1]
+		}
+	}
+	switch format {
+	case "":
+		return HelpFormatANSI, nil
+	case HelpFormatANSI, HelpFormatPlain, HelpFormatMarkdown:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown help format %q, must be one of %s, %s or %s",
+			format, HelpFormatANSI, HelpFormatPlain, HelpFormatMarkdown)
+	}
+}
+
+// helpWidth returns the width help is wrapped at: $COLUMNS in the
+// invocation's environment if set, else the width of the terminal.
+func (inv *Invocation) helpWidth() int {
+	if n, err := strconv.Atoi(inv.Environ.Get("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return ttyWidth()
+}
+
+// plainHelpFuncs override the template functions styling help for
+// HelpFormatPlain.
+var plainHelpFuncs = template.FuncMap{
+	"keyword": func(s string) string {
+		return s
+	},
+	"prettyHeader": func(s string) string {
+		return strings.ToUpper(s) + ":"
+	},
+	"long": func(cmd *Command) string {
+		if !cmd.Markdown {
+			return cmd.Long
+		}
+		return markdown.Render(cmd.Long, markdown.Options{})
+	},
+	"link": func(text, _ string) string {
+		return text
+	},
+}
+
 // DefaultHelpFn returns a function that generates usage (help)
 // output for a given command.
 func DefaultHelpFn() HandlerFunc {
 	return func(inv *Invocation) error {
+		format, err := inv.helpFormat()
+		if err != nil {
+			return err
+		}
+
 		// We use stdout for help and not stderr since there's no straightforward
-		// way to distinguish between a user error and a help request.
-		//
+		// way to distinguish between a user error and a help request, unless
+		// the command's stdout is reserved for scripts.
+		w := inv.helpWriter()
+		if format == HelpFormatMarkdown {
+			_, err = io.WriteString(w, markdownHelp(inv.Command, inv.Environ))
+			if err != nil {
+				return err
+			}
+			return inv.unknownSubcommand()
+		}
+
 		// We buffer writes to stdout because the newlineLimiter writes one
 		// rune at a time.
-		outBuf := bufio.NewWriter(inv.Stdout)
+		outBuf := bufio.NewWriter(w)
 		out := newlineLimiter{w: outBuf, limit: 2}
 		tabwriter := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
-		err := defaultHelpTemplate.Execute(tabwriter, inv.Command)
+		tpl, err := defaultHelpTemplate.Clone()
+		if err != nil {
+			return fmt.Errorf("clone template: %w", err)
+		}
+		tpl.Funcs(template.FuncMap{
+			"optionGroups": func(cmd *Command) []optionGroup {
+				return optionGroups(cmd, inv.Environ)
+			},
+		})
+		keyword := func(s string) string {
+			txt := pretty.String(s)
+			pretty.FgColor(helpColor(keywordColor)).Format(txt)
+			return txt.String()
+		}
+		if format == HelpFormatPlain {
+			tpl.Funcs(plainHelpFuncs)
+			keyword = func(s string) string { return s }
+		}
+		width := inv.helpWidth()
+		tpl.Funcs(template.FuncMap{
+			"formatOptions": func(opts OptionSet, groups []optionGroup) string {
+				return formatOptions(opts, groups, width, keyword)
+			},
+		})
+		err = tpl.Execute(tabwriter, inv.Command)
 		if err != nil {
 			return fmt.Errorf("execute template: %w", err)
 		}
@@ -355,14 +494,359 @@ func DefaultHelpFn() HandlerFunc {
 		if err != nil {
 			return err
 		}
-		if len(inv.Args) > 0 && !usageWantsArgRe.MatchString(inv.Command.Use) {
-			_, _ = fmt.Fprintf(inv.Stderr, "---\nerror: unknown subcommand %q\n", inv.Args[0])
+		return inv.unknownSubcommand()
+	}
+}
+
+// helpWriter returns the stream help is written to: Stderr if the command
+// or one of its parents sets HelpToStderr, else Stdout.
+func (inv *Invocation) helpWriter() io.Writer {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		if cmd.HelpToStderr {
+			return inv.Stderr
+		}
+	}
+	return inv.Stdout
+}
+
+// unknownSubcommand returns an UnknownSubcommandError if arguments are left
+// after help was shown, and reports it unless the command takes arguments.
+func (inv *Invocation) unknownSubcommand() error {
+	if len(inv.Args) > 0 && !usageWantsArgRe.MatchString(inv.Command.Synopsis()) {
+		inv.message(Message{
+			Level:  MessageError,
+			Kind:   MessageKindUnknownCommand,
+			Header: fmt.Sprintf("unknown subcommand %q", inv.Args[0]),
+			Text:   fmt.Sprintf("---\nerror: unknown subcommand %q\n", inv.Args[0]),
+		})
+	}
+	if len(inv.Args) > 0 {
+		// Return an error so that exit status is non-zero when
+		// a subcommand is not found.
+		return &UnknownSubcommandError{Args: inv.Args}
+	}
+	return nil
+}
+
+// markdownHelp returns the help of cmd as a Markdown document.
+func markdownHelp(cmd *Command, environ Environ) string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "# %s\n\n", cmd.FullName())
+	if cmd.Short != "" {
+		_, _ = fmt.Fprintf(&sb, "%s\n\n", cmd.Short)
+	}
+	_, _ = fmt.Fprintf(&sb, "## Usage\n\n```console\n%s\n```\n\n", cmd.FullUsage())
+	if cmd.Deprecated != "" {
+		_, _ = fmt.Fprintf(&sb, "**Deprecated:** %s\n\n", cmd.Deprecated)
+	}
+	if replacement, err := cmd.replacement(); replacement != nil && err == nil {
+		_, _ = fmt.Fprintf(&sb, "Runs `%s` instead.\n\n", replacement.FullName())
+	}
+	if len(cmd.Aliases) > 0 {
+		_, _ = fmt.Fprintf(&sb, "Aliases: %s\n\n", strings.Join(cmd.Aliases, ", "))
+	}
+	if cmd.Long != "" {
+		_, _ = fmt.Fprintf(&sb, "%s\n\n", strings.TrimSpace(cmd.Long))
+	}
+
+	if children := filterSlice(cmd.Children, func(c *Command) bool { return !c.Hidden }); len(children) > 0 {
+		_, _ = sb.WriteString("## Subcommands\n\n| Name | Purpose |\n| ---- | ------- |\n")
+		for _, child := range children {
+			name := "`" + child.Name() + "`"
+			for _, alias := range child.Aliases {
+				name += ", `" + alias + "`"
+			}
+			_, _ = fmt.Fprintf(&sb, "| %s | %s |\n", name, child.Short)
+		}
+		_, _ = sb.WriteString("\n")
+	}
+
+	for _, group := range optionGroups(cmd, environ) {
+		heading := "Options"
+		if group.Name != "" {
+			heading = group.Name + " Options"
+		}
+		_, _ = fmt.Fprintf(&sb, "## %s\n\n", heading)
+		if group.Description != "" {
+			_, _ = fmt.Fprintf(&sb, "%s\n\n", strings.TrimSpace(group.Description))
+		}
+		for _, opt := range group.Options {
+			_, _ = fmt.Fprintf(&sb, "### %s\n\n", markdownOptionHeading(opt))
+			_, _ = sb.WriteString("|             |     |\n| ----------- | --- |\n")
+			// Pipes in enum types would end the table cell.
+			_, _ = fmt.Fprintf(&sb, "| Type        | `%s` |\n", strings.ReplaceAll(typeHelp(&opt), "|", "\\|"))
+			if opt.Env != "" {
+				_, _ = fmt.Fprintf(&sb, "| Environment | `$%s` |\n", opt.Env)
+			}
+			if def := opt.defaultText(); def != "" {
+				_, _ = fmt.Fprintf(&sb, "| Default     | `%s` |\n", def)
+			}
+			if opt.Description != "" {
+				_, _ = fmt.Fprintf(&sb, "\n%s\n", opt.Description)
+			}
+			if s := countHelp(opt); s != "" {
+				_, _ = fmt.Fprintf(&sb, "\n%s\n", s)
+			}
+			if len(opt.UseInstead) > 0 {
+				var instead []string
+				for _, s := range opt.UseInstead {
+					instead = append(instead, "`"+markdownOptionHeading(s)+"`")
+				}
+				_, _ = fmt.Fprintf(&sb, "\n**Deprecated:** use %s instead.\n", strings.Join(instead, " and "))
+			}
+			_, _ = sb.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// markdownOptionHeading returns the flags of opt, e.g. "--verbose, -v", or
+// its environment variable or name if it has no flag.
+func markdownOptionHeading(opt Option) string {
+	switch {
+	case opt.Flag != "":
+		heading := opt.flagHelp()
+		if opt.FlagShorthand != "" {
+			heading += ", -" + opt.FlagShorthand
+		}
+		return heading
+	case opt.Env != "":
+		return "$" + opt.Env
+	default:
+		return opt.Name
+	}
+}
+
+// typeHelp returns the type of opt as shown in help.
+func typeHelp(opt *Option) string {
+	switch v := opt.Value.(type) {
+	case *Enum:
+		return strings.Join(v.Choices, "|")
+	case *EnumArray:
+		return fmt.Sprintf("[%s]", strings.Join(v.Choices, "|"))
+	case *EnumSet:
+		return fmt.Sprintf("[%s|%s|-...]", strings.Join(v.Choices, "|"), EnumSetAll)
+	default:
+		return v.Type()
+	}
+}
+
+const (
+	// optionIndent is the indentation of options in help.
+	optionIndent = 2
+	// optionDescriptionIndent is the indentation of option descriptions in
+	// the stacked layout.
+	optionDescriptionIndent = 10
+	// minOptionDescriptionWidth is the narrowest description column of the
+	// column layout. At narrower widths, options are stacked.
+	minOptionDescriptionWidth = 40
+)
+
+// optionHelp holds the cells of an option in help, unstyled so that their
+// widths can be measured.
+type optionHelp struct {
+	flags, typ, env, def string
+	// lines are the description, deprecation notice and choices.
+	lines []string
+}
+
+func newOptionHelp(opt Option) optionHelp {
+	h := optionHelp{typ: typeHelp(&opt)}
+	switch {
+	case opt.Flag != "" && opt.FlagShorthand != "":
+		h.flags = "-" + opt.FlagShorthand + ", " + opt.flagHelp()
+	case opt.Flag != "":
+		h.flags = "    " + opt.flagHelp()
+	}
+	if opt.Env != "" {
+		h.env = "$" + opt.Env
+	}
+	if def := opt.defaultText(); def != "" {
+		h.def = "(default: " + def + ")"
+	}
+	if opt.Description != "" {
+		h.lines = append(h.lines, opt.Description)
+	}
+	if s := countHelp(opt); s != "" {
+		h.lines = append(h.lines, s)
+	}
+	if len(opt.UseInstead) > 0 {
+		h.lines = append(h.lines, fmt.Sprintf("DEPRECATED: Use %s instead.", useInstead(opt)))
+	}
+	for _, d := range enumDetails(opt.Value) {
+		if d.Description != "" && !d.Hidden && d.Deprecated == "" {
+			h.lines = append(h.lines, "  "+d.Value+": "+d.Description)
+		}
+	}
+	return h
+}
+
+// formatOptions renders opts, one of groups, for help at width. Flags,
+// types, environment variables, defaults and descriptions are aligned in
+// columns across all groups if the descriptions get at least
+// minOptionDescriptionWidth, and otherwise each option is stacked above its
+// indented description. keyword styles flags and environment variables.
+func formatOptions(opts OptionSet, groups []optionGroup, width int, keyword func(string) string) string {
+	helps := make([]optionHelp, len(opts))
+	for i, opt := range opts {
+		helps[i] = newOptionHelp(opt)
+	}
+
+	var widths [4]int
+	for _, group := range groups {
+		for _, opt := range group.Options {
+			for i, cell := range newOptionHelp(opt).cells() {
+				widths[i] = max(widths[i], len(cell))
+			}
+		}
+	}
+	descStart := optionIndent
+	for _, w := range widths {
+		if w > 0 {
+			descStart += w + 2
+		}
+	}
+
+	var sb strings.Builder
+	if width-descStart < minOptionDescriptionWidth {
+		for _, h := range helps {
+			_, _ = sb.WriteString("\n")
+			_, _ = sb.WriteString(strings.Repeat(" ", optionIndent))
+			_, _ = sb.WriteString(h.stackedHeader(keyword))
+			_, _ = sb.WriteString("\n")
+			for _, line := range h.lines {
+				_, _ = sb.WriteString(indentLines(line, optionDescriptionIndent, width))
+			}
+		}
+		return sb.String()
+	}
+
+	_, _ = sb.WriteString("\n")
+	for _, h := range helps {
+		var row strings.Builder
+		_, _ = row.WriteString(strings.Repeat(" ", optionIndent))
+		for i, cell := range h.cells() {
+			if widths[i] == 0 {
+				continue
+			}
+			styled := cell
+			if i == 0 || i == 2 {
+				styled = styleFlags(cell, keyword)
+			}
+			_, _ = row.WriteString(styled)
+			_, _ = row.WriteString(strings.Repeat(" ", widths[i]-len(cell)+2))
+		}
+		var desc []string
+		for _, line := range h.lines {
+			desc = append(desc, strings.Split(wordwrap.WrapString(line, uint(width-descStart)), "\n")...)
+		}
+		if len(desc) == 0 {
+			_, _ = sb.WriteString(strings.TrimRight(row.String(), " "))
+			_, _ = sb.WriteString("\n")
+			continue
+		}
+		for i, line := range desc {
+			if i == 0 {
+				_, _ = sb.WriteString(row.String())
+			} else {
+				_, _ = sb.WriteString(strings.Repeat(" ", descStart))
+			}
+			_, _ = sb.WriteString(line)
+			_, _ = sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// cells returns the flags, type, environment variable and default of the
+// option.
+func (h optionHelp) cells() [4]string {
+	return [4]string{h.flags, h.typ, h.env, h.def}
+}
+
+// stackedHeader returns the line above the description in the stacked
+// layout, e.g. "-f, --format json|table, $FORMAT (default: table)".
+func (h optionHelp) stackedHeader(keyword func(string) string) string {
+	var sb strings.Builder
+	if h.flags != "" {
+		_, _ = sb.WriteString(styleFlags(h.flags, keyword))
+	} else {
+		// Line options without a flag up with the long flags.
+		_, _ = sb.WriteString(strings.Repeat(" ", 4))
+	}
+	if h.env != "" && h.flags == "" {
+		_, _ = sb.WriteString(keyword(h.env))
+		if h.typ != "" {
+			_, _ = sb.WriteString(" " + h.typ)
+		}
+	} else {
+		if h.typ != "" {
+			_, _ = sb.WriteString(" " + h.typ)
+		}
+		if h.env != "" {
+			_, _ = sb.WriteString(", " + keyword(h.env))
+		}
+	}
+	if h.def != "" {
+		_, _ = sb.WriteString(" " + h.def)
+	}
+	return sb.String()
+}
+
+// styleFlags styles the words of s, keeping spaces and commas unstyled.
+func styleFlags(s string, keyword func(string) string) string {
+	var sb strings.Builder
+	for i, word := range strings.Split(s, " ") {
+		if i > 0 {
+			_, _ = sb.WriteString(" ")
+		}
+		word, comma := strings.CutSuffix(word, ",")
+		if word != "" {
+			_, _ = sb.WriteString(keyword(word))
+		}
+		if comma {
+			_, _ = sb.WriteString(",")
+		}
+	}
+	return sb.String()
+}
+
+// indentLines wraps s to width and indents every line by spaces.
+func indentLines(s string, spaces, width int) string {
+	spacing := strings.Repeat(" ", spaces)
+	var sb strings.Builder
+	for _, line := range strings.Split(wordwrap.WrapString(s, uint(width-spaces)), "\n") {
+		_, _ = sb.WriteString(spacing)
+		_, _ = sb.WriteString(line)
+		_, _ = sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// useInstead returns the options to use instead of the deprecated opt, e.g.
+// "--new and $NEW".
+func useInstead(opt Option) string {
+	var sb strings.Builder
+	for i, s := range opt.UseInstead {
+		if i > 0 {
+			if i == len(opt.UseInstead)-1 {
+				_, _ = sb.WriteString(" and ")
+			} else {
+				_, _ = sb.WriteString(", ")
+			}
 		}
-		if len(inv.Args) > 0 {
-			// Return an error so that exit status is non-zero when
-			// a subcommand is not found.
-			return &UnknownSubcommandError{Args: inv.Args}
+		if s.Flag != "" {
+			_, _ = sb.WriteString("--")
+			_, _ = sb.WriteString(s.Flag)
+		} else if s.FlagShorthand != "" {
+			_, _ = sb.WriteString("-")
+			_, _ = sb.WriteString(s.FlagShorthand)
+		} else if s.Env != "" {
+			_, _ = sb.WriteString("$")
+			_, _ = sb.WriteString(s.Env)
+		} else {
+			_, _ = sb.WriteString(s.Name)
 		}
-		return nil
 	}
+	return sb.String()
 }
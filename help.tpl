@@ -16,7 +16,7 @@
 
 {{- with .Long}}
 {{"\n"}}
-{{- indent . 2}}
+{{- indent (long $) 2}}
 {{ "\n" }}
 {{- end }}
 {{ with visibleChildren . }}
@@ -30,42 +30,28 @@
 {{- "\n" }}
 {{- end }}
 {{- range $index, $group := optionGroups . }}
-{{ with $group.Name }} {{- print $group.Name " Options" | prettyHeader }} {{ else -}} {{ prettyHeader "Options"}}{{- end -}}
+{{ with $group.Name }} {{- print $group.Name " Options" | prettyHeader }}{{ else -}} {{ prettyHeader "Options"}}{{- end -}}
 {{- with $group.Description }}
 {{ formatGroupDescription . }}
 {{- else }}
 {{- end }}
-    {{- range $index, $option := $group.Options }}
-	{{- if not (eq $option.FlagShorthand "") }}{{- print "\n "}} {{ keyword "-"}}{{keyword $option.FlagShorthand }}{{", "}}
-	{{- else }}{{- print "\n      " -}}
-	{{- end }}
-    {{- with flagName $option }}{{keyword "--"}}{{ keyword . }}{{ end }} {{- with typeHelper $option }} {{ . }}{{ end }}
-    {{- with envName $option }}, {{ print "$" . | keyword }}{{ end }}
-    {{- with $option.Default }} (default: {{ . }}){{ end }}
-        {{- with $option.Description }}
-            {{- $desc := $option.Description }}
-{{ indent $desc 10 }}
-{{- if isDeprecated $option }}{{ indent (printf "DEPRECATED: Use %s instead." (useInstead $option)) 10 }}{{ end }}
-        {{- end -}}
-    {{- end }}
+{{- formatOptions $group.Options (optionGroups $) }}
 {{- end }}
 {{- if .Parent }}
 ———
 Run `{{ rootCommandName . }} --help` for a list of global options.
-{{- else }}
-{{- if .ContactInfo }}
+{{- end }}
+{{- with contactInfo . }}
 {{ prettyHeader "Contact"}}
-{{- with .ContactInfo }}
+{{- with . }}
 {{- with .RepoLink }}{{- print "\n "}}Repository:
-    {{ keyword . }}{{ end }}
+    {{ link (keyword .) . }}{{ end }}
 {{- with .IssuesLink }}{{- print "\n "}}Issues:
-    {{ keyword . }}{{ end }}
+    {{ link (keyword .) . }}{{ end }}
 {{- with .ChatLink }}{{- print "\n "}}Chat:
-    {{ keyword . }}{{ end }}
+    {{ link (keyword .) . }}{{ end }}
 {{- with .EmailLink }}{{- print "\n "}}Email:
-    {{ keyword . }}{{ end }}
-{{- end }}
-{{- else }}
+    {{ link (keyword .) (print "mailto:" .) }}{{ end }}
 {{- end }}
 {{- end }}
 
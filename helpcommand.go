@@ -0,0 +1,95 @@
+package serpent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// addHelpCommand adds a hidden "help [command]" child to the root command
+// c, unless it has a "help" child already, e.g. HelpSearchCommand. It shows
+// the help of the command at the path given as arguments, like "git help".
+func (c *Command) addHelpCommand() {
+	if c.child("help") != nil {
+		return
+	}
+	c.AddSubcommands(&Command{
+		Use:    "help [command]",
+		Short:  "Show help for a command.",
+		Hidden: true,
+		Handler: func(inv *Invocation) error {
+			return inv.helpFor(inv.Command.Parent, inv.Args)
+		},
+	})
+}
+
+// helpFor shows the help of the command at path below root, suggesting
+// similarly named commands if there's none.
+func (inv *Invocation) helpFor(root *Command, path []string) error {
+	cmd := root
+	for i, name := range path {
+		child := cmd.child(name)
+		if child == nil {
+			err := fmt.Sprintf("unknown command %q", strings.Join(append([]string{root.Name()}, path[:i+1]...), " "))
+			if suggestions := suggestCommands(cmd, name); len(suggestions) > 0 {
+				err += ", did you mean " + strings.Join(suggestions, " or ") + "?"
+			}
+			return errors.New(err)
+		}
+		child.Parent = cmd
+		cmd = child
+	}
+
+	help := cmd.HelpHandler
+	if help == nil {
+		help = DefaultHelpFn()
+	}
+	return help(inv.with(func(i *Invocation) {
+		i.Command = cmd
+		i.Args = nil
+	}))
+}
+
+// suggestionDistance is the largest edit distance between a mistyped
+// command name and the commands suggested for it.
+const suggestionDistance = 2
+
+// suggestCommands returns the full names of the visible children of cmd
+// named or aliased similarly to name, which is mistyped or a prefix.
+func suggestCommands(cmd *Command, name string) []string {
+	var suggestions []string
+	for _, child := range cmd.Children {
+		if child.Hidden {
+			continue
+		}
+		for _, candidate := range append([]string{child.Name()}, child.Aliases...) {
+			if strings.HasPrefix(candidate, name) || editDistance(candidate, name) <= suggestionDistance {
+				suggestions = append(suggestions, fmt.Sprintf("%q", cmd.FullName()+" "+child.Name()))
+				break
+			}
+		}
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur := make([]int, len(br)+1)
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,61 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestHelpCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := func() *serpent.Command {
+		return &serpent.Command{
+			Use: "app",
+			Children: []*serpent.Command{
+				{
+					Use:   "server",
+					Short: "Manage servers.",
+					Children: []*serpent.Command{
+						{Use: "start", Short: "Start a server.", Aliases: []string{"up"}},
+						{Use: "stop", Short: "Stop a server."},
+					},
+				},
+			},
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		inv := cmd().Invoke(append([]string{"help"}, args...)...)
+		io := fakeIO(inv)
+		err := inv.Run()
+		return io.Stdout.String(), err
+	}
+
+	out, err := run("server", "start")
+	require.NoError(t, err)
+	require.Contains(t, out, "Start a server.")
+	require.Contains(t, out, "app server start")
+
+	out, err = run()
+	require.NoError(t, err)
+	require.Contains(t, out, "server")
+	// The help command is hidden.
+	require.NotContains(t, out, "Show help for a command.")
+
+	_, err = run("server", "strat")
+	require.ErrorContains(t, err, `unknown command "app server strat", did you mean "app server start"?`)
+
+	_, err = run("server", "s")
+	require.ErrorContains(t, err, `did you mean "app server start" or "app server stop"?`)
+
+	// A help command of the tree takes precedence.
+	c := cmd()
+	c.AddSubcommands(serpent.HelpSearchCommand())
+	inv := c.Invoke("help", "--search", "stop")
+	io := fakeIO(inv)
+	require.NoError(t, inv.Run())
+	require.Equal(t, "app server stop  Stop a server.\n", io.Stdout.String())
+}
@@ -0,0 +1,149 @@
+package serpent
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// helpSearchSnippet is the number of runes of context shown around a match
+// in a long description.
+const helpSearchSnippet = 30
+
+// HelpSearchCommand returns a "help [command]" command showing the help of
+// a command of the tree. With --search, it instead searches the names,
+// descriptions and options of every visible command for the query, and
+// lists the matching commands with the lines that matched. Every word of the
+// query must match, case-insensitively.
+func HelpSearchCommand() *Command {
+	var query string
+	return &Command{
+		Use:   "help [command]",
+		Short: "Show help for a command, or search the help of all commands.",
+		Options: OptionSet{
+			{
+				Name:        "search",
+				Description: "Search the help of all commands for these words.",
+				Flag:        "search",
+				Value:       StringOf(&query),
+			},
+		},
+		Handler: func(inv *Invocation) error {
+			root := inv.Command
+			for root.Parent != nil {
+				root = root.Parent
+			}
+
+			if query == "" {
+				return inv.helpFor(root, inv.Args)
+			}
+
+			terms := strings.Fields(strings.ToLower(query))
+			tw := tabwriter.NewWriter(inv.Stdout, 0, 0, 2, ' ', 0)
+			var found bool
+			var walk func(cmd *Command)
+			walk = func(cmd *Command) {
+				if cmd.Hidden || cmd == inv.Command {
+					return
+				}
+				if context, ok := searchHelp(cmd, terms); ok {
+					found = true
+					_, _ = fmt.Fprintf(tw, "%s\t%s\n", cmd.FullName(), cmd.Short)
+					for _, line := range context {
+						_, _ = fmt.Fprintf(tw, "  %s\n", line)
+					}
+				}
+				for _, child := range cmd.Children {
+					child.Parent = cmd
+					walk(child)
+				}
+			}
+			walk(root)
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("no commands match %q", query)
+			}
+			return nil
+		},
+	}
+}
+
+// searchHelp reports whether every term is found in the help of cmd, and
+// returns the options and lines of the long description matching a term
+// as context. Matches in the name and short description need no context.
+func searchHelp(cmd *Command, terms []string) ([]string, bool) {
+	var (
+		context []string
+		text    = []string{cmd.FullName(), cmd.Short, cmd.Long}
+	)
+	for _, opt := range cmd.Options {
+		if opt.Hidden {
+			continue
+		}
+		heading := opt.Name
+		if opt.Flag != "" {
+			heading = "--" + opt.Flag
+		}
+		text = append(text, heading, opt.Name, opt.Description)
+		if containsAny(heading+" "+opt.Name+" "+opt.Description, terms) {
+			context = append(context, Keyword(heading)+"\t"+opt.Description)
+		}
+	}
+	for _, line := range strings.Split(cmd.Long, "\n") {
+		if snippet, ok := helpSnippet(line, terms); ok {
+			context = append(context, snippet)
+		}
+	}
+
+	all := strings.ToLower(strings.Join(text, "\n"))
+	for _, term := range terms {
+		if !strings.Contains(all, term) {
+			return nil, false
+		}
+	}
+	return context, true
+}
+
+// containsAny reports whether s contains any of terms, case-insensitively.
+func containsAny(s string, terms []string) bool {
+	s = strings.ToLower(s)
+	for _, term := range terms {
+		if strings.Contains(s, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// helpSnippet returns the part of line around the first match of a term,
+// with the match highlighted, if any term matches.
+func helpSnippet(line string, terms []string) (string, bool) {
+	runes := []rune(strings.TrimSpace(line))
+	lower := strings.ToLower(string(runes))
+	for _, term := range terms {
+		i := strings.Index(lower, term)
+		if i < 0 {
+			continue
+		}
+		// Lowercasing keeps the number of runes of nearly all text, so the
+		// offsets in lower are clamped rather than mapped back.
+		start := min(len([]rune(lower[:i])), len(runes))
+		end := min(start+len([]rune(term)), len(runes))
+
+		from, to := max(start-helpSearchSnippet, 0), min(end+helpSearchSnippet, len(runes))
+		var sb strings.Builder
+		if from > 0 {
+			_, _ = sb.WriteString("...")
+		}
+		_, _ = sb.WriteString(string(runes[from:start]))
+		_, _ = sb.WriteString(Keyword(string(runes[start:end])))
+		_, _ = sb.WriteString(string(runes[end:to]))
+		if to < len(runes) {
+			_, _ = sb.WriteString("...")
+		}
+		return sb.String(), true
+	}
+	return "", false
+}
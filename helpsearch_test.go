@@ -0,0 +1,69 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestHelpSearchCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := func() *serpent.Command {
+		var port int64
+		return &serpent.Command{
+			Use: "app",
+			Children: []*serpent.Command{
+				{
+					Use:   "server",
+					Short: "Manage servers.",
+					Children: []*serpent.Command{
+						{
+							Use:   "start",
+							Short: "Start a server.",
+							Long:  "Starts the server in the background.\nThe server listens on the given port until it's stopped.",
+							Options: serpent.OptionSet{
+								{Name: "port", Flag: "port", Description: "Port to listen on.", Value: serpent.Int64Of(&port)},
+							},
+						},
+						{Use: "stop", Short: "Stop a server."},
+					},
+				},
+				{Use: "secret", Short: "Hidden port command.", Hidden: true},
+				serpent.HelpSearchCommand(),
+			},
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		inv := cmd().Invoke(append([]string{"help"}, args...)...)
+		io := fakeIO(inv)
+		err := inv.Run()
+		return io.Stdout.String(), err
+	}
+
+	out, err := run("--search", "port")
+	require.NoError(t, err)
+	require.Equal(t, "app server start  Start a server.\n"+
+		"  --port          Port to listen on.\n"+
+		"  ...e server listens on the given port until it's stopped.\n", out)
+
+	// Every word must match.
+	out, err = run("--search", "manage server")
+	require.NoError(t, err)
+	require.Equal(t, "app server  Manage servers.\n", out)
+
+	_, err = run("--search", "xyz")
+	require.ErrorContains(t, err, `no commands match "xyz"`)
+
+	// Without --search, the help of the command is shown.
+	out, err = run("server", "start")
+	require.NoError(t, err)
+	require.Contains(t, out, "app server start")
+	require.Contains(t, out, "--port")
+
+	_, err = run("nope")
+	require.ErrorContains(t, err, `unknown command "app nope"`)
+}
@@ -0,0 +1,245 @@
+package serpent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bketelsen/serpent/files"
+)
+
+// HistoryEntry is a recorded invocation.
+type HistoryEntry struct {
+	Time time.Time `json:"time"`
+	// Command is the full name of the command that ran, e.g. "app server start".
+	Command string `json:"command"`
+	// Args are the arguments passed to the root command, suitable for
+	// re-running the invocation. The values of secret options are replaced
+	// with SanitizedSecret so they're never written to disk.
+	Args []string `json:"args"`
+	// Redacted is set if Args had secrets replaced, in which case the
+	// entry can't be re-run.
+	Redacted bool `json:"redacted,omitempty"`
+	// Flags are the names of the options set by flags.
+	Flags    []string      `json:"flags,omitempty"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+}
+
+// CommandLine returns the entry's root command name and arguments.
+func (e HistoryEntry) CommandLine() string {
+	root, _, _ := strings.Cut(e.Command, " ")
+	return strings.Join(append([]string{root}, e.Args...), " ")
+}
+
+// HistoryMiddleware returns a middleware that appends every invocation to a
+// JSON lines history file at path. If path is empty, the history is kept in
+// $XDG_STATE_HOME/<root command>/history.jsonl.
+//
+// History is opt-in: add the middleware to the commands that should be
+// recorded, e.g. with Walk from the root command. Failing to record history doesn't fail the command.
+func HistoryMiddleware(path string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			start := time.Now()
+			err := next(inv)
+
+			root := inv.Command
+			for root.Parent != nil {
+				root = root.Parent
+			}
+			args := sanitizeArgs(root, inv.rawArgs, true)
+			entry := HistoryEntry{
+				Time:     start.UTC(),
+				Command:  inv.Command.FullName(),
+				Args:     args,
+				Redacted: !slices.Equal(args, inv.rawArgs),
+				Flags:    flagsSet(inv),
+				ExitCode: exitCode(err),
+				Duration: time.Since(start),
+			}
+			if herr := appendHistory(historyPath(inv, path), entry); herr != nil {
+				inv.Warn("Failed to record history.", herr.Error())
+			}
+			return err
+		}
+	}
+}
+
+// ReadHistory reads the entries of the history file at path, oldest first.
+// A missing file has no entries.
+func ReadHistory(path string) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var e HistoryEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
+
+// HistoryCommand returns a "history" command that lists, searches and
+// re-runs the invocations recorded by HistoryMiddleware with the same path.
+func HistoryCommand(path string) *Command {
+	var (
+		limit int64
+		rerun int64
+	)
+	return &Command{
+		Use:   "history [query]",
+		Short: "Search and re-run previous commands.",
+		Long: "Lists previous invocations, most recent last. With a query, only " +
+			"invocations containing it are listed. Use --rerun with an ID to run " +
+			"an invocation again.",
+		Options: OptionSet{
+			{
+				Name:        "limit",
+				Description: "Maximum number of invocations to list.",
+				Flag:        "limit",
+				Default:     "20",
+				Value:       Int64Of(&limit),
+			},
+			{
+				Name:        "rerun",
+				Description: "Run the invocation with the given ID again.",
+				Flag:        "rerun",
+				Value:       Int64Of(&rerun),
+			},
+		},
+		Middleware: RequireRangeArgs(0, 1),
+		Handler: func(inv *Invocation) error {
+			entries, err := ReadHistory(historyPath(inv, path))
+			if err != nil {
+				return fmt.Errorf("read history: %w", err)
+			}
+
+			if rerun != 0 {
+				if rerun < 0 || int(rerun) > len(entries) {
+					return fmt.Errorf("no history entry with ID %d", rerun)
+				}
+				return inv.rerun(entries[rerun-1])
+			}
+
+			var query string
+			if len(inv.Args) > 0 {
+				query = strings.ToLower(inv.Args[0])
+			}
+			var ids []int
+			for i, e := range entries {
+				if strings.Contains(strings.ToLower(e.CommandLine()), query) {
+					ids = append(ids, i)
+				}
+			}
+			if limit > 0 && len(ids) > int(limit) {
+				ids = ids[len(ids)-int(limit):]
+			}
+
+			tw := tabwriter.NewWriter(inv.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "ID\tTIME\tEXIT\tDURATION\tCOMMAND")
+			for _, i := range ids {
+				e := entries[i]
+				_, _ = fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n",
+					i+1,
+					e.Time.Local().Format(time.DateTime),
+					e.ExitCode,
+					e.Duration.Round(time.Millisecond),
+					e.CommandLine(),
+				)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+// rerun runs the root command with the entry's arguments, reusing the
+// invocation's IO, environment and context. Entries with redacted secrets
+// aren't run, as the placeholders would be used as the secrets.
+func (inv *Invocation) rerun(e HistoryEntry) error {
+	if e.Redacted {
+		return fmt.Errorf("can't re-run %q: the values of its secret flags weren't recorded", e.CommandLine())
+	}
+	root := inv.Command
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	inv.message(Message{
+		Level:  MessageInfo,
+		Kind:   MessageKindRerun,
+		Header: "Running " + e.CommandLine(),
+		Text:   fmt.Sprintf("%s %s\n", Keyword("Running"), e.CommandLine()),
+	})
+	return inv.with(func(i *Invocation) {
+		i.Command = root
+		i.Args = e.Args
+		i.parsedFlags = nil
+	}).Run()
+}
+
+func historyPath(inv *Invocation, path string) string {
+	if path != "" {
+		return path
+	}
+	return filepath.Join(stateDir(inv), "history.jsonl")
+}
+
+func appendHistory(path string, e HistoryEntry) error {
+	byt, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := files.Append(path)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(byt, '\n'))
+	return errors.Join(err, f.Close())
+}
+
+// flagsSet returns the names of options on the command and its parents that
+// were set by a flag.
+func flagsSet(inv *Invocation) []string {
+	var names []string
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.ValueSource == ValueSourceFlag && opt.Flag != "" {
+				names = append(names, opt.Flag)
+			}
+		}
+	}
+	return names
+}
+
+// exitCode returns the process exit code conventionally associated with err.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return 1
+}
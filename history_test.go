@@ -0,0 +1,161 @@
+package serpent_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	var ran []string
+	makeRoot := func() *serpent.Command {
+		var verbose bool
+		root := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "verbose", Flag: "verbose", Value: serpent.BoolOf(&verbose)},
+			},
+			Children: []*serpent.Command{
+				{
+					Use: "deploy",
+					Handler: func(inv *serpent.Invocation) error {
+						ran = append(ran, strings.Join(inv.Args, " "))
+						if len(inv.Args) > 0 && inv.Args[0] == "fail" {
+							return errors.New("deploy failed")
+						}
+						return nil
+					},
+				},
+				serpent.HistoryCommand(path),
+			},
+		}
+
+		root.Walk(func(cmd *serpent.Command) {
+			if cmd.Middleware == nil {
+				cmd.Middleware = serpent.HistoryMiddleware(path)
+				return
+			}
+			cmd.Middleware = serpent.Chain(serpent.HistoryMiddleware(path), cmd.Middleware)
+		})
+		return root
+	}
+
+	run := func(args ...string) string {
+		inv := makeRoot().Invoke(args...)
+		io := fakeIO(inv)
+		err := inv.Run()
+		if len(args) > 1 && args[1] == "fail" {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+		return io.Stdout.String()
+	}
+
+	run("deploy", "--verbose", "prod")
+	run("deploy", "fail")
+
+	entries, err := serpent.ReadHistory(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "app deploy", entries[0].Command)
+	require.Equal(t, []string{"deploy", "--verbose", "prod"}, entries[0].Args)
+	require.Equal(t, []string{"verbose"}, entries[0].Flags)
+	require.Equal(t, 0, entries[0].ExitCode)
+	require.Equal(t, 1, entries[1].ExitCode)
+
+	out := run("history", "prod")
+	require.Contains(t, out, "app deploy --verbose prod")
+	require.NotContains(t, out, "fail")
+
+	run("history", "--rerun", "1")
+	require.Equal(t, []string{"prod", "fail", "prod"}, ran)
+
+	out = run("history", "--limit", "1")
+	require.Contains(t, out, "app history --rerun 1")
+	require.NotContains(t, out, "deploy")
+
+	_, err = serpent.ReadHistory(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+}
+
+func TestHistorySecrets(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	var token, region string
+	cmd := &serpent.Command{
+		Use: "app",
+		Options: serpent.OptionSet{
+			{Name: "token", Flag: "token", Value: serpent.StringOf(&token), Sensitive: true},
+			{Name: "region", Flag: "region", Value: serpent.StringOf(&region)},
+		},
+		Middleware: serpent.HistoryMiddleware(path),
+		Handler:    func(*serpent.Invocation) error { return nil },
+	}
+
+	err := cmd.Invoke("--token", "hunter2", "--region=eu", "target").Run()
+	require.NoError(t, err)
+	err = cmd.Invoke("--token=hunter2").Run()
+	require.NoError(t, err)
+
+	entries, err := serpent.ReadHistory(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, []string{"--token", serpent.SanitizedSecret, "--region=eu", "target"}, entries[0].Args)
+	require.Equal(t, []string{"--token=" + serpent.SanitizedSecret}, entries[1].Args)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "hunter2")
+}
+
+func TestHistoryRerunRedacted(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	var tokens []string
+	var token string
+	root := &serpent.Command{
+		Use: "app",
+		Children: []*serpent.Command{
+			{
+				Use: "login",
+				Options: serpent.OptionSet{
+					{Name: "token", Flag: "token", Value: serpent.StringOf(&token), Sensitive: true},
+				},
+				Handler: func(*serpent.Invocation) error {
+					tokens = append(tokens, token)
+					return nil
+				},
+			},
+			serpent.HistoryCommand(path),
+		},
+	}
+	root.Walk(func(cmd *serpent.Command) {
+		cmd.Middleware = serpent.HistoryMiddleware(path)
+	})
+
+	require.NoError(t, root.Invoke("login", "--token", "s3cret").Run())
+
+	entries, err := serpent.ReadHistory(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.True(t, entries[0].Redacted)
+
+	err = root.Invoke("history", "--rerun", "1").Run()
+	require.ErrorContains(t, err, "can't re-run")
+	require.Equal(t, []string{"s3cret"}, tokens)
+}
@@ -0,0 +1,272 @@
+package serpent
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AnnotationHTTPClient marks the options configuring the client returned by
+// HTTPClient, see HTTPClientOptions. Its value is the setting of the option.
+const AnnotationHTTPClient = "serpent.http_client"
+
+// DefaultHTTPTimeout is the default of the --http-timeout option.
+const DefaultHTTPTimeout = 30 * time.Second
+
+// httpRetries is the number of times idempotent requests failing with a
+// transient error are retried.
+const httpRetries = 3
+
+// httpRetryBackoff is the wait before the first retry, doubled for each
+// following one.
+var httpRetryBackoff = 100 * time.Millisecond
+
+// HTTPClientConfig configures an HTTP client, see HTTPClientOptions.
+type HTTPClientConfig struct {
+	// TLS holds the CA certificates trusted in addition to the system ones
+	// and whether to skip verifying certificates.
+	TLS TLSConfig
+	// Proxy is the URL of the proxy to send requests through. If empty, the
+	// proxy is set by the HTTPS_PROXY, HTTP_PROXY and NO_PROXY variables.
+	Proxy string
+	// Timeout is the time limit of requests, retries included. Zero means
+	// DefaultHTTPTimeout.
+	Timeout time.Duration
+}
+
+// HTTPClientOptions returns the --ca-cert, --insecure, --proxy and
+// --http-timeout options setting cfg, for commands that fetch remote
+// resources such as configs and updates. They're usually added to the root
+// command, and configure the client returned by HTTPClient. cfg may be nil
+// when only HTTPClient is used.
+func HTTPClientOptions(cfg *HTTPClientConfig) OptionSet {
+	if cfg == nil {
+		cfg = &HTTPClientConfig{}
+	}
+	mark := func(setting string) Annotations {
+		return Annotations{}.Mark(AnnotationHTTPClient, setting)
+	}
+	return OptionSet{
+		{
+			Name:        "ca-cert",
+			Description: "Path to a PEM bundle of CA certificates trusted in addition to the system ones.",
+			Flag:        "ca-cert",
+			Value:       StringOf(&cfg.TLS.CACert),
+			Annotations: mark("ca-cert"),
+		},
+		{
+			Name:        "insecure",
+			Description: "Skip verifying TLS certificates. Only use this for testing.",
+			Flag:        "insecure",
+			Value:       BoolOf(&cfg.TLS.Insecure),
+			Annotations: mark("insecure"),
+		},
+		{
+			Name:        "proxy",
+			Description: "URL of the proxy to send HTTP requests through, instead of the one set by HTTPS_PROXY, HTTP_PROXY and NO_PROXY.",
+			Flag:        "proxy",
+			Value:       StringOf(&cfg.Proxy),
+			Annotations: mark("proxy"),
+		},
+		{
+			Name:        "http-timeout",
+			Description: "Time limit of HTTP requests, retries included.",
+			Flag:        "http-timeout",
+			Default:     DefaultHTTPTimeout.String(),
+			Value:       DurationOf(&cfg.Timeout),
+			Annotations: mark("timeout"),
+		},
+	}
+}
+
+// HTTPClient returns an HTTP client configured by the HTTPClientOptions of
+// the command or its parents, with defaults for the missing ones. Requests
+// go through the proxy set by the invocation's HTTPS_PROXY, HTTP_PROXY and
+// NO_PROXY variables unless --proxy is set. Idempotent requests failing with
+// a network error or a 429, 502, 503 or 504 status are retried with
+// exponential backoff.
+func (inv *Invocation) HTTPClient() (*http.Client, error) {
+	settings := inv.httpClientSettings()
+	cfg := HTTPClientConfig{
+		TLS: TLSConfig{
+			CACert:   settings["ca-cert"],
+			Insecure: settings["insecure"] == "true",
+		},
+		Proxy: settings["proxy"],
+	}
+	if s := settings["timeout"]; s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse http timeout: %w", err)
+		}
+		cfg.Timeout = d
+	}
+	return cfg.Client(inv.Environ)
+}
+
+// Client returns an HTTP client configured by cfg, retrying like the one
+// returned by Invocation.HTTPClient. Without a Proxy, the proxy is set by
+// the HTTPS_PROXY, HTTP_PROXY and NO_PROXY variables of environ.
+func (cfg HTTPClientConfig) Client(environ Environ) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFromEnviron(environ)
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	tlsConfig, err := cfg.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	return &http.Client{
+		Transport: &retryTransport{base: transport},
+		Timeout:   timeout,
+	}, nil
+}
+
+// httpClientSettings returns the values of the closest options annotated
+// with AnnotationHTTPClient, by setting.
+func (inv *Invocation) httpClientSettings() map[string]string {
+	settings := make(map[string]string)
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			setting, ok := opt.Annotations.Get(AnnotationHTTPClient)
+			if !ok || opt.Value == nil {
+				continue
+			}
+			if _, ok := settings[setting]; !ok {
+				settings[setting] = opt.Value.String()
+			}
+		}
+	}
+	return settings
+}
+
+// proxyFromEnviron is like http.ProxyFromEnvironment, but reads the
+// variables from environ rather than the process environment.
+func proxyFromEnviron(environ Environ) func(*http.Request) (*url.URL, error) {
+	get := func(name string) string {
+		if v := environ.Get(name); v != "" {
+			return v
+		}
+		return environ.Get(strings.ToLower(name))
+	}
+	httpsProxy, httpProxy, noProxy := get("HTTPS_PROXY"), get("HTTP_PROXY"), get("NO_PROXY")
+
+	return func(req *http.Request) (*url.URL, error) {
+		proxy := httpProxy
+		if req.URL.Scheme == "https" {
+			proxy = httpsProxy
+		}
+		if proxy == "" || bypassProxy(noProxy, req.URL.Hostname()) {
+			return nil, nil
+		}
+		u, err := url.Parse(proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			// Like curl, accept proxies without a scheme.
+			if u, err = url.Parse("http://" + proxy); err != nil {
+				return nil, fmt.Errorf("invalid proxy address %q: %w", proxy, err)
+			}
+		}
+		return u, nil
+	}
+}
+
+// bypassProxy reports whether host matches an entry of the comma-separated
+// noProxy list: "*", a host name or IP address, or a domain, with or without
+// a leading dot, matching its subdomains.
+func bypassProxy(noProxy, host string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		switch {
+		case entry == "":
+		case entry == "*", entry == host:
+			return true
+		case strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")):
+			return true
+		}
+	}
+	return false
+}
+
+// retryTransport retries idempotent requests failing with a transient
+// error.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req.Method) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+		resp, err := t.base.RoundTrip(r)
+		if attempt == httpRetries || !transientHTTPError(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(httpRetryBackoff << attempt)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// transientHTTPError reports whether a request is worth retrying.
+func transientHTTPError(resp *http.Response, err error) bool {
+	if err != nil {
+		var tlsErr *tls.CertificateVerificationError
+		return !errors.As(err, &tlsErr)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package serpent_test
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestInvocation_HTTPClient(t *testing.T) {
+	t.Parallel()
+
+	// get runs the command with args, fetching url with the client of the
+	// invocation.
+	get := func(t *testing.T, url string, environ map[string]string, args ...string) (string, error) {
+		t.Helper()
+		var body string
+		cmd := &serpent.Command{
+			Use:     "app",
+			Options: serpent.HTTPClientOptions(nil),
+			Handler: func(inv *serpent.Invocation) error {
+				client, err := inv.HTTPClient()
+				if err != nil {
+					return err
+				}
+				resp, err := client.Get(url)
+				if err != nil {
+					return err
+				}
+				defer resp.Body.Close()
+				byt, err := io.ReadAll(resp.Body)
+				body = string(byt)
+				return err
+			},
+		}
+		inv := cmd.Invoke(args...)
+		for k, v := range environ {
+			inv.Environ.Set(k, v)
+		}
+		return body, inv.Run()
+	}
+
+	t.Run("Retry", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = io.WriteString(w, "ok")
+		}))
+		defer srv.Close()
+
+		body, err := get(t, srv.URL, nil)
+		require.NoError(t, err)
+		require.Equal(t, "ok", body)
+		require.EqualValues(t, 3, calls.Load())
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "secure")
+		}))
+		defer srv.Close()
+
+		_, err := get(t, srv.URL, nil)
+		require.ErrorContains(t, err, "certificate")
+
+		body, err := get(t, srv.URL, nil, "--insecure")
+		require.NoError(t, err)
+		require.Equal(t, "secure", body)
+
+		caCert := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: srv.Certificate().Raw,
+		}), 0o600))
+		body, err = get(t, srv.URL, nil, "--ca-cert", caCert)
+		require.NoError(t, err)
+		require.Equal(t, "secure", body)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "secure")
+		}))
+		defer srv.Close()
+
+		var cfg serpent.HTTPClientConfig
+		cmd := &serpent.Command{
+			Use:     "app",
+			Options: serpent.HTTPClientOptions(&cfg),
+			Handler: func(inv *serpent.Invocation) error { return nil },
+		}
+		require.NoError(t, cmd.Invoke("--insecure", "--http-timeout", "5s").Run())
+		require.True(t, cfg.TLS.Insecure)
+		require.Equal(t, 5*time.Second, cfg.Timeout)
+
+		client, err := cfg.Client(nil)
+		require.NoError(t, err)
+		require.Equal(t, 5*time.Second, client.Timeout)
+		resp, err := client.Get(srv.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		byt, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "secure", string(byt))
+	})
+
+	t.Run("Proxy", func(t *testing.T) {
+		t.Parallel()
+
+		var proxied atomic.Value
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied.Store(r.URL.String())
+			_, _ = io.WriteString(w, "proxied")
+		}))
+		defer proxy.Close()
+		const target = "http://example.invalid/config"
+
+		body, err := get(t, target, nil, "--proxy", proxy.URL)
+		require.NoError(t, err)
+		require.Equal(t, "proxied", body)
+		require.Equal(t, target, proxied.Load())
+
+		body, err = get(t, target, map[string]string{"HTTP_PROXY": proxy.URL})
+		require.NoError(t, err)
+		require.Equal(t, "proxied", body)
+
+		_, err = get(t, target, map[string]string{
+			"HTTP_PROXY": proxy.URL,
+			"NO_PROXY":   "localhost,.invalid",
+		})
+		require.Error(t, err, "the host is resolved without the proxy")
+	})
+}
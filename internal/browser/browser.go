@@ -0,0 +1,123 @@
+// Package browser opens URLs in the user's browser. It is shared by
+// serpent's bug-report command and the ui package.
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Opener opens url in a browser.
+type Opener func(url string) error
+
+type openerKey struct{}
+
+// ErrInvalidURL is returned for URLs that aren't opened, see Open.
+var ErrInvalidURL = errors.New("invalid URL")
+
+// WithOpener returns ctx with open replacing the browser, e.g. in tests.
+func WithOpener(ctx context.Context, open Opener) context.Context {
+	return context.WithValue(ctx, openerKey{}, open)
+}
+
+// Open opens u with the Opener of ctx if any, else with the commands listed
+// in the BROWSER variable, else with the platform's default browser. It
+// reports false without an error when there's no browser to open, such as
+// in SSH sessions and on Unix systems without a display. getenv looks up
+// environment variables.
+//
+// Only http, https and file URLs are opened, as the URL is passed to
+// commands that may take other URLs for file names or options. Others fail
+// with ErrInvalidURL.
+func Open(ctx context.Context, getenv func(string) string, u string) (bool, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "file":
+	default:
+		return false, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidURL, parsed.Scheme)
+	}
+
+	if open, ok := ctx.Value(openerKey{}).(Opener); ok {
+		err := open(u)
+		return err == nil, err
+	}
+
+	if browsers := getenv("BROWSER"); browsers != "" {
+		// BROWSER is a list of commands, in which %s is replaced with the
+		// URL, or else followed by it.
+		var errs []string
+		for _, browser := range strings.Split(browsers, string(os.PathListSeparator)) {
+			args := strings.Fields(browser)
+			if len(args) == 0 {
+				continue
+			}
+			substituted := false
+			for i, arg := range args {
+				if strings.Contains(arg, "%s") {
+					args[i] = strings.ReplaceAll(arg, "%s", u)
+					substituted = true
+				}
+			}
+			if !substituted {
+				args = append(args, u)
+			}
+			if err := start(exec.CommandContext(ctx, args[0], args[1:]...)); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			return true, nil
+		}
+		if len(errs) > 0 {
+			return false, fmt.Errorf("BROWSER: %s", strings.Join(errs, "; "))
+		}
+	}
+
+	if headless(getenv) {
+		return false, nil
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	if err := start(cmd); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// headless reports whether no browser can be shown to the user: in SSH
+// sessions, where it would open on the remote machine, and on Unix systems
+// without a display server.
+func headless(getenv func(string) string) bool {
+	if getenv("SSH_CONNECTION") != "" || getenv("SSH_TTY") != "" {
+		return true
+	}
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return false
+	}
+	return getenv("DISPLAY") == "" && getenv("WAYLAND_DISPLAY") == ""
+}
+
+// start starts cmd without waiting for the browser to exit.
+func start(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
@@ -0,0 +1,89 @@
+package browser_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent/internal/browser"
+)
+
+func env(kv ...string) func(string) string {
+	m := make(map[string]string)
+	for i := 0; i < len(kv); i += 2 {
+		m[kv[i]] = kv[i+1]
+	}
+	return func(k string) string { return m[k] }
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	const u = "https://example.com/issues/new"
+
+	t.Run("Opener", func(t *testing.T) {
+		t.Parallel()
+
+		var got string
+		ctx := browser.WithOpener(context.Background(), func(u string) error {
+			got = u
+			return nil
+		})
+		opened, err := browser.Open(ctx, env(), u)
+		require.NoError(t, err)
+		require.True(t, opened)
+		require.Equal(t, u, got)
+
+		failing := browser.WithOpener(context.Background(), func(string) error {
+			return errors.New("no browser")
+		})
+		opened, err = browser.Open(failing, env(), u)
+		require.Error(t, err)
+		require.False(t, opened)
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := browser.WithOpener(context.Background(), func(string) error { return nil })
+		_, err := browser.Open(ctx, env(), "javascript:alert(1)")
+		require.ErrorIs(t, err, browser.ErrInvalidURL)
+	})
+
+	t.Run("Headless", func(t *testing.T) {
+		t.Parallel()
+
+		opened, err := browser.Open(context.Background(), env("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22"), u)
+		require.NoError(t, err)
+		require.False(t, opened)
+	})
+
+	t.Run("BROWSER", func(t *testing.T) {
+		t.Parallel()
+		if runtime.GOOS == "windows" {
+			t.Skip("uses a shell script")
+		}
+
+		dir := t.TempDir()
+		out := filepath.Join(dir, "opened")
+		script := filepath.Join(dir, "browser")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho \"$2\" > \""+out+"\"\n"), 0o700))
+
+		// The first command is missing, so the second one is used.
+		browsers := strings.Join([]string{filepath.Join(dir, "missing"), script + " --new-tab %s"}, string(os.PathListSeparator))
+		opened, err := browser.Open(context.Background(), env("BROWSER", browsers, "SSH_TTY", "/dev/pts/0"), u)
+		require.NoError(t, err)
+		require.True(t, opened, "BROWSER is used even over SSH")
+		require.Eventually(t, func() bool {
+			byt, err := os.ReadFile(out)
+			return err == nil && strings.TrimSpace(string(byt)) == u
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,52 @@
+// Package filecomplete completes file names. It is shared by serpent's
+// completion of Path values and the completion package.
+package filecomplete
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Complete returns the files and directories starting with word, the
+// directories ending with a path separator. filter, which may be nil, selects
+// the files to return.
+func Complete(word string, filter func(info os.FileInfo) bool) []string {
+	var out []string
+	dir, _ := filepath.Split(word)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return out
+	}
+	defer f.Close()
+	if dir == "." {
+		dir = ""
+	}
+
+	infos, err := f.Readdir(0)
+	if err != nil {
+		return out
+	}
+
+	for _, info := range infos {
+		if filter != nil && !filter(info) {
+			continue
+		}
+
+		var cur string
+		if info.IsDir() {
+			cur = fmt.Sprintf("%s%s%c", dir, info.Name(), os.PathSeparator)
+		} else {
+			cur = fmt.Sprintf("%s%s", dir, info.Name())
+		}
+
+		if strings.HasPrefix(cur, word) {
+			out = append(out, cur)
+		}
+	}
+	return out
+}
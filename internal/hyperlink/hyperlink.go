@@ -0,0 +1,54 @@
+// Package hyperlink formats OSC 8 terminal hyperlinks. It is shared by
+// serpent's help output and the ui package.
+package hyperlink
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Format returns text wrapped in an OSC 8 escape sequence linking to url.
+func Format(text, url string) string {
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// Plain returns the plain text representation of a link, "text (url)", or
+// just url if text is empty or equal to it.
+func Plain(text, url string) string {
+	if text == "" || text == url {
+		return url
+	}
+	return text + " (" + url + ")"
+}
+
+// Enabled reports whether hyperlinks should be emitted. isTerminal describes
+// whether output is a terminal, and getenv is used to detect the terminal
+// emulator. FORCE_HYPERLINK=1 or FORCE_HYPERLINK=0 overrides detection.
+func Enabled(isTerminal bool, getenv func(string) string) bool {
+	if v := getenv("FORCE_HYPERLINK"); v != "" {
+		b, err := strconv.ParseBool(v)
+		return err == nil && b
+	}
+	return isTerminal && supported(getenv)
+}
+
+// supported reports whether the terminal described by the environment is
+// known to render OSC 8 hyperlinks.
+func supported(getenv func(string) string) bool {
+	if getenv("TERM") == "dumb" {
+		return false
+	}
+	switch getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby":
+		return true
+	}
+	if getenv("WT_SESSION") != "" || getenv("KITTY_WINDOW_ID") != "" || getenv("DOMTERM") != "" {
+		return true
+	}
+	// VTE based terminals (GNOME Terminal, Tilix, ...) support hyperlinks
+	// since 0.50.
+	if v, err := strconv.Atoi(getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	return strings.HasPrefix(getenv("TERM"), "xterm-kitty") || strings.HasPrefix(getenv("TERM"), "foot")
+}
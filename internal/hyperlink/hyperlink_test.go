@@ -0,0 +1,37 @@
+package hyperlink_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent/internal/hyperlink"
+)
+
+func TestEnabled(t *testing.T) {
+	t.Parallel()
+
+	env := func(kv ...string) func(string) string {
+		m := make(map[string]string)
+		for i := 0; i < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return func(k string) string { return m[k] }
+	}
+
+	require.False(t, hyperlink.Enabled(false, env("TERM_PROGRAM", "iTerm.app")))
+	require.True(t, hyperlink.Enabled(true, env("TERM_PROGRAM", "iTerm.app")))
+	require.True(t, hyperlink.Enabled(true, env("VTE_VERSION", "6003")))
+	require.False(t, hyperlink.Enabled(true, env("VTE_VERSION", "4000")))
+	require.False(t, hyperlink.Enabled(true, env("TERM", "dumb", "WT_SESSION", "1")))
+	require.True(t, hyperlink.Enabled(false, env("FORCE_HYPERLINK", "1")))
+	require.False(t, hyperlink.Enabled(true, env("FORCE_HYPERLINK", "0", "TERM_PROGRAM", "vscode")))
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "\x1b]8;;https://example.com\x1b\\docs\x1b]8;;\x1b\\", hyperlink.Format("docs", "https://example.com"))
+	require.Equal(t, "docs (https://example.com)", hyperlink.Plain("docs", "https://example.com"))
+	require.Equal(t, "https://example.com", hyperlink.Plain("https://example.com", "https://example.com"))
+}
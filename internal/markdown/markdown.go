@@ -0,0 +1,162 @@
+// Package markdown renders a pragmatic subset of Markdown for terminals. It
+// is shared by serpent's help output and the ui package.
+//
+// Supported syntax: ATX headings, fenced code blocks, ordered and unordered
+// lists, block quotes, horizontal rules, and the inline `code`, **bold**,
+// *italic* and [link](url) forms. Anything else is passed through untouched.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	headingStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#337CA0"))
+	codeStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#ED567A"))
+	boldStyle    = lipgloss.NewStyle().Bold(true)
+	italicStyle  = lipgloss.NewStyle().Italic(true)
+	linkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("5")).Underline(true)
+	quoteStyle   = lipgloss.NewStyle().Faint(true)
+)
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	unorderedRe = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe   = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	quoteRe     = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	ruleRe      = regexp.MustCompile(`^\s*(-\s*){3,}$|^\s*(\*\s*){3,}$|^\s*(_\s*){3,}$`)
+	boldRe      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe    = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	linkRe      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+const codeFence = "```"
+
+// LinkFunc renders a link. It's used so callers can render terminal
+// hyperlinks where supported.
+type LinkFunc func(text, url string) string
+
+// Options configures Render.
+type Options struct {
+	// Styled enables terminal styling. When false, Markdown syntax is
+	// stripped and the result is plain text suitable for pipes and files.
+	Styled bool
+	// Link renders links. If nil, links are rendered as "text (url)".
+	Link LinkFunc
+}
+
+// Render renders md according to opts.
+func Render(md string, opts Options) string {
+	var (
+		out    []string
+		inCode bool
+	)
+	for _, line := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), codeFence) {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			if opts.Styled {
+				line = codeStyle.Render(line)
+			}
+			out = append(out, "    "+line)
+			continue
+		}
+		out = append(out, renderLine(line, opts))
+	}
+	return strings.Join(out, "\n")
+}
+
+func renderLine(line string, opts Options) string {
+	if m := headingRe.FindStringSubmatch(line); m != nil {
+		text := inline(m[2], opts)
+		if !opts.Styled {
+			return text
+		}
+		if len(m[1]) == 1 {
+			text = strings.ToUpper(text)
+		}
+		return headingStyle.Render(text)
+	}
+	if ruleRe.MatchString(line) {
+		if !opts.Styled {
+			return "---"
+		}
+		return quoteStyle.Render(strings.Repeat("─", 40))
+	}
+	if m := unorderedRe.FindStringSubmatch(line); m != nil {
+		bullet := "-"
+		if opts.Styled {
+			bullet = "•"
+		}
+		return m[1] + bullet + " " + inline(m[2], opts)
+	}
+	if m := orderedRe.FindStringSubmatch(line); m != nil {
+		return m[1] + m[2] + ". " + inline(m[3], opts)
+	}
+	if m := quoteRe.FindStringSubmatch(line); m != nil {
+		if !opts.Styled {
+			return "  " + inline(m[1], opts)
+		}
+		return quoteStyle.Render("│ ") + inline(m[1], opts)
+	}
+	return inline(line, opts)
+}
+
+// inline renders inline formatting. Code spans are rendered verbatim.
+func inline(s string, opts Options) string {
+	parts := strings.Split(s, "`")
+	if len(parts)%2 == 0 {
+		// Unbalanced backticks, treat them literally.
+		return inlineText(s, opts)
+	}
+	var sb strings.Builder
+	for i, part := range parts {
+		if i%2 == 1 {
+			if opts.Styled {
+				part = codeStyle.Render(part)
+			}
+			_, _ = sb.WriteString(part)
+			continue
+		}
+		_, _ = sb.WriteString(inlineText(part, opts))
+	}
+	return sb.String()
+}
+
+func inlineText(s string, opts Options) string {
+	s = linkRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := linkRe.FindStringSubmatch(m)
+		text, url := sub[1], sub[2]
+		if opts.Link != nil {
+			return opts.Link(text, url)
+		}
+		if opts.Styled {
+			text = linkStyle.Render(text)
+		}
+		if sub[1] == url {
+			return text
+		}
+		return text + " (" + url + ")"
+	})
+	s = boldRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := boldRe.FindStringSubmatch(m)
+		text := sub[1] + sub[2]
+		if opts.Styled {
+			return boldStyle.Render(text)
+		}
+		return text
+	})
+	s = italicRe.ReplaceAllStringFunc(s, func(m string) string {
+		text := italicRe.FindStringSubmatch(m)[1]
+		if opts.Styled {
+			return italicStyle.Render(text)
+		}
+		return text
+	})
+	return s
+}
@@ -0,0 +1,46 @@
+// Package textdiff highlights what changed between two versions of a text,
+// like "watch -d". It is shared by serpent's --watch and --every modes and
+// the ui package.
+package textdiff
+
+import "strings"
+
+// Highlight returns updated with the characters that differ from old at the
+// same line and column passed to mark, consecutive changed characters being
+// marked together. Characters past the end of the old line, and lines past
+// the end of old, are changes. Deleted text isn't shown.
+func Highlight(old, updated string, mark func(string) string) string {
+	oldLines := strings.Split(old, "\n")
+	lines := strings.Split(updated, "\n")
+	for i, line := range lines {
+		var prev []rune
+		if i < len(oldLines) {
+			prev = []rune(oldLines[i])
+		}
+		lines[i] = highlightLine(prev, []rune(line), mark)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func highlightLine(old, line []rune, mark func(string) string) string {
+	var (
+		sb      strings.Builder
+		changed []rune
+	)
+	flush := func() {
+		if len(changed) > 0 {
+			_, _ = sb.WriteString(mark(string(changed)))
+			changed = changed[:0]
+		}
+	}
+	for i, r := range line {
+		if i >= len(old) || old[i] != r {
+			changed = append(changed, r)
+			continue
+		}
+		flush()
+		_, _ = sb.WriteRune(r)
+	}
+	flush()
+	return sb.String()
+}
@@ -0,0 +1,33 @@
+package textdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent/internal/textdiff"
+)
+
+func TestHighlight(t *testing.T) {
+	t.Parallel()
+
+	mark := func(s string) string { return "[" + s + "]" }
+	for _, tc := range []struct {
+		name, old, updated, want string
+	}{
+		{"Unchanged", "up 3 pods\n", "up 3 pods\n", "up 3 pods\n"},
+		{"Changed", "up 3 pods\nready", "up 12 pods\nready", "up [12 pods]\nready"},
+		{"Runs", "a-b-c", "x-b-z", "[x]-b-[z]"},
+		{"Longer", "ab\n", "abcd\nnew\n", "ab[cd]\n[new]\n"},
+		{"Shorter", "abcd\nold", "ab", "ab"},
+		{"Runes", "héllo", "hèllo", "h[è]llo"},
+		{"First", "", "text", "[text]"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, textdiff.Highlight(tc.old, tc.updated, mark))
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package serpent
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+// LintSeverity is the severity of a LintIssue.
+type LintSeverity string
+
+const (
+	// LintError issues make the command fail at runtime, or are certain to
+	// be mistakes.
+	LintError LintSeverity = "error"
+	// LintWarning issues are likely to be mistakes.
+	LintWarning LintSeverity = "warning"
+)
+
+// LintIssue is a problem with the definition of a command found by Lint.
+type LintIssue struct {
+	Severity LintSeverity
+	// Command is the full name of the command the issue was found in.
+	Command string
+	// Option is the name of the option the issue is about, if any.
+	Option  string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Command, i.Severity, i.Message)
+}
+
+// Lint checks cmd and all its children for mistakes in their definition,
+// such as options without a name, duplicate command names or enum defaults
+// that aren't valid choices. Some of the issues would also make the command
+// fail when run, Lint finds them all up front so that it can run as a test:
+//
+//	func TestLint(t *testing.T) {
+//		for _, issue := range serpent.Lint(rootCmd()) {
+//			t.Error(issue)
+//		}
+//	}
+func Lint(cmd *Command) []LintIssue {
+	var issues []LintIssue
+	cmd.Walk(func(c *Command) {
+		for _, child := range c.Children {
+			child.Parent = c
+		}
+		issues = append(issues, lintCommand(c)...)
+	})
+	return append(issues, lintEnvPrefix(cmd)...)
+}
+
+// lintCommand checks c and its options, but not its children.
+func lintCommand(c *Command) []LintIssue {
+	var issues []LintIssue
+	issue := func(severity LintSeverity, opt, format string, args ...any) {
+		issues = append(issues, LintIssue{
+			Severity: severity,
+			Command:  c.FullName(),
+			Option:   opt,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	flags := make(map[string]string)
+	for _, opt := range c.Options {
+		name := optionName(opt)
+		if name == "" {
+			issue(LintError, "", "option must have a Name, Flag, Env or YAML field")
+		}
+		for _, i := range lintOption(opt) {
+			i.Command = c.FullName()
+			issues = append(issues, i)
+		}
+		if !enumDefaultValid(opt) {
+			issue(LintError, name, "option %q default %q is not one of its choices", name, opt.Default)
+		}
+
+		shadows := false
+		for _, flag := range []string{"--" + opt.Flag, "-" + opt.FlagShorthand} {
+			if flag == "--" || flag == "-" {
+				continue
+			}
+			if other, ok := flags[flag]; ok {
+				issue(LintError, name, "options %q and %q both use flag %s", other, name, flag)
+				continue
+			}
+			flags[flag] = name
+			for p := c.Parent; p != nil; p = p.Parent {
+				shadowed := p.Options.byFlagForm(flag)
+				if shadowed == nil {
+					continue
+				}
+				shadows = true
+				if !opt.Override {
+					issue(LintError, name, "option %q flag %s shadows option %q of %q, set Override if that's intended", name, flag, optionName(*shadowed), p.FullName())
+				}
+				break
+			}
+		}
+		if opt.Override && !shadows {
+			issue(LintWarning, name, "option %q sets Override but doesn't shadow an inherited flag", name)
+		}
+	}
+
+	if _, err := c.replacement(); err != nil {
+		issue(LintError, "", "invalid ReplacedBy: %v", err)
+	} else if replacesItself(c) {
+		issue(LintError, "", "command is replaced by itself through ReplacedBy")
+	}
+
+	names := make(map[string]string)
+	for _, child := range c.Children {
+		for _, name := range append([]string{child.Name()}, child.Aliases...) {
+			if other, ok := names[name]; ok {
+				issue(LintError, "", "commands %q and %q both use the name %q", other, child.Name(), name)
+				continue
+			}
+			names[name] = child.Name()
+		}
+	}
+	return issues
+}
+
+// replacesItself reports whether following ReplacedBy from c leads back to
+// c, which would redirect forever.
+func replacesItself(c *Command) bool {
+	seen := []*Command{c}
+	for cmd := c; ; {
+		next, err := cmd.replacement()
+		if next == nil || err != nil {
+			return false
+		}
+		if slices.Contains(seen, next) {
+			return next == c
+		}
+		seen = append(seen, next)
+		cmd = next
+	}
+}
+
+// lintOption returns the issues with opt that also fail the command at
+// runtime.
+func lintOption(opt Option) []LintIssue {
+	var issues []LintIssue
+	name := optionName(opt)
+	issue := func(format string, args ...any) {
+		issues = append(issues, LintIssue{
+			Severity: LintError,
+			Option:   name,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	if opt.AllowIndirection && opt.Value != nil && opt.Value.Type() != "string" {
+		issue("option %q allows indirection but is not a string", name)
+	}
+	if _, ok := opt.Value.(pflag.SliceValue); ok && opt.AllowTemplates {
+		issue("option %q allows templates but is an array", name)
+	}
+	if opt.MinCount > 0 || opt.MaxCount > 0 {
+		if _, ok := opt.Value.(pflag.SliceValue); !ok {
+			issue("option %q sets MinCount or MaxCount but is not an array", name)
+		}
+		if opt.MaxCount > 0 && opt.MinCount > opt.MaxCount {
+			issue("option %q MinCount %d is greater than its MaxCount %d", name, opt.MinCount, opt.MaxCount)
+		}
+	}
+	if opt.Description != "" {
+		// Enforce that description uses sentence form.
+		if unicode.IsLower(rune(opt.Description[0])) {
+			issue("option %q description should start with a capital letter", name)
+		}
+		if !strings.HasSuffix(opt.Description, ".") {
+			issue("option %q description should end with a period", name)
+		}
+	}
+	return issues
+}
+
+// lintEnvPrefix warns about environment variables that don't use the
+// prefix most environment variables of the command tree share.
+func lintEnvPrefix(root *Command) []LintIssue {
+	type env struct {
+		cmd *Command
+		opt Option
+	}
+	var envs []env
+	counts := make(map[string]int)
+	root.Walk(func(c *Command) {
+		for _, opt := range c.Options {
+			if opt.Env == "" {
+				continue
+			}
+			envs = append(envs, env{cmd: c, opt: opt})
+			if prefix, _, ok := strings.Cut(opt.Env, "_"); ok {
+				counts[prefix+"_"]++
+			}
+		}
+	})
+
+	var prefixes []string
+	for prefix := range counts {
+		prefixes = append(prefixes, prefix)
+	}
+	// Break ties deterministically.
+	sort.Strings(prefixes)
+	var prefix string
+	for _, p := range prefixes {
+		if counts[p] > counts[prefix] {
+			prefix = p
+		}
+	}
+	// Without a clear majority, there's no convention to enforce.
+	if counts[prefix] < 2 || counts[prefix]*2 <= len(envs) {
+		return nil
+	}
+
+	var issues []LintIssue
+	for _, e := range envs {
+		if strings.HasPrefix(e.opt.Env, prefix) {
+			continue
+		}
+		name := optionName(e.opt)
+		issues = append(issues, LintIssue{
+			Severity: LintWarning,
+			Command:  e.cmd.FullName(),
+			Option:   name,
+			Message:  fmt.Sprintf("option %q environment variable %s doesn't use the %s prefix", name, e.opt.Env, prefix),
+		})
+	}
+	return issues
+}
+
+// optionName returns the name of opt as init would set it.
+func optionName(opt Option) string {
+	for _, name := range []string{opt.Name, opt.Flag, opt.Env, opt.YAML} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// enumDefaultValid reports whether the default of an enum option, if any,
+// is one of its choices.
+func enumDefaultValid(opt Option) bool {
+	if opt.Default == "" {
+		return true
+	}
+	var (
+		choices  []string
+		defaults = []string{opt.Default}
+	)
+	switch v := opt.Value.(type) {
+	case *Enum:
+		choices = v.Choices
+	case *EnumArray:
+		choices = v.Choices
+		var err error
+		defaults, err = readAsCSV(opt.Default)
+		if err != nil {
+			return false
+		}
+	default:
+		return true
+	}
+	for _, d := range defaults {
+		if _, ok := matchEnumChoice(choices, enumDetails(opt.Value), d); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(ss []string, s string) bool {
+	for _, v := range ss {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// byFlagForm returns the option using flag, e.g. "--verbose" or "-v".
+func (optSet OptionSet) byFlagForm(flag string) *Option {
+	for i := range optSet {
+		opt := &optSet[i]
+		if (opt.Flag != "" && flag == "--"+opt.Flag) || (opt.FlagShorthand != "" && flag == "-"+opt.FlagShorthand) {
+			return opt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Clean", func(t *testing.T) {
+		t.Parallel()
+
+		root := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "verbose", Flag: "verbose", FlagShorthand: "v", Env: "APP_VERBOSE", Description: "Log more.", Value: serpent.BoolOf(new(bool))},
+				{Name: "format", Flag: "format", Env: "APP_FORMAT", Default: "JSON", Value: serpent.EnumOf(new(string), "json", "text")},
+			},
+			Children: []*serpent.Command{
+				{Use: "server", Aliases: []string{"srv"}},
+				{Use: "client"},
+			},
+		}
+		require.Empty(t, serpent.Lint(root))
+	})
+
+	t.Run("Issues", func(t *testing.T) {
+		t.Parallel()
+
+		root := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "verbose", Flag: "verbose", FlagShorthand: "v", Env: "APP_VERBOSE", Value: serpent.BoolOf(new(bool))},
+				{Name: "format", Flag: "format", Env: "APP_FORMAT", Default: "yaml", Value: serpent.EnumOf(new(string), "json", "text")},
+				{Name: "fields", Flag: "fields", Env: "APP_FIELDS", Default: "id,size", Value: serpent.EnumArrayOf(new([]string), "id", "name")},
+				{Name: "token", Env: "TOKEN", Description: "the token", Value: serpent.StringOf(new(string))},
+				{Description: "Nothing.", Value: serpent.StringOf(new(string))},
+			},
+			Children: []*serpent.Command{
+				{
+					Use: "server",
+					Options: serpent.OptionSet{
+						{Name: "version", Flag: "verbose", Value: serpent.BoolOf(new(bool))},
+						{Name: "format", Flag: "format", Override: true, Value: serpent.StringOf(new(string))},
+						{Name: "force", Flag: "force", Override: true, Value: serpent.BoolOf(new(bool))},
+						{Name: "port", Flag: "port", FlagShorthand: "p", AllowIndirection: true, Value: serpent.Int64Of(new(int64))},
+						{Name: "path", Flag: "path", FlagShorthand: "p", Value: serpent.StringOf(new(string))},
+					},
+				},
+				{Use: "serve", Aliases: []string{"server"}},
+				{Use: "old", ReplacedBy: []string{"gone"}},
+				{Use: "ping", ReplacedBy: []string{"pong"}},
+				{Use: "pong", ReplacedBy: []string{"ping"}},
+			},
+		}
+
+		var got []string
+		for _, issue := range serpent.Lint(root) {
+			got = append(got, issue.String())
+		}
+		require.ElementsMatch(t, []string{
+			`app: error: option "format" default "yaml" is not one of its choices`,
+			`app: error: option "fields" default "id,size" is not one of its choices`,
+			`app: error: option "token" description should start with a capital letter`,
+			`app: error: option "token" description should end with a period`,
+			`app: error: option must have a Name, Flag, Env or YAML field`,
+			`app: error: commands "server" and "serve" both use the name "server"`,
+			`app server: error: option "version" flag --verbose shadows option "verbose" of "app", set Override if that's intended`,
+			`app server: warning: option "force" sets Override but doesn't shadow an inherited flag`,
+			`app server: error: option "port" allows indirection but is not a string`,
+			`app server: error: options "port" and "path" both use flag -p`,
+			`app old: error: invalid ReplacedBy: no command "app gone"`,
+			`app ping: error: command is replaced by itself through ReplacedBy`,
+			`app pong: error: command is replaced by itself through ReplacedBy`,
+			`app: warning: option "token" environment variable TOKEN doesn't use the APP_ prefix`,
+		}, got)
+	})
+}
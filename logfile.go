@@ -0,0 +1,142 @@
+package serpent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bketelsen/serpent/files"
+)
+
+// LogToFileMiddleware returns a middleware that duplicates everything written
+// to Stderr, including Warn, Info and ui.Step output, to a per-run log file.
+// Each line is prefixed with the time elapsed since the run started, and the
+// file begins and ends with the command, its start time, duration and result.
+//
+// The log is written to the value of pathOption when it's set, and otherwise
+// to $XDG_STATE_HOME/<root command>/logs (~/.local/state/<root command>/logs
+// by default). When the handler fails, the log path is printed to Stderr.
+func LogToFileMiddleware(pathOption *Option) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			start := time.Now()
+
+			path := ""
+			if pathOption != nil && pathOption.Value != nil {
+				path = pathOption.Value.String()
+			}
+			if path == "" {
+				path = defaultRunLogPath(inv, start)
+			}
+			f, err := files.Append(path)
+			if err != nil {
+				return fmt.Errorf("open log file: %w", err)
+			}
+			defer f.Close()
+
+			_, _ = fmt.Fprintf(f, "# %s started %s\n", runLogCommandLine(inv), start.Format(time.RFC3339))
+
+			lw := &runLogWriter{w: f, start: start}
+			remove := inv.AddOutputHook(func(stream OutputStream, p []byte) {
+				if stream == OutputStderr {
+					lw.write(p)
+				}
+			})
+
+			err = next(inv)
+			remove()
+			lw.flush()
+
+			result := "ok"
+			if err != nil {
+				result = "error: " + err.Error()
+			}
+			_, _ = fmt.Fprintf(f, "# finished in %s (%s)\n", time.Since(start).Round(time.Millisecond), result)
+
+			if err != nil {
+				inv.message(Message{
+					Level:  MessageInfo,
+					Kind:   MessageKindLogFile,
+					Header: "see " + abbreviateHome(inv, path),
+					Text:   fmt.Sprintf("see %s\n", abbreviateHome(inv, path)),
+				})
+			}
+			return err
+		}
+	}
+}
+
+// runLogWriter writes lines prefixed with the elapsed time, buffering
+// partial lines until they're complete.
+type runLogWriter struct {
+	mu    sync.Mutex
+	w     *os.File
+	start time.Time
+	buf   []byte
+}
+
+func (l *runLogWriter) write(p []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return
+		}
+		l.writeLine(l.buf[:i])
+		l.buf = l.buf[i+1:]
+	}
+}
+
+func (l *runLogWriter) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) > 0 {
+		l.writeLine(l.buf)
+		l.buf = nil
+	}
+}
+
+func (l *runLogWriter) writeLine(line []byte) {
+	elapsed := time.Since(l.start).Seconds()
+	_, _ = fmt.Fprintf(l.w, "[+%.3fs] %s\n", elapsed, line)
+}
+
+func defaultRunLogPath(inv *Invocation, start time.Time) string {
+	name := fmt.Sprintf("run-%s-%d.log", start.Format("20060102-150405"), os.Getpid())
+	return filepath.Join(stateDir(inv), "logs", name)
+}
+
+// stateDir returns the XDG state directory of the root command, e.g.
+// ~/.local/state/app.
+func stateDir(inv *Invocation) string {
+	root := inv.Command
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	return files.StateDir(inv.Environ.Get, root.Name())
+}
+
+func runLogCommandLine(inv *Invocation) string {
+	return strings.Join(append([]string{inv.Command.FullName()}, inv.Args...), " ")
+}
+
+// abbreviateHome replaces the home directory prefix of path with "~".
+func abbreviateHome(inv *Invocation, path string) string {
+	home := inv.Environ.Get("HOME")
+	if home == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("~", rel)
+	}
+	return path
+}
@@ -0,0 +1,72 @@
+package serpent_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestLogToFileMiddleware(t *testing.T) {
+	t.Parallel()
+
+	makeCmd := func(path *serpent.Option, fail bool) *serpent.Command {
+		return &serpent.Command{
+			Use:        "app",
+			Middleware: serpent.LogToFileMiddleware(path),
+			Handler: func(inv *serpent.Invocation) error {
+				_, _ = inv.Stdout.Write([]byte("not logged\n"))
+				_, _ = inv.Stderr.Write([]byte("[build] compiling\npartial"))
+				if fail {
+					return errors.New("build failed")
+				}
+				return nil
+			},
+		}
+	}
+
+	t.Run("DefaultPath", func(t *testing.T) {
+		t.Parallel()
+
+		home := t.TempDir()
+		inv := makeCmd(nil, true).Invoke()
+		inv.Environ.Set("HOME", home)
+		io := fakeIO(inv)
+		require.ErrorContains(t, inv.Run(), "build failed")
+
+		logs, err := filepath.Glob(filepath.Join(home, ".local", "state", "app", "logs", "run-*.log"))
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		rel, err := filepath.Rel(home, logs[0])
+		require.NoError(t, err)
+		require.Contains(t, io.Stderr.String(), "see "+filepath.Join("~", rel)+"\n")
+
+		byt, err := os.ReadFile(logs[0])
+		require.NoError(t, err)
+		require.Contains(t, string(byt), "# app started ")
+		require.Regexp(t, `\[\+\d+\.\d{3}s\] \[build\] compiling\n`, string(byt))
+		require.Regexp(t, `\[\+\d+\.\d{3}s\] partial\n`, string(byt))
+		require.Contains(t, string(byt), "(error: build failed)")
+		require.NotContains(t, string(byt), "not logged")
+		require.NotContains(t, string(byt), "see ")
+	})
+
+	t.Run("PathOption", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "run.log")
+		opt := &serpent.Option{Value: serpent.StringOf(&path)}
+		inv := makeCmd(opt, false).Invoke()
+		io := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.NotContains(t, io.Stderr.String(), "see ")
+
+		byt, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Contains(t, string(byt), "(ok)")
+	})
+}
@@ -0,0 +1,64 @@
+package serpent
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/charmbracelet/log"
+)
+
+// LoggingConfig configures a logger, see LoggingOptions.
+type LoggingConfig struct {
+	// Level is the minimum level of the logged messages: "debug", "info",
+	// "warn" or "error". Empty means "info".
+	Level string
+	// Format is the format of the logged messages: "text", "json" or
+	// "logfmt". Empty means "text".
+	Format string
+}
+
+// LoggingOptions returns the --log-level and --log-format options setting
+// cfg. Use cfg.Logger to build the logger once the options are parsed, e.g.
+// in a middleware replacing Invocation.Logger.
+func LoggingOptions(cfg *LoggingConfig) OptionSet {
+	return OptionSet{
+		{
+			Name:        "log-level",
+			Description: "Minimum level of the logged messages.",
+			Flag:        "log-level",
+			Default:     "info",
+			Value:       EnumOf(&cfg.Level, "debug", "info", "warn", "error"),
+		},
+		{
+			Name:        "log-format",
+			Description: "Format of the logged messages.",
+			Flag:        "log-format",
+			Default:     "text",
+			Value:       EnumOf(&cfg.Format, "text", "json", "logfmt"),
+		},
+	}
+}
+
+// Logger returns a logger writing to w at the level and in the format set by
+// cfg.
+func (cfg LoggingConfig) Logger(w io.Writer) (*log.Logger, error) {
+	logger := log.New(w)
+	if cfg.Level != "" {
+		level, err := log.ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, err
+		}
+		logger.SetLevel(level)
+	}
+	switch cfg.Format {
+	case "", "text":
+		logger.SetFormatter(log.TextFormatter)
+	case "json":
+		logger.SetFormatter(log.JSONFormatter)
+	case "logfmt":
+		logger.SetFormatter(log.LogfmtFormatter)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	return logger, nil
+}
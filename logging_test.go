@@ -0,0 +1,35 @@
+package serpent_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestLoggingOptions(t *testing.T) {
+	t.Parallel()
+
+	var cfg serpent.LoggingConfig
+	cmd := &serpent.Command{
+		Use:     "app",
+		Options: serpent.LoggingOptions(&cfg),
+		Handler: func(inv *serpent.Invocation) error { return nil },
+	}
+	require.NoError(t, cmd.Invoke("--log-level", "warn", "--log-format", "json").Run())
+	require.Equal(t, serpent.LoggingConfig{Level: "warn", Format: "json"}, cfg)
+	require.Error(t, cmd.Invoke("--log-format", "xml").Run())
+
+	var buf bytes.Buffer
+	logger, err := cfg.Logger(&buf)
+	require.NoError(t, err)
+	logger.Info("hidden")
+	logger.Warn("shown", "key", "value")
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "shown", entry["msg"])
+	require.Equal(t, "value", entry["key"])
+}
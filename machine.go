@@ -0,0 +1,153 @@
+package serpent
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// AnnotationMachineMode marks the boolean option that enables machine mode.
+const AnnotationMachineMode = "serpent.machine_mode"
+
+// MachineModeOption returns a hidden --json-log option, also settable with
+// the env environment variable (e.g. MYAPP_MACHINE=1), that switches Warn,
+// Info, Error and ui.Step output to newline-delimited JSON events so scripts
+// and CI can parse progress reliably.
+//
+// The option is usually added to the root command.
+func MachineModeOption(env string) Option {
+	var enabled bool
+	return Option{
+		Name:        "json-log",
+		Description: "Write progress and log messages as newline-delimited JSON.",
+		Flag:        "json-log",
+		Env:         env,
+		Value:       BoolOf(&enabled),
+		Hidden:      true,
+		Annotations: Annotations{}.Mark(AnnotationMachineMode, "true"),
+	}
+}
+
+// MachineMode reports whether machine mode is enabled by an option annotated
+// with AnnotationMachineMode on the command or any of its parents.
+func (inv *Invocation) MachineMode() bool {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if !opt.Annotations.IsSet(AnnotationMachineMode) {
+				continue
+			}
+			if b, ok := opt.Value.(*Bool); ok && b.Value() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EventKind is the kind of an Event.
+type EventKind string
+
+const (
+	// EventStep reports a step of a multi-step operation, see ui.Step.
+	EventStep EventKind = "step"
+	// EventProgress reports how many of a number of items are done, see
+	// ui.Progress and Parallel.
+	EventProgress EventKind = "progress"
+	// EventLog is a message written with Info, Warn and Error, or by
+	// serpent itself.
+	EventLog EventKind = "log"
+	// EventResult is the last event sent on an Events channel, holding the
+	// result of the invocation.
+	EventResult EventKind = "result"
+)
+
+// Event is a machine-readable progress or log event.
+type Event struct {
+	Time  time.Time `json:"time"`
+	Kind  EventKind `json:"kind,omitempty"`
+	Level string    `json:"level"`
+	// Step is set for step and progress events, see ui.Step.
+	Step  string   `json:"step,omitempty"`
+	Msg   string   `json:"msg"`
+	Lines []string `json:"lines,omitempty"`
+	// Completed and Total are set for progress events.
+	Completed int `json:"completed,omitempty"`
+	Total     int `json:"total,omitempty"`
+	// ExitCode and Error are set for result events.
+	ExitCode int    `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Fields are set from the invocation's log fields, see WithLogFields.
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// Emit sends the event on the invocation's Events channel if a host reads
+// it, and otherwise writes it to Stderr as a line of JSON. The time is set if
+// it's zero.
+func (inv *Invocation) Emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if len(inv.logFields) > 0 {
+		fields := make(map[string]any, len(inv.logFields)/2+len(e.Fields))
+		for i := 0; i+1 < len(inv.logFields); i += 2 {
+			fields[fmt.Sprint(inv.logFields[i])] = logFieldValue(inv.logFields[i+1])
+		}
+		for k, v := range e.Fields {
+			fields[k] = v
+		}
+		e.Fields = fields
+	}
+	if inv.events != nil {
+		inv.events.send(e)
+		return
+	}
+	byt, err := json.Marshal(e)
+	if err != nil {
+		// Events only contain strings, this should never happen.
+		panic(fmt.Sprintf("marshal event: %v", err))
+	}
+	_, _ = inv.Stderr.Write(append(byt, '\n'))
+}
+
+// WithLogFields returns the invocation with keyvals, alternating keys and
+// values, added to the fields of its Logger and of the events it emits in
+// machine mode. It's typically used in middleware:
+//
+//	inv = inv.WithLogFields("cmd", inv.Command.FullName(), "run_id", id)
+func (inv *Invocation) WithLogFields(keyvals ...any) *Invocation {
+	return inv.with(func(i *Invocation) {
+		if i.Logger != nil {
+			i.Logger = i.Logger.With(keyvals...)
+		}
+		i.logFields = append(append([]any(nil), inv.logFields...), keyvals...)
+	})
+}
+
+// withMachineLogger returns the invocation with a Logger writing JSON to
+// Stderr if machine mode is enabled.
+func (inv *Invocation) withMachineLogger() *Invocation {
+	if inv.Logger == nil || !inv.MachineMode() {
+		return inv
+	}
+	return inv.with(func(i *Invocation) {
+		i.Logger = i.Logger.With()
+		i.Logger.SetFormatter(log.JSONFormatter)
+		i.Logger.SetOutput(i.Stderr)
+	})
+}
+
+// logFieldValue returns v as a value that can be marshaled to JSON.
+func logFieldValue(v any) any {
+	switch v := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,110 @@
+package serpent_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestMachineMode(t *testing.T) {
+	t.Parallel()
+
+	cmd := func() *serpent.Command {
+		return &serpent.Command{
+			Use:     "root",
+			Options: serpent.OptionSet{serpent.MachineModeOption("APP_MACHINE")},
+			Children: []*serpent.Command{{
+				Use: "deploy",
+				Handler: func(inv *serpent.Invocation) error {
+					ui.Step(inv, "build", "building %s", "app")
+					inv.Warn("careful", "detail")
+					return nil
+				},
+			}},
+		}
+	}
+
+	t.Run("Human", func(t *testing.T) {
+		t.Parallel()
+
+		inv := cmd().Invoke("deploy")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stderr.String(), "[build] building app\n")
+		require.Contains(t, stdio.Stderr.String(), "WARNING: careful")
+	})
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		env  string
+	}{
+		{name: "Flag", args: []string{"deploy", "--json-log"}},
+		{name: "Env", args: []string{"deploy"}, env: "1"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inv := cmd().Invoke(tc.args...)
+			if tc.env != "" {
+				inv.Environ.Set("APP_MACHINE", tc.env)
+			}
+			stdio := fakeIO(inv)
+			require.NoError(t, inv.Run())
+
+			lines := strings.Split(strings.TrimSpace(stdio.Stderr.String()), "\n")
+			require.Len(t, lines, 2)
+
+			var step, warn serpent.Event
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &step))
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &warn))
+			require.Equal(t, "build", step.Step)
+			require.Equal(t, "building app", step.Msg)
+			require.False(t, step.Time.IsZero())
+			require.Equal(t, "warn", warn.Level)
+			require.Equal(t, "careful", warn.Msg)
+			require.Equal(t, []string{"detail"}, warn.Lines)
+		})
+	}
+}
+
+func TestInvocation_WithLogFields(t *testing.T) {
+	t.Parallel()
+
+	cmd := &serpent.Command{
+		Use:     "deploy",
+		Options: serpent.OptionSet{serpent.MachineModeOption("APP_MACHINE")},
+		Middleware: func(next serpent.HandlerFunc) serpent.HandlerFunc {
+			return func(inv *serpent.Invocation) error {
+				return next(inv.WithLogFields("cmd", inv.Command.Name(), "attempt", 1))
+			}
+		},
+		Handler: func(inv *serpent.Invocation) error {
+			ui.Step(inv, "build", "building")
+			inv.Logger.Info("built", "size", 42)
+			return nil
+		},
+	}
+
+	inv := cmd.Invoke("--json-log")
+	stdio := fakeIO(inv)
+	require.NoError(t, inv.Run())
+	lines := strings.Split(strings.TrimSpace(stdio.Stderr.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var step serpent.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &step))
+	require.Equal(t, map[string]any{"cmd": "deploy", "attempt": float64(1)}, step.Fields)
+
+	var logLine map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &logLine))
+	require.Equal(t, "built", logLine["msg"])
+	require.Equal(t, "deploy", logLine["cmd"])
+	require.Equal(t, float64(42), logLine["size"])
+}
@@ -0,0 +1,39 @@
+package serpent
+
+import "sync"
+
+// maintenance holds the constructor of the maintenance commands, registered
+// by the maintenance package, which can't be imported here since it imports
+// serpent.
+var maintenance struct {
+	sync.RWMutex
+	commands func() []*Command
+}
+
+// RegisterMaintenanceCommands makes MaintenanceCommands return the commands
+// constructed by commands. The maintenance package registers its Commands
+// when imported.
+func RegisterMaintenanceCommands(commands func() []*Command) {
+	maintenance.Lock()
+	defer maintenance.Unlock()
+	maintenance.commands = commands
+}
+
+// MaintenanceCommands returns the hidden gendocs, genman, gencomp and gentree
+// commands generating release artifacts from the shipped binary, to be added
+// to the root command. They're implemented by the maintenance package, which
+// must be imported:
+//
+//	import _ "github.com/bketelsen/serpent/maintenance"
+//
+//	root.AddSubcommands(serpent.MaintenanceCommands()...)
+//
+// It panics if the maintenance package isn't imported.
+func MaintenanceCommands() []*Command {
+	maintenance.RLock()
+	defer maintenance.RUnlock()
+	if maintenance.commands == nil {
+		panic("MaintenanceCommands called without importing github.com/bketelsen/serpent/maintenance")
+	}
+	return maintenance.commands()
+}
@@ -0,0 +1,159 @@
+// Package maintenance provides hidden commands that generate release
+// artifacts, such as documentation, man pages and completion scripts, from
+// the shipped binary itself.
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/completion"
+)
+
+func init() {
+	serpent.RegisterMaintenanceCommands(Commands)
+}
+
+// Commands returns the hidden gendocs, genman, gencomp and gentree commands,
+// to be added to the root command. They're also returned by
+// serpent.MaintenanceCommands:
+//
+//	root.AddSubcommands(maintenance.Commands()...)
+func Commands() []*serpent.Command {
+	return []*serpent.Command{
+		gendocsCommand(),
+		genmanCommand(),
+		gencompCommand(),
+		gentreeCommand(),
+	}
+}
+
+// root returns the root of the command tree the invoked command is in.
+func root(inv *serpent.Invocation) *serpent.Command {
+	cmd := inv.Command
+	for cmd.Parent != nil {
+		cmd = cmd.Parent
+	}
+	return cmd
+}
+
+func dirOption(dir *string, def string) serpent.Option {
+	return serpent.Option{
+		Name:        "dir",
+		Description: "Directory to write the files to.",
+		Flag:        "dir",
+		Default:     def,
+		Value:       serpent.StringOf(dir),
+	}
+}
+
+func gendocsCommand() *serpent.Command {
+	var dir string
+	return &serpent.Command{
+		Use:        "gendocs",
+		Short:      "Generate Markdown documentation for every command.",
+		Hidden:     true,
+		Middleware: serpent.RequireNArgs(0),
+		Options:    serpent.OptionSet{dirOption(&dir, "docs")},
+		Handler: func(inv *serpent.Invocation) error {
+			return WriteMarkdown(dir, root(inv).Tree())
+		},
+	}
+}
+
+func genmanCommand() *serpent.Command {
+	var (
+		dir     string
+		section int64
+	)
+	return &serpent.Command{
+		Use:        "genman",
+		Short:      "Generate man pages for every command.",
+		Hidden:     true,
+		Middleware: serpent.RequireNArgs(0),
+		Options: serpent.OptionSet{
+			dirOption(&dir, "man"),
+			{
+				Name:        "section",
+				Description: "Manual section of the pages.",
+				Flag:        "section",
+				Default:     "1",
+				Value:       serpent.Int64Of(&section),
+			},
+		},
+		Handler: func(inv *serpent.Invocation) error {
+			r := root(inv)
+			return WriteManPages(dir, r.Tree(), ManOptions{
+				Section: int(section),
+				Version: r.Version,
+			})
+		},
+	}
+}
+
+func gencompCommand() *serpent.Command {
+	var dir string
+	return &serpent.Command{
+		Use:        "gencomp",
+		Short:      "Generate completion scripts for every supported shell.",
+		Hidden:     true,
+		Middleware: serpent.RequireNArgs(0),
+		Options:    serpent.OptionSet{dirOption(&dir, "completions")},
+		Handler: func(inv *serpent.Invocation) error {
+			return WriteCompletions(dir, root(inv).Name())
+		},
+	}
+}
+
+func gentreeCommand() *serpent.Command {
+	return &serpent.Command{
+		Use:        "gentree",
+		Short:      "Print the command tree as JSON.",
+		Hidden:     true,
+		Middleware: serpent.RequireNArgs(0),
+		Handler: func(inv *serpent.Invocation) error {
+			enc := json.NewEncoder(inv.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(root(inv).Tree())
+		},
+	}
+}
+
+// completionFiles maps shells to the conventional names of their completion
+// scripts, given the program name.
+var completionFiles = map[string]string{
+	completion.ShellBash:       "%s.bash",
+	completion.ShellZsh:        "_%s",
+	completion.ShellFish:       "%s.fish",
+	completion.ShellPowershell: "%s.ps1",
+}
+
+// WriteCompletions writes the completion script of programName for every
+// supported shell to dir.
+func WriteCompletions(dir, programName string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for shellName, pattern := range completionFiles {
+		shell, err := completion.ShellByName(shellName, programName)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, fmt.Sprintf(pattern, programName))
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = shell.WriteCompletion(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("write %s completion: %w", shellName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package maintenance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/maintenance"
+)
+
+func rootCmd() *serpent.Command {
+	var name string
+	root := &serpent.Command{
+		Use:     "app",
+		Short:   "Manage the app.",
+		Version: "1.2.3",
+		Children: []*serpent.Command{
+			{
+				Use:   "server",
+				Short: "Manage servers.",
+				Children: []*serpent.Command{
+					{
+						Use:   "start <name>",
+						Short: "Start a server.",
+						Long:  "Start a server by name.",
+						Options: serpent.OptionSet{
+							{
+								Name:        "name",
+								Flag:        "name",
+								Env:         "APP_NAME",
+								Default:     "default-server",
+								Description: "Name of the server.",
+								Value:       serpent.StringOf(&name),
+							},
+						},
+						Handler: func(*serpent.Invocation) error { return nil },
+					},
+				},
+			},
+			{
+				Use:     "secret",
+				Hidden:  true,
+				Handler: func(*serpent.Invocation) error { return nil },
+			},
+		},
+	}
+	root.AddSubcommands(maintenance.Commands()...)
+	return root
+}
+
+func TestCommands(t *testing.T) {
+	t.Parallel()
+
+	t.Run("GenDocs", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		err := rootCmd().Invoke("gendocs", "--dir", dir).Run()
+		require.NoError(t, err)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		require.Equal(t, []string{"app.md", "app_server.md", "app_server_start.md"}, names)
+
+		page, err := os.ReadFile(filepath.Join(dir, "app_server_start.md"))
+		require.NoError(t, err)
+		require.Contains(t, string(page), "app server start <name>")
+		require.Contains(t, string(page), "### --name")
+		require.Contains(t, string(page), "`$APP_NAME`")
+		require.Contains(t, string(page), "`default-server`")
+
+		page, err = os.ReadFile(filepath.Join(dir, "app.md"))
+		require.NoError(t, err)
+		require.Contains(t, string(page), "[server](app_server.md)")
+		require.NotContains(t, string(page), "gendocs")
+	})
+
+	t.Run("GenMan", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		err := rootCmd().Invoke("genman", "--dir", dir, "--section", "8").Run()
+		require.NoError(t, err)
+
+		page, err := os.ReadFile(filepath.Join(dir, "app-server-start.8"))
+		require.NoError(t, err)
+		require.Contains(t, string(page), `.TH "APP-SERVER-START" "8" "" "app 1.2.3"`)
+		require.Contains(t, string(page), `\fB\-\-name\fR`)
+		require.Contains(t, string(page), `\fBapp\-server\fR(8)`)
+		_, err = os.Stat(filepath.Join(dir, "app.8"))
+		require.NoError(t, err)
+	})
+
+	t.Run("GenComp", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		err := rootCmd().Invoke("gencomp", "--dir", dir).Run()
+		require.NoError(t, err)
+
+		for _, name := range []string{"app.bash", "_app", "app.fish", "app.ps1"} {
+			script, err := os.ReadFile(filepath.Join(dir, name))
+			require.NoError(t, err, name)
+			require.NotEmpty(t, script, name)
+		}
+	})
+
+	t.Run("Serpent", func(t *testing.T) {
+		t.Parallel()
+
+		var names []string
+		for _, cmd := range serpent.MaintenanceCommands() {
+			require.True(t, cmd.Hidden, cmd.Name())
+			names = append(names, cmd.Name())
+		}
+		require.Equal(t, []string{"gendocs", "genman", "gencomp", "gentree"}, names)
+	})
+
+	t.Run("GenTree", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		inv := rootCmd().Invoke("gentree")
+		inv.Stdout = &out
+		require.NoError(t, inv.Run())
+
+		var tree serpent.CommandTree
+		require.NoError(t, json.Unmarshal(out.Bytes(), &tree))
+		require.Equal(t, "app", tree.Name)
+		require.Len(t, tree.Children, 1)
+		start := tree.Children[0].Children[0]
+		require.Equal(t, "app server start", start.Name)
+		require.Len(t, start.Options, 1)
+		require.Equal(t, "APP_NAME", start.Options[0].Env)
+	})
+}
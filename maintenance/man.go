@@ -0,0 +1,104 @@
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bketelsen/serpent"
+)
+
+// ManOptions configures WriteManPages.
+type ManOptions struct {
+	// Section is the manual section of the pages, 1 if unset.
+	Section int
+	// Version is shown in the footer of the pages.
+	Version string
+}
+
+// WriteManPages writes a roff man page for every command in tree to dir,
+// named after the command's full name, e.g. "app-server-start.1".
+func WriteManPages(dir string, tree serpent.CommandTree, opts ManOptions) error {
+	if opts.Section == 0 {
+		opts.Section = 1
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return writeManPage(dir, tree, nil, opts)
+}
+
+func writeManPage(dir string, t serpent.CommandTree, parent *serpent.CommandTree, opts ManOptions) error {
+	root, _, _ := strings.Cut(t.Name, " ")
+	name := manName(t)
+
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, ".TH %q %q \"\" %q %q\n",
+		strings.ToUpper(name), fmt.Sprint(opts.Section), strings.TrimSpace(root+" "+opts.Version), root+" Manual")
+	_, _ = fmt.Fprintf(&sb, ".SH NAME\n%s", roffEscape(name))
+	if t.Short != "" {
+		_, _ = fmt.Fprintf(&sb, " \\- %s", roffEscape(t.Short))
+	}
+	_, _ = fmt.Fprintf(&sb, "\n.SH SYNOPSIS\n\\fB%s\\fR\n", roffEscape(usage(t)))
+	if t.Long != "" {
+		_, _ = fmt.Fprintf(&sb, ".SH DESCRIPTION\n%s\n", roffEscape(strings.TrimSpace(t.Long)))
+	}
+
+	if len(t.Options) > 0 {
+		_, _ = sb.WriteString(".SH OPTIONS\n")
+		for _, opt := range t.Options {
+			_, _ = fmt.Fprintf(&sb, ".TP\n\\fB%s\\fR \\fI%s\\fR\n", roffEscape(optionHeading(opt)), roffEscape(optionType(opt)))
+			if opt.Description != "" {
+				_, _ = fmt.Fprintf(&sb, "%s\n", roffEscape(opt.Description))
+			}
+			if opt.Env != "" {
+				_, _ = fmt.Fprintf(&sb, ".br\nEnvironment: \\fB$%s\\fR\n", roffEscape(opt.Env))
+			}
+			if opt.Default != "" {
+				_, _ = fmt.Fprintf(&sb, ".br\nDefault: %s\n", roffEscape(opt.Default))
+			}
+		}
+	}
+
+	var seeAlso []string
+	if parent != nil {
+		seeAlso = append(seeAlso, fmt.Sprintf("\\fB%s\\fR(%d)", roffEscape(manName(*parent)), opts.Section))
+	}
+	for _, child := range t.Children {
+		seeAlso = append(seeAlso, fmt.Sprintf("\\fB%s\\fR(%d)", roffEscape(manName(child)), opts.Section))
+	}
+	if len(seeAlso) > 0 {
+		_, _ = fmt.Fprintf(&sb, ".SH SEE ALSO\n%s\n", strings.Join(seeAlso, ", "))
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s.%d", name, opts.Section))
+	if err := os.WriteFile(path, []byte(sb.String()), 0o600); err != nil {
+		return err
+	}
+	for _, child := range t.Children {
+		if err := writeManPage(dir, child, &t, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manName returns the name of the man page of t, e.g. "app-server-start".
+func manName(t serpent.CommandTree) string {
+	return strings.ReplaceAll(t.Name, " ", "-")
+}
+
+// roffEscape escapes s for use as text in a roff document.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		// Lines starting with a control character would be requests.
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
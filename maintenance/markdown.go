@@ -0,0 +1,123 @@
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bketelsen/serpent"
+)
+
+// WriteMarkdown writes a Markdown page for every command in tree to dir,
+// named after the command's full name, e.g. "app_server_start.md".
+func WriteMarkdown(dir string, tree serpent.CommandTree) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return writeMarkdown(dir, tree, nil)
+}
+
+func writeMarkdown(dir string, t serpent.CommandTree, parent *serpent.CommandTree) error {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "# %s\n\n", t.Name)
+	if t.Short != "" {
+		_, _ = fmt.Fprintf(&sb, "%s\n\n", t.Short)
+	}
+	_, _ = fmt.Fprintf(&sb, "## Usage\n\n```console\n%s\n```\n\n", usage(t))
+	if len(t.Aliases) > 0 {
+		_, _ = fmt.Fprintf(&sb, "Aliases: %s\n\n", strings.Join(t.Aliases, ", "))
+	}
+	if t.Long != "" {
+		_, _ = fmt.Fprintf(&sb, "%s\n\n", strings.TrimSpace(t.Long))
+	}
+
+	if len(t.Children) > 0 {
+		_, _ = sb.WriteString("## Subcommands\n\n| Name | Purpose |\n| ---- | ------- |\n")
+		for _, child := range t.Children {
+			_, name, _ := cutLast(child.Name, " ")
+			_, _ = fmt.Fprintf(&sb, "| [%s](%s) | %s |\n", name, pageName(child, ".md"), child.Short)
+		}
+		_, _ = sb.WriteString("\n")
+	}
+
+	if len(t.Options) > 0 {
+		_, _ = sb.WriteString("## Options\n")
+		for _, opt := range t.Options {
+			_, _ = fmt.Fprintf(&sb, "\n### %s\n\n", optionHeading(opt))
+			_, _ = sb.WriteString("|             |     |\n| ----------- | --- |\n")
+			_, _ = fmt.Fprintf(&sb, "| Type        | `%s` |\n", optionType(opt))
+			if opt.Env != "" {
+				_, _ = fmt.Fprintf(&sb, "| Environment | `$%s` |\n", opt.Env)
+			}
+			if opt.YAML != "" {
+				_, _ = fmt.Fprintf(&sb, "| YAML        | `%s` |\n", opt.YAML)
+			}
+			if opt.Default != "" {
+				_, _ = fmt.Fprintf(&sb, "| Default     | `%s` |\n", opt.Default)
+			}
+			if opt.Description != "" {
+				_, _ = fmt.Fprintf(&sb, "\n%s\n", opt.Description)
+			}
+		}
+		_, _ = sb.WriteString("\n")
+	}
+
+	if parent != nil {
+		_, _ = fmt.Fprintf(&sb, "See also [%s](%s).\n", parent.Name, pageName(*parent, ".md"))
+	}
+
+	err := os.WriteFile(filepath.Join(dir, pageName(t, ".md")), []byte(sb.String()), 0o600)
+	if err != nil {
+		return err
+	}
+	for _, child := range t.Children {
+		if err := writeMarkdown(dir, child, &t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageName returns the file name of the page of t.
+func pageName(t serpent.CommandTree, ext string) string {
+	return strings.ReplaceAll(t.Name, " ", "_") + ext
+}
+
+// usage returns the usage line of t, e.g. "app server start <name>".
+func usage(t serpent.CommandTree) string {
+	parent, _, _ := cutLast(t.Name, " ")
+	if parent == "" {
+		return t.Use
+	}
+	return parent + " " + t.Use
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return "", s, false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// optionHeading returns the flags of opt, e.g. "--verbose, -v", or its name
+// if it has no flag.
+func optionHeading(opt serpent.OptionSchema) string {
+	if opt.Flag == "" {
+		return opt.Name
+	}
+	heading := "--" + opt.Flag
+	if opt.FlagShorthand != "" {
+		heading += ", -" + opt.FlagShorthand
+	}
+	return heading
+}
+
+// optionType returns the type of opt as shown in help.
+func optionType(opt serpent.OptionSchema) string {
+	if len(opt.Choices) > 0 {
+		return strings.Join(opt.Choices, "|")
+	}
+	return opt.Type
+}
@@ -33,30 +33,125 @@ func (m cliMessage) String() string {
 	return str.String()
 }
 
+// MessageLevel is the level of a Message.
+type MessageLevel string
+
+const (
+	MessageInfo  MessageLevel = "info"
+	MessageWarn  MessageLevel = "warn"
+	MessageError MessageLevel = "error"
+)
+
+// Kinds of the messages serpent writes itself.
+const (
+	// MessageKindDeprecated warns that a command is deprecated.
+	MessageKindDeprecated = "deprecated"
+	// MessageKindUnknownCommand reports an unknown subcommand below help.
+	MessageKindUnknownCommand = "unknown-command"
+	// MessageKindRerun announces the command line rerun from history.
+	MessageKindRerun = "rerun"
+	// MessageKindLogFile points to the log file of a failed command.
+	MessageKindLogFile = "log-file"
+	// MessageKindRunManifest points to the run manifest of a failed command.
+	MessageKindRunManifest = "run-manifest"
+)
+
+// Message is a message for the user written by serpent itself, or with
+// Info, Warn and Error.
+type Message struct {
+	Level MessageLevel
+	// Kind identifies the messages serpent writes itself, see
+	// MessageKindDeprecated and friends. It's empty for Info, Warn and
+	// Error.
+	Kind   string
+	Header string
+	Lines  []string
+	// Text is the message as rendered for humans.
+	Text string
+}
+
+// MessageHandler handles the messages of an invocation, e.g. to silence or
+// restyle them. See WithMessageHandler.
+type MessageHandler func(inv *Invocation, msg Message)
+
+// DefaultMessageHandler emits msg as an event if EventsEnabled, and writes
+// its Text to stderr otherwise.
+func DefaultMessageHandler(inv *Invocation, msg Message) {
+	if inv.EventsEnabled() {
+		inv.Emit(Event{Kind: EventLog, Level: string(msg.Level), Msg: msg.Header, Lines: msg.Lines})
+		return
+	}
+	_, _ = fmt.Fprint(inv.Stderr, msg.Text)
+}
+
+// WithMessageHandler returns the invocation with all messages, including
+// the framework's own such as deprecation warnings, passed to handler
+// instead of DefaultMessageHandler. For example, to hide deprecation
+// warnings:
+//
+//	inv = inv.WithMessageHandler(func(inv *serpent.Invocation, msg serpent.Message) {
+//		if msg.Kind != serpent.MessageKindDeprecated {
+//			serpent.DefaultMessageHandler(inv, msg)
+//		}
+//	})
+func (inv *Invocation) WithMessageHandler(handler MessageHandler) *Invocation {
+	return inv.with(func(i *Invocation) {
+		i.messageHandler = handler
+	})
+}
+
+// message passes msg to the invocation's message handler. Only errors are
+// passed in quiet mode.
+func (inv *Invocation) message(msg Message) {
+	if msg.Level != MessageError && inv.Quiet() {
+		return
+	}
+	if inv.messageHandler != nil {
+		inv.messageHandler(inv, msg)
+		return
+	}
+	DefaultMessageHandler(inv, msg)
+}
+
 // Warn writes a log to the writer provided.
 func (inv *Invocation) Warn(header string, lines ...string) {
-	_, _ = fmt.Fprint(inv.Stderr, cliMessage{
-		Style:  DefaultStyles.Warn,
-		Prefix: "WARNING: ",
+	inv.message(Message{
+		Level:  MessageWarn,
 		Header: header,
 		Lines:  lines,
-	}.String())
+		Text: cliMessage{
+			Style:  DefaultStyles.Warn,
+			Prefix: "WARNING: ",
+			Header: header,
+			Lines:  lines,
+		}.String(),
+	})
 }
 
 // Info writes a log to the writer provided.
 func (inv *Invocation) Info(header string, lines ...string) {
-	_, _ = fmt.Fprint(inv.Stderr, cliMessage{
+	inv.message(Message{
+		Level:  MessageInfo,
 		Header: header,
 		Lines:  lines,
-	}.String())
+		Text: cliMessage{
+			Header: header,
+			Lines:  lines,
+		}.String(),
+	})
 }
 
 // Error writes a log to the writer provided.
 func (inv *Invocation) Error(header string, lines ...string) {
-	_, _ = fmt.Fprint(inv.Stderr, cliMessage{
-		Style:  DefaultStyles.Error,
-		Prefix: "ERROR: ",
+	inv.message(Message{
+		Level:  MessageError,
 		Header: header,
 		Lines:  lines,
-	}.String())
+		Text: cliMessage{
+			Style:  DefaultStyles.Error,
+			Prefix: "ERROR: ",
+			Header: header,
+			Lines:  lines,
+		}.String(),
+	})
 }
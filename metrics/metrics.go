@@ -0,0 +1,238 @@
+// Package metrics records Prometheus metrics about the commands run by a
+// serpent program, such as a daemon-style subcommand running a server, and
+// exposes them in the Prometheus text format.
+//
+// Wire the middleware into the root command and serve the metrics from the
+// long-running handler:
+//
+//	m := metrics.New("myapp")
+//	root.PersistentMiddleware = m.Middleware()
+//	...
+//	Handler: func(inv *serpent.Invocation) error {
+//		if err := m.Serve(inv, addr); err != nil {
+//			return err
+//		}
+//		...
+//	}
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bketelsen/serpent"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the duration
+// histogram buckets. They span short commands to long-running ones.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600}
+
+// Registry records the invocations, errors and durations of commands by
+// their full name. It's safe for concurrent use.
+type Registry struct {
+	// Namespace prefixes the names of the metrics, e.g. "myapp" for
+	// "myapp_command_invocations_total".
+	Namespace string
+	// Buckets are the upper bounds of the duration histogram buckets,
+	// DefaultBuckets if nil. They must be sorted and not change after the
+	// first observation.
+	Buckets []float64
+
+	mu       sync.Mutex
+	commands map[string]*commandMetrics
+}
+
+// commandMetrics are the metrics of one command.
+type commandMetrics struct {
+	invocations int64
+	errors      int64
+	running     int64
+	// buckets are the cumulative counts of durations per bucket.
+	buckets []int64
+	sum     float64
+}
+
+// New returns a registry with metrics prefixed with namespace.
+func New(namespace string) *Registry {
+	return &Registry{Namespace: namespace}
+}
+
+// Middleware returns middleware recording the invocation of the command, its
+// duration and whether it failed. It's meant to be used as the
+// PersistentMiddleware of the root command so that it applies to all
+// commands.
+func (r *Registry) Middleware() serpent.MiddlewareFunc {
+	return func(next serpent.HandlerFunc) serpent.HandlerFunc {
+		return func(inv *serpent.Invocation) error {
+			name := inv.Command.FullName()
+			r.start(name)
+			start := time.Now()
+			err := next(inv)
+			r.finish(name, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+func (r *Registry) command(name string) *commandMetrics {
+	if r.commands == nil {
+		r.commands = make(map[string]*commandMetrics)
+	}
+	m, ok := r.commands[name]
+	if !ok {
+		m = &commandMetrics{buckets: make([]int64, len(r.buckets()))}
+		r.commands[name] = m
+	}
+	return m
+}
+
+func (r *Registry) buckets() []float64 {
+	if r.Buckets == nil {
+		return DefaultBuckets
+	}
+	return r.Buckets
+}
+
+func (r *Registry) start(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.command(name)
+	m.invocations++
+	m.running++
+}
+
+func (r *Registry) finish(name string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.command(name)
+	m.running--
+	if err != nil {
+		m.errors++
+	}
+	seconds := d.Seconds()
+	m.sum += seconds
+	for i, le := range r.buckets() {
+		if seconds <= le {
+			m.buckets[i]++
+		}
+	}
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.commands))
+	for name := range r.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	family := func(name, typ, help string, fn func(m *commandMetrics, label string)) {
+		_, _ = fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", r.metricName(name), help, r.metricName(name), typ)
+		for _, command := range names {
+			fn(r.commands[command], `command="`+escapeLabel(command)+`"`)
+		}
+	}
+	family("command_invocations_total", "counter", "Number of command invocations.", func(m *commandMetrics, label string) {
+		_, _ = fmt.Fprintf(&sb, "%s{%s} %d\n", r.metricName("command_invocations_total"), label, m.invocations)
+	})
+	family("command_errors_total", "counter", "Number of command invocations that returned an error.", func(m *commandMetrics, label string) {
+		_, _ = fmt.Fprintf(&sb, "%s{%s} %d\n", r.metricName("command_errors_total"), label, m.errors)
+	})
+	family("command_running", "gauge", "Number of command invocations in progress.", func(m *commandMetrics, label string) {
+		_, _ = fmt.Fprintf(&sb, "%s{%s} %d\n", r.metricName("command_running"), label, m.running)
+	})
+	family("command_duration_seconds", "histogram", "Duration of completed command invocations.", func(m *commandMetrics, label string) {
+		name := r.metricName("command_duration_seconds")
+		for i, le := range r.buckets() {
+			_, _ = fmt.Fprintf(&sb, "%s_bucket{%s,le=%q} %d\n", name, label, formatFloat(le), m.buckets[i])
+		}
+		completed := m.invocations - m.running
+		_, _ = fmt.Fprintf(&sb, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, label, completed)
+		_, _ = fmt.Fprintf(&sb, "%s_sum{%s} %s\n", name, label, formatFloat(m.sum))
+		_, _ = fmt.Fprintf(&sb, "%s_count{%s} %d\n", name, label, completed)
+	})
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+func (r *Registry) metricName(name string) string {
+	if r.Namespace == "" {
+		return name
+	}
+	return r.Namespace + "_" + name
+}
+
+// Handler returns an HTTP handler serving the metrics in the Prometheus
+// text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// Serve serves the metrics at /metrics on addr until the invocation's
+// context is canceled. It returns once the listener is open, so that errors
+// such as the address being in use are reported to the handler.
+func (r *Registry) Serve(inv *serpent.Invocation, addr string) error {
+	var (
+		ln  net.Listener
+		err error
+	)
+	if inv.Net != nil {
+		ln, err = inv.Net.Listen("tcp", addr)
+	} else {
+		ln, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("listen for metrics on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-inv.Context().Done()
+		_ = srv.Close()
+	}()
+	go func() {
+		err := srv.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			inv.Warn("Metrics server stopped.", err.Error())
+		}
+	}()
+	return nil
+}
+
+// AddressOption returns a --metrics-address option for the address Serve
+// listens on, such as "127.0.0.1:9090". Metrics are usually not served if
+// it's empty.
+func AddressOption(addr *string) serpent.Option {
+	return serpent.Option{
+		Name:        "metrics-address",
+		Description: "Address to serve Prometheus metrics on, disabled if empty.",
+		Flag:        "metrics-address",
+		Value:       serpent.StringOf(addr),
+	}
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,105 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/metrics"
+)
+
+func rootCmd(m *metrics.Registry, handler serpent.HandlerFunc) *serpent.Command {
+	return &serpent.Command{
+		Use:                  "app",
+		PersistentMiddleware: m.Middleware(),
+		Children: []*serpent.Command{
+			{Use: "ok", Handler: func(*serpent.Invocation) error { return nil }},
+			{Use: "fail", Handler: func(*serpent.Invocation) error { return errors.New("boom") }},
+			{Use: "serve", Handler: handler},
+		},
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	m := metrics.New("app")
+	m.Buckets = []float64{1, 10}
+	root := rootCmd(m, nil)
+	require.NoError(t, root.Invoke("ok").Run())
+	require.NoError(t, root.Invoke("ok").Run())
+	require.Error(t, root.Invoke("fail").Run())
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE app_command_invocations_total counter",
+		`app_command_invocations_total{command="app ok"} 2`,
+		`app_command_invocations_total{command="app fail"} 1`,
+		`app_command_errors_total{command="app ok"} 0`,
+		`app_command_errors_total{command="app fail"} 1`,
+		`app_command_running{command="app ok"} 0`,
+		"# TYPE app_command_duration_seconds histogram",
+		`app_command_duration_seconds_bucket{command="app ok",le="1"} 2`,
+		`app_command_duration_seconds_bucket{command="app ok",le="+Inf"} 2`,
+		`app_command_duration_seconds_count{command="app fail"} 1`,
+	} {
+		require.Contains(t, body, line+"\n")
+	}
+}
+
+// listenNet records the listener it opens.
+type listenNet struct {
+	ln chan net.Listener
+}
+
+func (n listenNet) Listen(network, _ string) (net.Listener, error) {
+	ln, err := net.Listen(network, "127.0.0.1:0")
+	if err == nil {
+		n.ln <- ln
+	}
+	return ln, err
+}
+
+func TestRegistry_Serve(t *testing.T) {
+	t.Parallel()
+
+	m := metrics.New("")
+	var body string
+	root := rootCmd(m, func(inv *serpent.Invocation) error {
+		nt := inv.Net.(listenNet)
+		if err := m.Serve(inv, "ignored"); err != nil {
+			return err
+		}
+		ln := <-nt.ln
+		req, err := http.NewRequestWithContext(inv.Context(), http.MethodGet, "http://"+ln.Addr().String()+"/metrics", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		byt, err := io.ReadAll(resp.Body)
+		body = string(byt)
+		return err
+	})
+
+	inv := root.Invoke("serve").WithContext(context.Background())
+	inv.Net = listenNet{ln: make(chan net.Listener, 1)}
+	require.NoError(t, inv.Run())
+	// The serving command is counted while it runs.
+	require.Contains(t, body, `command_running{command="app serve"} 1`)
+}
@@ -18,6 +18,8 @@ const (
 	ValueSourceNone    ValueSource = ""
 	ValueSourceFlag    ValueSource = "flag"
 	ValueSourceEnv     ValueSource = "env"
+	ValueSourceProject ValueSource = "project"
+	ValueSourceProfile ValueSource = "profile"
 	ValueSourceYAML    ValueSource = "yaml"
 	ValueSourceDefault ValueSource = "default"
 )
@@ -25,11 +27,43 @@ const (
 var valueSourcePriority = []ValueSource{
 	ValueSourceFlag,
 	ValueSourceEnv,
+	ValueSourceProject,
+	ValueSourceProfile,
 	ValueSourceYAML,
 	ValueSourceDefault,
 	ValueSourceNone,
 }
 
+// ValueOrigin describes where the value of an option came from in more
+// detail than its ValueSource, e.g. the environment variable, config file or
+// flag form that set it.
+type ValueOrigin struct {
+	Source ValueSource `json:"source,omitempty"`
+	// Detail is the environment variable name, config file path or flag
+	// form the value was read from, if known.
+	Detail string `json:"detail,omitempty"`
+}
+
+// String returns the origin as it's shown to users, e.g. "env APP_TOKEN"
+// or "yaml /etc/app.yaml".
+func (o ValueOrigin) String() string {
+	if o.Detail == "" {
+		return string(o.Source)
+	}
+	return string(o.Source) + " " + o.Detail
+}
+
+// OptionScope controls which config files may set an option.
+type OptionScope string
+
+const (
+	// ScopeUser options may only be set in user config files.
+	ScopeUser OptionScope = ""
+	// ScopeProject options may also be set in project config files, see
+	// ProjectConfigPath.
+	ScopeProject OptionScope = "project"
+)
+
 // Option is a configuration option for a CLI application.
 type Option struct {
 	Name        string `json:"name,omitempty"`
@@ -38,24 +72,54 @@ type Option struct {
 	// `ValueSource != ValueSourceNone`
 	// If `Default` is set, then `Required` is ignored.
 	Required bool `json:"required,omitempty"`
+	// MinCount and MaxCount bound the number of values of array options,
+	// such as StringArray and EnumArray, e.g. to accept at most 5 --tag
+	// flags. Zero means no bound. The values set by any source count, so a
+	// MinCount makes the option required.
+	MinCount int `json:"min_count,omitempty"`
+	MaxCount int `json:"max_count,omitempty"`
 
 	// Flag is the long name of the flag used to configure this option. If unset,
 	// flag configuring is disabled.
 	Flag string `json:"flag,omitempty"`
 	// FlagShorthand is the one-character shorthand for the flag. If unset, no
-	// shorthand is used.
+	// shorthand is used. As with pflag, "-n5", "-n=5" and "-n 5" set the
+	// same value, and shorthands of flags that take no value, such as
+	// booleans, combine: "-abn5" is "-a -b -n 5".
 	FlagShorthand string `json:"flag_shorthand,omitempty"`
+	// Override marks the flag as intentionally shadowing a flag of the same
+	// name inherited from a parent command. Lint reports shadowed flags
+	// without it as errors.
+	Override bool `json:"override,omitempty"`
 
 	// Env is the environment variable used to configure this option. If unset,
 	// environment configuring is disabled.
 	Env string `json:"env,omitempty"`
+	// EnvAliases are alternative environment variables read, in order, when
+	// Env is unset. See OptionSet.EnvAliases.
+	EnvAliases []string `json:"env_aliases,omitempty"`
+	// EmptyEnvMeansUnset treats an empty environment variable as if it was
+	// unset, instead of setting the option to an empty value. It exists for
+	// compatibility with environments that relied on the old behavior, see
+	// OptionSet.LegacyEmptyEnv. Empty variables are always ignored for
+	// options that aren't string-like, since e.g. PORT= can't be parsed as
+	// a number.
+	EmptyEnvMeansUnset bool `json:"empty_env_means_unset,omitempty"`
 
 	// YAML is the YAML key used to configure this option. If unset, YAML
 	// configuring is disabled.
 	YAML string `json:"yaml,omitempty"`
+	// Scope controls which config files may set the option. By default only
+	// user config files may, since project config files come from whatever
+	// repository the user happens to be in.
+	Scope OptionScope `json:"scope,omitempty"`
 
 	// Default is parsed into Value if set.
 	Default string `json:"default,omitempty"`
+	// DefaultDescription is shown in help and generated config files instead
+	// of Default, for defaults that are dynamic or sensitive, e.g. "current
+	// user" or "auto-detected".
+	DefaultDescription string `json:"default_description,omitempty"`
 	// Value includes the types listed in values.go.
 	Value pflag.Value `json:"value,omitempty"`
 
@@ -74,8 +138,37 @@ type Option struct {
 	Hidden bool `json:"hidden,omitempty"`
 
 	ValueSource ValueSource `json:"value_source,omitempty"`
+	// ValueSourceDetail refines ValueSource, see ValueOrigin.
+	ValueSourceDetail string `json:"value_source_detail,omitempty"`
+
+	// AllowIndirection permits the value of a string option to be read from a
+	// file with "@/path/to/file", or from another environment variable with
+	// "env:VARNAME". A leading "@@" escapes a literal "@". This keeps secrets
+	// off the command line.
+	AllowIndirection bool `json:"allow_indirection,omitempty"`
+	// IndirectSource records the reference the value was resolved from, e.g.
+	// "@/path/to/file" or "env:VARNAME", if any.
+	IndirectSource string `json:"indirect_source,omitempty"`
+	// AllowTemplates expands the value as a Go template once it's parsed,
+	// e.g. "/backups/{{ date \"2006-01-02\" }}". Only the env, hostname and
+	// date functions are available, see ExpandTemplate.
+	AllowTemplates bool `json:"allow_templates,omitempty"`
+	// TemplateSource records the template the value was expanded from, if
+	// any.
+	TemplateSource string `json:"template_source,omitempty"`
+	// Sensitive encrypts the value with the ConfigCipher when the option set
+	// is written with MarshalYAML, and decrypts it when a config file is
+	// loaded, so that it isn't stored in plaintext.
+	Sensitive bool `json:"sensitive,omitempty"`
 
 	CompletionHandler CompletionHandlerFunc `json:"-"`
+
+	// OnChange is called with the previous and the new value, as strings,
+	// whenever a value source sets the value: a flag, an environment
+	// variable, a config file, the default, or the resolution of stdin and
+	// indirect values. It may be called several times, in the order the
+	// sources are applied, which helps debugging precedence.
+	OnChange func(old, new string) `json:"-"`
 }
 
 // optionNoMethods is just a wrapper around Option so we can defer to the
@@ -92,6 +185,43 @@ func (o *Option) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, (*optionNoMethods)(o))
 }
 
+// Origin returns where the option's value came from.
+func (o *Option) Origin() ValueOrigin {
+	return ValueOrigin{Source: o.ValueSource, Detail: o.ValueSourceDetail}
+}
+
+// originText returns the origin of the option's value for display, with the
+// template it was expanded from, if any.
+func (o *Option) originText() string {
+	s := o.Origin().String()
+	if o.TemplateSource != "" {
+		s += fmt.Sprintf(" (template %q)", o.TemplateSource)
+	}
+	return s
+}
+
+// valueString returns the option's value as a string, if any.
+func (o *Option) valueString() string {
+	if o.Value == nil {
+		return ""
+	}
+	return o.Value.String()
+}
+
+// changed calls OnChange, if set, once a value source set the value that
+// was previously old.
+func (o *Option) changed(old string) {
+	if o.OnChange != nil {
+		o.OnChange(old, o.valueString())
+	}
+}
+
+// setOrigin marks the option's value as coming from origin.
+func (o *Option) setOrigin(origin ValueOrigin) {
+	o.ValueSource = origin.Source
+	o.ValueSourceDetail = origin.Detail
+}
+
 func (o Option) YAMLPath() string {
 	if o.YAML == "" {
 		return ""
@@ -197,6 +327,41 @@ func (optSet *OptionSet) Add(opts ...Option) {
 	*optSet = append(*optSet, opts...)
 }
 
+// MergePrefixed adds copies of the options of other with their names, flags,
+// environment variables and YAML keys prefixed, so that the options of a
+// reusable component, such as HTTPClientOptions, can be added to many
+// commands without colliding with theirs. With the prefix "http", the flag
+// "proxy" becomes "http-proxy", the environment variable "PROXY" becomes
+// "HTTP_PROXY" and so on. Names already starting with the prefix are kept,
+// and flag shorthands are dropped.
+//
+// The copies share their values with other, so each command needs its own
+// set, e.g. from calling the component's constructor again.
+func (optSet *OptionSet) MergePrefixed(prefix string, other OptionSet) {
+	envPrefix := strings.ToUpper(strings.ReplaceAll(prefix, "-", "_")) + "_"
+	withPrefix := func(s, prefix string) string {
+		if s == "" || strings.HasPrefix(s, prefix) {
+			return s
+		}
+		return prefix + s
+	}
+	for _, opt := range other {
+		opt.Name = withPrefix(opt.Name, prefix+"-")
+		opt.Flag = withPrefix(opt.Flag, prefix+"-")
+		opt.FlagShorthand = ""
+		opt.Env = withPrefix(opt.Env, envPrefix)
+		if opt.EnvAliases != nil {
+			aliases := make([]string, len(opt.EnvAliases))
+			for i, alias := range opt.EnvAliases {
+				aliases[i] = withPrefix(alias, envPrefix)
+			}
+			opt.EnvAliases = aliases
+		}
+		opt.YAML = withPrefix(opt.YAML, prefix+"-")
+		*optSet = append(*optSet, opt)
+	}
+}
+
 // Filter will only return options that match the given filter. (return true)
 func (optSet OptionSet) Filter(filter func(opt Option) bool) OptionSet {
 	cpy := make(OptionSet, 0)
@@ -208,7 +373,9 @@ func (optSet OptionSet) Filter(filter func(opt Option) bool) OptionSet {
 	return cpy
 }
 
-// FlagSet returns a pflag.FlagSet for the OptionSet.
+// FlagSet returns a pflag.FlagSet for the OptionSet. Bool flags can also be
+// turned off with --no-<flag>, unless they start with "no-" or the set
+// already has such a flag.
 func (optSet *OptionSet) FlagSet() *pflag.FlagSet {
 	if optSet == nil {
 		return &pflag.FlagSet{}
@@ -237,22 +404,129 @@ func (optSet *OptionSet) FlagSet() *pflag.FlagSet {
 			Shorthand:   opt.FlagShorthand,
 			Usage:       opt.Description,
 			Value:       val,
-			DefValue:    "",
+			DefValue:    opt.defaultText(),
 			Changed:     false,
 			Deprecated:  "",
 			NoOptDefVal: noOptDefValue,
 			Hidden:      opt.Hidden,
 		})
 	}
+	for _, opt := range *optSet {
+		if !opt.negatable() || fs.Lookup("no-"+opt.Flag) != nil {
+			continue
+		}
+		fs.AddFlag(&pflag.Flag{
+			Name:        "no-" + opt.Flag,
+			Usage:       "Turn off --" + opt.Flag + ".",
+			Value:       negatedBool{b: opt.Value.(*Bool)},
+			DefValue:    "false",
+			NoOptDefVal: "true",
+			Hidden:      true,
+		})
+	}
 	fs.Usage = func() {
 		_, _ = os.Stderr.WriteString("Override (*FlagSet).Usage() to print help text.\n")
 	}
 	return fs
 }
 
+// OptionsFromFlagSet returns Options for the flags of fs, such as those
+// registered by libraries like client-go or glog, so that they show up in
+// help and can be set from the environment. Each option sets the flag's
+// Value, and its environment variable is the flag name in upper case with
+// dashes replaced by underscores, e.g. KUBECONFIG for --kubeconfig. Use
+// OptionSet.MergePrefixed to namespace them.
+//
+// The flag's default is shown in help but not re-applied, as the Value
+// already holds it. Deprecated flags are hidden. Flags without a Value get
+// DiscardValue. Since the flags are parsed by the command, fs itself isn't
+// marked as changed.
+func OptionsFromFlagSet(fs *pflag.FlagSet) OptionSet {
+	var opts OptionSet
+	fs.VisitAll(func(f *pflag.Flag) {
+		opt := Option{
+			Name:        f.Name,
+			Description: f.Usage,
+			Flag:        f.Name,
+			Env:         strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_")),
+			Value:       f.Value,
+			Hidden:      f.Hidden || f.Deprecated != "",
+		}
+		if f.ShorthandDeprecated == "" {
+			opt.FlagShorthand = f.Shorthand
+		}
+		if f.DefValue != "" && f.DefValue != "[]" {
+			opt.DefaultDescription = f.DefValue
+		}
+		switch {
+		case opt.Value == nil:
+			opt.Value = DiscardValue
+		case f.NoOptDefVal != "":
+			opt.Value = noOptDefValue{Value: f.Value, noOptDefVal: f.NoOptDefVal}
+		}
+		opts = append(opts, opt)
+	})
+	return opts
+}
+
+// noOptDefValue carries the NoOptDefVal of an imported flag, e.g. "true" for
+// boolean flags, so that FlagSet keeps it.
+type noOptDefValue struct {
+	pflag.Value
+	noOptDefVal string
+}
+
+func (v noOptDefValue) NoOptDefValue() string {
+	return v.noOptDefVal
+}
+
+// EnvAliases adds the alternative environment variable names returned by
+// aliases for each option's Env to its EnvAliases. It lets applications opt
+// into legacy names, or prefixes added by the environment they run in:
+//
+//	// Homebrew strips all environment variables that do not start with
+//	// HOMEBREW_ from the environment of the commands it runs.
+//	cmd.Options.EnvAliases(serpent.EnvPrefixAliases("HOMEBREW_"))
+func (optSet *OptionSet) EnvAliases(aliases func(name string) []string) {
+	for i := range *optSet {
+		opt := &(*optSet)[i]
+		if opt.Env == "" {
+			continue
+		}
+		opt.EnvAliases = append(opt.EnvAliases, aliases(opt.Env)...)
+	}
+}
+
+// EnvPrefixAliases returns an alias function for OptionSet.EnvAliases that
+// aliases every environment variable with each of prefixes.
+func EnvPrefixAliases(prefixes ...string) func(name string) []string {
+	return func(name string) []string {
+		aliases := make([]string, 0, len(prefixes))
+		for _, prefix := range prefixes {
+			aliases = append(aliases, prefix+name)
+		}
+		return aliases
+	}
+}
+
+// LegacyEmptyEnv sets EmptyEnvMeansUnset on every option, restoring the old
+// behavior of ignoring empty environment variables. There's no way to clear
+// a Default from the environment with it set.
+func (optSet *OptionSet) LegacyEmptyEnv() {
+	for i := range *optSet {
+		(*optSet)[i].EmptyEnvMeansUnset = true
+	}
+}
+
 // ParseEnv parses the given environment variables into the OptionSet.
 // Use EnvsWithPrefix to filter out prefixes.
 func (optSet *OptionSet) ParseEnv(vs []EnvVar) error {
+	return optSet.parseEnv(vs, false)
+}
+
+// parseEnv is ParseEnv, leaving options set by a flag alone if keepFlags is
+// set.
+func (optSet *OptionSet) parseEnv(vs []EnvVar, keepFlags bool) error {
 	if optSet == nil {
 		return nil
 	}
@@ -268,43 +542,51 @@ func (optSet *OptionSet) ParseEnv(vs []EnvVar) error {
 	}
 
 	for i, opt := range *optSet {
-		if opt.Env == "" {
+		if opt.Env == "" || (keepFlags && opt.ValueSource == ValueSourceFlag) {
 			continue
 		}
 
-		envVal, ok := envs[opt.Env]
-		if !ok {
-			// Homebrew strips all environment variables that do not start with `HOMEBREW_`.
-			// This prevented using brew to invoke the Coder agent, because the environment
-			// variables to not get passed down.
-			//
-			// A customer wanted to use their custom tap inside a workspace, which was failing
-			// because the agent lacked the environment variables to authenticate with Git.
-			envVal, ok = envs[`HOMEBREW_`+opt.Env]
-		}
-		// Currently, empty values are treated as if the environment variable is
-		// unset. This behavior is technically not correct as there is now no
-		// way for a user to change a Default value to an empty string from
-		// the environment. Unfortunately, we have old configuration files
-		// that rely on the faulty behavior.
-		//
-		// TODO: We should remove this hack in May 2023, when deployments
-		// have had months to migrate to the new behavior.
-		if !ok || envVal == "" {
+		var (
+			envName string
+			envVal  string
+			ok      bool
+		)
+		for _, envName = range append([]string{opt.Env}, opt.EnvAliases...) {
+			if envVal, ok = envs[envName]; ok {
+				break
+			}
+		}
+		if !ok || (envVal == "" && (opt.EmptyEnvMeansUnset || !acceptsEmpty(opt.Value))) {
 			continue
 		}
 
-		(*optSet)[i].ValueSource = ValueSourceEnv
+		(*optSet)[i].setOrigin(ValueOrigin{Source: ValueSourceEnv, Detail: envName})
+		old := opt.valueString()
 		if err := opt.Value.Set(envVal); err != nil {
 			merr = multierror.Append(
-				merr, fmt.Errorf("parse %q: %w", opt.Name, err),
+				merr, fmt.Errorf("parse %q from %s: %w", opt.Name, envName, err),
 			)
+			continue
 		}
+		opt.changed(old)
 	}
 
 	return merr.ErrorOrNil()
 }
 
+// acceptsEmpty reports whether an empty string is a meaningful value for v,
+// as opposed to a parse error.
+func acceptsEmpty(v pflag.Value) bool {
+	if v == nil {
+		return false
+	}
+	switch v.Type() {
+	case "string", "string-array":
+		return true
+	}
+	return false
+}
+
 // SetDefaults sets the default values for each Option, skipping values
 // that already have a value source.
 func (optSet *OptionSet) SetDefaults() error {
@@ -358,7 +640,7 @@ func (optSet *OptionSet) SetDefaults() error {
 		// set the default, but mark the source for all options.
 		if opts[0].ValueSource != ValueSourceNone {
 			for _, opt := range opts[1:] {
-				opt.ValueSource = opts[0].ValueSource
+				opt.setOrigin(opts[0].Origin())
 			}
 			continue
 		}
@@ -383,19 +665,203 @@ func (optSet *OptionSet) SetDefaults() error {
 		if optWithDefault == nil {
 			continue
 		}
+		old := optWithDefault.valueString()
 		if err := optWithDefault.Value.Set(optWithDefault.Default); err != nil {
 			merr = multierror.Append(
 				merr, fmt.Errorf("parse %q: %w", optWithDefault.Name, err),
 			)
+		} else {
+			optWithDefault.changed(old)
 		}
 		for _, opt := range opts {
-			opt.ValueSource = ValueSourceDefault
+			opt.setOrigin(ValueOrigin{Source: ValueSourceDefault})
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
+
+// negatable reports whether the flag of opt can be turned off with
+// --no-<flag>, see OptionSet.FlagSet.
+func (opt *Option) negatable() bool {
+	_, ok := opt.Value.(*Bool)
+	return ok && opt.Flag != "" && !strings.HasPrefix(opt.Flag, "no-")
+}
+
+// showsNegation reports whether help and completion show the --no-<flag>
+// negation of opt, which they do for negatable flags that are on by default.
+func (opt *Option) showsNegation() bool {
+	return opt.negatable() && opt.Default == "true"
+}
+
+// flagHelp returns the long flag of opt as shown in help, e.g. "--verbose",
+// or "--[no-]color" if its negation is shown.
+func (opt *Option) flagHelp() string {
+	if opt.showsNegation() {
+		return "--[no-]" + opt.Flag
+	}
+	return "--" + opt.Flag
+}
+
+// defaultText returns the default shown to users.
+func (opt *Option) defaultText() string {
+	if opt.DefaultDescription != "" {
+		return opt.DefaultDescription
+	}
+	return opt.Default
+}
+
+// checkGates returns an error for every option that was set although a group
+// it belongs to is gated and the gate is closed in environ.
+func (optSet *OptionSet) checkGates(environ Environ) error {
+	var merr *multierror.Error
+	for _, opt := range *optSet {
+		if opt.ValueSource == ValueSourceNone {
+			continue
+		}
+		if gate := opt.Group.closedGate(environ); gate != "" {
+			merr = multierror.Append(merr, fmt.Errorf(
+				"option %q set by %s is disabled, set %s=1 to enable it", opt.Name, opt.Origin(), gate,
+			))
 		}
 	}
+	return merr.ErrorOrNil()
+}
+
+// checkCounts returns an error for every array option with fewer values than
+// its MinCount or more than its MaxCount.
+func (optSet *OptionSet) checkCounts() error {
+	var merr *multierror.Error
+	for _, opt := range *optSet {
+		if opt.MinCount == 0 && opt.MaxCount == 0 {
+			continue
+		}
+		sv, ok := opt.Value.(pflag.SliceValue)
+		if !ok {
+			continue
+		}
+		n := len(sv.GetSlice())
+		label := fmt.Sprintf("option %q", opt.Name)
+		if opt.Flag != "" {
+			label = "--" + opt.Flag
+		}
+		switch {
+		case opt.MinCount > 0 && n < opt.MinCount:
+			merr = multierror.Append(merr, fmt.Errorf("%s takes at least %s, got %d", label, countValues(opt.MinCount), n))
+		case opt.MaxCount > 0 && n > opt.MaxCount:
+			merr = multierror.Append(merr, fmt.Errorf("%s takes at most %s, got %d", label, countValues(opt.MaxCount), n))
+		}
+	}
+	return merr.ErrorOrNil()
+}
+
+// countHelp describes the MinCount and MaxCount of opt for help, e.g.
+// "Takes at most 5 values.", or returns "" if it has neither.
+func countHelp(opt Option) string {
+	switch {
+	case opt.MinCount > 0 && opt.MaxCount > 0:
+		return fmt.Sprintf("Takes %d to %d values.", opt.MinCount, opt.MaxCount)
+	case opt.MinCount > 0:
+		return fmt.Sprintf("Takes at least %s.", countValues(opt.MinCount))
+	case opt.MaxCount > 0:
+		return fmt.Sprintf("Takes at most %s.", countValues(opt.MaxCount))
+	default:
+		return ""
+	}
+}
+
+// countValues returns "1 value" or "n values".
+func countValues(n int) string {
+	if n == 1 {
+		return "1 value"
+	}
+	return fmt.Sprintf("%d values", n)
+}
+
+// resolveIndirection replaces the values of options with AllowIndirection
+// set that reference a file or environment variable with the contents of the
+// file or variable.
+func (optSet *OptionSet) resolveIndirection(environ Environ) error {
+	if optSet == nil {
+		return nil
+	}
+
+	var merr *multierror.Error
+	for i := range *optSet {
+		opt := &(*optSet)[i]
+		// Clear the source of a previous run of the command.
+		opt.IndirectSource = ""
+		if !opt.AllowIndirection || opt.Value == nil {
+			continue
+		}
 
+		raw := opt.Value.String()
+		var (
+			resolved string
+			err      error
+		)
+		switch {
+		case strings.HasPrefix(raw, "@@"):
+			resolved = raw[1:]
+		case strings.HasPrefix(raw, "@"):
+			var byt []byte
+			byt, err = os.ReadFile(raw[1:])
+			resolved = strings.TrimSuffix(strings.TrimSuffix(string(byt), "\n"), "\r")
+		case strings.HasPrefix(raw, "env:"):
+			var ok bool
+			resolved, ok = environ.Lookup(raw[len("env:"):])
+			if !ok {
+				err = fmt.Errorf("environment variable %q is not set", raw[len("env:"):])
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("resolve %q: %w", opt.Name, err))
+			continue
+		}
+		old := opt.valueString()
+		if err := opt.Value.Set(resolved); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("parse %q: %w", opt.Name, err))
+			continue
+		}
+		opt.changed(old)
+		if !strings.HasPrefix(raw, "@@") {
+			opt.IndirectSource = raw
+		}
+	}
 	return merr.ErrorOrNil()
 }
 
+// shareValueSources marks options that share a Value with the value source
+// of the highest priority option in their group. Unlike SetDefaults, it never
+// sets a value.
+func (optSet *OptionSet) shareValueSources() {
+	if optSet == nil {
+		return
+	}
+
+	groupByValue := make(map[pflag.Value][]*Option)
+	for i := range *optSet {
+		opt := &(*optSet)[i]
+		if opt.Value == nil {
+			continue
+		}
+		groupByValue[opt.Value] = append(groupByValue[opt.Value], opt)
+	}
+	for _, opts := range groupByValue {
+		source := ValueSourceNone
+		for _, opt := range opts {
+			if slices.Index(valueSourcePriority, opt.ValueSource) < slices.Index(valueSourcePriority, source) {
+				source = opt.ValueSource
+			}
+		}
+		for _, opt := range opts {
+			opt.ValueSource = source
+		}
+	}
+}
+
 // ByName returns the Option with the given name, or nil if no such option
 // exists.
 func (optSet OptionSet) ByName(name string) *Option {
@@ -419,3 +885,17 @@ func (optSet OptionSet) ByFlag(flag string) *Option {
 	}
 	return nil
 }
+
+// byFoldedFlag is like ByFlag, ignoring case.
+func (optSet OptionSet) byFoldedFlag(flag string) *Option {
+	if flag == "" {
+		return nil
+	}
+	for i := range optSet {
+		opt := &optSet[i]
+		if strings.EqualFold(opt.Flag, flag) {
+			return opt
+		}
+	}
+	return nil
+}
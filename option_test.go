@@ -2,10 +2,16 @@ package serpent_test
 
 import (
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	serpent "github.com/bketelsen/serpent"
 )
@@ -147,6 +153,30 @@ func TestOptionSet_ParseEnv(t *testing.T) {
 			},
 		}
 
+		err := os.ParseEnv(serpent.ParseEnviron([]string{"CODER_WORKSPACE_NAME="}, "CODER_"))
+		require.NoError(t, err)
+		require.Equal(t, serpent.ValueSourceEnv, os[0].ValueSource)
+
+		err = os.SetDefaults()
+		require.NoError(t, err)
+		require.EqualValues(t, "", workspaceName)
+	})
+
+	t.Run("EmptyValueLegacy", func(t *testing.T) {
+		t.Parallel()
+
+		var workspaceName serpent.String
+
+		os := serpent.OptionSet{
+			serpent.Option{
+				Name:    "Workspace Name",
+				Value:   &workspaceName,
+				Default: "defname",
+				Env:     "WORKSPACE_NAME",
+			},
+		}
+		os.LegacyEmptyEnv()
+
 		err := os.SetDefaults()
 		require.NoError(t, err)
 
@@ -155,6 +185,34 @@ func TestOptionSet_ParseEnv(t *testing.T) {
 		require.EqualValues(t, "defname", workspaceName)
 	})
 
+	t.Run("EmptyValueNonString", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			port    serpent.Int64
+			timeout serpent.Duration
+			verbose serpent.Bool
+		)
+
+		os := serpent.OptionSet{
+			{Name: "port", Value: &port, Default: "8080", Env: "PORT"},
+			{Name: "timeout", Value: &timeout, Default: "5s", Env: "TIMEOUT"},
+			{Name: "verbose", Value: &verbose, Default: "true", Env: "VERBOSE"},
+		}
+
+		err := os.ParseEnv(serpent.ParseEnviron([]string{"PORT=", "TIMEOUT=", "VERBOSE="}, ""))
+		require.NoError(t, err)
+		for _, opt := range os {
+			require.Equal(t, serpent.ValueSourceNone, opt.ValueSource, opt.Name)
+		}
+
+		err = os.SetDefaults()
+		require.NoError(t, err)
+		require.EqualValues(t, 8080, port)
+		require.EqualValues(t, 5*time.Second, timeout)
+		require.EqualValues(t, true, verbose)
+	})
+
 	t.Run("StringSlice", func(t *testing.T) {
 		t.Parallel()
 
@@ -215,6 +273,7 @@ func TestOptionSet_ParseEnv(t *testing.T) {
 				Env:   "AGENT_TOKEN",
 			},
 		}
+		os.EnvAliases(serpent.EnvPrefixAliases("HOMEBREW_"))
 
 		err := os.ParseEnv([]serpent.EnvVar{
 			{Name: "HOMEBREW_AGENT_TOKEN", Value: "foo"},
@@ -222,6 +281,59 @@ func TestOptionSet_ParseEnv(t *testing.T) {
 		require.NoError(t, err)
 		require.EqualValues(t, "foo", agentToken)
 	})
+
+	t.Run("NoAliases", func(t *testing.T) {
+		t.Parallel()
+
+		var agentToken serpent.String
+
+		os := serpent.OptionSet{
+			serpent.Option{
+				Name:  "Agent Token",
+				Value: &agentToken,
+				Env:   "AGENT_TOKEN",
+			},
+		}
+
+		err := os.ParseEnv([]serpent.EnvVar{
+			{Name: "HOMEBREW_AGENT_TOKEN", Value: "foo"},
+		})
+		require.NoError(t, err)
+		require.Empty(t, agentToken)
+	})
+
+	t.Run("EnvAliases", func(t *testing.T) {
+		t.Parallel()
+
+		var token serpent.String
+
+		os := serpent.OptionSet{
+			serpent.Option{
+				Name:  "Token",
+				Value: &token,
+				Env:   "APP_TOKEN",
+			},
+		}
+		os.EnvAliases(func(name string) []string {
+			return []string{"LEGACY_" + name, "OLD_" + name}
+		})
+		require.Equal(t, []string{"LEGACY_APP_TOKEN", "OLD_APP_TOKEN"}, os[0].EnvAliases)
+
+		err := os.ParseEnv([]serpent.EnvVar{
+			{Name: "OLD_APP_TOKEN", Value: "old"},
+			{Name: "LEGACY_APP_TOKEN", Value: "legacy"},
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, "legacy", token)
+		require.Equal(t, "env LEGACY_APP_TOKEN", os[0].Origin().String())
+
+		err = os.ParseEnv([]serpent.EnvVar{
+			{Name: "APP_TOKEN", Value: "new"},
+			{Name: "LEGACY_APP_TOKEN", Value: "legacy"},
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, "new", token)
+	})
 }
 
 func TestOptionSet_JsonMarshal(t *testing.T) {
@@ -303,3 +415,530 @@ func compareOptionsExceptValues(t *testing.T, exp, found serpent.Option) {
 	// UseInstead is the same comparison problem, just check the length
 	require.Equalf(t, len(exp.UseInstead), len(found.UseInstead), "option use instead %q", exp.Name)
 }
+
+func TestOption_OnChange(t *testing.T) {
+	t.Parallel()
+
+	type change struct{ old, new string }
+	cmd := func(changes *[]change) *serpent.Command {
+		var level string
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{
+					Name:    "level",
+					Flag:    "level",
+					Env:     "APP_LEVEL",
+					Default: "info",
+					Value:   serpent.StringOf(&level),
+					OnChange: func(old, new string) {
+						*changes = append(*changes, change{old, new})
+					},
+				},
+			},
+			Handler: func(*serpent.Invocation) error { return nil },
+		}
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		t.Parallel()
+
+		var changes []change
+		require.NoError(t, cmd(&changes).Invoke().Run())
+		require.Equal(t, []change{{"", "info"}}, changes)
+	})
+
+	t.Run("EnvThenFlag", func(t *testing.T) {
+		t.Parallel()
+
+		var changes []change
+		inv := cmd(&changes).Invoke("--level", "debug")
+		inv.Environ.Set("APP_LEVEL", "warn")
+		require.NoError(t, inv.Run())
+		require.Equal(t, []change{{"", "warn"}, {"warn", "debug"}}, changes)
+	})
+}
+
+func TestEnumChoices(t *testing.T) {
+	t.Parallel()
+
+	choices := []serpent.EnumChoice{
+		{Value: "json", Description: "Machine-readable output."},
+		{Value: "table", Description: "Human-readable output.", Aliases: []string{"text"}},
+		{Value: "csv", Hidden: true},
+		{Value: "xml", Deprecated: "Use json instead."},
+	}
+	cmd := func(format *string, formats *[]string) *serpent.Command {
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "format", Flag: "format", Description: "Output format.", Value: serpent.EnumOfChoices(format, choices...)},
+				{Name: "formats", Flag: "formats", Value: serpent.EnumArrayOfChoices(formats, choices...)},
+			},
+			Handler: func(*serpent.Invocation) error { return nil },
+		}
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			format  string
+			formats []string
+		)
+		inv := cmd(&format, &formats).Invoke("--format", "TEXT", "--formats", "JSON,csv,table")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Equal(t, "table", format)
+		require.Equal(t, []string{"json", "csv", "table"}, formats)
+		require.Empty(t, stdio.Stderr.String())
+
+		err := cmd(&format, &formats).Invoke("--format", "yaml").Run()
+		require.ErrorContains(t, err, "invalid choice: yaml, should be one of [json table]")
+	})
+
+	t.Run("Deprecated", func(t *testing.T) {
+		t.Parallel()
+
+		var format string
+		inv := cmd(&format, new([]string)).Invoke("--format", "xml")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Equal(t, "xml", format)
+		require.Contains(t, stdio.Stderr.String(), `"xml" is deprecated for option "format"!. Use json instead.`)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		t.Parallel()
+
+		inv := cmd(new(string), new([]string)).Invoke("--help")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), "json|table")
+		require.Contains(t, stdio.Stdout.String(), "json: Machine-readable output.")
+		require.Contains(t, stdio.Stdout.String(), "table: Human-readable output.")
+		require.NotContains(t, stdio.Stdout.String(), "xml")
+	})
+
+	t.Run("Completion", func(t *testing.T) {
+		t.Parallel()
+
+		inv := cmd(new(string), new([]string)).Invoke("--format", "")
+		inv.Environ.Set(serpent.CompletionModeEnv, "1")
+		inv.Environ.Set(serpent.CompletionDescriptionsEnv, "1")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Equal(t, "json\tMachine-readable output.\ntable\tHuman-readable output.\n", stdio.Stdout.String())
+	})
+}
+
+func TestEnumSet(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Set", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range []struct {
+			in   string
+			want []string
+		}{
+			{in: "a,c", want: []string{"a", "c"}},
+			{in: "C,a,a", want: []string{"a", "c"}},
+			{in: "all,-b", want: []string{"a", "c"}},
+			{in: "-b", want: []string{"a", "c"}},
+			{in: "a,b,-a", want: []string{"b"}},
+			{in: "-all,b", want: []string{"b"}},
+			{in: "", want: nil},
+		} {
+			var got []string
+			require.NoError(t, serpent.EnumSetOf(&got, "a", "b", "c").Set(tc.in), tc.in)
+			require.Equal(t, tc.want, got, tc.in)
+		}
+
+		var got []string
+		err := serpent.EnumSetOf(&got, "a", "b").Set("a,d")
+		require.ErrorContains(t, err, "invalid choice: d")
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		t.Parallel()
+
+		var features []string
+		os := serpent.OptionSet{
+			{Name: "features", YAML: "features", Value: serpent.EnumSetOf(&features, "a", "b", "c")},
+		}
+		var n yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte("features: [all, -b]\n"), &n))
+		require.NoError(t, os.UnmarshalYAML(&n))
+		require.Equal(t, []string{"a", "c"}, features)
+
+		out, err := yaml.Marshal(os[0].Value)
+		require.NoError(t, err)
+		require.Equal(t, "- a\n- c\n", string(out))
+	})
+
+	t.Run("Completion", func(t *testing.T) {
+		t.Parallel()
+
+		cmd := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "features", Flag: "features", Value: serpent.EnumSetOf(new([]string), "a", "b", "c")},
+			},
+			Handler: func(*serpent.Invocation) error { return nil },
+		}
+		inv := cmd.Invoke("--features=a,-b,")
+		inv.Environ.Set(serpent.CompletionModeEnv, "1")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Equal(t, "--features=a,-b,c\n--features=a,-b,all\n", stdio.Stdout.String())
+	})
+}
+
+type logLevel int
+
+func parseLogLevel(s string) (logLevel, error) {
+	for i, name := range []string{"debug", "info", "warn"} {
+		if s == name {
+			return logLevel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown level %q", s)
+}
+
+func (l logLevel) String() string {
+	return []string{"debug", "info", "warn"}[l]
+}
+
+func TestValueOf(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Flag", func(t *testing.T) {
+		t.Parallel()
+
+		var level logLevel
+		cmd := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{
+					Name:    "level",
+					Flag:    "level",
+					Default: "info",
+					Value:   serpent.ValueOf(&level, parseLogLevel, logLevel.String).WithNoOptDefValue("debug"),
+				},
+			},
+			Handler: func(*serpent.Invocation) error { return nil },
+		}
+		require.NoError(t, cmd.Invoke().Run())
+		require.Equal(t, logLevel(1), level)
+		require.NoError(t, cmd.Invoke("--level=warn").Run())
+		require.Equal(t, logLevel(2), level)
+		require.NoError(t, cmd.Invoke("--level").Run())
+		require.Equal(t, logLevel(0), level)
+		require.ErrorContains(t, cmd.Invoke("--level=loud").Run(), `unknown level "loud"`)
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		t.Parallel()
+
+		var level logLevel
+		v := serpent.ValueOf(&level, parseLogLevel, nil)
+		require.Equal(t, "loglevel", v.Type())
+		require.Equal(t, "custom", v.WithType("custom").Type())
+
+		require.NoError(t, yaml.Unmarshal([]byte("warn"), v))
+		require.Equal(t, logLevel(2), v.Value())
+		out, err := yaml.Marshal(v)
+		require.NoError(t, err)
+		require.Equal(t, "warn\n", string(out))
+
+		require.NoError(t, json.Unmarshal([]byte(`"info"`), v))
+		require.Equal(t, logLevel(1), level)
+		byt, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.Equal(t, `"info"`, string(byt))
+	})
+}
+
+type route struct {
+	Src string `json:"src" yaml:"src"`
+	Dst string `json:"dst" yaml:"dst"`
+}
+
+func TestStructSlice(t *testing.T) {
+	t.Parallel()
+
+	newCmd := func(routes *serpent.Struct[[]route], tags *[]string) *serpent.Command {
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "route", Flag: "route", Env: "ROUTES", YAML: "routes", Value: routes},
+				{Name: "tag", Flag: "tag", Value: serpent.StringArrayOf(tags)},
+			},
+			Children: []*serpent.Command{{
+				Use:     "sub",
+				Handler: func(*serpent.Invocation) error { return nil },
+			}},
+			Handler: func(*serpent.Invocation) error { return nil },
+		}
+	}
+
+	t.Run("Flags", func(t *testing.T) {
+		t.Parallel()
+
+		var routes serpent.Struct[[]route]
+		inv := newCmd(&routes, new([]string)).Invoke(
+			"--route", `{"src":"a","dst":"b"}`,
+			"--route", `{src: c, dst: d}`,
+		)
+		require.NoError(t, inv.Run())
+		require.Equal(t, []route{{Src: "a", Dst: "b"}, {Src: "c", Dst: "d"}}, routes.Value)
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Parallel()
+
+		var routes serpent.Struct[[]route]
+		inv := newCmd(&routes, new([]string)).Invoke()
+		inv.Environ.Set("ROUTES", `[{"src":"a","dst":"b"},{"src":"c","dst":"d"}]`)
+		require.NoError(t, inv.Run())
+		require.Equal(t, []route{{Src: "a", Dst: "b"}, {Src: "c", Dst: "d"}}, routes.Value)
+	})
+
+	t.Run("FlagsOverrideEnv", func(t *testing.T) {
+		t.Parallel()
+
+		var routes serpent.Struct[[]route]
+		inv := newCmd(&routes, new([]string)).Invoke("--route", `{"src":"e","dst":"f"}`)
+		inv.Environ.Set("ROUTES", `{"src":"a","dst":"b"}`)
+		require.NoError(t, inv.Run())
+		require.Equal(t, []route{{Src: "e", Dst: "f"}}, routes.Value)
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		t.Parallel()
+
+		var routes serpent.Struct[[]route]
+		os := newCmd(&routes, new([]string)).Options
+		var n yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte("routes:\n  - src: a\n    dst: b\n"), &n))
+		require.NoError(t, os.UnmarshalYAML(&n))
+		require.Equal(t, []route{{Src: "a", Dst: "b"}}, routes.Value)
+	})
+
+	t.Run("Subcommand", func(t *testing.T) {
+		t.Parallel()
+
+		// The arguments are parsed at every depth, which mustn't duplicate
+		// the values of parent flags.
+		var (
+			routes serpent.Struct[[]route]
+			tags   []string
+		)
+		inv := newCmd(&routes, &tags).Invoke("--route", `{"src":"a","dst":"b"}`, "--tag", "x", "sub")
+		require.NoError(t, inv.Run())
+		require.Equal(t, []route{{Src: "a", Dst: "b"}}, routes.Value)
+		require.Equal(t, []string{"x"}, tags)
+	})
+}
+
+func TestOptionSet_MergePrefixed(t *testing.T) {
+	t.Parallel()
+
+	component := func(timeout *string, retries *int64) serpent.OptionSet {
+		return serpent.OptionSet{
+			{Name: "timeout", Flag: "timeout", FlagShorthand: "t", Env: "TIMEOUT", YAML: "timeout", Value: serpent.StringOf(timeout)},
+			{Name: "api-retries", Flag: "api-retries", Env: "RETRIES", EnvAliases: []string{"TRIES"}, Value: serpent.Int64Of(retries)},
+		}
+	}
+	var (
+		timeout, ownTimeout string
+		retries             int64
+	)
+	opts := serpent.OptionSet{
+		{Name: "timeout", Flag: "timeout", Env: "TIMEOUT", YAML: "timeout", Value: serpent.StringOf(&ownTimeout)},
+	}
+	opts.MergePrefixed("api", component(&timeout, &retries))
+
+	require.Len(t, opts, 3)
+	merged := opts.ByName("api-timeout")
+	require.NotNil(t, merged)
+	require.Equal(t, "api-timeout", merged.Flag)
+	require.Empty(t, merged.FlagShorthand)
+	require.Equal(t, "API_TIMEOUT", merged.Env)
+	require.Equal(t, "api-timeout", merged.YAML)
+	// Names already prefixed are kept.
+	merged = opts.ByName("api-retries")
+	require.NotNil(t, merged)
+	require.Equal(t, "api-retries", merged.Flag)
+	require.Equal(t, "API_RETRIES", merged.Env)
+	require.Equal(t, []string{"API_TRIES"}, merged.EnvAliases)
+
+	cmd := &serpent.Command{
+		Use:     "app",
+		Options: opts,
+		Handler: func(inv *serpent.Invocation) error { return nil },
+	}
+	inv := cmd.Invoke("--timeout", "1s", "--api-timeout", "2s")
+	inv.Environ.Set("API_TRIES", "5")
+	fakeIO(inv)
+	require.NoError(t, inv.Run())
+	require.Equal(t, "1s", ownTimeout)
+	require.Equal(t, "2s", timeout)
+	require.EqualValues(t, 5, retries)
+}
+
+func TestOptionsFromFlagSet(t *testing.T) {
+	t.Parallel()
+
+	fs := pflag.NewFlagSet("lib", pflag.ContinueOnError)
+	kubeconfig := fs.String("kubeconfig", "~/.kube/config", "Path to the kubeconfig file.")
+	verbose := fs.BoolP("verbose", "v", false, "Log more.")
+	fs.Int("old-retries", 3, "Retries.")
+	require.NoError(t, fs.MarkDeprecated("old-retries", "use --retries"))
+
+	opts := serpent.OptionsFromFlagSet(fs)
+	require.Len(t, opts, 3)
+	require.Equal(t, "KUBECONFIG", opts.ByName("kubeconfig").Env)
+	require.Equal(t, "~/.kube/config", opts.ByName("kubeconfig").DefaultDescription)
+	require.Equal(t, "v", opts.ByName("verbose").FlagShorthand)
+	require.True(t, opts.ByName("old-retries").Hidden)
+
+	cmd := &serpent.Command{
+		Use:     "app",
+		Options: opts,
+		Handler: func(inv *serpent.Invocation) error { return nil },
+	}
+	inv := cmd.Invoke("-v")
+	inv.Environ.Set("KUBECONFIG", "/tmp/kubeconfig")
+	require.NoError(t, inv.Run())
+	require.True(t, *verbose)
+	require.Equal(t, "/tmp/kubeconfig", *kubeconfig)
+}
+
+func TestOptionSet_NegatableBool(t *testing.T) {
+	t.Parallel()
+
+	newCmd := func(color *bool) *serpent.Command {
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "color", Description: "Colorize output.", Flag: "color", YAML: "color", Default: "true", Value: serpent.BoolOf(color)},
+				{Name: "no-cache", Flag: "no-cache", Value: serpent.BoolOf(new(bool))},
+			},
+			Handler: func(inv *serpent.Invocation) error { return nil },
+		}
+	}
+
+	var color bool
+	cmd := newCmd(&color)
+	require.NoError(t, cmd.Invoke("--no-color").Run())
+	require.False(t, color)
+	require.Equal(t, serpent.ValueOrigin{Source: serpent.ValueSourceFlag, Detail: "--no-color"}, cmd.Options.ByName("color").Origin())
+
+	byt, err := yaml.Marshal(&cmd.Options)
+	require.NoError(t, err)
+	require.Contains(t, string(byt), "color: false")
+	var n yaml.Node
+	require.NoError(t, yaml.Unmarshal(byt, &n))
+	loaded := true
+	require.NoError(t, newCmd(&loaded).Options.UnmarshalYAML(&n))
+	require.False(t, loaded)
+
+	require.NoError(t, newCmd(&color).Invoke("--no-color=false").Run())
+	require.True(t, color)
+	require.Error(t, newCmd(&color).Invoke("--no-no-cache").Run())
+
+	inv := newCmd(&color).Invoke("--help")
+	stdio := fakeIO(inv)
+	require.NoError(t, inv.Run())
+	require.Contains(t, stdio.Stdout.String(), "--[no-]color")
+	require.NotContains(t, stdio.Stdout.String(), "--[no-]no-cache")
+
+	inv = newCmd(&color).Invoke("--")
+	inv.Environ.Set(serpent.CompletionModeEnv, "1")
+	stdio = fakeIO(inv)
+	require.NoError(t, inv.Run())
+	require.Contains(t, stdio.Stdout.String(), "--no-color\n")
+}
+
+func TestOptionSet_NegatableBoolYAML(t *testing.T) {
+	t.Parallel()
+
+	run := func(yamlConfig string, args ...string) (color bool, out string) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(yamlConfig), 0o600))
+
+		var config serpent.YAMLConfigPath
+		cmd := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "config", Flag: "config", Value: &config},
+				{Name: "color", Flag: "color", YAML: "color", Default: "true", Value: serpent.BoolOf(&color)},
+			},
+			Handler: func(inv *serpent.Invocation) error {
+				byt, err := yaml.Marshal(&inv.Command.Options)
+				out = string(byt)
+				return err
+			},
+		}
+		require.NoError(t, cmd.Invoke(append([]string{"--config", path}, args...)...).Run())
+		return color, out
+	}
+
+	// The negation isn't a YAML key of its own: it sets the same value.
+	color, out := run("color: true\n", "--no-color")
+	require.False(t, color)
+	require.Contains(t, out, "color: false")
+	require.NotContains(t, out, "no-color")
+
+	color, out = run("color: false\n")
+	require.False(t, color)
+	require.Contains(t, out, "color: false")
+
+	color, _ = run("color: false\n", "--no-color=false")
+	require.True(t, color)
+}
+
+func TestOption_Counts(t *testing.T) {
+	t.Parallel()
+
+	newCmd := func() *serpent.Command {
+		var tags, levels []string
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "tag", Flag: "tag", Env: "APP_TAGS", MaxCount: 2, Value: serpent.StringArrayOf(&tags)},
+				{Name: "level", Flag: "level", MinCount: 1, MaxCount: 3, Value: serpent.EnumArrayOf(&levels, "a", "b", "c")},
+			},
+			Handler: func(inv *serpent.Invocation) error { return nil },
+		}
+	}
+
+	require.NoError(t, newCmd().Invoke("--tag", "x", "--tag", "y", "--level", "a").Run())
+
+	err := newCmd().Invoke("--tag", "x", "--tag", "y", "--tag", "z", "--level", "a").Run()
+	require.ErrorContains(t, err, "--tag takes at most 2 values, got 3")
+
+	inv := newCmd().Invoke("--level", "a")
+	inv.Environ.Set("APP_TAGS", "x,y,z")
+	require.ErrorContains(t, inv.Run(), "--tag takes at most 2 values, got 3")
+
+	err = newCmd().Invoke().Run()
+	require.ErrorContains(t, err, "--level takes at least 1 value, got 0")
+
+	inv = newCmd().Invoke("--help")
+	stdio := fakeIO(inv)
+	require.NoError(t, inv.Run())
+	require.Contains(t, stdio.Stdout.String(), "Takes at most 2 values.")
+	require.Contains(t, stdio.Stdout.String(), "Takes 1 to 3 values.")
+
+	issues := serpent.Lint(&serpent.Command{
+		Use: "app",
+		Options: serpent.OptionSet{
+			{Name: "name", Flag: "name", MaxCount: 1, Value: serpent.StringOf(new(string))},
+			{Name: "tag", Flag: "tag", MinCount: 3, MaxCount: 2, Value: serpent.StringArrayOf(new([]string))},
+		},
+	})
+	require.Len(t, issues, 2)
+}
@@ -0,0 +1,146 @@
+package serpent
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// OptionsDumpCommand is the hidden built-in command that prints the options
+// of a command as JSON, e.g. "app __options server start". It's meant for
+// external tools such as config management and form builders.
+const OptionsDumpCommand = "__options"
+
+// OptionsDumpVersion is the version of the OptionsDump schema. It's
+// incremented on incompatible changes.
+const OptionsDumpVersion = 1
+
+// OptionsDump is the output of OptionsDumpCommand.
+type OptionsDump struct {
+	Version int `json:"version"`
+	// Command is the full name of the command, e.g. "app server start".
+	Command string         `json:"command"`
+	Options []OptionSchema `json:"options"`
+}
+
+// OptionSchema describes an option without its value.
+type OptionSchema struct {
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	Flag          string `json:"flag,omitempty"`
+	FlagShorthand string `json:"flag_shorthand,omitempty"`
+	Env           string `json:"env,omitempty"`
+	YAML          string `json:"yaml,omitempty"`
+	// Type is the type of the value, e.g. "string" or "duration".
+	Type string `json:"type"`
+	// Choices are the accepted values of enums.
+	Choices []string `json:"choices,omitempty"`
+	// Default is the DefaultDescription if set, or else the Default.
+	Default  string      `json:"default,omitempty"`
+	Required bool        `json:"required,omitempty"`
+	MinCount int         `json:"min_count,omitempty"`
+	MaxCount int         `json:"max_count,omitempty"`
+	Hidden   bool        `json:"hidden,omitempty"`
+	Scope    OptionScope `json:"scope,omitempty"`
+	// Group is the full name of the group, e.g. "Networking / TLS".
+	Group string `json:"group,omitempty"`
+	// Gate is the environment variable enabling the option, if any.
+	Gate       string `json:"gate,omitempty"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	// Inherited is set for options of a parent command.
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// wantsOptionsDump reports whether OptionsDumpCommand is invoked. A command
+// may shadow it with a child of the same name.
+func (inv *Invocation) wantsOptionsDump() bool {
+	if len(inv.Args) == 0 || inv.Args[0] != OptionsDumpCommand {
+		return false
+	}
+	return !slices.ContainsFunc(inv.Command.Children, func(c *Command) bool {
+		return c.Name() == OptionsDumpCommand
+	})
+}
+
+// dumpOptions writes the OptionsDump of the command at path below the
+// invoked command to Stdout.
+func (inv *Invocation) dumpOptions(path []string) error {
+	cmd := inv.Command
+	for _, name := range path {
+		i := slices.IndexFunc(cmd.Children, func(c *Command) bool {
+			return c.Name() == name || slices.Contains(c.Aliases, name)
+		})
+		if i < 0 {
+			return &UnknownSubcommandError{Args: path}
+		}
+		cmd.Children[i].Parent = cmd
+		cmd = cmd.Children[i]
+	}
+
+	dump := OptionsDump{
+		Version: OptionsDumpVersion,
+		Command: cmd.FullName(),
+		Options: []OptionSchema{},
+	}
+	// Deeper options override shallower ones with the same flag, so they're
+	// left out of the dump.
+	seen := make(map[string]bool)
+	for c := cmd; c != nil; c = c.Parent {
+		for _, opt := range c.Options {
+			if opt.Flag != "" {
+				if seen[opt.Flag] {
+					continue
+				}
+				seen[opt.Flag] = true
+			}
+			dump.Options = append(dump.Options, opt.schema(c != cmd))
+		}
+	}
+
+	enc := json.NewEncoder(inv.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dump); err != nil {
+		return fmt.Errorf("encode options: %w", err)
+	}
+	return nil
+}
+
+func (opt *Option) schema(inherited bool) OptionSchema {
+	s := OptionSchema{
+		Name:          opt.Name,
+		Description:   opt.Description,
+		Flag:          opt.Flag,
+		FlagShorthand: opt.FlagShorthand,
+		Env:           opt.Env,
+		YAML:          opt.YAML,
+		Default:       opt.defaultText(),
+		Required:      opt.Required,
+		MinCount:      opt.MinCount,
+		MaxCount:      opt.MaxCount,
+		Hidden:        opt.Hidden,
+		Scope:         opt.Scope,
+		Deprecated:    len(opt.UseInstead) > 0,
+		Inherited:     inherited,
+	}
+	if opt.Value != nil {
+		s.Type = opt.Value.Type()
+	}
+	switch v := opt.Value.(type) {
+	case *Enum:
+		s.Choices = v.Choices
+	case *EnumArray:
+		s.Choices = v.Choices
+	case *EnumSet:
+		s.Choices = v.Choices
+	}
+	if opt.Group != nil {
+		s.Group = opt.Group.FullName()
+		for _, g := range opt.Group.Ancestry() {
+			if g.Gate != "" {
+				s.Gate = g.Gate
+				break
+			}
+		}
+	}
+	return s
+}
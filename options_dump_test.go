@@ -0,0 +1,79 @@
+package serpent_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestOptionsDump(t *testing.T) {
+	t.Parallel()
+
+	cmd := func() *serpent.Command {
+		var (
+			verbose bool
+			format  string
+			port    int64
+		)
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "verbose", Flag: "verbose", Env: "APP_VERBOSE", Value: serpent.BoolOf(&verbose)},
+				{Name: "format", Flag: "format", Value: serpent.EnumOf(&format, "json", "text")},
+			},
+			Children: []*serpent.Command{{
+				Use:     "server",
+				Aliases: []string{"srv"},
+				Options: serpent.OptionSet{
+					{
+						Name:        "port",
+						Description: "Port to listen on.",
+						Flag:        "port",
+						YAML:        "port",
+						Default:     "8080",
+						Group:       &serpent.Group{Name: "Networking", Gate: "APP_EXPERIMENTAL"},
+						Value:       serpent.Int64Of(&port),
+					},
+					{Name: "format", Flag: "format", Value: serpent.StringOf(&format)},
+				},
+				Handler: func(inv *serpent.Invocation) error { return nil },
+			}},
+		}
+	}
+
+	inv := cmd().Invoke(serpent.OptionsDumpCommand, "srv")
+	io := fakeIO(inv)
+	require.NoError(t, inv.Run())
+
+	var dump serpent.OptionsDump
+	require.NoError(t, json.Unmarshal(io.Stdout.Bytes(), &dump))
+	require.Equal(t, serpent.OptionsDump{
+		Version: serpent.OptionsDumpVersion,
+		Command: "app server",
+		Options: []serpent.OptionSchema{
+			{Name: "format", Flag: "format", Type: "string"},
+			{
+				Name:        "port",
+				Description: "Port to listen on.",
+				Flag:        "port",
+				YAML:        "port",
+				Type:        "int",
+				Default:     "8080",
+				Group:       "Networking",
+				Gate:        "APP_EXPERIMENTAL",
+			},
+			{Name: "verbose", Flag: "verbose", Env: "APP_VERBOSE", Type: "bool", Inherited: true},
+		},
+	}, dump)
+
+	inv = cmd().Invoke(serpent.OptionsDumpCommand)
+	io = fakeIO(inv)
+	require.NoError(t, inv.Run())
+	require.Contains(t, io.Stdout.String(), `"choices": [`)
+
+	err := cmd().Invoke(serpent.OptionsDumpCommand, "missing").Run()
+	require.ErrorContains(t, err, `unknown subcommand "missing"`)
+}
@@ -0,0 +1,100 @@
+package serpent
+
+import (
+	"io"
+	"sync"
+)
+
+// OutputStream identifies the stream an output hook observed a write on.
+type OutputStream string
+
+const (
+	OutputStdout OutputStream = "stdout"
+	OutputStderr OutputStream = "stderr"
+)
+
+// OutputHook observes a write to Stdout or Stderr. p must not be retained or
+// modified.
+type OutputHook func(stream OutputStream, p []byte)
+
+// outputHooks is shared between copies of an Invocation so that hooks added
+// by middleware observe writes made by the handler.
+type outputHooks struct {
+	mu    sync.Mutex
+	next  int
+	hooks map[int]OutputHook
+}
+
+func (h *outputHooks) call(stream OutputStream, p []byte) {
+	h.mu.Lock()
+	hooks := make([]OutputHook, 0, len(h.hooks))
+	for i := 0; i < h.next; i++ {
+		if hook, ok := h.hooks[i]; ok {
+			hooks = append(hooks, hook)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(stream, p)
+	}
+}
+
+// hookWriter calls the output hooks for every write to w.
+type hookWriter struct {
+	w      io.Writer
+	stream OutputStream
+	hooks  *outputHooks
+}
+
+func (h *hookWriter) Write(p []byte) (int, error) {
+	n, err := h.w.Write(p)
+	if n > 0 {
+		h.hooks.call(h.stream, p[:n])
+	}
+	return n, err
+}
+
+// Fd returns the file descriptor of the wrapped writer so terminal detection
+// keeps working, or an invalid descriptor if it isn't a file.
+func (h *hookWriter) Fd() uintptr {
+	if f, ok := h.w.(interface{ Fd() uintptr }); ok {
+		return f.Fd()
+	}
+	return ^uintptr(0)
+}
+
+// installOutputHooks wraps Stdout and Stderr so writes are observed by
+// output hooks. It's a no-op if they're already wrapped.
+func (inv *Invocation) installOutputHooks() {
+	if inv.outputHooks != nil {
+		return
+	}
+	inv.outputHooks = &outputHooks{hooks: make(map[int]OutputHook)}
+	inv.Stdout = &hookWriter{w: inv.Stdout, stream: OutputStdout, hooks: inv.outputHooks}
+	inv.Stderr = &hookWriter{w: inv.Stderr, stream: OutputStderr, hooks: inv.outputHooks}
+}
+
+// AddOutputHook registers hook to observe all writes to the invocation's
+// Stdout and Stderr, e.g. for logging to a file, redaction checks, or
+// progress aggregation. It returns a function that removes the hook.
+//
+// Writers are wrapped by WithOS. If they haven't been, AddOutputHook wraps
+// them, in which case it must be called before the Stdout and Stderr fields
+// are handed out, such as from middleware.
+func (inv *Invocation) AddOutputHook(hook OutputHook) (remove func()) {
+	inv.installOutputHooks()
+
+	h := inv.outputHooks
+	h.mu.Lock()
+	id := h.next
+	h.next++
+	h.hooks[id] = hook
+	h.mu.Unlock()
+
+	return func() {
+		h.mu.Lock()
+		delete(h.hooks, id)
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,279 @@
+package serpent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// paletteRows is the number of matches shown by the interactive palette.
+const paletteRows = 10
+
+// paletteEntry is a command the palette can select.
+type paletteEntry struct {
+	cmd *Command
+	// path is the command's name relative to the root, e.g. "server start".
+	path string
+	// text is everything the query is also matched against.
+	text string
+}
+
+// PaletteCommand returns a "palette [query]" command that fuzzy finds
+// across the names, descriptions and flags of every visible command. On a
+// terminal, it opens an interactive finder previewing the help of the
+// selected command and runs it on enter. Otherwise, the matches for the
+// query are listed, best first.
+func PaletteCommand() *Command {
+	return &Command{
+		Use:   "palette [query]",
+		Short: "Find and run a command.",
+		Handler: func(inv *Invocation) error {
+			root := inv.Command
+			for root.Parent != nil {
+				root = root.Parent
+			}
+			entries := paletteEntries(root, inv.Command)
+			query := strings.Join(inv.Args, " ")
+
+			in, ok := inv.Stdin.(*os.File)
+			out, outOK := inv.Stdout.(interface{ Fd() uintptr })
+			if !ok || !outOK || !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+				tw := tabwriter.NewWriter(inv.Stdout, 0, 0, 2, ' ', 0)
+				for _, e := range matchPalette(entries, query) {
+					_, _ = fmt.Fprintf(tw, "%s\t%s\n", e.path, e.cmd.Short)
+				}
+				return tw.Flush()
+			}
+
+			e, err := runPalette(inv, in, entries, query)
+			if err != nil || e == nil {
+				return err
+			}
+			return inv.with(func(i *Invocation) {
+				i.Command = root
+				i.Args = strings.Fields(e.path)
+				i.parsedFlags = nil
+			}).Run()
+		},
+	}
+}
+
+// paletteEntries returns the visible descendants of root, leaving out skip.
+func paletteEntries(root, skip *Command) []paletteEntry {
+	var entries []paletteEntry
+	var walk func(cmd *Command, path []string)
+	walk = func(cmd *Command, path []string) {
+		for _, child := range cmd.Children {
+			child.Parent = cmd
+			if child.Hidden || child == skip {
+				continue
+			}
+			childPath := append(slices.Clone(path), child.Name())
+			text := []string{strings.Join(childPath, " "), child.Short}
+			for _, opt := range child.Options {
+				if !opt.Hidden && opt.Flag != "" {
+					text = append(text, "--"+opt.Flag)
+				}
+			}
+			entries = append(entries, paletteEntry{
+				cmd:  child,
+				path: strings.Join(childPath, " "),
+				text: strings.Join(text, " "),
+			})
+			walk(child, childPath)
+		}
+	}
+	walk(root, nil)
+	return entries
+}
+
+// matchPalette returns the entries matching query, best first. Matches on
+// the command path rank above matches on descriptions and flags.
+func matchPalette(entries []paletteEntry, query string) []paletteEntry {
+	type scored struct {
+		paletteEntry
+		score int
+	}
+	var matches []scored
+	for _, e := range entries {
+		score := fuzzyScore(query, e.path)
+		if score >= 0 {
+			score += 1000
+		} else {
+			score = fuzzyScore(query, e.text)
+		}
+		if score >= 0 {
+			matches = append(matches, scored{e, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b scored) int {
+		return b.score - a.score
+	})
+
+	out := make([]paletteEntry, len(matches))
+	for i, m := range matches {
+		out[i] = m.paletteEntry
+	}
+	return out
+}
+
+// fuzzyScore returns how well query matches s as a case-insensitive
+// subsequence, ignoring spaces in query, or -1 if it doesn't match.
+// Consecutive matches and matches at the start of words score higher.
+func fuzzyScore(query, s string) int {
+	q := []rune(strings.ToLower(strings.ReplaceAll(query, " ", "")))
+	runes := []rune(strings.ToLower(s))
+	if len(q) == 0 {
+		return 0
+	}
+
+	// Matching greedily from the first occurrence misses better matches
+	// later on, e.g. "st" in "server start", so try every occurrence of the
+	// first rune.
+	best := -1
+	for start, r := range runes {
+		if r == q[0] {
+			best = max(best, fuzzyScoreFrom(q, runes, start))
+		}
+	}
+	return best
+}
+
+func fuzzyScoreFrom(q, runes []rune, start int) int {
+	var (
+		score int
+		prev  = -2
+		qi    int
+	)
+	for i := start; i < len(runes) && qi < len(q); i++ {
+		if runes[i] != q[qi] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 2
+		}
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) {
+			score += 3
+		}
+		prev = i
+		qi++
+	}
+	if qi < len(q) {
+		return -1
+	}
+	return score
+}
+
+// runPalette runs the interactive finder on the terminal, returning the
+// selected entry or nil if the user cancelled.
+func runPalette(inv *Invocation, in *os.File, entries []paletteEntry, query string) (*paletteEntry, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = term.Restore(int(in.Fd()), state) }()
+
+	// Use the alternate screen so the terminal is left as it was.
+	_, _ = io.WriteString(inv.Stdout, "\x1b[?1049h")
+	defer func() { _, _ = io.WriteString(inv.Stdout, "\x1b[?1049l") }()
+
+	q := []rune(query)
+	selected := 0
+	buf := make([]byte, 64)
+	for {
+		matches := matchPalette(entries, string(q))
+		selected = min(max(selected, 0), max(len(matches)-1, 0))
+		renderPalette(inv, string(q), matches, selected)
+
+		n, err := in.Read(buf)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		key := buf[:n]
+		switch {
+		case bytes.Equal(key, []byte{3}), bytes.Equal(key, []byte{27}):
+			// Ctrl-C or Esc.
+			return nil, nil
+		case bytes.Equal(key, []byte{'\r'}), bytes.Equal(key, []byte{'\n'}):
+			if len(matches) == 0 {
+				continue
+			}
+			return &matches[selected], nil
+		case bytes.Equal(key, []byte{127}), bytes.Equal(key, []byte{8}):
+			if len(q) > 0 {
+				q = q[:len(q)-1]
+				selected = 0
+			}
+		case bytes.Equal(key, []byte("\x1b[A")), bytes.Equal(key, []byte{16}):
+			// Up or Ctrl-P.
+			selected--
+		case bytes.Equal(key, []byte("\x1b[B")), bytes.Equal(key, []byte{14}):
+			// Down or Ctrl-N.
+			selected++
+		default:
+			for len(key) > 0 {
+				r, size := utf8.DecodeRune(key)
+				key = key[size:]
+				if unicode.IsPrint(r) {
+					q = append(q, r)
+					selected = 0
+				}
+			}
+		}
+	}
+}
+
+func renderPalette(inv *Invocation, query string, matches []paletteEntry, selected int) {
+	var b strings.Builder
+	// Raw mode doesn't translate newlines, so every line ends in "\r\n".
+	b.WriteString("\x1b[H\x1b[2J")
+	_, _ = fmt.Fprintf(&b, "%s %s\r\n\r\n", Keyword(">"), query)
+
+	start := 0
+	if selected >= paletteRows {
+		start = selected - paletteRows + 1
+	}
+	for i := start; i < len(matches) && i < start+paletteRows; i++ {
+		marker := "  "
+		path := matches[i].path
+		if i == selected {
+			marker = Keyword("▶ ")
+			path = Keyword(path)
+		}
+		_, _ = fmt.Fprintf(&b, "%s%s  %s\r\n", marker, path, matches[i].cmd.Short)
+	}
+	if len(matches) == 0 {
+		b.WriteString("  No matching commands.\r\n")
+	}
+
+	if len(matches) > 0 {
+		var help bytes.Buffer
+		_ = DefaultHelpFn()(inv.with(func(i *Invocation) {
+			i.Command = matches[selected].cmd
+			i.Args = nil
+			i.Stdout = &help
+		}))
+		lines := strings.Split(strings.TrimSpace(help.String()), "\n")
+		if len(lines) > 15 {
+			lines = lines[:15]
+		}
+		b.WriteString("\r\n")
+		for _, line := range lines {
+			b.WriteString(line + "\r\n")
+		}
+	}
+	_, _ = io.WriteString(inv.Stdout, b.String())
+}
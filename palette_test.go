@@ -0,0 +1,60 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestPaletteCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := func() *serpent.Command {
+		var port int64
+		return &serpent.Command{
+			Use: "app",
+			Children: []*serpent.Command{
+				{
+					Use:   "server",
+					Short: "Manage servers.",
+					Children: []*serpent.Command{
+						{
+							Use:   "start",
+							Short: "Start a server.",
+							Options: serpent.OptionSet{
+								{Name: "port", Flag: "port", Value: serpent.Int64Of(&port)},
+							},
+						},
+						{Use: "stop", Short: "Stop a server."},
+					},
+				},
+				{Use: "secret", Short: "Hidden command.", Hidden: true},
+				{Use: "status", Short: "Show the status."},
+				serpent.PaletteCommand(),
+			},
+		}
+	}
+
+	run := func(args ...string) string {
+		inv := cmd().Invoke(append([]string{"palette"}, args...)...)
+		io := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		return io.Stdout.String()
+	}
+
+	require.Equal(t, "server        Manage servers.\n"+
+		"server start  Start a server.\n"+
+		"server stop   Stop a server.\n"+
+		"status        Show the status.\n", run())
+
+	// Matches on the path rank first, then descriptions and flags.
+	require.Equal(t, "server start  Start a server.\n"+
+		"server stop   Stop a server.\n"+
+		"status        Show the status.\n", run("sst"))
+	require.Equal(t, "server stop   Stop a server.\n"+
+		"server start  Start a server.\n", run("stp"))
+	require.Equal(t, "server start  Start a server.\n", run("port"))
+	require.Empty(t, run("xyz"))
+}
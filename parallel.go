@@ -0,0 +1,152 @@
+package serpent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// progressBarWidth is the number of cells of the progress bar Parallel
+// draws on terminals.
+const progressBarWidth = 30
+
+// ConcurrencyOption returns a --concurrency option for Parallel, defaulting
+// to def workers. A value of 0 or less uses one worker per CPU.
+func ConcurrencyOption(def int) Option {
+	var n int64
+	return Option{
+		Name:        "concurrency",
+		Description: "Number of items to process at once, 0 for one per CPU.",
+		Flag:        "concurrency",
+		Default:     strconv.Itoa(def),
+		Value:       Int64Of(&n),
+	}
+}
+
+// Parallel calls worker for every item, running as many workers at once as
+// the concurrency option, usually a ConcurrencyOption, allows. Progress is
+// drawn as a progress bar on a terminal, and reported per item otherwise.
+//
+// Every item is processed even if some fail, and the errors are joined,
+// each prefixed with its item. Ctrl-C cancels the context passed to the
+// workers and skips the items that haven't started yet.
+func Parallel[T any](inv *Invocation, concurrency Option, items []T, worker func(ctx context.Context, item T) error) error {
+	n, err := strconv.Atoi(concurrency.Value.String())
+	if err != nil {
+		return fmt.Errorf("parse %q: %w", concurrency.Name, err)
+	}
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+
+	ctx, stop := inv.SignalNotifyContext(inv.Context(), os.Interrupt)
+	defer stop()
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, n)
+		errs     = make([]error, len(items))
+		progress = newParallelProgress(inv, len(items))
+	)
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, item T) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := worker(ctx, item)
+			if err != nil {
+				errs[i] = fmt.Errorf("%v: %w", item, err)
+			}
+			progress.done(fmt.Sprint(item), err)
+		}(i, item)
+	}
+	wg.Wait()
+	progress.finish()
+
+	err = errors.Join(errs...)
+	if ctx.Err() != nil {
+		err = errors.Join(ctx.Err(), err)
+	}
+	return err
+}
+
+// parallelProgress reports the progress of Parallel.
+type parallelProgress struct {
+	inv   *Invocation
+	total int
+	// bar is set if progress is drawn as a progress bar.
+	bar bool
+
+	mu        sync.Mutex
+	completed int
+}
+
+func newParallelProgress(inv *Invocation, total int) *parallelProgress {
+	p := &parallelProgress{inv: inv, total: total}
+	if f, ok := inv.Stderr.(interface{ Fd() uintptr }); ok && !inv.EventsEnabled() && !inv.Quiet() {
+		p.bar = term.IsTerminal(int(f.Fd()))
+	}
+	p.draw()
+	return p
+}
+
+// done records that item is done.
+func (p *parallelProgress) done(item string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.completed++
+	msg := "done"
+	if err != nil {
+		msg = "failed: " + err.Error()
+	}
+	switch {
+	case err == nil && p.inv.Quiet():
+		// Only failures are reported in quiet mode.
+	case p.inv.EventsEnabled():
+		level := "info"
+		if err != nil {
+			level = "error"
+		}
+		p.inv.Emit(Event{Kind: EventProgress, Level: level, Step: item, Msg: msg, Completed: p.completed, Total: p.total})
+	case p.bar:
+		// Failures are kept above the progress bar.
+		if err != nil {
+			_, _ = fmt.Fprintf(p.inv.Stderr, "\r\x1b[K%s %s\n", Keyword("["+item+"]"), msg)
+		}
+		p.draw()
+	default:
+		_, _ = fmt.Fprintf(p.inv.Stderr, "%s %s (%d/%d)\n", Keyword("["+item+"]"), msg, p.completed, p.total)
+	}
+}
+
+// draw redraws the progress bar, if any.
+func (p *parallelProgress) draw() {
+	if !p.bar || p.total == 0 {
+		return
+	}
+	filled := progressBarWidth * p.completed / p.total
+	_, _ = fmt.Fprintf(p.inv.Stderr, "\r[%s%s] %d/%d",
+		strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled), p.completed, p.total)
+}
+
+// finish ends the progress bar's line.
+func (p *parallelProgress) finish() {
+	if p.bar && p.total > 0 {
+		_, _ = fmt.Fprintln(p.inv.Stderr)
+	}
+}
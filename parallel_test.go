@@ -0,0 +1,88 @@
+package serpent_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestParallel(t *testing.T) {
+	t.Parallel()
+
+	run := func(args []string, handler serpent.HandlerFunc) (*ioBufs, error) {
+		concurrency := serpent.ConcurrencyOption(4)
+		cmd := &serpent.Command{
+			Use:     "root",
+			Options: serpent.OptionSet{concurrency},
+			Handler: handler,
+		}
+		inv := cmd.Invoke(args...)
+		io := fakeIO(inv)
+		return io, inv.Run()
+	}
+
+	t.Run("Bounded", func(t *testing.T) {
+		t.Parallel()
+
+		var running, maxRunning atomic.Int64
+		items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+		var sum atomic.Int64
+		io, err := run([]string{"--concurrency", "2"}, func(inv *serpent.Invocation) error {
+			return serpent.Parallel(inv, inv.Command.Options[0], items, func(ctx context.Context, item int) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				sum.Add(int64(item))
+				return nil
+			})
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 36, sum.Load())
+		require.LessOrEqual(t, maxRunning.Load(), int64(2))
+		require.Contains(t, io.Stderr.String(), "done (8/8)")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		io, err := run(nil, func(inv *serpent.Invocation) error {
+			return serpent.Parallel(inv, inv.Command.Options[0], []string{"a", "b", "c"}, func(ctx context.Context, item string) error {
+				if item == "b" {
+					return errors.New("boom")
+				}
+				return nil
+			})
+		})
+		require.ErrorContains(t, err, "b: boom")
+		require.NotContains(t, err.Error(), "a:")
+		require.Contains(t, io.Stderr.String(), "[b] failed: boom")
+		require.Contains(t, io.Stderr.String(), "[a] done")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		t.Parallel()
+
+		var started atomic.Int64
+		_, err := run([]string{"--concurrency", "1"}, func(inv *serpent.Invocation) error {
+			ctx, cancel := context.WithCancel(inv.Context())
+			return serpent.Parallel(inv.WithContext(ctx), inv.Command.Options[0], []int{1, 2, 3}, func(ctx context.Context, item int) error {
+				started.Add(1)
+				cancel()
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.EqualValues(t, 1, started.Load())
+	})
+}
@@ -0,0 +1,155 @@
+package serpent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/pflag"
+
+	"github.com/bketelsen/serpent/internal/filecomplete"
+)
+
+// PathMode changes how a Path is resolved and checked, see PathOf.
+type PathMode int
+
+const (
+	// PathExpandUser expands a leading "~" to the home directory, for
+	// values that don't come from a shell, such as config files.
+	PathExpandUser PathMode = 1 << iota
+	// PathMustExist fails the command if the path doesn't exist.
+	PathMustExist
+	// PathMustBeFile fails the command if the path exists and isn't a
+	// regular file.
+	PathMustBeFile
+	// PathMustBeDir fails the command if the path exists and isn't a
+	// directory. Only directories are completed.
+	PathMustBeDir
+)
+
+var _ pflag.Value = (*Path)(nil)
+
+// Path is a file path value. Before the handler runs, once middleware such
+// as WorkDirMiddleware has run, relative paths are resolved against the
+// WorkDir of the invocation and checked according to its modes. Its flag
+// completes file names.
+type Path struct {
+	p     *string
+	modes PathMode
+}
+
+// PathOf returns a Path setting p, resolved and checked according to modes:
+//
+//	Value: serpent.PathOf(&config, serpent.PathMustExist, serpent.PathExpandUser),
+func PathOf(p *string, modes ...PathMode) *Path {
+	v := &Path{p: p}
+	for _, m := range modes {
+		v.modes |= m
+	}
+	return v
+}
+
+func (p *Path) Set(v string) error {
+	*p.p = v
+	return nil
+}
+
+func (p *Path) String() string {
+	return *p.p
+}
+
+// Value returns the path, which is absolute once resolved.
+func (p *Path) Value() string {
+	return *p.p
+}
+
+func (*Path) Type() string {
+	return "path"
+}
+
+// resolve returns the path made absolute for inv, failing if it doesn't pass
+// the checks of its modes.
+func (p *Path) resolve(inv *Invocation) (string, error) {
+	path := *p.p
+	if path == "" {
+		return "", nil
+	}
+	if p.modes&PathExpandUser != 0 && (path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator))) {
+		home := inv.Environ.Get("HOME")
+		if home == "" {
+			var err error
+			if home, err = os.UserHomeDir(); err != nil {
+				return "", fmt.Errorf("expand %s: %w", path, err)
+			}
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	path = inv.ResolvePath(path)
+
+	if p.modes&(PathMustExist|PathMustBeFile|PathMustBeDir) == 0 {
+		return path, nil
+	}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && p.modes&PathMustExist == 0:
+		return path, nil
+	case errors.Is(err, os.ErrNotExist):
+		return "", fmt.Errorf("%s does not exist", path)
+	case err != nil:
+		return "", err
+	case p.modes&PathMustBeDir != 0 && !info.IsDir():
+		return "", fmt.Errorf("%s is not a directory", path)
+	case p.modes&PathMustBeFile != 0 && !info.Mode().IsRegular():
+		return "", fmt.Errorf("%s is not a file", path)
+	}
+	return path, nil
+}
+
+// complete returns the file names completing word, only directories if the
+// path must be one.
+func (p *Path) complete(word string) []string {
+	var filter func(os.FileInfo) bool
+	if p.modes&PathMustBeDir != 0 {
+		filter = func(info os.FileInfo) bool { return info.IsDir() }
+	}
+	return filecomplete.Complete(word, filter)
+}
+
+// resolvePaths returns next with the Path options of the command and its
+// parents resolved first, so that middleware setting the WorkDir applies.
+func resolvePaths(next HandlerFunc) HandlerFunc {
+	return func(inv *Invocation) error {
+		if err := inv.resolvePaths(); err != nil {
+			return err
+		}
+		return next(inv)
+	}
+}
+
+// resolvePaths resolves the Path options of the command and its parents, see
+// Path.
+func (inv *Invocation) resolvePaths() error {
+	var merr *multierror.Error
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			p, ok := opt.Value.(*Path)
+			if !ok {
+				continue
+			}
+			path, err := p.resolve(inv)
+			if err != nil {
+				label := fmt.Sprintf("option %q", opt.Name)
+				if opt.Flag != "" {
+					label = "--" + opt.Flag
+				}
+				merr = multierror.Append(merr, fmt.Errorf("%s: %w", label, err))
+				continue
+			}
+			*p.p = path
+		}
+	}
+	return merr.ErrorOrNil()
+}
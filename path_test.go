@@ -0,0 +1,87 @@
+package serpent_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestPathOf(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.yaml"), nil, 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "data"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".apprc"), nil, 0o600))
+
+	run := func(t *testing.T, modes []serpent.PathMode, args ...string) (string, error) {
+		t.Helper()
+		var path string
+		cmd := &serpent.Command{
+			Use:        "app",
+			Options:    serpent.OptionSet{serpent.WorkDirOption(), {Name: "path", Flag: "path", Value: serpent.PathOf(&path, modes...)}},
+			Middleware: serpent.WorkDirMiddleware(),
+			Handler:    func(inv *serpent.Invocation) error { return nil },
+		}
+		inv := cmd.Invoke(append([]string{"-C", dir}, args...)...)
+		inv.Environ.Set("HOME", home)
+		return path, inv.Run()
+	}
+
+	for _, tc := range []struct {
+		name  string
+		modes []serpent.PathMode
+		arg   string
+		want  string
+		err   string
+	}{
+		{name: "Relative", arg: "app.yaml", want: filepath.Join(dir, "app.yaml")},
+		{name: "Absolute", arg: filepath.Join(home, "x"), want: filepath.Join(home, "x")},
+		{name: "Missing", arg: "missing", want: filepath.Join(dir, "missing")},
+		{name: "NoExpand", arg: "~/.apprc", want: filepath.Join(dir, "~", ".apprc")},
+		{name: "ExpandUser", modes: []serpent.PathMode{serpent.PathExpandUser, serpent.PathMustExist}, arg: "~/.apprc", want: filepath.Join(home, ".apprc")},
+		{name: "MustExist", modes: []serpent.PathMode{serpent.PathMustExist}, arg: "missing", err: "--path: " + filepath.Join(dir, "missing") + " does not exist"},
+		{name: "MustBeDir", modes: []serpent.PathMode{serpent.PathMustBeDir}, arg: "app.yaml", err: "is not a directory"},
+		{name: "MustBeFile", modes: []serpent.PathMode{serpent.PathMustBeFile}, arg: "data", err: "is not a file"},
+		{name: "MustBeFileMissing", modes: []serpent.PathMode{serpent.PathMustBeFile}, arg: "missing", want: filepath.Join(dir, "missing")},
+		{name: "Empty", modes: []serpent.PathMode{serpent.PathMustExist}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var args []string
+			if tc.arg != "" {
+				args = []string{"--path", tc.arg}
+			}
+			path, err := run(t, tc.modes, args...)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, path)
+		})
+	}
+
+	t.Run("Completion", func(t *testing.T) {
+		t.Parallel()
+
+		var path string
+		cmd := &serpent.Command{
+			Use:     "app",
+			Options: serpent.OptionSet{{Name: "path", Flag: "path", Value: serpent.PathOf(&path, serpent.PathMustBeDir)}},
+			Handler: func(inv *serpent.Invocation) error { return nil },
+		}
+		inv := cmd.Invoke("--path", dir+string(filepath.Separator))
+		inv.Environ.Set(serpent.CompletionModeEnv, "1")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Equal(t, filepath.Join(dir, "data")+string(filepath.Separator)+"\n", stdio.Stdout.String())
+	})
+}
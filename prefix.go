@@ -0,0 +1,85 @@
+package serpent
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+)
+
+// prefixChildren returns the visible children with a name or alias starting
+// with prefix, ignoring case if fold is set, sorted by name.
+func prefixChildren(children map[string]*Command, prefix string, fold bool) []*Command {
+	if fold {
+		prefix = strings.ToLower(prefix)
+	}
+	var matches []*Command
+	for name, child := range children {
+		if fold {
+			name = strings.ToLower(name)
+		}
+		if child.Hidden || !strings.HasPrefix(name, prefix) || slices.Contains(matches, child) {
+			continue
+		}
+		matches = append(matches, child)
+	}
+	slices.SortFunc(matches, func(a, b *Command) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return matches
+}
+
+// selectPrefixChild returns the child matching the prefix arg, see
+// Command.AllowPrefixMatching, or nil if none does. When several match, the
+// user is asked to pick one if stdin is a terminal.
+func (inv *Invocation) selectPrefixChild(children map[string]*Command, arg string) (*Command, error) {
+	if arg == "" {
+		return nil, nil
+	}
+	matches := prefixChildren(children, arg, inv.Command.caseInsensitive())
+	switch {
+	case len(matches) == 0:
+		return nil, nil
+	case len(matches) == 1:
+		return matches[0], nil
+	case !stdinIsTerminal(inv):
+		names := make([]string, len(matches))
+		for i, m := range matches {
+			names[i] = m.Name()
+		}
+		return nil, fmt.Errorf("ambiguous command %q, it could be %s", arg, strings.Join(names, ", "))
+	}
+	return pickCommand(inv, arg, matches)
+}
+
+// pickCommand asks the user to pick one of matches.
+func pickCommand(inv *Invocation, arg string, matches []*Command) (*Command, error) {
+	_, _ = fmt.Fprintf(inv.Stderr, "%q matches several commands:\n", arg)
+	tw := tabwriter.NewWriter(inv.Stderr, 0, 0, 2, ' ', 0)
+	for i, m := range matches {
+		_, _ = fmt.Fprintf(tw, "  %d) %s\t%s\n", i+1, Keyword(m.Name()), m.Short)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(inv.Stderr, "Select a command [1-%d]: ", len(matches))
+
+	line, err := bufio.NewReader(inv.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("ambiguous command %q: no command selected", arg)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(matches) {
+		return nil, fmt.Errorf("ambiguous command %q: invalid selection %q", arg, strings.TrimSpace(line))
+	}
+	return matches[n-1], nil
+}
+
+// stdinIsTerminal reports whether the invocation's stdin is a terminal.
+func stdinIsTerminal(inv *Invocation) bool {
+	f, ok := inv.Stdin.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,243 @@
+package serpent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bketelsen/serpent/files"
+)
+
+// AnnotationProfile marks the option that selects a config file profile.
+const AnnotationProfile = "serpent.profile"
+
+// profilesKey is the top-level config file key holding profiles.
+const profilesKey = "profiles"
+
+// ProfileOption returns a --profile option, also settable with the env
+// environment variable (e.g. MYAPP_PROFILE), that selects a named section
+// under the "profiles" key of the YAML config files:
+//
+//	url: https://example.com
+//	profiles:
+//	  staging:
+//	    url: https://staging.example.com
+//
+// The selected profile's values take precedence over the rest of the config
+// file and defaults, but not over environment variables and flags. When the
+// option isn't set, the profile chosen with "profile use" is selected.
+//
+// The option is usually added to the root command next to a YAMLConfigPath
+// option.
+func ProfileOption(env string) Option {
+	var name string
+	return Option{
+		Name:        "profile",
+		Description: "Name of the config file profile to use.",
+		Flag:        "profile",
+		Env:         env,
+		Value:       StringOf(&name),
+		Annotations: Annotations{}.Mark(AnnotationProfile, "true"),
+	}
+}
+
+// Profile returns the name of the selected profile, or "" if none is
+// selected. ok reports whether profiles are enabled by a ProfileOption on
+// the command or any of its parents.
+func (inv *Invocation) Profile() (name string, ok bool) {
+	name, _, ok = inv.profile()
+	return name, ok
+}
+
+// profile is Profile, additionally reporting whether the profile was
+// selected explicitly by the option rather than by "profile use".
+func (inv *Invocation) profile() (name string, explicit, ok bool) {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if !opt.Annotations.IsSet(AnnotationProfile) {
+				continue
+			}
+			if v := opt.Value.String(); v != "" {
+				return v, true, true
+			}
+			return inv.currentProfile(), false, true
+		}
+	}
+	return "", false, false
+}
+
+// currentProfilePath is where "profile use" records the selected profile.
+func currentProfilePath(inv *Invocation) string {
+	return filepath.Join(stateDir(inv), "profile")
+}
+
+func (inv *Invocation) currentProfile() string {
+	byt, err := os.ReadFile(currentProfilePath(inv))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(byt))
+}
+
+// extractProfiles removes the profiles key from the config document n and
+// returns the profile sections by name, in file order.
+func extractProfiles(n *yaml.Node) (names []string, profiles map[string]*yaml.Node, err error) {
+	root := n
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) != 1 {
+			return nil, nil, fmt.Errorf("expected one node in document, got %d", len(root.Content))
+		}
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, nil
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != profilesKey {
+			continue
+		}
+		section := root.Content[i+1]
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+
+		if section.Kind != yaml.MappingNode {
+			return nil, nil, fmt.Errorf("%q must be a mapping of profile names to options", profilesKey)
+		}
+		profiles = make(map[string]*yaml.Node, len(section.Content)/2)
+		for j := 0; j+1 < len(section.Content); j += 2 {
+			name := section.Content[j].Value
+			names = append(names, name)
+			profiles[name] = section.Content[j+1]
+		}
+		return names, profiles, nil
+	}
+	return nil, nil, nil
+}
+
+// readProfiles reads the profiles of every YAML config file referenced by
+// the command or its parents.
+func (inv *Invocation) readProfiles() (names []string, profiles map[string]*yaml.Node, err error) {
+	profiles = make(map[string]*yaml.Node)
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			path, ok := opt.Value.(*YAMLConfigPath)
+			if !ok || path.String() == "" {
+				continue
+			}
+
+			n, err := readYAMLFile(path.String())
+			if err != nil {
+				return nil, nil, err
+			}
+			fileNames, fileProfiles, err := extractProfiles(n)
+			if err != nil {
+				return nil, nil, fmt.Errorf("decoding yaml: %w", err)
+			}
+			for _, name := range fileNames {
+				if _, ok := profiles[name]; !ok {
+					names = append(names, name)
+				}
+				profiles[name] = fileProfiles[name]
+			}
+		}
+	}
+	return names, profiles, nil
+}
+
+// ProfileCommand returns a "profile" command with list, show and use
+// subcommands for inspecting and selecting the profiles enabled by
+// ProfileOption.
+func ProfileCommand() *Command {
+	return &Command{
+		Use:   "profile",
+		Short: "Manage config file profiles.",
+		Children: []*Command{
+			profileListCommand(),
+			profileShowCommand(),
+			profileUseCommand(),
+		},
+	}
+}
+
+func profileListCommand() *Command {
+	return &Command{
+		Use:        "list",
+		Short:      "List the profiles in the config file.",
+		Middleware: RequireNArgs(0),
+		Handler: func(inv *Invocation) error {
+			names, _, err := inv.readProfiles()
+			if err != nil {
+				return err
+			}
+			current, _ := inv.Profile()
+			for _, name := range names {
+				marker := " "
+				if name == current {
+					marker = "*"
+				}
+				_, _ = fmt.Fprintf(inv.Stdout, "%s %s\n", marker, name)
+			}
+			return nil
+		},
+	}
+}
+
+func profileShowCommand() *Command {
+	return &Command{
+		Use:        "show [name]",
+		Short:      "Show the values of a profile, the selected one by default.",
+		Middleware: RequireRangeArgs(0, 1),
+		Handler: func(inv *Invocation) error {
+			name, _ := inv.Profile()
+			if len(inv.Args) > 0 {
+				name = inv.Args[0]
+			}
+			if name == "" {
+				return errors.New("no profile selected")
+			}
+
+			_, profiles, err := inv.readProfiles()
+			if err != nil {
+				return err
+			}
+			profile, ok := profiles[name]
+			if !ok {
+				return fmt.Errorf("profile %q not found", name)
+			}
+			byt, err := yaml.Marshal(profile)
+			if err != nil {
+				return err
+			}
+			_, err = inv.Stdout.Write(byt)
+			return err
+		},
+	}
+}
+
+func profileUseCommand() *Command {
+	return &Command{
+		Use:        "use <name>",
+		Short:      "Select the profile used when --profile isn't set.",
+		Middleware: RequireNArgs(1),
+		Handler: func(inv *Invocation) error {
+			name := inv.Args[0]
+			_, profiles, err := inv.readProfiles()
+			if err != nil {
+				return err
+			}
+			if _, ok := profiles[name]; !ok {
+				return fmt.Errorf("profile %q not found", name)
+			}
+
+			if err := files.WriteFile(currentProfilePath(inv), []byte(name+"\n"), files.PrivateFile); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(inv.Stdout, "Using profile %s.\n", Keyword(name))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package serpent_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestProfiles(t *testing.T) {
+	t.Parallel()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+url: https://example.com
+region: us
+profiles:
+  staging:
+    url: https://staging.example.com
+  prod:
+    url: https://prod.example.com
+`), 0o600))
+
+	type result struct {
+		url, region string
+		stdout      string
+	}
+	run := func(stateDir string, env map[string]string, args ...string) (result, error) {
+		var (
+			res    result
+			config serpent.YAMLConfigPath
+		)
+		root := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "config", Flag: "config", Value: &config},
+				serpent.ProfileOption("APP_PROFILE"),
+				{Name: "url", Flag: "url", Env: "APP_URL", YAML: "url", Value: serpent.StringOf(&res.url)},
+				{Name: "region", YAML: "region", Value: serpent.StringOf(&res.region)},
+			},
+			Handler: func(inv *serpent.Invocation) error { return nil },
+			Children: []*serpent.Command{
+				serpent.ProfileCommand(),
+			},
+		}
+
+		inv := root.Invoke(append([]string{"--config", configPath}, args...)...)
+		inv.Environ.Set("XDG_STATE_HOME", stateDir)
+		for k, v := range env {
+			inv.Environ.Set(k, v)
+		}
+		io := fakeIO(inv)
+		err := inv.Run()
+		res.stdout = io.Stdout.String()
+		return res, err
+	}
+
+	t.Run("NoProfile", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := run(t.TempDir(), nil)
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com", res.url)
+		require.Equal(t, "us", res.region)
+	})
+
+	t.Run("Flag", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := run(t.TempDir(), nil, "--profile", "staging")
+		require.NoError(t, err)
+		require.Equal(t, "https://staging.example.com", res.url)
+		require.Equal(t, "us", res.region)
+	})
+
+	t.Run("EnvOverridesProfile", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := run(t.TempDir(), map[string]string{
+			"APP_PROFILE": "staging",
+			"APP_URL":     "https://env.example.com",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "https://env.example.com", res.url)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := run(t.TempDir(), nil, "--profile", "missing")
+		require.ErrorContains(t, err, `profile "missing" not found`)
+	})
+
+	t.Run("Commands", func(t *testing.T) {
+		t.Parallel()
+
+		stateDir := t.TempDir()
+
+		res, err := run(stateDir, nil, "profile", "list")
+		require.NoError(t, err)
+		require.Equal(t, "  staging\n  prod\n", res.stdout)
+
+		_, err = run(stateDir, nil, "profile", "use", "missing")
+		require.ErrorContains(t, err, `profile "missing" not found`)
+
+		_, err = run(stateDir, nil, "profile", "use", "prod")
+		require.NoError(t, err)
+
+		res, err = run(stateDir, nil, "profile", "list")
+		require.NoError(t, err)
+		require.Equal(t, "  staging\n* prod\n", res.stdout)
+
+		res, err = run(stateDir, nil, "profile", "show")
+		require.NoError(t, err)
+		require.Equal(t, "url: https://prod.example.com\n", res.stdout)
+
+		res, err = run(stateDir, nil)
+		require.NoError(t, err)
+		require.Equal(t, "https://prod.example.com", res.url)
+
+		res, err = run(stateDir, nil, "--profile", "staging")
+		require.NoError(t, err)
+		require.Equal(t, "https://staging.example.com", res.url)
+	})
+}
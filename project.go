@@ -0,0 +1,74 @@
+package serpent
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+)
+
+var _ pflag.Value = (*ProjectConfigPath)(nil)
+
+// ProjectConfigPath is a value type for a project-level YAML config file,
+// e.g. ".myapp.yaml", whose values take precedence over the YAMLConfigPath
+// files of the user. Unless a path is set explicitly, a file called Name is
+// discovered with FindProjectConfig from the working directory of the
+// invocation, see Invocation.WorkDir.
+//
+// Only options with Scope set to ScopeProject may be set in the file.
+type ProjectConfigPath struct {
+	// Name is the file name searched for.
+	Name string
+
+	path string
+}
+
+// ProjectConfigOf returns a ProjectConfigPath discovering files called name.
+func ProjectConfigOf(name string) *ProjectConfigPath {
+	return &ProjectConfigPath{Name: name}
+}
+
+func (p *ProjectConfigPath) Set(v string) error {
+	p.path = v
+	return nil
+}
+
+func (p *ProjectConfigPath) String() string {
+	return p.path
+}
+
+func (*ProjectConfigPath) Type() string {
+	return "project-config-path"
+}
+
+// Path returns the explicitly set path, or else the one discovered from dir,
+// usually the WorkDir of the invocation. It returns an empty string if
+// there's no project config file.
+func (p *ProjectConfigPath) Path(dir string) string {
+	if p.path != "" || p.Name == "" {
+		return p.path
+	}
+	return FindProjectConfig(dir, p.Name)
+}
+
+// FindProjectConfig walks up from dir looking for a file called name. The
+// search stops at the root of the git repository containing dir, so files
+// outside of the project aren't picked up, or at the file system root when
+// dir isn't in a repository. It returns an empty string if no file is found.
+func FindProjectConfig(dir, name string) string {
+	dir = filepath.Clean(dir)
+	for {
+		path := filepath.Join(dir, name)
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,93 @@
+package serpent_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	nested := filepath.Join(repo, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0o755))
+
+	// Outside the repository, so never found from within it.
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".app.yaml"), nil, 0o600))
+	require.Empty(t, serpent.FindProjectConfig(nested, ".app.yaml"))
+	require.Equal(t, filepath.Join(root, ".app.yaml"), serpent.FindProjectConfig(root, ".app.yaml"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".app.yaml"), nil, 0o600))
+	require.Equal(t, filepath.Join(repo, ".app.yaml"), serpent.FindProjectConfig(nested, ".app.yaml"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(nested, ".app.yaml"), nil, 0o600))
+	require.Equal(t, filepath.Join(nested, ".app.yaml"), serpent.FindProjectConfig(nested, ".app.yaml"))
+}
+
+func TestProjectConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(userPath, []byte("region: us\ntoken: user-token\n"), 0o600))
+
+	run := func(project string) (region, token string, err error) {
+		projectPath := filepath.Join(t.TempDir(), ".app.yaml")
+		require.NoError(t, os.WriteFile(projectPath, []byte(project), 0o600))
+
+		var config serpent.YAMLConfigPath
+		cmd := &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "config", Flag: "config", Value: &config},
+				{Name: "project-config", Flag: "project-config", Value: serpent.ProjectConfigOf(".app.yaml")},
+				{Name: "region", Flag: "region", YAML: "region", Scope: serpent.ScopeProject, Value: serpent.StringOf(&region)},
+				{Name: "token", YAML: "token", Value: serpent.StringOf(&token)},
+			},
+			Handler: func(inv *serpent.Invocation) error { return nil },
+		}
+		err = cmd.Invoke("--config", userPath, "--project-config", projectPath).Run()
+		return region, token, err
+	}
+
+	region, token, err := run("region: eu\n")
+	require.NoError(t, err)
+	require.Equal(t, "eu", region)
+	require.Equal(t, "user-token", token)
+
+	_, _, err = run("token: project-token\n")
+	require.ErrorContains(t, err, `option "token" can't be set in a project config file`)
+}
+
+func TestProjectConfigWorkDir(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".app.yaml"), []byte("region: eu\n"), 0o600))
+	nested := filepath.Join(repo, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+
+	var region string
+	cmd := &serpent.Command{
+		Use: "app",
+		Options: serpent.OptionSet{
+			serpent.WorkDirOption(),
+			{Name: "project-config", Flag: "project-config", Value: serpent.ProjectConfigOf(".app.yaml")},
+			{Name: "region", Flag: "region", YAML: "region", Scope: serpent.ScopeProject, Value: serpent.StringOf(&region)},
+		},
+		Middleware: serpent.WorkDirMiddleware(),
+		Handler:    func(*serpent.Invocation) error { return nil },
+	}
+
+	err := cmd.Invoke("-C", nested).Run()
+	require.NoError(t, err)
+	require.Equal(t, "eu", region)
+}
@@ -0,0 +1,41 @@
+package serpent
+
+// AnnotationQuiet marks the boolean option that enables quiet mode.
+const AnnotationQuiet = "serpent.quiet"
+
+// QuietOption returns a --quiet, -q option setting quiet, that suppresses
+// Info and Warn messages, ui.Step and ui.Progress output and other progress
+// reports of the command and its children, see Invocation.Quiet. Errors and
+// the results written to Stdout are still written. quiet may be nil.
+//
+// The option is usually added to the root command.
+func QuietOption(quiet *bool) Option {
+	if quiet == nil {
+		quiet = new(bool)
+	}
+	return Option{
+		Name:          "quiet",
+		Description:   "Only write errors and results, without progress and informational messages.",
+		Flag:          "quiet",
+		FlagShorthand: "q",
+		Value:         BoolOf(quiet),
+		Annotations:   Annotations{}.Mark(AnnotationQuiet, "true"),
+	}
+}
+
+// Quiet reports whether quiet mode is enabled by an option annotated with
+// AnnotationQuiet on the command or any of its parents. Helpers writing
+// progress or informational output to Stderr should skip it when quiet.
+func (inv *Invocation) Quiet() bool {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if !opt.Annotations.IsSet(AnnotationQuiet) {
+				continue
+			}
+			if b, ok := opt.Value.(*Bool); ok && b.Value() {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package serpent_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestQuietOption(t *testing.T) {
+	t.Parallel()
+
+	cmd := func(quiet *bool) *serpent.Command {
+		return &serpent.Command{
+			Use:     "root",
+			Options: serpent.OptionSet{serpent.QuietOption(quiet)},
+			Children: []*serpent.Command{{
+				Use: "deploy",
+				Handler: func(inv *serpent.Invocation) error {
+					ui.Step(inv, "build", "building %s", "app")
+					ui.Progress(inv, "upload", 1, 2)
+					inv.Info("deployed")
+					inv.Warn("careful")
+					inv.Error("broken")
+					_, _ = inv.Stdout.Write([]byte("result\n"))
+					return errors.New("failed")
+				},
+			}},
+		}
+	}
+
+	t.Run("Loud", func(t *testing.T) {
+		t.Parallel()
+
+		inv := cmd(nil).Invoke("deploy")
+		stdio := fakeIO(inv)
+		require.Error(t, inv.Run())
+		require.False(t, inv.Quiet())
+		require.Contains(t, stdio.Stderr.String(), "[build] building app")
+		require.Contains(t, stdio.Stderr.String(), "[upload] 1/2")
+		require.Contains(t, stdio.Stderr.String(), "deployed")
+		require.Contains(t, stdio.Stderr.String(), "WARNING: careful")
+	})
+
+	t.Run("Quiet", func(t *testing.T) {
+		t.Parallel()
+
+		var quiet bool
+		inv := cmd(&quiet).Invoke("deploy", "-q")
+		stdio := fakeIO(inv)
+		require.ErrorContains(t, inv.Run(), "failed")
+		require.True(t, quiet)
+		require.Equal(t, "result\n", stdio.Stdout.String())
+		require.NotContains(t, stdio.Stderr.String(), "build")
+		require.NotContains(t, stdio.Stderr.String(), "upload")
+		require.NotContains(t, stdio.Stderr.String(), "deployed")
+		require.NotContains(t, stdio.Stderr.String(), "careful")
+		require.Contains(t, stdio.Stderr.String(), "ERROR: broken")
+	})
+}
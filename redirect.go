@@ -0,0 +1,102 @@
+package serpent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// rootCommand returns the root of the tree c belongs to.
+func (c *Command) rootCommand() *Command {
+	for c.Parent != nil {
+		c = c.Parent
+	}
+	return c
+}
+
+// replacement returns the command c is replaced by, or nil if c isn't
+// replaced.
+func (c *Command) replacement() (*Command, error) {
+	if len(c.ReplacedBy) == 0 {
+		return nil, nil
+	}
+	root := c.rootCommand()
+	cmd := root.Find(c.ReplacedBy...)
+	if cmd == nil {
+		return nil, fmt.Errorf("no command %q", strings.Join(append([]string{root.Name()}, c.ReplacedBy...), " "))
+	}
+	return cmd, nil
+}
+
+// redirect runs the command replacing the current one with the same
+// arguments, after warning about the deprecation. The arguments naming the
+// current command are swapped for its ReplacedBy path, and the flags in
+// ReplacedFlags are renamed.
+func (inv *Invocation) redirect(state *runState) error {
+	old := inv.Command
+	cmd, err := old.replacement()
+	if err != nil {
+		return fmt.Errorf("redirecting %q: %w", old.FullName(), err)
+	}
+	if !inv.IsCompletionMode() {
+		lines := []string{fmt.Sprintf("Running %q instead.", cmd.FullName())}
+		if old.Deprecated != "" {
+			lines = append([]string{old.Deprecated}, lines...)
+		}
+		inv.message(Message{
+			Level:  MessageWarn,
+			Kind:   MessageKindDeprecated,
+			Header: fmt.Sprintf("%q is deprecated!", old.FullName()),
+			Lines:  lines,
+			Text:   fmt.Sprintf("%s %q is deprecated!. %s\n", prettyHeader("warning"), old.FullName(), strings.Join(lines, " ")),
+		})
+	}
+	return inv.with(func(i *Invocation) {
+		i.Command = old.rootCommand()
+		i.Args = inv.redirectArgs(state)
+		i.parsedFlags = nil
+	}).Run()
+}
+
+// redirectArgs returns the arguments of the current invocation rewritten for
+// the command replacing it.
+func (inv *Invocation) redirectArgs(state *runState) []string {
+	// The first positional arguments are the names of the commands down to
+	// the current one.
+	var names []int
+	renames := make(map[int]string)
+	walkArgs(state.allArgs, inv.parsedFlags, func(i int, form string) bool {
+		if form == "" {
+			if len(names) < state.commandDepth {
+				names = append(names, i)
+			}
+			return true
+		}
+		name, ok := strings.CutPrefix(form, "--")
+		if !ok {
+			return true
+		}
+		if to, ok := inv.Command.ReplacedFlags[name]; ok {
+			renames[i] = "--" + to
+		} else if to, ok := inv.Command.ReplacedFlags[strings.TrimPrefix(name, "no-")]; ok && strings.HasPrefix(name, "no-") {
+			renames[i] = "--no-" + to
+		}
+		return true
+	})
+
+	args := slices.Clone(inv.Command.ReplacedBy)
+	for i, arg := range state.allArgs {
+		if slices.Contains(names, i) {
+			continue
+		}
+		if to, ok := renames[i]; ok {
+			_, value, hasValue := strings.Cut(arg, "=")
+			arg = to
+			if hasValue {
+				arg += "=" + value
+			}
+		}
+		args = append(args, arg)
+	}
+	return args
+}
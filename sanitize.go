@@ -0,0 +1,155 @@
+package serpent
+
+import "strings"
+
+// AnnotationSecret marks an option as holding a secret, see SanitizeArgs.
+const AnnotationSecret = "serpent.secret"
+
+// SecretValue is implemented by option values holding secrets, such as
+// secret.Value, so that they're treated as if annotated with
+// AnnotationSecret.
+type SecretValue interface {
+	IsSecret() bool
+}
+
+const (
+	// SanitizedSecret replaces the values of secret options in sanitized
+	// arguments.
+	SanitizedSecret = "***"
+	// SanitizedArg replaces positional arguments in sanitized arguments.
+	SanitizedArg = "<arg>"
+)
+
+// IsSecret reports whether the option holds a secret, because it's annotated
+// with AnnotationSecret, Sensitive or its value is a SecretValue.
+func (o *Option) IsSecret() bool {
+	if o.Sensitive || o.Annotations.IsSet(AnnotationSecret) {
+		return true
+	}
+	sv, ok := o.Value.(SecretValue)
+	return ok && sv.IsSecret()
+}
+
+// SanitizeArgs returns the arguments the root command was invoked with,
+// safe to include in telemetry and crash reports: the values of secret
+// options are replaced with SanitizedSecret and positional arguments, which
+// are free-form, with SanitizedArg. Subcommand and flag names, and the values
+// of other options, are kept.
+func SanitizeArgs(inv *Invocation) []string {
+	root := inv.Command
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return sanitizeArgs(root, inv.rawArgs, false)
+}
+
+// sanitizeArgs sanitizes args like SanitizeArgs. If keepArgs is set,
+// positional arguments are kept and only secrets are replaced.
+func sanitizeArgs(root *Command, args []string, keepArgs bool) []string {
+	sanitized := make([]string, len(args))
+	// The arguments walkCommandArgs skips are the values of the flag before
+	// them, which are secret if secretNext is set for that flag.
+	walked := make([]bool, len(args))
+	secretNext := make([]bool, len(args))
+	shorthands := make([]int, len(args))
+	end := walkCommandArgs(root, args, func(cmd *Command, i int, form string) bool {
+		arg := args[i]
+		walked[i] = true
+		if form == "" {
+			if cmd.child(arg) != nil {
+				sanitized[i] = arg
+			} else {
+				sanitized[i] = sanitizeArg(arg, keepArgs)
+			}
+			return true
+		}
+		if sanitized[i] == "" {
+			sanitized[i] = arg
+		}
+		// The value of the flag follows it in arg, if it's inline.
+		var (
+			pos    int
+			inline bool
+		)
+		if strings.HasPrefix(form, "--") {
+			eq := strings.IndexByte(arg, '=')
+			pos, inline = eq+1, eq >= 0
+		} else {
+			shorthands[i]++
+			pos = shorthands[i] + 1
+			if inline = pos < len(arg); inline && arg[pos] == '=' {
+				pos++
+			}
+		}
+		opt := lookupFlag(cmd, form)
+		if opt == nil || !opt.IsSecret() {
+			return true
+		}
+		if !inline {
+			secretNext[i] = true
+			return true
+		}
+		sanitized[i] = arg[:pos] + SanitizedSecret
+		return true
+	})
+	for i := 0; i < len(args); i++ {
+		switch {
+		case i >= end:
+			if i == end && args[i] == "--" {
+				sanitized[i] = args[i]
+			} else {
+				sanitized[i] = sanitizeArg(args[i], keepArgs)
+			}
+		case !walked[i]:
+			sanitized[i] = sanitizeValue(args[i], i > 0 && secretNext[i-1])
+		}
+	}
+	return sanitized
+}
+
+func sanitizeValue(value string, secret bool) string {
+	if secret {
+		return SanitizedSecret
+	}
+	return value
+}
+
+func sanitizeArg(arg string, keep bool) string {
+	if keep {
+		return arg
+	}
+	return SanitizedArg
+}
+
+// lookupFlag returns the option of cmd or its parents using flag, e.g.
+// "--verbose" or "-v".
+func lookupFlag(cmd *Command, flag string) *Option {
+	for ; cmd != nil; cmd = cmd.Parent {
+		if opt := cmd.Options.byFlagForm(flag); opt != nil {
+			return opt
+		}
+		if name, ok := strings.CutPrefix(flag, "--"); ok && cmd.caseInsensitive() {
+			if opt := cmd.Options.byFoldedFlag(name); opt != nil {
+				return opt
+			}
+		}
+	}
+	return nil
+}
+
+// child returns the child of c named or aliased name, ignoring case if c is
+// case-insensitive.
+func (c *Command) child(name string) *Command {
+	fold := c.caseInsensitive()
+	for _, child := range c.Children {
+		if namesEqual(child.Name(), name, fold) {
+			return child
+		}
+		for _, alias := range child.Aliases {
+			if namesEqual(alias, name, fold) {
+				return child
+			}
+		}
+	}
+	return nil
+}
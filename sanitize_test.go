@@ -0,0 +1,75 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/secret"
+)
+
+func TestSanitizeArgs(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	var password string
+	root := &serpent.Command{
+		Use: "app",
+		Options: serpent.OptionSet{
+			{Name: "token", Flag: "token", FlagShorthand: "t", Annotations: serpent.Annotations{}.Mark(serpent.AnnotationSecret, "true"), Value: serpent.StringOf(new(string))},
+			{Name: "output", Flag: "output", FlagShorthand: "o", Value: serpent.StringOf(new(string))},
+			{Name: "verbose", Flag: "verbose", FlagShorthand: "v", Value: serpent.BoolOf(new(bool))},
+			{Name: "color", Flag: "color", FlagShorthand: "c", Value: serpent.ValueOf(new(string), func(s string) (string, error) { return s, nil }, nil).WithNoOptDefValue("always")},
+		},
+		Children: []*serpent.Command{
+			{
+				Use:     "login <url>",
+				Aliases: []string{"signin"},
+				Options: serpent.OptionSet{
+					{Name: "password", Flag: "password", FlagShorthand: "p", Value: secret.Of("app", &password)},
+				},
+				Handler: func(inv *serpent.Invocation) error {
+					got = serpent.SanitizeArgs(inv)
+					return nil
+				},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "Flags",
+			args: []string{"--token", "abc", "--output", "json", "login", "https://example.com", "--password=hunter2"},
+			want: []string{"--token", "***", "--output", "json", "login", "<arg>", "--password=***"},
+		},
+		{
+			name: "Shorthands",
+			args: []string{"-vtabc", "-o", "yaml", "signin", "-p", "hunter2", "https://example.com"},
+			want: []string{"-vt***", "-o", "yaml", "signin", "-p", "***", "<arg>"},
+		},
+		{
+			name: "NoOptDefVal",
+			args: []string{"--color", "login", "--token", "s3cret", "-c", "https://example.com"},
+			want: []string{"--color", "login", "--token", "***", "-c", "<arg>"},
+		},
+		{
+			name: "NoOptDefValBeforeChildSecret",
+			args: []string{"--color", "login", "--password", "hunter2"},
+			want: []string{"--color", "login", "--password", "***"},
+		},
+		{
+			name: "DoubleDash",
+			args: []string{"login", "--token=abc", "--", "https://example.com"},
+			want: []string{"login", "--token=***", "--", "<arg>"},
+		},
+	} {
+		err := root.Invoke(tc.args...).Run()
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.want, got, tc.name)
+	}
+}
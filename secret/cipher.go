@@ -0,0 +1,110 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// CipherKeyName is the keyring key under which Cipher keeps its encryption
+// key.
+const CipherKeyName = "config-encryption-key"
+
+// Cipher encrypts values with AES-256-GCM using a key held in a keyring, for
+// serpent.ConfigCipher:
+//
+//	serpent.ConfigCipher = secret.NewCipher("myapp")
+//
+// The key is created on first use. Values encrypted on one machine can only
+// be decrypted with the same key, so config files with encrypted values
+// don't move between machines.
+type Cipher struct {
+	// Service is the keyring service the key is kept in.
+	Service string
+	// Keyring holds the key. If nil, DefaultKeyring is used.
+	Keyring Keyring
+
+	mu  sync.Mutex
+	gcm cipher.AEAD
+}
+
+// NewCipher returns a Cipher keeping its key in the DefaultKeyring under
+// service.
+func NewCipher(service string) *Cipher {
+	return &Cipher{Service: service}
+}
+
+// Encrypt returns plaintext encrypted and authenticated, prefixed with a
+// random nonce. It creates the key if the keyring has none.
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := c.aead(true)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt returns the plaintext of a value returned by Encrypt, failing if
+// it was encrypted with another key or tampered with.
+func (c *Cipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := c.aead(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// aead returns the AES-GCM cipher with the key of the keyring, creating the
+// key if create is set and there's none.
+func (c *Cipher) aead(create bool) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gcm != nil {
+		return c.gcm, nil
+	}
+
+	kr := c.Keyring
+	if kr == nil {
+		kr = DefaultKeyring
+	}
+	encoded, err := kr.Get(c.Service, CipherKeyName)
+	var key []byte
+	switch {
+	case errors.Is(err, ErrNotFound) && create:
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		if err := kr.Set(c.Service, CipherKeyName, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return nil, fmt.Errorf("store encryption key: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("load encryption key from keyring service %q: %w", c.Service, err)
+	default:
+		if key, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("decode encryption key: %w", err)
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
+	if c.gcm, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	return c.gcm, nil
+}
@@ -0,0 +1,69 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// osKeyring stores secrets in the macOS Keychain using the security(1) tool.
+type osKeyring struct{}
+
+// errItemNotFound is the exit status of security(1) when an item is missing.
+const errItemNotFound = 44
+
+func (osKeyring) Set(service, key, value string) error {
+	// The command is fed to security(1)'s interactive mode on stdin so the
+	// secret never appears in argv, where any local user could read it.
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(addPasswordCommand(service, key, value))
+	cmd.Stderr = &stderr
+	_, err := cmd.Output()
+	if err == nil && stderr.Len() > 0 {
+		// Interactive mode exits cleanly even when a command fails.
+		err = errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return securityError(err)
+}
+
+// addPasswordCommand returns the security(1) interactive command storing
+// value. The password is hex encoded with -X so it needs no quoting.
+func addPasswordCommand(service, key, value string) string {
+	return fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		securityQuote(service), securityQuote(key), hex.EncodeToString([]byte(value)))
+}
+
+// securityQuote quotes s for security(1)'s interactive command parser.
+func securityQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func (osKeyring) Get(service, key string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", key, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (osKeyring) Delete(service, key string) error {
+	return runSecurity("delete-generic-password", "-s", service, "-a", key)
+}
+
+func runSecurity(args ...string) error {
+	_, err := exec.Command("security", args...).Output()
+	return securityError(err)
+}
+
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errItemNotFound {
+		return ErrNotFound
+	}
+	return err
+}
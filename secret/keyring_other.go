@@ -0,0 +1,18 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd
+
+package secret
+
+// osKeyring is used on platforms without a supported keyring.
+type osKeyring struct{}
+
+func (osKeyring) Set(string, string, string) error {
+	return ErrUnsupported
+}
+
+func (osKeyring) Get(string, string) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (osKeyring) Delete(string, string) error {
+	return ErrUnsupported
+}
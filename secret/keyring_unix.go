@@ -0,0 +1,58 @@
+//go:build linux || freebsd || openbsd || netbsd
+
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// osKeyring stores secrets using libsecret's secret-tool(1), which talks to
+// the Secret Service (GNOME Keyring, KWallet, ...).
+type osKeyring struct{}
+
+func (osKeyring) Set(service, key, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", service+" "+key, "service", service, "username", key)
+	cmd.Stdin = strings.NewReader(value)
+	return runSecretTool(cmd)
+}
+
+func (osKeyring) Get(service, key string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", service, "username", key)
+	cmd.Stdout = &stdout
+	err := runSecretTool(cmd)
+	if err != nil {
+		var exitErr *exec.ExitError
+		// secret-tool exits 1 without output when nothing matched.
+		if errors.As(err, &exitErr) && stdout.Len() == 0 {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func (k osKeyring) Delete(service, key string) error {
+	// secret-tool clear succeeds even if nothing matched.
+	if _, err := k.Get(service, key); err != nil {
+		return err
+	}
+	return runSecretTool(exec.Command("secret-tool", "clear", "service", service, "username", key))
+}
+
+func runSecretTool(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%w: secret-tool not found, install libsecret", ErrUnsupported)
+	}
+	if err != nil && stderr.Len() > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
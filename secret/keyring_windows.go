@@ -0,0 +1,108 @@
+package secret
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// osKeyring stores secrets in the Windows Credential Manager.
+type osKeyring struct{}
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead    = advapi32.NewProc("CredReadW")
+	procCredWrite   = advapi32.NewProc("CredWriteW")
+	procCredDelete  = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+	errNotFoundCode = syscall.Errno(1168) // ERROR_NOT_FOUND
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+// credential mirrors the CREDENTIALW struct.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func targetName(service, key string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + key)
+}
+
+func (osKeyring) Set(service, key, value string) error {
+	target, err := targetName(service, key)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(key)
+	if err != nil {
+		return err
+	}
+	blob := []byte(value)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	r, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+func (osKeyring) Get(service, key string) (string, error) {
+	target, err := targetName(service, key)
+	if err != nil {
+		return "", err
+	}
+	var pcred *credential
+	r, _, err := procCredRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&pcred)))
+	if r == 0 {
+		return "", credError(err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(pcred)))
+
+	if pcred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(pcred.CredentialBlob, pcred.CredentialBlobSize)), nil
+}
+
+func (osKeyring) Delete(service, key string) error {
+	target, err := targetName(service, key)
+	if err != nil {
+		return err
+	}
+	r, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if r == 0 {
+		return credError(err)
+	}
+	return nil
+}
+
+func credError(err error) error {
+	if errors.Is(err, errNotFoundCode) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+// Package secret stores credentials in the operating system's keyring
+// (macOS Keychain, Windows Credential Manager, or libsecret on Linux), so
+// tokens never need to live in plaintext configuration.
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// ErrNotFound is returned when a secret does not exist in the keyring.
+var ErrNotFound = errors.New("secret not found in keyring")
+
+// ErrUnsupported is returned when no keyring is available on this platform.
+var ErrUnsupported = errors.New("keyring not supported on this platform")
+
+// Keyring is a store of secrets, keyed by service and key.
+type Keyring interface {
+	Set(service, key, value string) error
+	// Get returns ErrNotFound if the secret does not exist.
+	Get(service, key string) (string, error)
+	// Delete returns ErrNotFound if the secret does not exist.
+	Delete(service, key string) error
+}
+
+// DefaultKeyring is the operating system keyring. It may be replaced, e.g.
+// with a MemoryKeyring in tests.
+var DefaultKeyring Keyring = osKeyring{}
+
+// Store saves value under service and key in the DefaultKeyring.
+func Store(service, key, value string) error {
+	return DefaultKeyring.Set(service, key, value)
+}
+
+// Load retrieves the value stored under service and key from the
+// DefaultKeyring.
+func Load(service, key string) (string, error) {
+	return DefaultKeyring.Get(service, key)
+}
+
+// Delete removes the value stored under service and key from the
+// DefaultKeyring.
+func Delete(service, key string) error {
+	return DefaultKeyring.Delete(service, key)
+}
+
+// MemoryKeyring is an in-memory Keyring, useful for testing.
+type MemoryKeyring struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+var _ Keyring = (*MemoryKeyring)(nil)
+
+func NewMemoryKeyring() *MemoryKeyring {
+	return &MemoryKeyring{secrets: make(map[string]string)}
+}
+
+func (m *MemoryKeyring) Set(service, key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[service+"\x00"+key] = value
+	return nil
+}
+
+func (m *MemoryKeyring) Get(service, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.secrets[service+"\x00"+key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (m *MemoryKeyring) Delete(service, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.secrets[service+"\x00"+key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.secrets, service+"\x00"+key)
+	return nil
+}
+
+// ReferencePrefix marks an option value as a reference to a keyring entry.
+const ReferencePrefix = "keyring:"
+
+var _ pflag.Value = (*Value)(nil)
+
+// Value is an option value that resolves references of the form
+// "keyring:<name>" by loading <name> from the keyring. Any other input is
+// used verbatim.
+type Value struct {
+	// Service is the keyring service references are looked up in.
+	Service string
+	// Keyring is used to resolve references. If nil, DefaultKeyring is used.
+	Keyring Keyring
+
+	value *string
+	ref   string
+}
+
+// Of returns a Value that stores the resolved secret in s.
+func Of(service string, s *string) *Value {
+	return &Value{Service: service, value: s}
+}
+
+func (v *Value) Set(s string) error {
+	name, ok := strings.CutPrefix(s, ReferencePrefix)
+	if !ok {
+		v.ref = ""
+		*v.value = s
+		return nil
+	}
+	if name == "" {
+		return fmt.Errorf("empty keyring reference %q", s)
+	}
+
+	kr := v.Keyring
+	if kr == nil {
+		kr = DefaultKeyring
+	}
+	secret, err := kr.Get(v.Service, name)
+	if err != nil {
+		return fmt.Errorf("load %q from keyring service %q: %w", name, v.Service, err)
+	}
+	v.ref = name
+	*v.value = secret
+	return nil
+}
+
+// String returns the keyring reference if the value was loaded from the
+// keyring, so that secrets aren't leaked into help output or configs.
+func (v *Value) String() string {
+	if v.ref != "" {
+		return ReferencePrefix + v.ref
+	}
+	return *v.value
+}
+
+func (v *Value) Value() string {
+	return *v.value
+}
+
+// Reference returns the name of the keyring entry the value was loaded
+// from, or an empty string if it was set directly.
+func (v *Value) Reference() string {
+	return v.ref
+}
+
+func (*Value) Type() string {
+	return "string"
+}
+
+// IsSecret implements serpent.SecretValue, so that secrets are redacted by
+// serpent.SanitizeArgs.
+func (*Value) IsSecret() bool {
+	return true
+}
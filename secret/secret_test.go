@@ -0,0 +1,107 @@
+package secret_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/secret"
+)
+
+func TestMemoryKeyring(t *testing.T) {
+	t.Parallel()
+
+	kr := secret.NewMemoryKeyring()
+	_, err := kr.Get("svc", "token")
+	require.ErrorIs(t, err, secret.ErrNotFound)
+
+	require.NoError(t, kr.Set("svc", "token", "hunter2"))
+	v, err := kr.Get("svc", "token")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", v)
+
+	require.NoError(t, kr.Delete("svc", "token"))
+	require.ErrorIs(t, kr.Delete("svc", "token"), secret.ErrNotFound)
+}
+
+func TestValue(t *testing.T) {
+	t.Parallel()
+
+	kr := secret.NewMemoryKeyring()
+	require.NoError(t, kr.Set("myapp", "prod", "s3cret"))
+
+	var token string
+	val := secret.Of("myapp", &token)
+	val.Keyring = kr
+
+	cmd := &serpent.Command{
+		Use: "root",
+		Options: serpent.OptionSet{{
+			Name:  "token",
+			Flag:  "token",
+			Env:   "TOKEN",
+			Value: val,
+		}},
+		Handler: func(inv *serpent.Invocation) error {
+			return nil
+		},
+	}
+
+	t.Run("Reference", func(t *testing.T) {
+		inv := cmd.Invoke("--token", "keyring:prod")
+		require.NoError(t, inv.Run())
+		require.Equal(t, "s3cret", token)
+		require.Equal(t, "prod", val.Reference())
+		// The secret itself is never exposed through String.
+		require.Equal(t, "keyring:prod", val.String())
+	})
+
+	t.Run("Plain", func(t *testing.T) {
+		inv := cmd.Invoke("--token", "plain")
+		require.NoError(t, inv.Run())
+		require.Equal(t, "plain", token)
+		require.Equal(t, "", val.Reference())
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		inv := cmd.Invoke()
+		inv.Environ.Set("TOKEN", "keyring:missing")
+		err := inv.Run()
+		require.ErrorIs(t, err, secret.ErrNotFound)
+	})
+}
+
+func TestCipher(t *testing.T) {
+	t.Parallel()
+
+	kr := secret.NewMemoryKeyring()
+	c := &secret.Cipher{Service: "app", Keyring: kr}
+
+	// There's no key to decrypt with until one is created.
+	_, err := c.Decrypt([]byte("ciphertext"))
+	require.ErrorIs(t, err, secret.ErrNotFound)
+
+	ciphertext, err := c.Encrypt([]byte("hunter2"))
+	require.NoError(t, err)
+	require.NotContains(t, string(ciphertext), "hunter2")
+	key, err := kr.Get("app", secret.CipherKeyName)
+	require.NoError(t, err)
+	require.NotEmpty(t, key)
+
+	// Another cipher with the same keyring decrypts.
+	plaintext, err := (&secret.Cipher{Service: "app", Keyring: kr}).Decrypt(ciphertext)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", string(plaintext))
+
+	other := &secret.Cipher{Service: "app", Keyring: secret.NewMemoryKeyring()}
+	_, err = other.Encrypt(nil)
+	require.NoError(t, err)
+	_, err = other.Decrypt(ciphertext)
+	require.Error(t, err)
+
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = c.Decrypt(tampered)
+	require.Error(t, err)
+}
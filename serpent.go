@@ -7,6 +7,7 @@
 package serpent
 
 import (
+	"strconv"
 	"strings"
 
 	"golang.org/x/exp/maps"
@@ -18,6 +19,12 @@ type Group struct {
 	Name        string `json:"name,omitempty"`
 	YAML        string `json:"yaml,omitempty"`
 	Description string `json:"description,omitempty"`
+	// Hidden hides the options of the group and its descendants from help.
+	Hidden bool `json:"hidden,omitempty"`
+	// Gate is an environment variable that must be set to a true value, e.g.
+	// "1", for the options of the group and its descendants to be shown in
+	// help and accepted. It's meant for experimental settings.
+	Gate string `json:"gate,omitempty"`
 }
 
 // Ancestry returns the group and all of its parents, in order.
@@ -34,6 +41,31 @@ func (g *Group) Ancestry() []Group {
 	return groups
 }
 
+// closedGate returns the Gate of the first group in the ancestry that isn't
+// enabled in environ, or an empty string if every gate is open.
+func (g *Group) closedGate(environ Environ) string {
+	for _, g := range g.Ancestry() {
+		if g.Gate == "" {
+			continue
+		}
+		if open, _ := strconv.ParseBool(environ.Get(g.Gate)); !open {
+			return g.Gate
+		}
+	}
+	return ""
+}
+
+// hidden reports whether the options of the group are hidden from help,
+// because a group in the ancestry is hidden or has a closed gate.
+func (g *Group) hidden(environ Environ) bool {
+	for _, g := range g.Ancestry() {
+		if g.Hidden {
+			return true
+		}
+	}
+	return g.closedGate(environ) != ""
+}
+
 func (g *Group) FullName() string {
 	var names []string
 	for _, g := range g.Ancestry() {
@@ -78,3 +110,56 @@ func (a Annotations) Get(key string) (string, bool) {
 	v, ok := a[key]
 	return v, ok
 }
+
+// GetBool retrieves a key from the map parsed as a bool, returning false if
+// the key is not found or its value isn't a valid bool.
+func (a Annotations) GetBool(key string) (bool, bool) {
+	v, ok := a.Get(key)
+	if !ok {
+		return false, false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, false
+	}
+	return b, true
+}
+
+// GetInt retrieves a key from the map parsed as an int, returning false if
+// the key is not found or its value isn't a valid int.
+func (a Annotations) GetInt(key string) (int, bool) {
+	v, ok := a.Get(key)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
+
+// Namespace returns the annotations whose keys start with namespace followed
+// by a dot, with that prefix removed. For example, the "docs" namespace of
+// {"docs.weight": "10"} is {"weight": "10"}. The result is nil if no keys
+// are in the namespace.
+func (a Annotations) Namespace(namespace string) Annotations {
+	var ns Annotations
+	prefix := namespace + "."
+	for k, v := range a {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if ns == nil {
+			ns = make(Annotations)
+		}
+		ns[strings.TrimPrefix(k, prefix)] = v
+	}
+	return ns
+}
+
+// MarkNamespace is like Mark, but sets the key in namespace, i.e.
+// "namespace.key". It is suitable for chaining.
+func (a Annotations) MarkNamespace(namespace, key, value string) Annotations {
+	return a.Mark(namespace+"."+key, value)
+}
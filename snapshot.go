@@ -0,0 +1,179 @@
+package serpent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bketelsen/serpent/files"
+)
+
+// runManifestKeep is the number of run manifests kept by
+// SnapshotMiddleware, the oldest being removed.
+const runManifestKeep = 20
+
+// manifestEnv are the environment variables recorded in run manifests
+// besides those of options, as they commonly change the behavior of
+// commands.
+var manifestEnv = []string{
+	"CI", "LANG", "LC_ALL", "NO_COLOR", "SHELL", "TERM",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"XDG_CACHE_HOME", "XDG_CONFIG_HOME", "XDG_STATE_HOME",
+}
+
+// RunManifest describes a run of a command for debugging, see
+// SnapshotMiddleware.
+type RunManifest struct {
+	// Command is the full name of the command, e.g. "app server start".
+	Command string `json:"command"`
+	// Args are the arguments of the root command, sanitized as by
+	// SanitizeArgs.
+	Args    []string `json:"args"`
+	Version string   `json:"version,omitempty"`
+	Commit  string   `json:"commit,omitempty"`
+	OS      string   `json:"os"`
+	Arch    string   `json:"arch"`
+	WorkDir string   `json:"work_dir"`
+	// Environ holds the environment variables of the options and a few
+	// common ones, such as TERM and HTTPS_PROXY, that are set. Secrets are
+	// replaced with SanitizedSecret.
+	Environ map[string]string `json:"environ"`
+	// Options are the resolved options of the command and its parents.
+	Options  []RunManifestOption `json:"options"`
+	Start    time.Time           `json:"start"`
+	Duration time.Duration       `json:"duration"`
+	ExitCode int                 `json:"exit_code"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// RunManifestOption is a resolved option in a RunManifest.
+type RunManifestOption struct {
+	Name string `json:"name"`
+	// Value is SanitizedSecret for secrets.
+	Value  string      `json:"value"`
+	Source ValueSource `json:"source,omitempty"`
+}
+
+// SnapshotMiddleware returns middleware recording each run of the command in
+// a JSON RunManifest in $XDG_STATE_HOME/<root command>/runs, so that support
+// can ask users to attach it to bug reports. The latest manifests are kept.
+// When the handler fails, the path of the manifest is printed to Stderr.
+// Failing to record the manifest doesn't fail the command.
+func SnapshotMiddleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			start := time.Now()
+			err := next(inv)
+
+			m := newRunManifest(inv, start)
+			m.Duration = time.Since(start)
+			m.ExitCode = exitCode(err)
+			if err != nil {
+				m.Error = err.Error()
+			}
+			dir := filepath.Join(stateDir(inv), "runs")
+			path := filepath.Join(dir, fmt.Sprintf("run-%s-%d.json", start.Format("20060102-150405.000000"), os.Getpid()))
+			byt, merr := json.MarshalIndent(m, "", "  ")
+			if merr == nil {
+				merr = files.WriteFile(path, byt, files.PrivateFile)
+			}
+			if merr != nil {
+				inv.Warn("Failed to record the run manifest.", merr.Error())
+				return err
+			}
+			pruneRunManifests(dir)
+
+			if err != nil {
+				inv.message(Message{
+					Level:  MessageInfo,
+					Kind:   MessageKindRunManifest,
+					Header: "run manifest: " + abbreviateHome(inv, path),
+					Text:   fmt.Sprintf("run manifest: %s\n", abbreviateHome(inv, path)),
+				})
+			}
+			return err
+		}
+	}
+}
+
+func newRunManifest(inv *Invocation, start time.Time) RunManifest {
+	m := RunManifest{
+		Command: inv.Command.FullName(),
+		Args:    SanitizeArgs(inv),
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		WorkDir: inv.WorkDir(),
+		Environ: make(map[string]string),
+		Options: []RunManifestOption{},
+		Start:   start.UTC(),
+	}
+	if vc := inv.Command.versionCommand(); vc != nil {
+		m.Version, m.Commit = vc.Version, vc.VersionCommit
+	}
+
+	secretEnv := make(map[string]bool)
+	envNames := append([]string(nil), manifestEnv...)
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for i := range cmd.Options {
+			opt := &cmd.Options[i]
+			for _, name := range append([]string{opt.Env}, opt.EnvAliases...) {
+				if name != "" {
+					envNames = append(envNames, name)
+					secretEnv[name] = secretEnv[name] || opt.IsSecret()
+				}
+			}
+			if opt.Value == nil {
+				continue
+			}
+			m.Options = append(m.Options, RunManifestOption{
+				Name:   opt.Name,
+				Value:  sanitizeValue(opt.Value.String(), opt.IsSecret()),
+				Source: opt.ValueSource,
+			})
+		}
+	}
+	for _, name := range envNames {
+		v, ok := inv.Environ.Lookup(name)
+		if !ok {
+			continue
+		}
+		m.Environ[name] = sanitizeEnvValue(name, v, secretEnv[name])
+	}
+	return m
+}
+
+// sanitizeEnvValue returns v, or SanitizedSecret if the variable name holds
+// a secret or looks like it does. Credentials in URLs, such as those of
+// proxies, are redacted.
+func sanitizeEnvValue(name, v string, secret bool) string {
+	upper := strings.ToUpper(name)
+	for _, s := range []string{"TOKEN", "SECRET", "PASSWORD", "KEY"} {
+		secret = secret || strings.Contains(upper, s)
+	}
+	if secret {
+		return sanitizeValue(v, true)
+	}
+	if u, err := url.Parse(v); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return v
+}
+
+// pruneRunManifests removes all but the latest runManifestKeep manifests in
+// dir. Their names sort by time.
+func pruneRunManifests(dir string) {
+	paths, err := filepath.Glob(filepath.Join(dir, "run-*.json"))
+	if err != nil || len(paths) <= runManifestKeep {
+		return
+	}
+	sort.Strings(paths)
+	for _, path := range paths[:len(paths)-runManifestKeep] {
+		_ = os.Remove(path)
+	}
+}
@@ -0,0 +1,239 @@
+package serpent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// DefaultStdinMaxSize is the most bytes read from stdin for a value when no
+// explicit limit is set.
+const DefaultStdinMaxSize = 10 << 20 // 10 MiB
+
+// ErrStdinIsTerminal is returned when a value should be read from stdin but
+// stdin is an interactive terminal rather than a pipe or file.
+var ErrStdinIsTerminal = errors.New("stdin is a terminal, pipe input to the command or pass a value instead of \"-\"")
+
+var _ pflag.Value = (*StringOrStdin)(nil)
+
+// StringOrStdin is a string value that follows the "use '-' to read from
+// stdin" convention. The contents of the invocation's Stdin are read once the
+// command is resolved, before the handler runs.
+type StringOrStdin struct {
+	Value *string
+	// MaxSize is the most bytes read from stdin. If zero,
+	// DefaultStdinMaxSize is used.
+	MaxSize int64
+
+	pending   bool
+	fromStdin bool
+}
+
+func StringOrStdinOf(s *string) *StringOrStdin {
+	return &StringOrStdin{Value: s}
+}
+
+func (s *StringOrStdin) Set(v string) error {
+	s.pending = v == "-"
+	s.fromStdin = s.pending
+	if s.pending {
+		*s.Value = ""
+		return nil
+	}
+	*s.Value = v
+	return nil
+}
+
+func (s *StringOrStdin) String() string {
+	if s.pending {
+		return "-"
+	}
+	return *s.Value
+}
+
+// FromStdin reports whether the value was read from stdin.
+func (s *StringOrStdin) FromStdin() bool {
+	return s.fromStdin
+}
+
+func (*StringOrStdin) Type() string {
+	return "string"
+}
+
+// readStdinValues reads stdin into every StringOrStdin value of the command
+// and its parents that was set to "-".
+func (inv *Invocation) readStdinValues() error {
+	var (
+		readBy string
+		merr   error
+	)
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			v, ok := opt.Value.(*StringOrStdin)
+			if !ok || !v.pending {
+				continue
+			}
+			if readBy != "" {
+				merr = errors.Join(merr, fmt.Errorf("options %q and %q cannot both read from stdin", readBy, opt.Name))
+				continue
+			}
+			readBy = opt.Name
+
+			byt, err := inv.readStdin(v.MaxSize)
+			if err != nil {
+				merr = errors.Join(merr, fmt.Errorf("read %q from stdin: %w", opt.Name, err))
+				continue
+			}
+			old := opt.valueString()
+			v.pending = false
+			*v.Value = string(byt)
+			opt.changed(old)
+		}
+	}
+	return merr
+}
+
+// readStdin reads all of stdin, up to maxSize bytes, refusing to block on an
+// interactive terminal.
+func (inv *Invocation) readStdin(maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultStdinMaxSize
+	}
+	if inv.Stdin == nil {
+		return nil, errors.New("stdin is not available")
+	}
+	if f, ok := inv.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return nil, ErrStdinIsTerminal
+	}
+
+	byt, err := io.ReadAll(io.LimitReader(inv.Stdin, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(byt)) > maxSize {
+		return nil, fmt.Errorf("input exceeds the maximum size of %d bytes", maxSize)
+	}
+	return byt, nil
+}
+
+// ReadFileArg reads the file at path, or the invocation's Stdin if path is
+// "-". Reads from stdin are limited to DefaultStdinMaxSize bytes.
+func (inv *Invocation) ReadFileArg(path string) ([]byte, error) {
+	if path == "-" {
+		return inv.readStdin(DefaultStdinMaxSize)
+	}
+	return os.ReadFile(path)
+}
+
+// AnnotationNullDelimited marks the option that switches EachStdinLine to
+// NUL-delimited input.
+const AnnotationNullDelimited = "serpent.null_delimited"
+
+// NullOption returns a --null (-0) option that makes EachStdinLine split
+// stdin on NUL characters instead of newlines, for items that may contain
+// newlines, such as the output of "find -print0".
+func NullOption() Option {
+	var null bool
+	return Option{
+		Name:          "null",
+		Description:   "Items on stdin are separated by NUL characters instead of newlines.",
+		Flag:          "null",
+		FlagShorthand: "0",
+		Value:         BoolOf(&null),
+		Annotations:   Annotations{}.Mark(AnnotationNullDelimited, "true"),
+	}
+}
+
+// EachStdinLine calls fn with every non-empty line read from stdin, in
+// order, for commands consuming piped lists of items. Lines are split on
+// NUL characters instead if a NullOption on the command or its parents is
+// set. It stops at the first error returned by fn, or when the invocation's
+// context is canceled, even while waiting for input.
+func (inv *Invocation) EachStdinLine(fn func(line string) error) error {
+	if inv.Stdin == nil {
+		return nil
+	}
+	sep := byte('\n')
+	if inv.nullDelimited() {
+		sep = 0
+	}
+
+	var (
+		lines = make(chan string)
+		errc  = make(chan error, 1)
+		done  = make(chan struct{})
+	)
+	defer close(done)
+	go func() {
+		s := bufio.NewScanner(inv.Stdin)
+		s.Buffer(nil, 1<<20)
+		s.Split(splitOn(sep))
+		for s.Scan() {
+			line := s.Text()
+			if sep == '\n' {
+				line = strings.TrimSuffix(line, "\r")
+			}
+			if line == "" {
+				continue
+			}
+			select {
+			case lines <- line:
+			case <-done:
+				return
+			}
+		}
+		errc <- s.Err()
+		close(lines)
+	}()
+
+	ctx := inv.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				if err := <-errc; err != nil {
+					return fmt.Errorf("read stdin: %w", err)
+				}
+				return nil
+			}
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// nullDelimited reports whether a NullOption of the command or its parents
+// is set.
+func (inv *Invocation) nullDelimited() bool {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Annotations.IsSet(AnnotationNullDelimited) {
+				return opt.Value.String() == "true"
+			}
+		}
+	}
+	return false
+}
+
+// splitOn is a bufio.SplitFunc splitting on sep.
+func splitOn(sep byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, sep); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
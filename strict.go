@@ -0,0 +1,87 @@
+package serpent
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// WithStrictConfig returns the invocation failing before the handler runs if
+// an environment variable of its Environ starting with envPrefix, e.g.
+// "MYAPP_", isn't the Env or an EnvAliases entry of an option of the command
+// tree. This catches typos such as MYAPP_PROT=8080 that would otherwise be
+// silently ignored. An empty envPrefix checks every variable, for invocations
+// whose Environ holds only the app's variables, see WithEnvPrefix.
+//
+// Keys of YAML config files that match no option are always rejected.
+func (inv *Invocation) WithStrictConfig(envPrefix string) *Invocation {
+	return inv.with(func(i *Invocation) {
+		i.strictConfig = true
+		i.strictEnvPrefix = envPrefix
+	})
+}
+
+// checkStrictEnv returns an error listing the environment variables with the
+// strict prefix that match no option, with the closest option variable as a
+// suggestion.
+func (inv *Invocation) checkStrictEnv() error {
+	if !inv.strictConfig {
+		return nil
+	}
+
+	root := inv.Command
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	// Aliases are accepted, but only the primary variables are suggested.
+	known := make(map[string]bool)
+	var names []string
+	var walk func(cmd *Command)
+	walk = func(cmd *Command) {
+		for _, opt := range cmd.Options {
+			if opt.Env != "" {
+				known[opt.Env] = true
+				names = append(names, opt.Env)
+			}
+			for _, alias := range opt.EnvAliases {
+				known[alias] = true
+			}
+		}
+		for _, child := range cmd.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	sort.Strings(names)
+	names = slices.Compact(names)
+
+	var errs []error
+	for _, v := range inv.Environ {
+		if !strings.HasPrefix(v.Name, inv.strictEnvPrefix) || known[v.Name] {
+			continue
+		}
+		msg := fmt.Sprintf("unknown environment variable %q", v.Name)
+		if suggestion := closestName(names, v.Name); suggestion != "" {
+			msg += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("strict config: %w", errors.Join(errs...))
+}
+
+// closestName returns the name within suggestionDistance of name, if any,
+// preferring the closest one.
+func closestName(names []string, name string) string {
+	best, bestDistance := "", suggestionDistance+1
+	for _, candidate := range names {
+		if d := editDistance(candidate, name); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,97 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestInvocation_WithStrictConfig(t *testing.T) {
+	t.Parallel()
+
+	makeCmd := func(ran *bool) *serpent.Command {
+		var port, workers int64
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "port", Flag: "port", Env: "MYAPP_PORT", Value: serpent.Int64Of(&port)},
+			},
+			Handler: func(*serpent.Invocation) error {
+				*ran = true
+				return nil
+			},
+			Children: []*serpent.Command{{
+				Use: "worker",
+				Options: serpent.OptionSet{
+					{Name: "workers", Flag: "workers", Env: "MYAPP_WORKERS", Value: serpent.Int64Of(&workers)},
+				},
+				Handler: func(*serpent.Invocation) error { return nil },
+			}},
+		}
+	}
+
+	t.Run("Typo", func(t *testing.T) {
+		t.Parallel()
+
+		var ran bool
+		inv := makeCmd(&ran).Invoke().WithStrictConfig("MYAPP_")
+		inv.Environ.Set("MYAPP_PROT", "8080")
+		inv.Environ.Set("HOME", "/home/me")
+		err := inv.Run()
+		require.ErrorContains(t, err, `unknown environment variable "MYAPP_PROT", did you mean "MYAPP_PORT"?`)
+		require.NotContains(t, err.Error(), "HOME")
+		require.False(t, ran)
+	})
+
+	t.Run("OtherCommandsOptions", func(t *testing.T) {
+		t.Parallel()
+
+		var ran bool
+		inv := makeCmd(&ran).Invoke().WithStrictConfig("MYAPP_")
+		inv.Environ.Set("MYAPP_PORT", "8080")
+		inv.Environ.Set("MYAPP_WORKERS", "4")
+		require.NoError(t, inv.Run())
+		require.True(t, ran)
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		t.Parallel()
+
+		var ran bool
+		inv := makeCmd(&ran).Invoke()
+		inv.Environ.Set("MYAPP_PROT", "8080")
+		require.NoError(t, inv.Run())
+		require.True(t, ran)
+	})
+
+	t.Run("EnvAlias", func(t *testing.T) {
+		t.Parallel()
+
+		var ran bool
+		cmd := makeCmd(&ran)
+		cmd.Options.EnvAliases(serpent.EnvPrefixAliases("HOMEBREW_"))
+		inv := cmd.Invoke().WithStrictConfig("")
+		inv.Environ.Set("HOMEBREW_MYAPP_PORT", "8080")
+		require.NoError(t, inv.Run())
+		require.True(t, ran)
+
+		ran = false
+		inv = cmd.Invoke().WithStrictConfig("")
+		inv.Environ.Set("HOMEBREW_MYAPP_PROT", "8080")
+		err := inv.Run()
+		require.ErrorContains(t, err, `unknown environment variable "HOMEBREW_MYAPP_PROT"`)
+		require.False(t, ran)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		t.Parallel()
+
+		var ran bool
+		inv := makeCmd(&ran).Invoke("--help").WithStrictConfig("MYAPP_")
+		inv.Environ.Set("MYAPP_PROT", "8080")
+		_ = fakeIO(inv)
+		require.NoError(t, inv.Run())
+	})
+}
@@ -0,0 +1,42 @@
+package serpent
+
+import (
+	"errors"
+	"os"
+	"sync"
+)
+
+// tempDir is the temporary directory of an invocation, shared by the copies
+// of the invocation made while it runs.
+type tempDir struct {
+	mu   sync.Mutex
+	path string
+}
+
+// TempDir returns a temporary directory for the invocation, creating it on
+// the first call. It's removed with its contents once Run returns, even if
+// the handler panics, so handlers don't need to clean up after themselves.
+// It must be called while the invocation runs, e.g. from a handler or
+// middleware.
+func (inv *Invocation) TempDir() (string, error) {
+	if inv.tempDir == nil {
+		return "", errors.New("TempDir called outside of Run")
+	}
+	inv.tempDir.mu.Lock()
+	defer inv.tempDir.mu.Unlock()
+	if inv.tempDir.path == "" {
+		root := inv.Command
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		path, err := os.MkdirTemp("", root.Name()+"-")
+		if err != nil {
+			return "", err
+		}
+		inv.tempDir.path = path
+		inv.Defer(func() error {
+			return os.RemoveAll(path)
+		})
+	}
+	return inv.tempDir.path, nil
+}
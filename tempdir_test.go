@@ -0,0 +1,87 @@
+package serpent_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestInvocation_TempDir(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RemovedAfterRun", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string
+		cmd := &serpent.Command{
+			Use: "root",
+			PersistentMiddleware: func(next serpent.HandlerFunc) serpent.HandlerFunc {
+				return func(inv *serpent.Invocation) error {
+					var err error
+					dir, err = inv.TempDir()
+					require.NoError(t, err)
+					return next(inv)
+				}
+			},
+			Children: []*serpent.Command{{
+				Use: "sub",
+				Handler: func(inv *serpent.Invocation) error {
+					got, err := inv.TempDir()
+					require.NoError(t, err)
+					require.Equal(t, dir, got, "one directory per invocation")
+					return os.WriteFile(filepath.Join(got, "file"), []byte("data"), 0o600)
+				},
+			}},
+		}
+		require.NoError(t, cmd.Invoke("sub").Run())
+		require.NotEmpty(t, dir)
+		require.NoDirExists(t, dir)
+	})
+
+	t.Run("RemovedOnPanic", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string
+		cmd := &serpent.Command{
+			Use: "root",
+			Handler: func(inv *serpent.Invocation) error {
+				var err error
+				dir, err = inv.TempDir()
+				require.NoError(t, err)
+				panic("boom")
+			},
+		}
+		require.Panics(t, func() { _ = cmd.Invoke().Run() })
+		require.NoDirExists(t, dir)
+	})
+
+	t.Run("Isolated", func(t *testing.T) {
+		t.Parallel()
+
+		var dirs []string
+		cmd := &serpent.Command{
+			Use: "root",
+			Handler: func(inv *serpent.Invocation) error {
+				dir, err := inv.TempDir()
+				dirs = append(dirs, dir)
+				return err
+			},
+		}
+		require.NoError(t, cmd.Invoke().Run())
+		require.NoError(t, cmd.Invoke().Run())
+		require.Len(t, dirs, 2)
+		require.NotEqual(t, dirs[0], dirs[1])
+	})
+
+	t.Run("OutsideRun", func(t *testing.T) {
+		t.Parallel()
+
+		cmd := &serpent.Command{Use: "root"}
+		_, err := cmd.Invoke().TempDir()
+		require.Error(t, err)
+	})
+}
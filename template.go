@@ -0,0 +1,74 @@
+package serpent
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ExpandTemplate expands s as a Go template, as done for the values of
+// options with AllowTemplates. Only these functions are available:
+//
+//   - env "NAME" returns the environment variable NAME of environ, or "";
+//   - hostname returns the host name of the machine;
+//   - date "LAYOUT" returns the current time formatted with LAYOUT, see
+//     time.Time.Format. All dates of s are the same time.
+func ExpandTemplate(s string, environ Environ) (string, error) {
+	now := time.Now()
+	tmpl, err := template.New("value").Funcs(template.FuncMap{
+		"env": func(name string) string {
+			return environ.Get(name)
+		},
+		"hostname": os.Hostname,
+		"date": func(layout string) string {
+			return now.Format(layout)
+		},
+	}).Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, nil); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// expandTemplates expands the values of the options with AllowTemplates that
+// contain a template action, recording the template as their TemplateSource.
+func (optSet *OptionSet) expandTemplates(environ Environ) error {
+	if optSet == nil {
+		return nil
+	}
+
+	var merr *multierror.Error
+	for i := range *optSet {
+		opt := &(*optSet)[i]
+		// Clear the source of a previous run of the command.
+		opt.TemplateSource = ""
+		if !opt.AllowTemplates || opt.Value == nil {
+			continue
+		}
+		raw := opt.Value.String()
+		if !strings.Contains(raw, "{{") {
+			continue
+		}
+		expanded, err := ExpandTemplate(raw, environ)
+		if err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("expand %q: %w", opt.Name, err))
+			continue
+		}
+		old := opt.valueString()
+		if err := opt.Value.Set(expanded); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("parse %q: %w", opt.Name, err))
+			continue
+		}
+		opt.changed(old)
+		opt.TemplateSource = raw
+	}
+	return merr.ErrorOrNil()
+}
@@ -0,0 +1,81 @@
+package serpent_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestOption_AllowTemplates(t *testing.T) {
+	t.Parallel()
+
+	newCmd := func(dir *string, allow bool) *serpent.Command {
+		return &serpent.Command{
+			Use: "app",
+			Options: serpent.OptionSet{
+				{Name: "output-dir", Flag: "output-dir", Env: "APP_OUTPUT_DIR", AllowTemplates: allow, Value: serpent.StringOf(dir)},
+			},
+			Handler:  func(inv *serpent.Invocation) error { return nil },
+			Children: []*serpent.Command{serpent.ConfigCommand()},
+		}
+	}
+
+	t.Run("Expand", func(t *testing.T) {
+		t.Parallel()
+
+		hostname, err := os.Hostname()
+		require.NoError(t, err)
+		var dir string
+		inv := newCmd(&dir, true).Invoke("--output-dir", `/backups/{{ env "USER" }}/{{ hostname }}/{{ date "2006" }}`)
+		inv.Environ.Set("USER", "alice")
+		require.NoError(t, inv.Run())
+		require.Equal(t, "/backups/alice/"+hostname+"/"+time.Now().Format("2006"), dir)
+	})
+
+	t.Run("Provenance", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string
+		inv := newCmd(&dir, true).Invoke("config", "show")
+		inv.Environ.Set("APP_OUTPUT_DIR", `/backups/{{ env "USER" }}`)
+		inv.Environ.Set("USER", "alice")
+		stdio := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, stdio.Stdout.String(), `/backups/alice  env APP_OUTPUT_DIR (template "/backups/{{ env \"USER\" }}")`)
+	})
+
+	t.Run("Rerun", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string
+		cmd := newCmd(&dir, true)
+		inv := cmd.Invoke("--output-dir", `/backups/{{ env "USER" }}`)
+		inv.Environ.Set("USER", "alice")
+		require.NoError(t, inv.Run())
+		require.NotEmpty(t, cmd.Options.ByName("output-dir").TemplateSource)
+
+		require.NoError(t, cmd.Invoke("--output-dir", "/backups/plain").Run())
+		require.Equal(t, "/backups/plain", dir)
+		require.Empty(t, cmd.Options.ByName("output-dir").TemplateSource)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string
+		require.NoError(t, newCmd(&dir, false).Invoke("--output-dir", "{{ hostname }}").Run())
+		require.Equal(t, "{{ hostname }}", dir)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+
+		var dir string
+		err := newCmd(&dir, true).Invoke("--output-dir", "{{ exec \"rm\" }}").Run()
+		require.ErrorContains(t, err, `expand "output-dir"`)
+	})
+}
@@ -0,0 +1,53 @@
+package serpent
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// Terminal is a terminal that isn't backed by the OS, such as a terminal
+// emulator running in a browser when the CLI is built for js/wasm.
+type Terminal struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	// Width is the number of columns, used to wrap help. Zero keeps the
+	// default.
+	Width int
+	// Environ is the environment of the invocation, as the browser has no
+	// OS environment.
+	Environ Environ
+	// Interrupt receives a value when the user interrupts the command,
+	// e.g. by pressing Ctrl-C, canceling the contexts returned by
+	// SignalNotifyContext. It may be nil.
+	Interrupt <-chan struct{}
+}
+
+// WithTerminal returns the invocation running in t rather than the OS
+// terminal: standard output and error both go to t.Stdout, the environment
+// is t.Environ and signals are delivered through t.Interrupt. Unlike WithOS,
+// nothing from the OS is used, so it's safe in browsers and playgrounds.
+func (inv *Invocation) WithTerminal(t Terminal) *Invocation {
+	inv = inv.WithIO(t.Stdin, t.Stdout, t.Stdout)
+	return inv.with(func(i *Invocation) {
+		i.Environ = slices.Clone(t.Environ)
+		if t.Width > 0 {
+			i.Environ.Set("COLUMNS", strconv.Itoa(t.Width))
+		}
+		i.signalNotifyContext = func(parent context.Context, _ ...os.Signal) (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(parent)
+			if t.Interrupt != nil {
+				go func() {
+					select {
+					case <-t.Interrupt:
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+			}
+			return ctx, cancel
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package serpent_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestInvocation_WithTerminal(t *testing.T) {
+	t.Parallel()
+
+	var (
+		out       bytes.Buffer
+		interrupt = make(chan struct{})
+		greeting  string
+	)
+	cmd := &serpent.Command{
+		Use: "app",
+		Options: serpent.OptionSet{
+			{Name: "greeting", Env: "GREETING", Value: serpent.StringOf(&greeting)},
+		},
+		Handler: func(inv *serpent.Invocation) error {
+			line, err := readLine(inv)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(inv.Stdout, "%s %s\n", greeting, line)
+			_, _ = fmt.Fprintln(inv.Stderr, "waiting")
+
+			ctx, stop := inv.SignalNotifyContext(inv.Context(), os.Interrupt)
+			defer stop()
+			close(interrupt)
+			<-ctx.Done()
+			return nil
+		},
+	}
+
+	environ := serpent.Environ{{Name: "GREETING", Value: "hello"}}
+	inv := cmd.Invoke().WithTerminal(serpent.Terminal{
+		Stdin:     strings.NewReader("world\n"),
+		Stdout:    &out,
+		Width:     100,
+		Environ:   environ,
+		Interrupt: interrupt,
+	})
+	require.NoError(t, inv.Run())
+	require.Equal(t, "hello world\nwaiting\n", out.String())
+	require.Equal(t, "100", inv.Environ.Get("COLUMNS"))
+	// The terminal's environment isn't modified.
+	require.Len(t, environ, 1)
+}
+
+func readLine(inv *serpent.Invocation) (string, error) {
+	var line string
+	_, err := fmt.Fscanln(inv.Stdin, &line)
+	return line, err
+}
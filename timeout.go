@@ -0,0 +1,81 @@
+package serpent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned when a command with a DefaultTimeout runs out of
+// time.
+type TimeoutError struct {
+	Timeout time.Duration
+	// Origin is where the timeout was set, e.g. the default or --timeout.
+	Origin ValueOrigin
+	Err    error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s (set by %s): %v", e.Timeout, e.Origin, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// addTimeoutOption adds the --timeout option enforcing DefaultTimeout to c,
+// unless c already defines a "timeout" option.
+func (c *Command) addTimeoutOption() {
+	if c.Options.ByName("timeout") != nil || c.Options.ByFlag("timeout") != nil {
+		return
+	}
+	c.Options.Add(Option{
+		Name:        "timeout",
+		Flag:        "timeout",
+		YAML:        "timeout",
+		Description: "Cancel the command if it takes longer than this, 0 disables the timeout.",
+		Default:     c.DefaultTimeout.String(),
+		Value:       DurationOf(&c.timeout),
+	})
+}
+
+// timeoutOption returns the option enforcing the closest DefaultTimeout of
+// c and its parents, or nil.
+func (c *Command) timeoutOption() *Option {
+	for ; c != nil; c = c.Parent {
+		if c.DefaultTimeout <= 0 {
+			continue
+		}
+		opt := c.Options.ByName("timeout")
+		if opt == nil || opt.Value != DurationOf(&c.timeout) {
+			// The command's own option takes precedence.
+			return nil
+		}
+		return opt
+	}
+	return nil
+}
+
+// withTimeout applies the timeout of the invoked command, if any, to ctx.
+// The returned function wraps errors caused by the timeout in a
+// TimeoutError.
+func (inv *Invocation) withTimeout(ctx context.Context) (context.Context, context.CancelFunc, func(error) error) {
+	opt := inv.Command.timeoutOption()
+	if opt == nil {
+		return ctx, func() {}, func(err error) error { return err }
+	}
+	timeout := opt.Value.(*Duration).Value()
+	if timeout <= 0 {
+		return ctx, func() {}, func(err error) error { return err }
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	origin := opt.Origin()
+	return ctx, cancel, func(err error) error {
+		if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+		return &TimeoutError{Timeout: timeout, Origin: origin, Err: err}
+	}
+}
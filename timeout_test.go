@@ -0,0 +1,98 @@
+package serpent_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestCommand_DefaultTimeout(t *testing.T) {
+	t.Parallel()
+
+	makeCmd := func(handler serpent.HandlerFunc) *serpent.Command {
+		return &serpent.Command{
+			Use:            "app",
+			DefaultTimeout: time.Hour,
+			Handler:        handler,
+			Children: []*serpent.Command{
+				{Use: "sub", Handler: handler},
+			},
+		}
+	}
+	wait := func(inv *serpent.Invocation) error {
+		<-inv.Context().Done()
+		return inv.Context().Err()
+	}
+
+	t.Run("Help", func(t *testing.T) {
+		t.Parallel()
+
+		inv := makeCmd(nil).Invoke("--help")
+		io := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		require.Contains(t, io.Stdout.String(), "--timeout duration (default: 1h0m0s)")
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		t.Parallel()
+
+		var deadline time.Time
+		err := makeCmd(func(inv *serpent.Invocation) error {
+			deadline, _ = inv.Context().Deadline()
+			return nil
+		}).Invoke().Run()
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+	})
+
+	t.Run("Flag", func(t *testing.T) {
+		t.Parallel()
+
+		err := makeCmd(wait).Invoke("sub", "--timeout", "10ms").Run()
+		var terr *serpent.TimeoutError
+		require.ErrorAs(t, err, &terr)
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+		require.Equal(t, 10*time.Millisecond, terr.Timeout)
+		require.ErrorContains(t, err, "timed out after 10ms (set by flag --timeout)")
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		t.Parallel()
+
+		cmd := makeCmd(wait)
+		cmd.DefaultTimeout = 10 * time.Millisecond
+		require.ErrorContains(t, cmd.Invoke().Run(), "timed out after 10ms (set by default)")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+
+		var hasDeadline bool
+		err := makeCmd(func(inv *serpent.Invocation) error {
+			_, hasDeadline = inv.Context().Deadline()
+			return nil
+		}).Invoke("--timeout", "0").Run()
+		require.NoError(t, err)
+		require.False(t, hasDeadline)
+	})
+
+	t.Run("OwnOption", func(t *testing.T) {
+		t.Parallel()
+
+		var timeout string
+		var hasDeadline bool
+		cmd := makeCmd(func(inv *serpent.Invocation) error {
+			_, hasDeadline = inv.Context().Deadline()
+			return nil
+		})
+		cmd.Options = serpent.OptionSet{{Name: "timeout", Flag: "timeout", Value: serpent.StringOf(&timeout)}}
+		require.NoError(t, cmd.Invoke("--timeout", "soon").Run())
+		require.Equal(t, "soon", timeout)
+		require.False(t, hasDeadline)
+	})
+}
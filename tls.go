@@ -0,0 +1,100 @@
+package serpent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig configures the TLS connections of a client, see TLSOptions.
+type TLSConfig struct {
+	// CACert is the path to a PEM bundle of CA certificates trusted in
+	// addition to the system ones.
+	CACert string
+	// Cert and Key are the paths to the PEM certificate and key the client
+	// authenticates with. They're set together.
+	Cert, Key string
+	// ServerName overrides the name the server certificate is verified
+	// against, which is the host name by default.
+	ServerName string
+	// Insecure skips verifying the server certificate.
+	Insecure bool
+}
+
+// TLSOptions returns the --tls-ca-cert, --tls-cert, --tls-key,
+// --tls-server-name and --tls-insecure options setting cfg, for commands
+// connecting to servers over TLS. Use cfg.Config to build the tls.Config once
+// the options are parsed.
+func TLSOptions(cfg *TLSConfig) OptionSet {
+	return OptionSet{
+		{
+			Name:        "tls-ca-cert",
+			Description: "Path to a PEM bundle of CA certificates trusted in addition to the system ones.",
+			Flag:        "tls-ca-cert",
+			Value:       StringOf(&cfg.CACert),
+		},
+		{
+			Name:        "tls-cert",
+			Description: "Path to the PEM client certificate, for servers requiring client authentication.",
+			Flag:        "tls-cert",
+			Value:       StringOf(&cfg.Cert),
+		},
+		{
+			Name:        "tls-key",
+			Description: "Path to the PEM key of the client certificate.",
+			Flag:        "tls-key",
+			Value:       StringOf(&cfg.Key),
+		},
+		{
+			Name:        "tls-server-name",
+			Description: "Name the server certificate is verified against, instead of the host name.",
+			Flag:        "tls-server-name",
+			Value:       StringOf(&cfg.ServerName),
+		},
+		{
+			Name:        "tls-insecure",
+			Description: "Skip verifying the server certificate. Only use this for testing.",
+			Flag:        "tls-insecure",
+			Value:       BoolOf(&cfg.Insecure),
+		},
+	}
+}
+
+// Config returns the client tls.Config set by cfg, requiring TLS 1.2 or
+// later.
+func (cfg TLSConfig) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.ServerName,
+	}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificates: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACert)
+		}
+		config.RootCAs = pool
+	}
+	if (cfg.Cert == "") != (cfg.Key == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+	if cfg.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.Insecure {
+		config.InsecureSkipVerify = true //nolint:gosec // Requested with --insecure.
+	}
+	return config, nil
+}
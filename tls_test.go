@@ -0,0 +1,45 @@
+package serpent_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestTLSOptions(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: srv.Certificate().Raw,
+	}), 0o600))
+
+	var cfg serpent.TLSConfig
+	cmd := &serpent.Command{
+		Use:     "app",
+		Options: serpent.TLSOptions(&cfg),
+		Handler: func(inv *serpent.Invocation) error { return nil },
+	}
+	require.NoError(t, cmd.Invoke("--tls-ca-cert", caCert, "--tls-server-name", "example.com").Run())
+
+	config, err := cfg.Config()
+	require.NoError(t, err)
+	require.Equal(t, "example.com", config.ServerName)
+	require.NotNil(t, config.RootCAs)
+	require.False(t, config.InsecureSkipVerify)
+
+	_, err = serpent.TLSConfig{Cert: caCert}.Config()
+	require.ErrorContains(t, err, "set together")
+	_, err = serpent.TLSConfig{CACert: filepath.Join(t.TempDir(), "missing.pem")}.Config()
+	require.ErrorContains(t, err, "read CA certificates")
+}
@@ -0,0 +1,43 @@
+package serpent
+
+// CommandTree describes a command and its visible subcommands, for tools
+// generating documentation or other artifacts from the command tree.
+type CommandTree struct {
+	// Name is the full name of the command, e.g. "app server start".
+	Name    string         `json:"name"`
+	Use     string         `json:"use"`
+	Aliases []string       `json:"aliases,omitempty"`
+	Short   string         `json:"short,omitempty"`
+	Long    string         `json:"long,omitempty"`
+	Options []OptionSchema `json:"options"`
+	// Children are the visible subcommands.
+	Children []CommandTree `json:"children,omitempty"`
+}
+
+// Tree returns the tree of c and its visible subcommands. Options are
+// described as in OptionsDump, without their values and leaving out hidden
+// ones; only the command's own options are included.
+func (c *Command) Tree() CommandTree {
+	t := CommandTree{
+		Name:    c.FullName(),
+		Use:     c.Synopsis(),
+		Aliases: c.Aliases,
+		Short:   c.Short,
+		Long:    c.Long,
+		Options: []OptionSchema{},
+	}
+	for _, opt := range c.Options {
+		if opt.Hidden {
+			continue
+		}
+		t.Options = append(t.Options, opt.schema(false))
+	}
+	for _, child := range c.Children {
+		if child.Hidden {
+			continue
+		}
+		child.Parent = c
+		t.Children = append(t.Children, child.Tree())
+	}
+	return t
+}
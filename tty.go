@@ -0,0 +1,21 @@
+//go:build !js
+
+package serpent
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ttyWidth returns the width of the terminal, or 80 if no terminal is
+// detected. The output streams are tried first: on Windows, only console
+// output handles have a size.
+func ttyWidth() int {
+	for _, f := range []*os.File{os.Stdout, os.Stderr, os.Stdin} {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	return 80
+}
@@ -0,0 +1,9 @@
+//go:build js
+
+package serpent
+
+// ttyWidth returns 80, as browsers have no OS terminal. Terminal.Width
+// sets the width of a browser terminal instead.
+func ttyWidth() int {
+	return 80
+}
@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/internal/browser"
+)
+
+// OpenURL opens url in the user's browser, saying so on the invocation's
+// Stderr unless quiet. The commands listed in the BROWSER variable are preferred to the
+// platform's default browser. When there's no browser to open, such as in
+// SSH sessions, or it fails to open, the URL is printed for the user to open
+// instead. Only http, https and file URLs are opened, others are an error.
+func OpenURL(inv *serpent.Invocation, url string) error {
+	opened, err := browser.Open(inv.Context(), inv.Environ.Get, url)
+	if errors.Is(err, browser.ErrInvalidURL) {
+		return err
+	}
+	if opened {
+		if inv.Quiet() {
+			return nil
+		}
+		_, _ = fmt.Fprintf(inv.Stderr, "Opening %s in your browser.\n", url)
+		return nil
+	}
+	if err != nil {
+		inv.Warn("Failed to open a browser.", err.Error())
+	}
+	_, _ = fmt.Fprintf(inv.Stderr, "Open %s in your browser.\n", url)
+	return nil
+}
+
+// WithURLOpener returns the invocation with open called by OpenURL, and by
+// serpent's bug-report command, instead of opening a browser, e.g. to test
+// commands that open URLs.
+func WithURLOpener(inv *serpent.Invocation, open func(url string) error) *serpent.Invocation {
+	return inv.WithContext(browser.WithOpener(inv.Context(), open))
+}
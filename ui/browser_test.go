@@ -0,0 +1,52 @@
+package ui_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestOpenURL(t *testing.T) {
+	t.Parallel()
+
+	newInv := func() (*serpent.Invocation, *bytes.Buffer) {
+		var stderr bytes.Buffer
+		inv := (&serpent.Command{}).Invoke()
+		inv.Stderr = &stderr
+		return inv, &stderr
+	}
+
+	t.Run("Opener", func(t *testing.T) {
+		t.Parallel()
+
+		inv, stderr := newInv()
+		var opened []string
+		inv = ui.WithURLOpener(inv, func(url string) error {
+			opened = append(opened, url)
+			return nil
+		})
+		require.NoError(t, ui.OpenURL(inv, "https://example.com"))
+		require.Equal(t, []string{"https://example.com"}, opened)
+		require.Equal(t, "Opening https://example.com in your browser.\n", stderr.String())
+	})
+
+	t.Run("Headless", func(t *testing.T) {
+		t.Parallel()
+
+		inv, stderr := newInv()
+		inv.Environ.Set("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22")
+		require.NoError(t, ui.OpenURL(inv, "https://example.com"))
+		require.Equal(t, "Open https://example.com in your browser.\n", stderr.String())
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		t.Parallel()
+
+		inv, _ := newInv()
+		require.Error(t, ui.OpenURL(inv, "file-manager:/etc"))
+	})
+}
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/bketelsen/serpent"
+)
+
+// AnnotationNoClipboard marks the option disabling CopyToClipboard, see
+// NoClipboardOption.
+const AnnotationNoClipboard = "ui.no_clipboard"
+
+// ErrNoClipboard is returned by CopyToClipboard when the text wasn't copied
+// because the clipboard is disabled or unavailable. Commands usually ignore
+// it, having printed the text anyway.
+var ErrNoClipboard = errors.New("no clipboard available")
+
+// NoClipboardOption returns a --no-clipboard option, also set by the
+// environment variable env if not empty, stopping CopyToClipboard from
+// touching the clipboard, e.g. when it holds something the user wants to
+// keep. Add it to the root command to apply to all commands.
+func NoClipboardOption(env string) serpent.Option {
+	var disabled bool
+	return serpent.Option{
+		Name:        "no-clipboard",
+		Description: "Don't copy tokens and IDs to the clipboard.",
+		Flag:        "no-clipboard",
+		Env:         env,
+		Value:       serpent.BoolOf(&disabled),
+		Annotations: serpent.Annotations{}.Mark(AnnotationNoClipboard, "true"),
+	}
+}
+
+type clipboardKey struct{}
+
+// WithClipboard returns the invocation with fn called by CopyToClipboard
+// instead of writing to the clipboard, e.g. to test commands that copy.
+func WithClipboard(inv *serpent.Invocation, fn func(text string) error) *serpent.Invocation {
+	return inv.WithContext(context.WithValue(inv.Context(), clipboardKey{}, fn))
+}
+
+// CopyToClipboard copies text, such as a token or ID the user needs to paste
+// elsewhere, to the clipboard with the platform's clipboard command: pbcopy,
+// clip, wl-copy, xclip or xsel. In SSH sessions, where that would copy to
+// the remote machine's clipboard, or when there's no such command, it asks
+// the terminal to copy with an OSC 52 escape sequence written to Stderr if
+// it's a terminal. Not every terminal supports it, so commands should print
+// the text too.
+//
+// It fails with ErrNoClipboard when the text can't be copied or the
+// NoClipboardOption is set.
+func CopyToClipboard(inv *serpent.Invocation, text string) error {
+	if clipboardDisabled(inv) {
+		return ErrNoClipboard
+	}
+	if fn, ok := inv.Context().Value(clipboardKey{}).(func(string) error); ok {
+		return fn(text)
+	}
+
+	getenv := inv.Environ.Get
+	remote := getenv("SSH_CONNECTION") != "" || getenv("SSH_TTY") != ""
+	if !remote {
+		if args := clipboardCommand(getenv); args != nil {
+			cmd := exec.CommandContext(inv.Context(), args[0], args[1:]...)
+			cmd.Stdin = strings.NewReader(text)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+			}
+			return nil
+		}
+	}
+	if !isTerminal(inv.Stderr) {
+		return ErrNoClipboard
+	}
+	_, err := fmt.Fprint(inv.Stderr, osc52(getenv, text))
+	return err
+}
+
+// clipboardDisabled reports whether the NoClipboardOption of the command or
+// any of its parents is set.
+func clipboardDisabled(inv *serpent.Invocation) bool {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Annotations.IsSet(AnnotationNoClipboard) && opt.Value.String() == "true" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clipboardCommand returns the command line copying its standard input to
+// the clipboard, or nil if none is found.
+func clipboardCommand(getenv func(string) string) []string {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip"}}
+	default:
+		if getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		if getenv("DISPLAY") != "" {
+			candidates = append(candidates,
+				[]string{"xclip", "-selection", "clipboard"},
+				[]string{"xsel", "--clipboard", "--input"},
+			)
+		}
+	}
+	for _, args := range candidates {
+		if _, err := exec.LookPath(args[0]); err == nil {
+			return args
+		}
+	}
+	return nil
+}
+
+// osc52 returns the escape sequence setting the clipboard to text, wrapped
+// to pass through tmux and screen, which otherwise swallow it.
+func osc52(getenv func(string) string, text string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	switch {
+	case getenv("TMUX") != "":
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case strings.HasPrefix(getenv("TERM"), "screen"):
+		return "\x1bP" + seq + "\x1b\\"
+	}
+	return seq
+}
@@ -0,0 +1,69 @@
+package ui_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestCopyToClipboard(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, args ...string) ([]string, error) {
+		t.Helper()
+		var copied []string
+		var copyErr error
+		cmd := &serpent.Command{
+			Use:     "app",
+			Options: serpent.OptionSet{ui.NoClipboardOption("APP_NO_CLIPBOARD")},
+			Children: []*serpent.Command{{
+				Use: "token",
+				Handler: func(inv *serpent.Invocation) error {
+					inv = ui.WithClipboard(inv, func(text string) error {
+						copied = append(copied, text)
+						return nil
+					})
+					copyErr = ui.CopyToClipboard(inv, "tok-123")
+					return nil
+				},
+			}},
+		}
+		inv := cmd.Invoke(args...)
+		inv.Stdout = &bytes.Buffer{}
+		inv.Stderr = &bytes.Buffer{}
+		require.NoError(t, inv.Run())
+		return copied, copyErr
+	}
+
+	t.Run("Copy", func(t *testing.T) {
+		t.Parallel()
+
+		copied, err := run(t, "token")
+		require.NoError(t, err)
+		require.Equal(t, []string{"tok-123"}, copied)
+	})
+
+	t.Run("NoClipboard", func(t *testing.T) {
+		t.Parallel()
+
+		copied, err := run(t, "token", "--no-clipboard")
+		require.ErrorIs(t, err, ui.ErrNoClipboard)
+		require.Empty(t, copied)
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		// Over SSH with Stderr not a terminal, there's no way to copy.
+		var stderr bytes.Buffer
+		inv := (&serpent.Command{}).Invoke()
+		inv.Environ.Set("SSH_TTY", "/dev/pts/0")
+		inv.Stderr = &stderr
+		require.ErrorIs(t, ui.CopyToClipboard(inv, "tok-123"), ui.ErrNoClipboard)
+		require.Empty(t, stderr.String())
+	})
+}
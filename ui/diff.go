@@ -0,0 +1,14 @@
+package ui
+
+import (
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/internal/textdiff"
+)
+
+// Diff returns updated with the text that changed since old highlighted
+// with serpent.Changed, comparing characters at the same line and column
+// like "watch -d". It's what the serpent.DiffOutputOption renders between
+// runs of --watch and --every, for commands refreshing their own output.
+func Diff(old, updated string) string {
+	return textdiff.Highlight(old, updated, serpent.Changed)
+}
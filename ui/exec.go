@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/bketelsen/serpent"
+)
+
+// ExitError is returned by Exec when the command exits with a non-zero
+// status.
+type ExitError struct {
+	Step string
+	Name string
+	Args []string
+	// Code is the exit code of the command, or -1 if it was killed by a
+	// signal.
+	Code int
+	Err  *exec.ExitError
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s: %q exited with status %d", e.Step, strings.Join(append([]string{e.Name}, e.Args...), " "), e.Code)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Exec runs an external command with stdio wired to the invocation. Every
+// line of output is prefixed with the step name so that output from several
+// steps can be told apart. The command is killed if inv.Context() is
+// canceled.
+//
+// A non-zero exit status is returned as an *ExitError.
+func Exec(inv *serpent.Invocation, step string, name string, args ...string) error {
+	ctx := inv.Context()
+
+	var mu sync.Mutex
+	prefix := serpent.Keyword("["+step+"]") + " "
+	stdout := &prefixWriter{w: inv.Stdout, prefix: prefix, mu: &mu}
+	stderr := &prefixWriter{w: inv.Stderr, prefix: prefix, mu: &mu}
+
+	//nolint:gosec
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = inv.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if len(inv.Environ) > 0 {
+		cmd.Env = inv.Environ.ToOS()
+	}
+
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s: %w", step, ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{
+			Step: step,
+			Name: name,
+			Args: args,
+			Code: exitErr.ExitCode(),
+			Err:  exitErr,
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: run %q: %w", step, name, err)
+	}
+	return nil
+}
+
+// prefixWriter writes each line written to it to w, preceded by prefix.
+// Partial lines are buffered until they are completed or Flush is called.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	// mu is shared between the writers of a single command so that lines
+	// don't interleave when stdout and stderr are the same writer.
+	mu  *sync.Mutex
+	buf bytes.Buffer
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, _ = p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i == -1 {
+			break
+		}
+		line := p.buf.Next(i + 1)
+		if _, err := io.WriteString(p.w, p.prefix+string(line)); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush writes any buffered partial line.
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.buf.Len() == 0 {
+		return
+	}
+	_, _ = io.WriteString(p.w, p.prefix+p.buf.String()+"\n")
+	p.buf.Reset()
+}
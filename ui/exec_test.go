@@ -0,0 +1,60 @@
+package ui_test
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on sh")
+	}
+
+	newInv := func() (*serpent.Invocation, *bytes.Buffer, *bytes.Buffer) {
+		var stdout, stderr bytes.Buffer
+		inv := (&serpent.Command{}).Invoke()
+		inv.Stdout = &stdout
+		inv.Stderr = &stderr
+		return inv, &stdout, &stderr
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		t.Parallel()
+
+		inv, stdout, stderr := newInv()
+		err := ui.Exec(inv, "build", "sh", "-c", "echo one; printf two; echo oops >&2")
+		require.NoError(t, err)
+		require.Equal(t, "[build] one\n[build] two\n", stdout.String())
+		require.Equal(t, "[build] oops\n", stderr.String())
+	})
+
+	t.Run("ExitError", func(t *testing.T) {
+		t.Parallel()
+
+		inv, _, _ := newInv()
+		err := ui.Exec(inv, "test", "sh", "-c", "exit 3")
+		var exitErr *ui.ExitError
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, 3, exitErr.Code)
+		require.Equal(t, "test", exitErr.Step)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		t.Parallel()
+
+		inv, _, _ := newInv()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := ui.Exec(inv.WithContext(ctx), "sleep", "sleep", "10")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// filterOperators is ordered so that two-character operators are matched
+// before their one-character prefixes.
+var filterOperators = []string{"!=", ">=", "<=", "=", ">", "<", "~"}
+
+type filterCondition struct {
+	column string
+	op     string
+	value  string
+}
+
+// TableFilter is a parsed filter expression that is evaluated against the
+// rendered columns of a table row.
+//
+// An expression is a list of conditions joined by "&&", for example
+// `age>20 && occupation=Adventurer`. Supported operators are =, !=, >, >=,
+// <, <= and ~ (contains). Values are compared numerically when both sides
+// are numbers, and case-insensitively as strings otherwise.
+type TableFilter struct {
+	conditions []filterCondition
+}
+
+// ParseTableFilter parses a filter expression. An empty expression returns a
+// nil filter, which matches every row.
+func ParseTableFilter(expr string) (*TableFilter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	var f TableFilter
+	for _, clause := range strings.Split(expr, "&&") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			return nil, fmt.Errorf("invalid filter %q: empty condition", expr)
+		}
+
+		idx, op := -1, ""
+		for _, candidate := range filterOperators {
+			i := strings.Index(clause, candidate)
+			if i == -1 {
+				continue
+			}
+			if idx == -1 || i < idx {
+				idx, op = i, candidate
+			}
+		}
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid filter condition %q, expected form <column><operator><value>", clause)
+		}
+
+		column := strings.TrimSpace(clause[:idx])
+		f.conditions = append(f.conditions, filterCondition{
+			column: strings.ToLower(strings.ReplaceAll(column, "_", " ")),
+			op:     op,
+			value:  strings.Trim(strings.TrimSpace(clause[idx+len(op):]), `"'`),
+		})
+	}
+	return &f, nil
+}
+
+// match reports whether the row satisfies every condition in the filter.
+// A nil filter matches everything.
+func (f *TableFilter) match(headers table.Row, row []any) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, cond := range f.conditions {
+		var cell string
+		for i, h := range headers {
+			if h == cond.column {
+				if row[i] != nil {
+					cell = fmt.Sprint(row[i])
+				}
+				break
+			}
+		}
+		if !cond.match(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c filterCondition) match(cell string) bool {
+	if c.op == "~" {
+		return strings.Contains(strings.ToLower(cell), strings.ToLower(c.value))
+	}
+
+	var cmp int
+	a, aErr := strconv.ParseFloat(cell, 64)
+	b, bErr := strconv.ParseFloat(c.value, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(strings.ToLower(cell), strings.ToLower(c.value))
+	}
+
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package ui
+
+import (
+	"os"
+
+	"github.com/bketelsen/serpent/internal/hyperlink"
+)
+
+// Link returns text as a clickable OSC 8 terminal hyperlink to url when
+// stdout is a terminal that supports them, and as "text (url)" otherwise.
+//
+// Set FORCE_HYPERLINK=1 or FORCE_HYPERLINK=0 to override detection.
+func Link(text, url string) string {
+	if hyperlinksEnabled() {
+		return hyperlink.Format(text, url)
+	}
+	return hyperlink.Plain(text, url)
+}
+
+func hyperlinksEnabled() bool {
+	return hyperlink.Enabled(isTerminal(os.Stdout), os.Getenv)
+}
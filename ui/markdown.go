@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"io"
+
+	"golang.org/x/term"
+
+	"github.com/bketelsen/serpent/internal/hyperlink"
+	"github.com/bketelsen/serpent/internal/markdown"
+)
+
+// Markdown writes md to w, rendered with terminal styling when w is a
+// terminal and as plain text otherwise, e.g. when output is piped.
+func Markdown(w io.Writer, md string) error {
+	_, err := io.WriteString(w, RenderMarkdown(md, isTerminal(w))+"\n")
+	return err
+}
+
+// RenderMarkdown renders md for display. Headings, code blocks, lists, quotes
+// and inline emphasis are styled when styled is true, and reduced to plain
+// text otherwise.
+//
+// Links are rendered as terminal hyperlinks when styled and supported, see
+// Link.
+func RenderMarkdown(md string, styled bool) string {
+	opts := markdown.Options{Styled: styled}
+	if styled && hyperlinksEnabled() {
+		opts.Link = hyperlink.Format
+	}
+	return markdown.Render(md, opts)
+}
+
+func isTerminal(w io.Writer) bool {
+	// Match any writer exposing a descriptor, including *os.File and the
+	// hook-wrapped writers installed by Invocation.WithOS.
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,55 @@
+package ui_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	md := "# Getting started\n" +
+		"\n" +
+		"Run **serpent** with `--help` or see [the docs](https://example.com).\n" +
+		"\n" +
+		"- first *item*\n" +
+		"  * nested\n" +
+		"1. numbered\n" +
+		"> quoted\n" +
+		"```sh\n" +
+		"$ echo **not bold**\n" +
+		"```\n" +
+		"---"
+
+	t.Run("Plain", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := ui.Markdown(&buf, md)
+		require.NoError(t, err)
+		require.Equal(t, "Getting started\n"+
+			"\n"+
+			"Run serpent with --help or see the docs (https://example.com).\n"+
+			"\n"+
+			"- first item\n"+
+			"  - nested\n"+
+			"1. numbered\n"+
+			"  quoted\n"+
+			"    $ echo **not bold**\n"+
+			"---\n", buf.String())
+	})
+
+	t.Run("Styled", func(t *testing.T) {
+		t.Parallel()
+
+		out := ui.RenderMarkdown(md, true)
+		require.Contains(t, out, "GETTING STARTED")
+		require.Contains(t, out, "• first item")
+		require.Contains(t, out, "    $ echo **not bold**")
+		require.NotContains(t, out, "```")
+	})
+}
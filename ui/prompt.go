@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bketelsen/serpent"
+)
+
+// lineReader reads the answers to prompts line by line, giving up when the
+// context of the prompt is done.
+type lineReader struct {
+	in *bufio.Reader
+	// pending receives the result of the read in flight, if any. A read
+	// interrupted by cancellation is left in flight rather than abandoned,
+	// so that a line arriving later isn't lost to the next prompt.
+	pending chan lineResult
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{in: bufio.NewReader(r)}
+}
+
+// readLine returns the next line, including its newline, or
+// serpent.Canceled if ctx is done first.
+func (r *lineReader) readLine(ctx context.Context) (string, error) {
+	if r.pending == nil {
+		// Buffered, so that the read never blocks on sending its result.
+		r.pending = make(chan lineResult, 1)
+		go func(pending chan<- lineResult) {
+			line, err := r.in.ReadString('\n')
+			pending <- lineResult{line: line, err: err}
+		}(r.pending)
+	}
+	select {
+	case res := <-r.pending:
+		r.pending = nil
+		return res.line, res.err
+	case <-ctx.Done():
+		return "", serpent.Canceled
+	}
+}
+
+// promptContext returns the context prompts of inv run in, done when the
+// invocation's context is canceled or SIGINT arrives.
+func promptContext(inv *serpent.Invocation) (context.Context, context.CancelFunc) {
+	return inv.SignalNotifyContext(inv.Context(), os.Interrupt)
+}
+
+// endPrompt ends the line of an interrupted prompt, so that the shell prompt
+// or error message that follows starts on a line of its own.
+func endPrompt(w io.Writer, err error) {
+	if errors.Is(err, serpent.Canceled) {
+		_, _ = fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/bketelsen/serpent"
+)
+
+// Step reports progress on a multi-step operation to the invocation's
+// Stderr. If events are enabled, e.g. in machine mode, the step is emitted as
+// an event instead. Nothing is reported in quiet mode.
+func Step(inv *serpent.Invocation, name string, format string, args ...any) {
+	if inv.Quiet() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if inv.EventsEnabled() {
+		inv.Emit(serpent.Event{Kind: serpent.EventStep, Level: "info", Step: name, Msg: msg})
+		return
+	}
+	_, _ = fmt.Fprintf(inv.Stderr, "%s %s\n", serpent.Keyword("["+name+"]"), msg)
+}
+
+// Progress reports that completed of total items of the operation name are
+// done to the invocation's Stderr, or as an event if events are enabled.
+// Nothing is reported in quiet mode.
+func Progress(inv *serpent.Invocation, name string, completed, total int) {
+	if inv.Quiet() {
+		return
+	}
+	if inv.EventsEnabled() {
+		inv.Emit(serpent.Event{
+			Kind:      serpent.EventProgress,
+			Level:     "info",
+			Step:      name,
+			Msg:       fmt.Sprintf("%d/%d", completed, total),
+			Completed: completed,
+			Total:     total,
+		})
+		return
+	}
+	_, _ = fmt.Fprintf(inv.Stderr, "%s %d/%d\n", serpent.Keyword("["+name+"]"), completed, total)
+}
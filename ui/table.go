@@ -3,14 +3,22 @@
 package ui
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"os"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fatih/structtag"
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"golang.org/x/term"
+
+	"github.com/bketelsen/serpent"
 )
 
 // Table creates a new table with standardized styles.
@@ -24,6 +32,105 @@ func Table() table.Writer {
 	return tableWriter
 }
 
+// TableOptions controls how DisplayTableWithOptions renders a table.
+type TableOptions struct {
+	// Sort is the column to sort by, optionally followed by ",asc" or
+	// ",desc". If empty, the default_sort column is used.
+	Sort string
+	// Filter is a row filter expression, see TableFilter.
+	Filter string
+	// Columns is the list of columns to display. If empty, all columns
+	// except wide ones are displayed.
+	Columns []string
+	// Wide displays columns tagged with `table:"...,wide"`.
+	Wide bool
+	// MaxWidth is the width the table is truncated to fit. If zero, the
+	// width of the terminal is used, and tables written elsewhere are never
+	// truncated. A negative value disables truncation.
+	MaxWidth int
+	// RelativeTime formats times relative to Now, e.g. "3m ago", instead of
+	// as RFC 3339 timestamps.
+	RelativeTime bool
+	// Now is the reference time for RelativeTime. If zero, time.Now() is used.
+	Now time.Time
+	// SliceSeparator joins slice values. If empty, slices are rendered as
+	// "[a, b, c]".
+	SliceSeparator string
+}
+
+func (o TableOptions) formatTime(t time.Time) string {
+	if !o.RelativeTime {
+		return t.Format(time.RFC3339)
+	}
+	now := o.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return RelativeTime(now, t)
+}
+
+// RelativeTime formats t relative to now in a compact, human-readable form
+// such as "3m ago" or "in 2h".
+func RelativeTime(now, t time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var s string
+	switch {
+	case d < time.Second:
+		return "just now"
+	case d < time.Minute:
+		s = fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		s = fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < 365*24*time.Hour:
+		s = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	default:
+		s = fmt.Sprintf("%dy", int(d/(365*24*time.Hour)))
+	}
+	if future {
+		return "in " + s
+	}
+	return s + " ago"
+}
+
+// Options returns the standard --sort, --filter and --column options bound
+// to the TableOptions, so listing commands can expose them consistently.
+func (o *TableOptions) Options() serpent.OptionSet {
+	return serpent.OptionSet{
+		{
+			Name:        "sort",
+			Description: "Column to sort by, optionally followed by \",asc\" or \",desc\".",
+			Flag:        "sort",
+			Value:       serpent.StringOf(&o.Sort),
+		},
+		{
+			Name:        "filter",
+			Description: "Only show rows matching the expression, e.g. \"age>20 && name~bob\".",
+			Flag:        "filter",
+			Value:       serpent.StringOf(&o.Filter),
+		},
+		{
+			Name:          "column",
+			Description:   "Columns to display in table output.",
+			Flag:          "column",
+			FlagShorthand: "c",
+			Value:         serpent.StringArrayOf(&o.Columns),
+		},
+		{
+			Name:        "wide",
+			Description: "Show additional columns in table output.",
+			Flag:        "wide",
+			Value:       serpent.BoolOf(&o.Wide),
+		},
+	}
+}
+
 // This type can be supplied as part of a slice to DisplayTable
 // or to a `TableFormat` `Format` call to render a separator.
 // Leading separators are not supported and trailing separators
@@ -31,31 +138,6 @@ func Table() table.Writer {
 // e.g. `[]any{someRow, TableSeparator, someRow}`
 type TableSeparator struct{}
 
-// filterTableColumns returns configurations to hide columns
-// that are not provided in the array. If the array is empty,
-// no filtering will occur!
-func filterTableColumns(header table.Row, columns []string) []table.ColumnConfig {
-	if len(columns) == 0 {
-		return nil
-	}
-	columnConfigs := make([]table.ColumnConfig, 0)
-	for _, headerTextRaw := range header {
-		headerText, _ := headerTextRaw.(string)
-		hidden := true
-		for _, column := range columns {
-			if strings.EqualFold(strings.ReplaceAll(column, "_", " "), headerText) {
-				hidden = false
-				break
-			}
-		}
-		columnConfigs = append(columnConfigs, table.ColumnConfig{
-			Name:   headerText,
-			Hidden: hidden,
-		})
-	}
-	return columnConfigs
-}
-
 // DisplayTable renders a table as a string. The input argument can be:
 //   - a struct slice.
 //   - an interface slice, where the first element is a struct,
@@ -68,7 +150,8 @@ func filterTableColumns(header table.Row, columns []string) []table.ColumnConfig
 // tag will be used to sort. An error will be returned if no field has this tag.
 //
 // Nested structs are processed if the field has the `table:"$NAME,recursive"`
-// tag and their fields will be named as `$PARENT_NAME $NAME`. If the tag is
+// tag and their fields will be named as `$PARENT_NAME $NAME`, or
+// `$PARENT_NAME.$NAME` with the `table:"$NAME,flatten"` tag. If the tag is
 // malformed or a field is marked as recursive but does not contain a struct or
 // a pointer to a struct, this function will return an error (even with an empty
 // input slice).
@@ -76,6 +159,15 @@ func filterTableColumns(header table.Row, columns []string) []table.ColumnConfig
 // If sort is empty, the input order will be used. If filterColumns is empty or
 // nil, all available columns are included.
 func DisplayTable(out any, sort string, filterColumns []string) (string, error) {
+	return DisplayTableWithOptions(out, TableOptions{
+		Sort:    sort,
+		Columns: filterColumns,
+	})
+}
+
+// DisplayTableWithOptions renders a table as a string, like DisplayTable, but
+// additionally supports descending sorts and row filter expressions.
+func DisplayTableWithOptions(out any, opts TableOptions) (string, error) {
 	v := reflect.Indirect(reflect.ValueOf(out))
 
 	if v.Kind() != reflect.Slice {
@@ -92,22 +184,33 @@ func DisplayTable(out any, sort string, filterColumns []string) (string, error)
 	}
 
 	// Get the list of table column headers.
-	headersRaw, defaultSort, err := typeToTableHeaders(tableType, true)
+	columns, defaultSort, err := typeToTableHeaders(tableType, true)
 	if err != nil {
 		return "", fmt.Errorf("get table headers recursively for type %q: %w", v.Type().Elem().String(), err)
 	}
+	headersRaw := make([]string, len(columns))
+	for i, column := range columns {
+		headersRaw[i] = column.name
+	}
 	if len(headersRaw) == 0 {
 		return "", errors.New(`no table headers found on the input type, make sure there is at least one "table" struct tag`)
 	}
+	sort, desc, err := parseTableSort(opts.Sort)
+	if err != nil {
+		return "", err
+	}
 	if sort == "" {
 		sort = defaultSort
 	}
-	headers := make(table.Row, len(headersRaw))
-	for i, header := range headersRaw {
-		headers[i] = strings.ReplaceAll(header, "_", " ")
+	filter, err := ParseTableFilter(opts.Filter)
+	if err != nil {
+		return "", err
 	}
-	// Verify that the given sort column and filter columns are valid.
-	if sort != "" || len(filterColumns) != 0 {
+	// Copy so autocorrection doesn't mutate the caller's slice.
+	filterColumns := append([]string(nil), opts.Columns...)
+	// Verify that the given sort column, filter columns and filter expression
+	// columns are valid.
+	if sort != "" || len(filterColumns) != 0 || filter != nil {
 		headersMap := make(map[string]string, len(headersRaw))
 		for _, header := range headersRaw {
 			headersMap[strings.ToLower(header)] = header
@@ -134,6 +237,18 @@ func DisplayTable(out any, sort string, filterColumns []string) (string, error)
 			// Autocorrect
 			filterColumns[i] = h
 		}
+
+		if filter != nil {
+			for i, cond := range filter.conditions {
+				h, ok := headersMap[cond.column]
+				if !ok {
+					return "", fmt.Errorf(`filter column %q not found in table headers, available columns are "%v"`, cond.column, strings.Join(headersRaw, `", "`))
+				}
+
+				// Autocorrect
+				filter.conditions[i].column = h
+			}
+		}
 	}
 
 	// Verify that the given sort column is valid.
@@ -151,20 +266,61 @@ func DisplayTable(out any, sort string, filterColumns []string) (string, error)
 			return "", fmt.Errorf("specified sort column %q not found in table headers, available columns are %q", sort, strings.Join(headersRaw, `", "`))
 		}
 	}
-	return renderTable(out, sort, headers, filterColumns)
+	sortBy := table.SortBy{Name: sort, Mode: table.Asc}
+	if desc {
+		sortBy.Mode = table.Dsc
+	}
+	opts.Columns = filterColumns
+	return renderTable(out, sortBy, columns, opts, filter)
+}
+
+// parseTableSort splits a sort specification of the form "column[,asc|desc]"
+// into the column name and whether the sort is descending.
+func parseTableSort(spec string) (column string, desc bool, err error) {
+	column, direction, _ := strings.Cut(spec, ",")
+	column = strings.TrimSpace(column)
+	switch strings.ToLower(strings.TrimSpace(direction)) {
+	case "", "asc":
+		return column, false, nil
+	case "desc":
+		return column, true, nil
+	default:
+		return "", false, fmt.Errorf("invalid sort direction %q, must be one of \"asc\" or \"desc\"", direction)
+	}
 }
 
-func renderTable(out any, sort string, headers table.Row, filterColumns []string) (string, error) {
+func renderTable(out any, sort table.SortBy, columns []tableColumn, opts TableOptions, filter *TableFilter) (string, error) {
 	v := reflect.Indirect(reflect.ValueOf(out))
 
+	headers := make(table.Row, len(columns))
+	for i, column := range columns {
+		headers[i] = column.name
+	}
+	columnConfigs := tableColumnConfigs(columns, opts.Columns, opts.Wide)
+
 	// Setup the table formatter.
 	tw := Table()
 	tw.AppendHeader(headers)
-	tw.SetColumnConfigs(filterTableColumns(headers, filterColumns))
-	if sort != "" {
-		tw.SortBy([]table.SortBy{{
-			Name: sort,
-		}})
+	sortColumn := -1
+	for i, h := range headers {
+		if h.(string) == sort.Name {
+			sortColumn = i
+		}
+	}
+
+	// Rows are sorted before they are added to the table, since the table
+	// formatter can only sort them by their formatted text. Separators stay
+	// after the same number of rows.
+	var (
+		rows       []tableRow
+		separators []int
+	)
+
+	// Track the natural width of every column so we can truncate them to
+	// fit the terminal later on.
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = text.RuneWidthWithoutEscSequences(h.(string))
 	}
 
 	// Write each struct to the table.
@@ -172,7 +328,7 @@ func renderTable(out any, sort string, headers table.Row, filterColumns []string
 		cur := v.Index(i).Interface()
 		_, ok := cur.(TableSeparator)
 		if ok {
-			tw.AppendSeparator()
+			separators = append(separators, len(rows))
 			continue
 		}
 		// Format the row as a slice.
@@ -183,19 +339,21 @@ func renderTable(out any, sort string, headers table.Row, filterColumns []string
 		}
 
 		rowSlice := make([]any, len(headers))
+		var key any
 		for i, h := range headers {
 			v, ok := rowMap[h.(string)]
 			if !ok {
 				v = nil
 			}
+			raw := v
 
 			// Special type formatting.
 			switch val := v.(type) {
 			case time.Time:
-				v = val.Format(time.RFC3339)
+				v = opts.formatTime(val)
 			case *time.Time:
 				if val != nil {
-					v = val.Format(time.RFC3339)
+					v = opts.formatTime(*val)
 				}
 
 			case *string:
@@ -233,7 +391,11 @@ func renderTable(out any, sort string, headers table.Row, filterColumns []string
 					for i := 0; i < vt.Len(); i++ {
 						strs = append(strs, fmt.Sprintf("%v", vt.Index(i).Interface()))
 					}
-					v = "[" + strings.Join(strs, ", ") + "]"
+					if opts.SliceSeparator != "" {
+						v = strings.Join(strs, opts.SliceSeparator)
+					} else {
+						v = "[" + strings.Join(strs, ", ") + "]"
+					}
 				default:
 					// Leave it as it is
 				}
@@ -252,55 +414,284 @@ func renderTable(out any, sort string, headers table.Row, filterColumns []string
 			}
 
 			rowSlice[i] = v
+			if i == sortColumn {
+				key = sortKey(raw, v)
+			}
+		}
+
+		if !filter.match(headers, rowSlice) {
+			continue
+		}
+		for i, cell := range rowSlice {
+			if w := text.RuneWidthWithoutEscSequences(fmt.Sprint(cell)); w > widths[i] {
+				widths[i] = w
+			}
 		}
+		rows = append(rows, tableRow{cells: rowSlice, key: key})
+	}
+
+	if sortColumn >= 0 {
+		slices.SortStableFunc(rows, func(a, b tableRow) int {
+			if sort.Mode == table.Dsc {
+				return compareSortKeys(b.key, a.key)
+			}
+			return compareSortKeys(a.key, b.key)
+		})
+	}
+	for i, row := range rows {
+		for len(separators) > 0 && separators[0] == i {
+			tw.AppendSeparator()
+			separators = separators[1:]
+		}
+		tw.AppendRow(row.cells)
+	}
 
-		tw.AppendRow(table.Row(rowSlice))
+	maxWidth := opts.MaxWidth
+	if maxWidth == 0 {
+		maxWidth = terminalWidth()
 	}
+	fitColumnWidths(columnConfigs, columns, widths, maxWidth)
+	tw.SetColumnConfigs(columnConfigs)
 
 	return tw.Render(), nil
 }
 
-// parseTableStructTag returns the name of the field according to the `table`
-// struct tag. If the table tag does not exist or is "-", an empty string is
-// returned. If the table tag is malformed, an error is returned.
-//
-// The returned name is transformed from "snake_case" to "normal text".
-func parseTableStructTag(field reflect.StructField) (name string, defaultSort, noSortOpt, recursive, skipParentName bool, err error) {
+// tableRow is a row of a table, with the value of its sort column.
+type tableRow struct {
+	cells table.Row
+	key   any
+}
+
+// sortKey returns the value a cell is sorted by: the time or duration raw
+// holds, as its formatted text, e.g. "3m ago", doesn't sort chronologically,
+// or the text of the cell otherwise.
+func sortKey(raw, cell any) any {
+	switch val := raw.(type) {
+	case time.Time:
+		return val
+	case *time.Time:
+		if val != nil {
+			return *val
+		}
+	case time.Duration:
+		return val
+	case *time.Duration:
+		if val != nil {
+			return *val
+		}
+	}
+	return fmt.Sprint(cell)
+}
+
+// compareSortKeys compares sort keys returned by sortKey. Text, such as nil
+// values, sorts before times and durations.
+func compareSortKeys(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b)
+		}
+	case time.Duration:
+		if b, ok := b.(time.Duration); ok {
+			return cmp.Compare(a, b)
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+		return -1
+	}
+	if _, ok := b.(string); ok {
+		return 1
+	}
+	return 0
+}
+
+// tableColumnPadding is the padding Table() puts after every column.
+const tableColumnPadding = 2
+
+// defaultMinColumnWidth is the narrowest a column without a min_width hint
+// is truncated to.
+const defaultMinColumnWidth = 6
+
+// tableColumnConfigs returns a configuration for every column, hiding wide
+// columns unless wide is set, and any columns not in filterColumns if it's
+// non-empty.
+func tableColumnConfigs(columns []tableColumn, filterColumns []string, wide bool) []table.ColumnConfig {
+	configs := make([]table.ColumnConfig, len(columns))
+	for i, column := range columns {
+		hidden := column.wide && !wide
+		if len(filterColumns) > 0 {
+			hidden = true
+			for _, fc := range filterColumns {
+				if strings.EqualFold(strings.ReplaceAll(fc, "_", " "), column.name) {
+					hidden = false
+					break
+				}
+			}
+		}
+		configs[i] = table.ColumnConfig{
+			Name:   column.name,
+			Hidden: hidden,
+		}
+	}
+	return configs
+}
+
+// fitColumnWidths shrinks the widest visible columns, down to their minimum
+// width, until the table fits in maxWidth. Truncated cells end in an ellipsis.
+// A maxWidth below zero disables truncation.
+func fitColumnWidths(configs []table.ColumnConfig, columns []tableColumn, widths []int, maxWidth int) {
+	if maxWidth < 0 {
+		return
+	}
+
+	total := 0
+	for i := range configs {
+		if !configs[i].Hidden {
+			total += widths[i] + tableColumnPadding
+		}
+	}
+
+	for total > maxWidth {
+		// Find the widest column that can still shrink.
+		widest := -1
+		for i := range configs {
+			if configs[i].Hidden || widths[i] <= columnMinWidth(columns[i]) {
+				continue
+			}
+			if widest == -1 || widths[i] > widths[widest] {
+				widest = i
+			}
+		}
+		if widest == -1 {
+			// Everything is at its minimum, nothing more we can do.
+			break
+		}
+		widths[widest]--
+		total--
+		configs[widest].WidthMax = widths[widest]
+		configs[widest].WidthMaxEnforcer = truncateWithEllipsis
+	}
+}
+
+func columnMinWidth(column tableColumn) int {
+	if column.minWidth > 0 {
+		return column.minWidth
+	}
+	return defaultMinColumnWidth
+}
+
+// truncateWithEllipsis trims s to maxLen, replacing the last visible
+// character with an ellipsis if it was cut.
+func truncateWithEllipsis(s string, maxLen int) string {
+	if text.RuneWidthWithoutEscSequences(s) <= maxLen {
+		return s
+	}
+	if maxLen <= 1 {
+		return text.Trim(s, maxLen)
+	}
+	return text.Trim(s, maxLen-1) + "…"
+}
+
+// terminalWidth returns the width of the terminal attached to stdout, or -1
+// if stdout is not a terminal, in which case tables are never truncated.
+func terminalWidth() int {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return -1
+	}
+	width, _, err := term.GetSize(fd)
+	if err != nil {
+		return -1
+	}
+	return width
+}
+
+// tableTag is the parsed form of a `table` struct tag.
+type tableTag struct {
+	// name is transformed from "snake_case" to "normal text".
+	name           string
+	defaultSort    bool
+	noSort         bool
+	recursive      bool
+	skipParentName bool
+	// flatten is like recursive, but child columns are named
+	// "$PARENT_NAME.$NAME".
+	flatten bool
+	// wide columns are only displayed when TableOptions.Wide is set or the
+	// column is requested explicitly.
+	wide bool
+	// minWidth is the narrowest the column may be truncated to when the
+	// table does not fit the terminal.
+	minWidth int
+}
+
+// parseTableStructTag returns the parsed `table` struct tag of the field. If
+// the table tag does not exist or is "-", the returned name is empty. If the
+// table tag is malformed, an error is returned.
+func parseTableStructTag(field reflect.StructField) (tableTag, error) {
 	tags, err := structtag.Parse(string(field.Tag))
 	if err != nil {
-		return "", false, false, false, false, fmt.Errorf("parse struct field tag %q: %w", string(field.Tag), err)
+		return tableTag{}, fmt.Errorf("parse struct field tag %q: %w", string(field.Tag), err)
 	}
 
 	tag, err := tags.Get("table")
 	if err != nil || tag.Name == "-" {
 		// tags.Get only returns an error if the tag is not found.
-		return "", false, false, false, false, nil
+		return tableTag{}, nil
 	}
 
-	defaultSortOpt := false
-	noSortOpt = false
-	recursiveOpt := false
-	skipParentNameOpt := false
+	parsed := tableTag{name: strings.ReplaceAll(tag.Name, "_", " ")}
 	for _, opt := range tag.Options {
 		switch opt {
 		case "default_sort":
-			defaultSortOpt = true
+			parsed.defaultSort = true
 		case "nosort":
-			noSortOpt = true
+			parsed.noSort = true
 		case "recursive":
-			recursiveOpt = true
+			parsed.recursive = true
 		case "recursive_inline":
 			// recursive_inline is a helper to make recursive tables look nicer.
 			// It skips prefixing the parent name to the child name. If you do this,
 			// make sure the child name is unique across all nested structs in the parent.
-			recursiveOpt = true
-			skipParentNameOpt = true
+			parsed.recursive = true
+			parsed.skipParentName = true
+		case "flatten":
+			parsed.recursive = true
+			parsed.flatten = true
+		case "wide":
+			parsed.wide = true
 		default:
-			return "", false, false, false, false, fmt.Errorf("unknown option %q in struct field tag", opt)
+			if v, ok := strings.CutPrefix(opt, "min_width="); ok {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 {
+					return tableTag{}, fmt.Errorf("invalid min_width %q in struct field tag", v)
+				}
+				parsed.minWidth = n
+				continue
+			}
+			return tableTag{}, fmt.Errorf("unknown option %q in struct field tag", opt)
 		}
 	}
 
-	return strings.ReplaceAll(tag.Name, "_", " "), defaultSortOpt, noSortOpt, recursiveOpt, skipParentNameOpt, nil
+	return parsed, nil
+}
+
+// childColumnName returns the name of a column nested under the field
+// described by parent.
+func childColumnName(parent tableTag, child string) string {
+	if parent.flatten {
+		return parent.name + "." + child
+	}
+	return fmt.Sprintf("%s %s", parent.name, child)
+}
+
+// tableColumn describes a single column of a table.
+type tableColumn struct {
+	name     string
+	wide     bool
+	minWidth int
 }
 
 func isStructOrStructPointer(t reflect.Type) bool {
@@ -314,7 +705,7 @@ func isStructOrStructPointer(t reflect.Type) bool {
 // requireDefault is only needed for the root call. This is recursive, so nested
 // structs do not need the default sort name.
 // nolint:revive
-func typeToTableHeaders(t reflect.Type, requireDefault bool) ([]string, string, error) {
+func typeToTableHeaders(t reflect.Type, requireDefault bool) ([]tableColumn, string, error) {
 	if !isStructOrStructPointer(t) {
 		return nil, "", fmt.Errorf("typeToTableHeaders called with a non-struct or a non-pointer-to-a-struct type")
 	}
@@ -322,16 +713,17 @@ func typeToTableHeaders(t reflect.Type, requireDefault bool) ([]string, string,
 		t = t.Elem()
 	}
 
-	headers := []string{}
+	headers := []tableColumn{}
 	defaultSortName := ""
 	noSortOpt := false
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		name, defaultSort, noSort, recursive, skip, err := parseTableStructTag(field)
+		tag, err := parseTableStructTag(field)
 		if err != nil {
 			return nil, "", fmt.Errorf("parse struct tags for field %q in type %q: %w", field.Name, t.String(), err)
 		}
-		if requireDefault && noSort {
+		name, recursive, skip := tag.name, tag.recursive, tag.skipParentName
+		if requireDefault && tag.noSort {
 			noSortOpt = true
 		}
 
@@ -343,7 +735,7 @@ func typeToTableHeaders(t reflect.Type, requireDefault bool) ([]string, string,
 		if name == "" {
 			continue
 		}
-		if defaultSort {
+		if tag.defaultSort {
 			if defaultSortName != "" {
 				return nil, "", fmt.Errorf("multiple fields marked as default sort in type %q", t.String())
 			}
@@ -356,16 +748,17 @@ func typeToTableHeaders(t reflect.Type, requireDefault bool) ([]string, string,
 				return nil, "", fmt.Errorf("field %q in type %q is marked as recursive but does not contain a struct or a pointer to a struct", field.Name, t.String())
 			}
 
-			childNames, defaultSort, err := typeToTableHeaders(fieldType, false)
+			children, defaultSort, err := typeToTableHeaders(fieldType, false)
 			if err != nil {
 				return nil, "", fmt.Errorf("get child field header names for field %q in type %q: %w", field.Name, fieldType.String(), err)
 			}
-			for _, childName := range childNames {
-				fullName := fmt.Sprintf("%s %s", name, childName)
-				if skip {
-					fullName = childName
+			for _, child := range children {
+				if !skip {
+					child.name = childColumnName(tag, child.name)
 				}
-				headers = append(headers, fullName)
+				// A wide parent makes all of its children wide.
+				child.wide = child.wide || tag.wide
+				headers = append(headers, child)
 			}
 			if defaultSortName == "" {
 				defaultSortName = defaultSort
@@ -373,7 +766,11 @@ func typeToTableHeaders(t reflect.Type, requireDefault bool) ([]string, string,
 			continue
 		}
 
-		headers = append(headers, name)
+		headers = append(headers, tableColumn{
+			name:     name,
+			wide:     tag.wide,
+			minWidth: tag.minWidth,
+		})
 	}
 
 	if defaultSortName == "" && requireDefault && !noSortOpt {
@@ -404,10 +801,11 @@ func valueToTableMap(val reflect.Value) (map[string]any, error) {
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Type().Field(i)
 		fieldVal := val.Field(i)
-		name, _, _, recursive, skip, err := parseTableStructTag(field)
+		tag, err := parseTableStructTag(field)
 		if err != nil {
 			return nil, fmt.Errorf("parse struct tags for field %q in type %T: %w", field.Name, val, err)
 		}
+		name, recursive, skip := tag.name, tag.recursive, tag.skipParentName
 		if name == "" {
 			continue
 		}
@@ -426,7 +824,7 @@ func valueToTableMap(val reflect.Value) (map[string]any, error) {
 				return nil, fmt.Errorf("get child field values for field %q in type %q: %w", field.Name, fieldType.String(), err)
 			}
 			for childName, childValue := range childMap {
-				fullName := fmt.Sprintf("%s %s", name, childName)
+				fullName := childColumnName(tag, childName)
 				if skip {
 					fullName = childName
 				}
@@ -58,6 +58,19 @@ type tableTest4 struct {
 	SortField string     `table:"sort_field"`
 }
 
+type tableTestWide struct {
+	Name        string `table:"name,default_sort"`
+	Description string `table:"description,min_width=8"`
+	ID          string `table:"id,wide"`
+}
+
+type tableTestFlatten struct {
+	Name    string     `table:"name,default_sort"`
+	Owner   tableTest2 `table:"owner,flatten"`
+	Tags    []string   `table:"tags"`
+	Created time.Time  `table:"created"`
+}
+
 func Test_DisplayTable(t *testing.T) {
 	t.Parallel()
 
@@ -200,6 +213,201 @@ foo   foo1        foo3              2022-08-02T15:49:10Z
 		compareTables(t, expected, out)
 	})
 
+	t.Run("SortDesc", func(t *testing.T) {
+		t.Parallel()
+
+		expected := `
+NAME  AGE
+baz    30
+bar    20
+foo    10
+		`
+
+		out, err := ui.DisplayTableWithOptions(in, ui.TableOptions{
+			Sort:    "age,desc",
+			Columns: []string{"name", "age"},
+		})
+		log.Println("rendered table:\n" + out)
+		require.NoError(t, err)
+		compareTables(t, expected, out)
+	})
+
+	t.Run("FilterExpression", func(t *testing.T) {
+		t.Parallel()
+
+		expected := `
+NAME  AGE  SUB 1 NAME
+bar    20  bar1
+baz    30  baz1
+		`
+
+		out, err := ui.DisplayTableWithOptions(in, ui.TableOptions{
+			Filter:  "age>=20 && sub_1_name~BA",
+			Columns: []string{"name", "age", "sub_1_name"},
+		})
+		log.Println("rendered table:\n" + out)
+		require.NoError(t, err)
+		compareTables(t, expected, out)
+
+		out, err = ui.DisplayTableWithOptions(in, ui.TableOptions{
+			Filter:  "name!=bar && age<30",
+			Columns: []string{"name", "age", "sub_1_name"},
+		})
+		require.NoError(t, err)
+		compareTables(t, `
+NAME  AGE  SUB 1 NAME
+foo    10  foo1
+		`, out)
+	})
+
+	t.Run("Wide", func(t *testing.T) {
+		t.Parallel()
+
+		wideIn := []tableTestWide{
+			{Name: "alice", Description: "short", ID: "1234"},
+		}
+
+		out, err := ui.DisplayTable(wideIn, "", nil)
+		require.NoError(t, err)
+		compareTables(t, `
+NAME   DESCRIPTION
+alice  short
+		`, out)
+
+		out, err = ui.DisplayTableWithOptions(wideIn, ui.TableOptions{Wide: true})
+		require.NoError(t, err)
+		compareTables(t, `
+NAME   DESCRIPTION  ID
+alice  short        1234
+		`, out)
+
+		// Explicitly requested wide columns are always shown.
+		out, err = ui.DisplayTable(wideIn, "", []string{"name", "id"})
+		require.NoError(t, err)
+		compareTables(t, `
+NAME   ID
+alice  1234
+		`, out)
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		t.Parallel()
+
+		truncIn := []tableTestWide{
+			{Name: "alice", Description: "a very long description that will not fit"},
+		}
+
+		out, err := ui.DisplayTableWithOptions(truncIn, ui.TableOptions{MaxWidth: 24})
+		log.Println("rendered table:\n" + out)
+		require.NoError(t, err)
+		compareTables(t, `
+NAME   DESCRIPTION
+alice  a very long de…
+		`, out)
+
+		// Columns never shrink below their minimum width.
+		out, err = ui.DisplayTableWithOptions(truncIn, ui.TableOptions{MaxWidth: 1})
+		require.NoError(t, err)
+		compareTables(t, `
+NAME   DESCRIP…
+alice  a very …
+		`, out)
+
+		// Negative widths disable truncation.
+		out, err = ui.DisplayTableWithOptions(truncIn, ui.TableOptions{MaxWidth: -1})
+		require.NoError(t, err)
+		compareTables(t, `
+NAME   DESCRIPTION
+alice  a very long description that will not fit
+		`, out)
+	})
+
+	t.Run("Flatten", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		flatIn := []tableTestFlatten{
+			{
+				Name:    "alpha",
+				Owner:   tableTest2{Name: stringWrapper{str: "alice"}, Age: 30},
+				Tags:    []string{"a", "b"},
+				Created: now.Add(-3 * time.Minute),
+			},
+			{
+				Name:    "beta",
+				Owner:   tableTest2{Name: stringWrapper{str: "bob"}, Age: 40},
+				Created: now.Add(-50 * time.Hour),
+			},
+		}
+
+		out, err := ui.DisplayTableWithOptions(flatIn, ui.TableOptions{
+			RelativeTime:   true,
+			Now:            now,
+			SliceSeparator: ",",
+		})
+		log.Println("rendered table:\n" + out)
+		require.NoError(t, err)
+		compareTables(t, `
+NAME   OWNER.NAME  OWNER.AGE  TAGS  CREATED
+alpha  alice              30  a,b   3m ago
+beta   bob                40        2d ago
+		`, out)
+
+		out, err = ui.DisplayTableWithOptions(flatIn, ui.TableOptions{
+			Sort:    "owner.age,desc",
+			Columns: []string{"name", "owner.age"},
+		})
+		require.NoError(t, err)
+		compareTables(t, `
+NAME   OWNER.AGE
+beta          40
+alpha         30
+		`, out)
+	})
+
+	t.Run("SortTimes", func(t *testing.T) {
+		t.Parallel()
+
+		type job struct {
+			Name    string        `table:"name,default_sort"`
+			Started time.Time     `table:"started"`
+			Took    time.Duration `table:"took"`
+		}
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		jobs := []job{
+			{Name: "a", Started: now.Add(-5 * time.Minute), Took: 90 * time.Second},
+			{Name: "b", Started: now.Add(-2 * time.Hour), Took: 5 * time.Minute},
+			{Name: "c", Started: now.Add(-40 * time.Minute), Took: 45 * time.Second},
+		}
+
+		// Lexicographically, "2h ago" < "40m ago" < "5m ago".
+		out, err := ui.DisplayTableWithOptions(jobs, ui.TableOptions{
+			Sort:         "started,desc",
+			RelativeTime: true,
+			Now:          now,
+		})
+		require.NoError(t, err)
+		compareTables(t, `
+NAME  STARTED  TOOK
+a     5m ago   1m30s
+c     40m ago  45s
+b     2h ago   5m0s
+		`, out)
+
+		// Lexicographically, "1m30s" < "45s" < "5m0s".
+		out, err = ui.DisplayTableWithOptions(jobs, ui.TableOptions{
+			Sort:    "took",
+			Columns: []string{"name", "took"},
+		})
+		require.NoError(t, err)
+		compareTables(t, `
+NAME  TOOK
+c     45s
+a     1m30s
+b     5m0s
+		`, out)
+	})
+
 	t.Run("Inline", func(t *testing.T) {
 		t.Parallel()
 
@@ -281,6 +489,32 @@ foo   <nil>      10  [a, b, c]  foo1               11  foo2        12         fo
 			require.Error(t, err)
 		})
 
+		t.Run("BadMinWidth", func(t *testing.T) {
+			t.Parallel()
+
+			type badMinWidth struct {
+				Field string `table:"field,nosort,min_width=zero"`
+			}
+			_, err := ui.DisplayTable([]badMinWidth{}, "", nil)
+			require.Error(t, err)
+		})
+
+		t.Run("BadSortDirection", func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ui.DisplayTable(in, "age,sideways", nil)
+			require.Error(t, err)
+		})
+
+		t.Run("BadFilterExpression", func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ui.DisplayTableWithOptions(in, ui.TableOptions{Filter: "age"})
+			require.Error(t, err)
+			_, err = ui.DisplayTableWithOptions(in, ui.TableOptions{Filter: "bad_column=1"})
+			require.Error(t, err)
+		})
+
 		t.Run("BadFilterColumns", func(t *testing.T) {
 			t.Parallel()
 
@@ -394,6 +628,24 @@ foo   <nil>      10  [a, b, c]  foo1               11  foo2        12         fo
 	})
 }
 
+func TestRelativeTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		t    time.Time
+		want string
+	}{
+		{now, "just now"},
+		{now.Add(-5 * time.Second), "5s ago"},
+		{now.Add(-90 * time.Minute), "1h ago"},
+		{now.Add(2 * time.Hour), "in 2h"},
+		{now.Add(-800 * 24 * time.Hour), "2y ago"},
+	} {
+		require.Equal(t, tc.want, ui.RelativeTime(now, tc.t))
+	}
+}
+
 // compareTables normalizes the incoming table lines
 func compareTables(t *testing.T, expected, out string) {
 	t.Helper()
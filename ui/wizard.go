@@ -0,0 +1,283 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bketelsen/serpent"
+)
+
+// AnnotationFilePath marks an option whose value is a file path, so the
+// Wizard offers a file picker for it. Options with a value type ending in
+// "path", such as serpent.YAMLConfigPath, are treated the same way.
+const AnnotationFilePath = "ui.file_path"
+
+// wizardMaxFiles is the number of files the file picker lists.
+const wizardMaxFiles = 20
+
+// Wizard walks the user through the options of a command interactively,
+// then runs the command or prints the equivalent command line.
+//
+// Required options are asked for first. Other options are offered a group
+// at a time, in the order of their first option. Enums are chosen from a
+// list and file paths from the files in the working directory. Hidden
+// options and options without a flag are skipped.
+type Wizard struct {
+	Command *serpent.Command
+	// PrintOnly prints the command line instead of running the command.
+	PrintOnly bool
+}
+
+// Run asks for the options on inv's Stdin and Stdout, then runs the command
+// with the invocation's IO and environment, or prints the command line.
+func (w *Wizard) Run(inv *serpent.Invocation) error {
+	args, err := w.Ask(inv)
+	if err != nil {
+		return err
+	}
+
+	root := w.Command
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	path := commandPath(w.Command)
+	if w.PrintOnly {
+		_, _ = fmt.Fprintln(inv.Stdout, shellJoin(append([]string{root.Name()}, append(path, args...)...)))
+		return nil
+	}
+
+	run := root.Invoke(append(path, args...)...).WithContext(inv.Context())
+	run.Stdout = inv.Stdout
+	run.Stderr = inv.Stderr
+	run.Stdin = inv.Stdin
+	run.Environ = inv.Environ
+	return run.Run()
+}
+
+// Ask asks for the options and returns them as flags, e.g. "--port=8080".
+// It returns serpent.Canceled if the invocation's context is canceled or
+// SIGINT arrives while waiting for an answer.
+func (w *Wizard) Ask(inv *serpent.Invocation) (args []string, err error) {
+	ctx, stop := promptContext(inv)
+	defer stop()
+	p := &wizardPrompter{ctx: ctx, in: newLineReader(inv.Stdin), out: inv.Stdout}
+	defer func() {
+		endPrompt(p.out, err)
+	}()
+
+	var (
+		required []serpent.Option
+		groups   []string
+		byGroup  = make(map[string][]serpent.Option)
+	)
+	for _, opt := range w.Command.Options {
+		if opt.Hidden || opt.Flag == "" || opt.Value == nil {
+			continue
+		}
+		if opt.Required && opt.Default == "" {
+			required = append(required, opt)
+			continue
+		}
+		group := ""
+		if opt.Group != nil {
+			group = opt.Group.Name
+		}
+		if _, ok := byGroup[group]; !ok {
+			groups = append(groups, group)
+		}
+		byGroup[group] = append(byGroup[group], opt)
+	}
+
+	for _, opt := range required {
+		arg, err := p.ask(opt)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg...)
+	}
+	for _, group := range groups {
+		name := group + " options"
+		if group == "" {
+			name = "other options"
+			if len(groups) == 1 {
+				name = "options"
+			}
+		}
+		ok, err := p.confirm(fmt.Sprintf("Configure %s?", name))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for _, opt := range byGroup[group] {
+			arg, err := p.ask(opt)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg...)
+		}
+	}
+	return args, nil
+}
+
+type wizardPrompter struct {
+	ctx context.Context
+	in  *lineReader
+	out io.Writer
+}
+
+func (p *wizardPrompter) readLine() (string, error) {
+	line, err := p.in.readLine(p.ctx)
+	if errors.Is(err, io.EOF) && line != "" {
+		err = nil
+	}
+	if errors.Is(err, io.EOF) {
+		return "", errors.New("wizard canceled: no more input")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func (p *wizardPrompter) confirm(question string) (bool, error) {
+	for {
+		_, _ = fmt.Fprintf(p.out, "%s [y/N]: ", question)
+		line, err := p.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(line) {
+		case "y", "yes":
+			return true, nil
+		case "", "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// ask asks for the value of opt, returning the flag setting it or nothing
+// if the option was left unset.
+func (p *wizardPrompter) ask(opt serpent.Option) ([]string, error) {
+	required := opt.Required && opt.Default == ""
+	label := serpent.Keyword(opt.Flag)
+	if opt.Description != "" {
+		label += " - " + opt.Description
+	}
+
+	var choices []string
+	multiple := false
+	switch v := opt.Value.(type) {
+	case *serpent.Enum:
+		choices = v.Choices
+	case *serpent.EnumArray:
+		choices = v.Choices
+		multiple = true
+	case *serpent.Bool:
+		choices = []string{"true", "false"}
+	default:
+		if opt.Annotations.IsSet(AnnotationFilePath) || strings.HasSuffix(opt.Value.Type(), "path") {
+			choices = listFiles()
+		}
+	}
+
+	for {
+		_, _ = fmt.Fprintln(p.out, label)
+		for i, c := range choices {
+			_, _ = fmt.Fprintf(p.out, "  %d) %s\n", i+1, c)
+		}
+		prompt := "> "
+		if opt.Default != "" {
+			prompt = fmt.Sprintf("[%s] > ", opt.Default)
+		}
+		_, _ = fmt.Fprint(p.out, prompt)
+
+		line, err := p.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			if required {
+				_, _ = fmt.Fprintln(p.out, "A value is required.")
+				continue
+			}
+			return nil, nil
+		}
+
+		value := resolveChoices(line, choices, multiple)
+		if err := opt.Value.Set(value); err != nil {
+			_, _ = fmt.Fprintf(p.out, "Invalid value: %v\n", err)
+			continue
+		}
+		return []string{"--" + opt.Flag + "=" + value}, nil
+	}
+}
+
+// resolveChoices replaces the numbers of listed choices in line with the
+// choices themselves.
+func resolveChoices(line string, choices []string, multiple bool) string {
+	parts := []string{line}
+	if multiple {
+		parts = strings.Split(line, ",")
+	}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if n, err := strconv.Atoi(part); err == nil && n >= 1 && n <= len(choices) {
+			part = choices[n-1]
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ",")
+}
+
+// listFiles returns the names of the files in the working directory for the
+// file picker.
+func listFiles() []string {
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") || e.IsDir() {
+			continue
+		}
+		names = append(names, e.Name())
+		if len(names) == wizardMaxFiles {
+			break
+		}
+	}
+	return names
+}
+
+// commandPath returns the names of cmd and its parents, excluding the root.
+func commandPath(cmd *serpent.Command) []string {
+	var path []string
+	for c := cmd; c.Parent != nil; c = c.Parent {
+		path = append([]string{c.Name()}, path...)
+	}
+	return path
+}
+
+var shellSafeRe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]+$`)
+
+// shellJoin joins args into a command line, quoting them for POSIX shells
+// as needed.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if shellSafeRe.MatchString(arg) {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
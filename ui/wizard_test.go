@@ -0,0 +1,193 @@
+package ui_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/ui"
+)
+
+func TestWizard(t *testing.T) {
+	t.Parallel()
+
+	type values struct {
+		region  string
+		name    string
+		verbose bool
+		ran     bool
+	}
+	newCmd := func(v *values) *serpent.Command {
+		network := &serpent.Group{Name: "Network"}
+		deploy := &serpent.Command{
+			Use: "deploy",
+			Options: serpent.OptionSet{
+				{
+					Name:        "region",
+					Flag:        "region",
+					Description: "Region to deploy to.",
+					Required:    true,
+					Value:       serpent.EnumOf(&v.region, "us", "eu"),
+				},
+				{
+					Name:  "name",
+					Flag:  "name",
+					Group: network,
+					Value: serpent.StringOf(&v.name),
+				},
+				{
+					Name:  "verbose",
+					Flag:  "verbose",
+					Value: serpent.BoolOf(&v.verbose),
+				},
+				{
+					Name:   "secret",
+					Flag:   "secret",
+					Hidden: true,
+					Value:  serpent.StringOf(new(string)),
+				},
+			},
+			Handler: func(*serpent.Invocation) error {
+				v.ran = true
+				return nil
+			},
+		}
+		root := &serpent.Command{Use: "app", Children: []*serpent.Command{deploy}}
+		root.Walk(func(*serpent.Command) {})
+		return deploy
+	}
+
+	newInv := func(input string) (*serpent.Invocation, *bytes.Buffer) {
+		var stdout bytes.Buffer
+		inv := (&serpent.Command{}).Invoke()
+		inv.Stdin = strings.NewReader(input)
+		inv.Stdout = &stdout
+		return inv, &stdout
+	}
+
+	t.Run("PrintOnly", func(t *testing.T) {
+		t.Parallel()
+
+		var v values
+		// An empty answer to a required option, an invalid choice, then the
+		// second choice by number.
+		inv, stdout := newInv("\nasia\n2\ny\nmy app\nn\n")
+		w := &ui.Wizard{Command: newCmd(&v), PrintOnly: true}
+		require.NoError(t, w.Run(inv))
+
+		out := stdout.String()
+		require.Contains(t, out, "  1) us\n  2) eu\n")
+		require.Contains(t, out, "A value is required.")
+		require.Contains(t, out, "Invalid value:")
+		require.Contains(t, out, "Configure Network options? [y/N]")
+		require.Contains(t, out, "Configure other options? [y/N]")
+		require.NotContains(t, out, "secret")
+		require.True(t, strings.HasSuffix(out, "app deploy --region=eu '--name=my app'\n"), out)
+		require.False(t, v.ran)
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		t.Parallel()
+
+		var v values
+		inv, _ := newInv("us\nn\ny\n1\n")
+		w := &ui.Wizard{Command: newCmd(&v)}
+		require.NoError(t, w.Run(inv))
+		require.True(t, v.ran)
+		require.Equal(t, "us", v.region)
+		require.True(t, v.verbose)
+	})
+
+	t.Run("EOF", func(t *testing.T) {
+		t.Parallel()
+
+		var v values
+		inv, _ := newInv("")
+		w := &ui.Wizard{Command: newCmd(&v)}
+		require.ErrorContains(t, w.Run(inv), "wizard canceled")
+	})
+
+	// fakeTerminal returns an invocation reading from a pipe that is never
+	// written to, like a terminal waiting for the user, and a channel
+	// receiving the wizard's output once the first prompt is shown.
+	fakeTerminal := func(t *testing.T) (*serpent.Invocation, <-chan string) {
+		inR, inW := io.Pipe()
+		outR, outW := io.Pipe()
+		t.Cleanup(func() {
+			_ = inW.Close()
+			_ = outR.Close()
+		})
+		inv := (&serpent.Command{}).Invoke()
+		inv.Stdin = inR
+		inv.Stdout = outW
+		prompted := make(chan string, 1)
+		go func() {
+			var out strings.Builder
+			buf := make([]byte, 1024)
+			for {
+				n, err := outR.Read(buf)
+				_, _ = out.Write(buf[:n])
+				if strings.HasSuffix(out.String(), "> ") {
+					prompted <- out.String()
+					_, _ = io.Copy(io.Discard, outR)
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+		return inv, prompted
+	}
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		t.Parallel()
+
+		var v values
+		inv, prompted := fakeTerminal(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		inv = inv.WithContext(ctx)
+
+		errc := make(chan error, 1)
+		go func() { errc <- (&ui.Wizard{Command: newCmd(&v)}).Run(inv) }()
+		require.Contains(t, <-prompted, "Region to deploy to.")
+		cancel()
+		err := <-errc
+		require.ErrorIs(t, err, serpent.Canceled)
+		require.False(t, v.ran)
+	})
+
+	t.Run("Interrupt", func(t *testing.T) {
+		t.Parallel()
+
+		var v values
+		inv, prompted := fakeTerminal(t)
+		interrupt := make(chan struct{})
+		inv = inv.WithTestSignalNotifyContext(t, func(parent context.Context, _ ...os.Signal) (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(parent)
+			go func() {
+				select {
+				case <-interrupt:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			return ctx, cancel
+		})
+
+		errc := make(chan error, 1)
+		go func() { errc <- (&ui.Wizard{Command: newCmd(&v)}).Run(inv) }()
+		<-prompted
+		close(interrupt)
+		err := <-errc
+		require.ErrorIs(t, err, serpent.Canceled)
+		require.False(t, v.ran)
+	})
+}
@@ -20,7 +20,9 @@ import (
 // NoOptDefValuer describes behavior when no
 // option is passed into the flag.
 //
-// This is useful for boolean or otherwise binary flags.
+// This is useful for boolean or otherwise binary flags. The flag then never
+// takes the next argument as its value, so other values must be passed as
+// "--flag=value" or "-f=value".
 type NoOptDefValuer interface {
 	NoOptDefValue() string
 }
@@ -164,6 +166,32 @@ func (Bool) Type() string {
 	return "bool"
 }
 
+// negatedBool is the value of the --no-<flag> flag turning off a Bool flag.
+type negatedBool struct {
+	b *Bool
+}
+
+func (n negatedBool) Set(s string) error {
+	var v Bool
+	if err := v.Set(s); err != nil {
+		return err
+	}
+	*n.b = !v
+	return nil
+}
+
+func (negatedBool) NoOptDefValue() string {
+	return "true"
+}
+
+func (n negatedBool) String() string {
+	return strconv.FormatBool(!bool(*n.b))
+}
+
+func (negatedBool) Type() string {
+	return "bool"
+}
+
 type String string
 
 func StringOf(s *string) *String {
@@ -408,16 +436,54 @@ var (
 // It implements the flag.Value interface, but in general these values should
 // only be accepted via config for ergonomics.
 //
-// The string encoding type is YAML.
+// The string encoding type is YAML, so JSON is accepted too. If T is a
+// slice, every Set appends to the value, so that a repeated flag such as
+// --route '{"src":"a","dst":"b"}' accumulates one element per occurrence. A
+// list appends all of its elements, e.g. from an environment variable.
 type Struct[T any] struct {
 	Value T
 }
 
 //nolint:revive
 func (s *Struct[T]) Set(v string) error {
+	if typ := reflect.TypeOf(s.Value); typ != nil && typ.Kind() == reflect.Slice {
+		return s.append(v)
+	}
 	return yaml.Unmarshal([]byte(v), &s.Value)
 }
 
+// append decodes v, a single element or a list of elements, and appends it
+// to the slice value. An empty v resets the value.
+func (s *Struct[T]) append(v string) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(v), &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		s.resetSlice()
+		return nil
+	}
+	n := doc.Content[0]
+	if n.Kind != yaml.SequenceNode {
+		n = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{n}}
+	}
+	var elems T
+	if err := n.Decode(&elems); err != nil {
+		return err
+	}
+	value := reflect.ValueOf(&s.Value).Elem()
+	value.Set(reflect.AppendSlice(value, reflect.ValueOf(elems)))
+	return nil
+}
+
+// resetSlice resets the value if T is a slice, before flags that accumulate
+// into it are parsed.
+func (s *Struct[T]) resetSlice() {
+	if typ := reflect.TypeOf(s.Value); typ != nil && typ.Kind() == reflect.Slice {
+		reflect.ValueOf(&s.Value).Elem().Set(reflect.Zero(typ))
+	}
+}
+
 //nolint:revive
 func (s *Struct[T]) String() string {
 	byt, err := yaml.Marshal(s.Value)
@@ -514,9 +580,71 @@ func (j *jsonValue) UnmarshalJSON(data []byte) error {
 
 var _ pflag.Value = (*Enum)(nil)
 
+// EnumChoice describes a choice of an Enum or EnumArray, see EnumOfChoices.
+type EnumChoice struct {
+	Value string
+	// Description is shown next to the choice in help and completions.
+	Description string
+	// Aliases are other names accepted for the choice, such as its names
+	// before it was renamed. They're stored as Value.
+	Aliases []string
+	// Hidden choices are accepted, but not shown in help and completions.
+	Hidden bool
+	// Deprecated choices are accepted, but not shown in help and
+	// completions, and using them prints Deprecated as a warning.
+	Deprecated string
+}
+
+// visibleChoices returns the values of the choices shown to users.
+func visibleChoices(details []EnumChoice) []string {
+	var choices []string
+	for _, d := range details {
+		if !d.Hidden && d.Deprecated == "" {
+			choices = append(choices, d.Value)
+		}
+	}
+	return choices
+}
+
+// matchEnumChoice returns the choice v refers to, matching choices, and
+// the values and aliases of details, case-insensitively.
+func matchEnumChoice(choices []string, details []EnumChoice, v string) (string, bool) {
+	for _, d := range details {
+		if strings.EqualFold(v, d.Value) {
+			return d.Value, true
+		}
+		for _, alias := range d.Aliases {
+			if strings.EqualFold(v, alias) {
+				return d.Value, true
+			}
+		}
+	}
+	for _, c := range choices {
+		if strings.EqualFold(v, c) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// enumDetails returns the details of the choices of v, if it's an enum.
+func enumDetails(v pflag.Value) []EnumChoice {
+	switch v := v.(type) {
+	case *Enum:
+		return v.Details
+	case *EnumArray:
+		return v.Details
+	}
+	return nil
+}
+
 type Enum struct {
+	// Choices are the values shown to users. Values are matched
+	// case-insensitively and stored as the choice they match.
 	Choices []string
 	Value   *string
+	// Details describes the choices, see EnumOfChoices.
+	Details []EnumChoice
 }
 
 func EnumOf(v *string, choices ...string) *Enum {
@@ -528,12 +656,21 @@ func EnumOf(v *string, choices ...string) *Enum {
 	}
 }
 
+// EnumOfChoices is like EnumOf, for choices with descriptions, aliases, or
+// that are hidden or deprecated.
+func EnumOfChoices(v *string, choices ...EnumChoice) *Enum {
+	choices = append([]EnumChoice{}, choices...)
+	return &Enum{
+		Choices: visibleChoices(choices),
+		Value:   v,
+		Details: choices,
+	}
+}
+
 func (e *Enum) Set(v string) error {
-	for _, c := range e.Choices {
-		if strings.EqualFold(v, c) {
-			*e.Value = v
-			return nil
-		}
+	if c, ok := matchEnumChoice(e.Choices, e.Details, v); ok {
+		*e.Value = c
+		return nil
 	}
 	return fmt.Errorf("invalid choice: %s, should be one of %v", v, e.Choices)
 }
@@ -636,16 +773,18 @@ var _ pflag.SliceValue = (*EnumArray)(nil)
 var _ pflag.Value = (*EnumArray)(nil)
 
 type EnumArray struct {
+	// Choices are the values shown to users. Values are matched
+	// case-insensitively and stored as the choice they match.
 	Choices []string
 	Value   *[]string
+	// Details describes the choices, see EnumArrayOfChoices.
+	Details []EnumChoice
 }
 
 func (e *EnumArray) Append(s string) error {
-	for _, c := range e.Choices {
-		if strings.EqualFold(s, c) {
-			*e.Value = append(*e.Value, s)
-			return nil
-		}
+	if c, ok := matchEnumChoice(e.Choices, e.Details, s); ok {
+		*e.Value = append(*e.Value, c)
+		return nil
 	}
 	return fmt.Errorf("invalid choice: %s, should be one of %v", s, e.Choices)
 }
@@ -655,19 +794,15 @@ func (e *EnumArray) GetSlice() []string {
 }
 
 func (e *EnumArray) Replace(ss []string) error {
+	values := make([]string, 0, len(ss))
 	for _, s := range ss {
-		found := false
-		for _, c := range e.Choices {
-			if strings.EqualFold(s, c) {
-				found = true
-				break
-			}
-		}
-		if !found {
+		c, ok := matchEnumChoice(e.Choices, e.Details, s)
+		if !ok {
 			return fmt.Errorf("invalid choice: %s, should be one of %v", s, e.Choices)
 		}
+		values = append(values, c)
 	}
-	*e.Value = ss
+	*e.Value = values
 	return nil
 }
 
@@ -704,3 +839,234 @@ func EnumArrayOf(v *[]string, choices ...string) *EnumArray {
 		Value:   v,
 	}
 }
+
+// EnumArrayOfChoices is like EnumArrayOf, for choices with descriptions,
+// aliases, or that are hidden or deprecated.
+func EnumArrayOfChoices(v *[]string, choices ...EnumChoice) *EnumArray {
+	choices = append([]EnumChoice{}, choices...)
+	return &EnumArray{
+		Choices: visibleChoices(choices),
+		Value:   v,
+		Details: choices,
+	}
+}
+
+var _ pflag.Value = (*EnumSet)(nil)
+
+// EnumSetAll selects all the choices of an EnumSet.
+const EnumSetAll = "all"
+
+// EnumSet is a set of choices, such as features to enable, parsed from a
+// comma-separated list that adds choices, or removes them when prefixed
+// with "-", in order. "all" adds every choice. A list starting with a
+// removal starts from all the choices, e.g. "-c" selects all but c, and
+// otherwise from none. The value is kept in the order of Choices.
+type EnumSet struct {
+	Choices []string
+	Value   *[]string
+}
+
+func EnumSetOf(v *[]string, choices ...string) *EnumSet {
+	choices = append([]string{}, choices...)
+	return &EnumSet{
+		Choices: choices,
+		Value:   v,
+	}
+}
+
+func (e *EnumSet) Set(v string) error {
+	if v == "" {
+		*e.Value = nil
+		return nil
+	}
+	items, err := readAsCSV(v)
+	if err != nil {
+		return err
+	}
+	return e.apply(items)
+}
+
+// apply sets the value from items, as described on EnumSet.
+func (e *EnumSet) apply(items []string) error {
+	selected := make(map[string]bool)
+	if len(items) > 0 && strings.HasPrefix(items[0], "-") {
+		for _, c := range e.Choices {
+			selected[c] = true
+		}
+	}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, remove := strings.CutPrefix(item, "-")
+		if strings.EqualFold(name, EnumSetAll) {
+			for _, c := range e.Choices {
+				selected[c] = !remove
+			}
+			continue
+		}
+		c, ok := matchEnumChoice(e.Choices, nil, name)
+		if !ok {
+			return fmt.Errorf("invalid choice: %s, should be one of %v or %s", name, e.Choices, EnumSetAll)
+		}
+		selected[c] = !remove
+	}
+
+	var values []string
+	for _, c := range e.Choices {
+		if selected[c] {
+			values = append(values, c)
+		}
+	}
+	*e.Value = values
+	return nil
+}
+
+func (e *EnumSet) String() string {
+	return writeAsCSV(*e.Value)
+}
+
+func (e *EnumSet) Type() string {
+	return fmt.Sprintf("enum-set[%v]", strings.Join(e.Choices, "\\|"))
+}
+
+func (e *EnumSet) MarshalYAML() (interface{}, error) {
+	n := yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range *e.Value {
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v})
+	}
+	return n, nil
+}
+
+// UnmarshalYAML accepts a list of items, e.g. [all, -c], or a
+// comma-separated string.
+func (e *EnumSet) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return e.Set(n.Value)
+	}
+	var items []string
+	if err := n.Decode(&items); err != nil {
+		return err
+	}
+	return e.apply(items)
+}
+
+// remaining returns the completions of the list being typed in cur, the
+// choices that aren't listed yet appended to the complete items.
+func (e *EnumSet) remaining(cur string) []string {
+	prefix := ""
+	if i := strings.LastIndex(cur, ","); i >= 0 {
+		prefix = cur[:i+1]
+	}
+	listed := make(map[string]bool)
+	for _, item := range strings.Split(prefix, ",") {
+		listed[strings.ToLower(strings.TrimPrefix(item, "-"))] = true
+	}
+	var completions []string
+	for _, c := range append(append([]string{}, e.Choices...), EnumSetAll) {
+		if !listed[strings.ToLower(c)] {
+			completions = append(completions, prefix+c)
+		}
+	}
+	return completions
+}
+
+var _ pflag.Value = (*Value[int])(nil)
+
+// Value is an option value of any type T, parsed and formatted with
+// functions, see ValueOf.
+type Value[T any] struct {
+	ptr    *T
+	parse  func(string) (T, error)
+	format func(T) string
+
+	typeName      string
+	noOptDefValue string
+}
+
+// ValueOf returns an option value stored in ptr, parsed with parse and
+// formatted with format, or fmt.Sprint if format is nil. The value
+// implements pflag.Value and marshals to YAML and JSON as its formatted
+// string:
+//
+//	var level slog.Level
+//	serpent.ValueOf(&level, func(s string) (slog.Level, error) {
+//		var l slog.Level
+//		return l, l.UnmarshalText([]byte(s))
+//	}, slog.Level.String)
+func ValueOf[T any](ptr *T, parse func(string) (T, error), format func(T) string) *Value[T] {
+	if format == nil {
+		format = func(v T) string { return fmt.Sprint(v) }
+	}
+	return &Value[T]{ptr: ptr, parse: parse, format: format}
+}
+
+// WithType sets the type name shown in help, the lowercased name of T by
+// default.
+func (v *Value[T]) WithType(name string) *Value[T] {
+	v.typeName = name
+	return v
+}
+
+// WithNoOptDefValue sets the value the flag takes when it's given without
+// one, as "--flag" instead of "--flag=value".
+func (v *Value[T]) WithNoOptDefValue(s string) *Value[T] {
+	v.noOptDefValue = s
+	return v
+}
+
+// Value returns the current value.
+func (v *Value[T]) Value() T {
+	return *v.ptr
+}
+
+func (v *Value[T]) Set(s string) error {
+	parsed, err := v.parse(s)
+	if err != nil {
+		return err
+	}
+	*v.ptr = parsed
+	return nil
+}
+
+func (v *Value[T]) String() string {
+	return v.format(*v.ptr)
+}
+
+func (v *Value[T]) Type() string {
+	if v.typeName != "" {
+		return v.typeName
+	}
+	if name := reflect.TypeOf(v.ptr).Elem().Name(); name != "" {
+		return strings.ToLower(name)
+	}
+	return "value"
+}
+
+func (v *Value[T]) NoOptDefValue() string {
+	return v.noOptDefValue
+}
+
+func (v *Value[T]) MarshalYAML() (interface{}, error) {
+	return yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Value: v.String(),
+	}, nil
+}
+
+func (v *Value[T]) UnmarshalYAML(n *yaml.Node) error {
+	return v.Set(n.Value)
+}
+
+func (v *Value[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+func (v *Value[T]) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return v.Set(s)
+}
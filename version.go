@@ -0,0 +1,112 @@
+package serpent
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// DefaultVersionTemplate renders the output of --version when a command
+// doesn't set VersionTemplate.
+const DefaultVersionTemplate = `{{ .Name }} {{ .Version }}{{ with .Commit }} ({{ . }}){{ end }}{{ with .Date }} built {{ . }}{{ end }}
+`
+
+// VersionInfo is the data VersionTemplate is executed with.
+type VersionInfo struct {
+	// Name is the name of the command defining Version, usually the root.
+	Name    string
+	Version string
+	Commit  string
+	Date    string
+}
+
+// versionCommand returns the closest of c and its parents with a Version,
+// or nil.
+func (c *Command) versionCommand() *Command {
+	for ; c != nil; c = c.Parent {
+		if c.Version != "" {
+			return c
+		}
+	}
+	return nil
+}
+
+// addVersionOption adds a "version" flag to c, unless c or its parents
+// already define one. The -v shorthand is only used if no command the flag
+// is inherited by uses it already.
+func (c *Command) addVersionOption() {
+	for p := c; p != nil; p = p.Parent {
+		if p.Options.ByName("version") != nil || p.Options.ByFlag("version") != nil {
+			return
+		}
+	}
+
+	shorthand := "v"
+	for p := c.Parent; p != nil; p = p.Parent {
+		if p.Options.byFlagForm("-v") != nil {
+			shorthand = ""
+		}
+	}
+	c.Walk(func(cmd *Command) {
+		if cmd.Options.byFlagForm("-v") != nil {
+			shorthand = ""
+		}
+	})
+
+	var val bool
+	c.Options.Add(Option{
+		Flag:          "version",
+		FlagShorthand: shorthand,
+		Description:   "Print the version.",
+		Value:         BoolOf(&val),
+		Name:          "version",
+	})
+}
+
+// versionRequested returns the command whose version was requested with
+// --version, if any.
+func (inv *Invocation) versionRequested() *Command {
+	vc := inv.Command.versionCommand()
+	if vc == nil {
+		return nil
+	}
+	// The closest definition of the flag is the one that was parsed. If
+	// it's below the command defining the version, the flag was overridden
+	// for another purpose.
+	inherited := false
+	for c := inv.Command; c != nil; c = c.Parent {
+		inherited = inherited || c == vc
+		vflag := c.Options.ByFlag("version")
+		if vflag == nil {
+			continue
+		}
+		if !inherited {
+			return nil
+		}
+		if fl := inv.parsedFlags.Lookup(vflag.Flag); fl != nil && fl.Changed {
+			return vc
+		}
+		return nil
+	}
+	return nil
+}
+
+// printVersion renders the version of vc with the closest VersionTemplate.
+func (inv *Invocation) printVersion(vc *Command) error {
+	text := DefaultVersionTemplate
+	for c := inv.Command; c != nil; c = c.Parent {
+		if c.VersionTemplate != "" {
+			text = c.VersionTemplate
+			break
+		}
+	}
+	tpl, err := template.New("version").Parse(text)
+	if err != nil {
+		return fmt.Errorf("parse version template: %w", err)
+	}
+	return tpl.Execute(inv.Stdout, VersionInfo{
+		Name:    vc.Name(),
+		Version: vc.Version,
+		Commit:  vc.VersionCommit,
+		Date:    vc.VersionDate,
+	})
+}
@@ -0,0 +1,95 @@
+package serpent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	makeRoot := func(verbose *bool) *serpent.Command {
+		root := &serpent.Command{
+			Use:     "app",
+			Version: "1.2.3",
+			Handler: func(inv *serpent.Invocation) error {
+				inv.Println("root ran")
+				return nil
+			},
+			Children: []*serpent.Command{
+				{
+					Use: "sub",
+					Handler: func(inv *serpent.Invocation) error {
+						inv.Println("sub ran")
+						return nil
+					},
+				},
+			},
+		}
+		if verbose != nil {
+			root.Children[0].Options = serpent.OptionSet{
+				{Name: "verbose", Flag: "verbose", FlagShorthand: "v", Value: serpent.BoolOf(verbose)},
+			}
+		}
+		return root
+	}
+	run := func(root *serpent.Command, args ...string) string {
+		t.Helper()
+		inv := root.Invoke(args...)
+		io := fakeIO(inv)
+		require.NoError(t, inv.Run())
+		return io.Stdout.String()
+	}
+
+	t.Run("Root", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "app 1.2.3\n", run(makeRoot(nil), "--version"))
+		require.Equal(t, "app 1.2.3\n", run(makeRoot(nil), "-v"))
+		require.Equal(t, "root ran\n", run(makeRoot(nil)))
+	})
+
+	t.Run("Subcommand", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "app 1.2.3\n", run(makeRoot(nil), "sub", "--version"))
+		require.Equal(t, "sub ran\n", run(makeRoot(nil), "sub"))
+	})
+
+	t.Run("ShorthandYields", func(t *testing.T) {
+		t.Parallel()
+
+		var verbose bool
+		require.Equal(t, "sub ran\n", run(makeRoot(&verbose), "sub", "-v"))
+		require.True(t, verbose)
+		require.Equal(t, "app 1.2.3\n", run(makeRoot(&verbose), "sub", "--version"))
+		require.Empty(t, serpent.Lint(makeRoot(&verbose)))
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		t.Parallel()
+
+		root := makeRoot(nil)
+		root.VersionCommit = "abc123"
+		root.VersionDate = "2024-01-02"
+		require.Equal(t, "app 1.2.3 (abc123) built 2024-01-02\n", run(root, "--version"))
+
+		root = makeRoot(nil)
+		root.VersionCommit = "abc123"
+		root.VersionTemplate = "{{ .Name }} version {{ .Version }}, commit {{ .Commit }}\n"
+		require.Equal(t, "app version 1.2.3, commit abc123\n", run(root, "sub", "--version"))
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		t.Parallel()
+
+		var version string
+		root := makeRoot(nil)
+		root.Children[0].Options = serpent.OptionSet{
+			{Name: "version", Flag: "version", Override: true, Value: serpent.StringOf(&version)},
+		}
+		require.Equal(t, "sub ran\n", run(root, "sub", "--version", "2"))
+		require.Equal(t, "2", version)
+	})
+}
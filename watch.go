@@ -0,0 +1,168 @@
+package serpent
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+const (
+	// watchPollInterval is how often watched files are checked for changes.
+	watchPollInterval = 200 * time.Millisecond
+	// watchDebounce is how long watched files must stay unchanged before the
+	// handler runs again, so that saving several files runs it once.
+	watchDebounce = 300 * time.Millisecond
+)
+
+// WatchMiddleware returns a middleware that, when pathsOption is set, runs
+// the handler again whenever a file below the paths it lists changes, until
+// the user interrupts it with Ctrl-C. It's a generic --watch for build and
+// test style commands:
+//
+//	var paths []string
+//	watch := serpent.Option{
+//		Name:        "watch",
+//		Flag:        "watch",
+//		Description: "Run again when files below these paths change.",
+//		Value:       serpent.StringArrayOf(&paths),
+//	}
+//	cmd := &serpent.Command{
+//		Options:    serpent.OptionSet{watch},
+//		Middleware: serpent.WatchMiddleware(&watch),
+//	}
+//
+// The screen is cleared before each run when Stdout is a terminal, and a
+// status line with the result of the run is written to Stderr after it.
+// Failed runs don't stop watching. Directories are watched recursively,
+// skipping hidden ones such as .git. Without paths, the handler runs once.
+//
+// Add a DiffOutputOption to let users highlight what changed in the output.
+func WatchMiddleware(pathsOption *Option) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			paths := watchPaths(pathsOption)
+			if len(paths) == 0 {
+				return next(inv)
+			}
+			for i, path := range paths {
+				paths[i] = inv.ResolvePath(path)
+			}
+
+			ctx, stop := inv.SignalNotifyContext(inv.Context(), os.Interrupt)
+			defer stop()
+			inv = inv.WithContext(ctx)
+
+			differ := newOutputDiffer(inv)
+			snapshot := watchSnapshot(paths)
+			for run := 0; ; run++ {
+				if run > 0 {
+					clearScreen(inv)
+				}
+				start := time.Now()
+				err := differ.run(next, inv)
+				if ctx.Err() != nil {
+					return nil
+				}
+				status := fmt.Sprintf("ok in %s", time.Since(start).Round(time.Millisecond))
+				if err != nil {
+					status = fmt.Sprintf("%s in %s: %v", DefaultStyles.Error.Render("failed"), time.Since(start).Round(time.Millisecond), err)
+				}
+				if err != nil || !inv.Quiet() {
+					_, _ = fmt.Fprintf(inv.Stderr, "\n%s %s, watching %s for changes, press Ctrl-C to stop.\n",
+						Timestamp(time.Now()), status, strings.Join(paths, ", "))
+				}
+
+				var ok bool
+				if snapshot, ok = watchForChange(ctx, paths, snapshot); !ok {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// watchPaths returns the paths listed by opt, a list or a single path.
+func watchPaths(opt *Option) []string {
+	if opt == nil || opt.Value == nil {
+		return nil
+	}
+	if v, ok := opt.Value.(pflag.SliceValue); ok {
+		return append([]string(nil), v.GetSlice()...)
+	}
+	if v := opt.Value.String(); v != "" {
+		return []string{v}
+	}
+	return nil
+}
+
+// watchedFile is the state of a watched file compared to detect changes.
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchSnapshot returns the state of the files below paths. Missing paths
+// are left out, so that creating them is a change.
+func watchSnapshot(paths []string) map[string]watchedFile {
+	snapshot := make(map[string]watchedFile)
+	for _, root := range paths {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			snapshot[path] = watchedFile{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+	}
+	return snapshot
+}
+
+// watchForChange waits for the files below paths to differ from snapshot
+// and then to stay unchanged for watchDebounce, returning their new state.
+// It reports false if ctx is done first.
+func watchForChange(ctx context.Context, paths []string, snapshot map[string]watchedFile) (map[string]watchedFile, bool) {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-ticker.C:
+		}
+		current := watchSnapshot(paths)
+		if !maps.Equal(current, snapshot) {
+			snapshot, changedAt = current, time.Now()
+			continue
+		}
+		if !changedAt.IsZero() && time.Since(changedAt) >= watchDebounce {
+			return snapshot, true
+		}
+	}
+}
+
+// clearScreen clears the terminal if Stdout is one.
+func clearScreen(inv *Invocation) {
+	if f, ok := inv.Stdout.(interface{ Fd() uintptr }); ok && term.IsTerminal(int(f.Fd())) {
+		_, _ = fmt.Fprint(inv.Stdout, "\x1b[H\x1b[2J")
+	}
+}
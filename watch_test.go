@@ -0,0 +1,89 @@
+package serpent_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestWatchMiddleware(t *testing.T) {
+	t.Parallel()
+
+	makeCmd := func(runs chan<- int) *serpent.Command {
+		var paths []string
+		watch := serpent.Option{
+			Name:  "watch",
+			Flag:  "watch",
+			Value: serpent.StringArrayOf(&paths),
+		}
+		var n int
+		return &serpent.Command{
+			Use:        "build",
+			Options:    serpent.OptionSet{watch},
+			Middleware: serpent.WatchMiddleware(&watch),
+			Handler: func(inv *serpent.Invocation) error {
+				n++
+				runs <- n
+				if n == 1 {
+					return errors.New("compile error")
+				}
+				return nil
+			},
+		}
+	}
+
+	t.Run("NoPaths", func(t *testing.T) {
+		t.Parallel()
+
+		runs := make(chan int, 1)
+		inv := makeCmd(runs).Invoke()
+		fakeIO(inv)
+		require.ErrorContains(t, inv.Run(), "compile error")
+		require.Equal(t, 1, <-runs)
+	})
+
+	t.Run("Rerun", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		src := filepath.Join(dir, "main.go")
+		require.NoError(t, os.WriteFile(src, []byte("package main"), 0o600))
+		require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0o700))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		runs := make(chan int)
+		inv := makeCmd(runs).Invoke("--watch", dir).WithContext(ctx)
+		io := fakeIO(inv)
+		errc := make(chan error, 1)
+		go func() { errc <- inv.Run() }()
+
+		require.Equal(t, 1, <-runs)
+		// Changes in hidden directories are ignored.
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "index"), []byte("x"), 0o600))
+		time.Sleep(time.Second)
+		select {
+		case n := <-runs:
+			t.Fatalf("unexpected run %d", n)
+		default:
+		}
+		require.NoError(t, os.WriteFile(src, []byte("package main\n\nfunc main() {}"), 0o600))
+		require.Equal(t, 2, <-runs)
+		// The status of the second run is written before watching again.
+		require.NoError(t, os.WriteFile(src, []byte("package main"), 0o600))
+		require.Equal(t, 3, <-runs)
+
+		cancel()
+		require.NoError(t, <-errc)
+		require.Contains(t, io.Stderr.String(), "failed in ")
+		require.Contains(t, io.Stderr.String(), "compile error, watching "+dir+" for changes")
+		require.Contains(t, io.Stderr.String(), "ok in ")
+	})
+}
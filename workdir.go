@@ -0,0 +1,104 @@
+package serpent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AnnotationWorkDir marks the option holding the working directory of the
+// invocation, see WorkDirOption.
+const AnnotationWorkDir = "serpent.work_dir"
+
+// WorkDirOption returns a -C/--chdir option, like "git -C" and "make -C",
+// selecting the directory the command runs in. WorkDirMiddleware validates it
+// and records it on the invocation, see WorkDir.
+//
+// The option is usually added to the root command.
+func WorkDirOption() Option {
+	var dir string
+	return Option{
+		Name:          "chdir",
+		Description:   "Run as if started in this directory.",
+		Flag:          "chdir",
+		FlagShorthand: "C",
+		Value:         StringOf(&dir),
+		Annotations:   Annotations{}.Mark(AnnotationWorkDir, "true"),
+	}
+}
+
+// WorkDirMiddleware returns middleware resolving the WorkDirOption of the
+// command or one of its parents to an absolute directory, failing if it
+// doesn't exist, and recording it as the invocation's WorkDir. Unlike
+// os.Chdir, it doesn't change the working directory of the process, so
+// invocations running concurrently each keep their own.
+func WorkDirMiddleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			dir := inv.workDirOption()
+			if dir == "" {
+				return next(inv)
+			}
+			dir = inv.ResolvePath(dir)
+			info, err := os.Stat(dir)
+			if err != nil {
+				return fmt.Errorf("chdir: %w", err)
+			}
+			if !info.IsDir() {
+				return fmt.Errorf("chdir: %s is not a directory", dir)
+			}
+			return next(inv.with(func(i *Invocation) {
+				i.workDir = dir
+			}))
+		}
+	}
+}
+
+// WorkDir returns the working directory of the invocation: the directory set
+// by WorkDirMiddleware if any, else the working directory of the process.
+// Handlers should resolve relative paths against it, e.g. with ResolvePath,
+// rather than rely on the working directory of the process.
+func (inv *Invocation) WorkDir() string {
+	if inv.workDir != "" {
+		return inv.workDir
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return dir
+}
+
+// ResolvePath returns path joined to the WorkDir of the invocation if it's
+// relative, else path unchanged.
+func (inv *Invocation) ResolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(inv.WorkDir(), path)
+}
+
+// workDirOption returns the value of the closest option annotated with
+// AnnotationWorkDir.
+func (inv *Invocation) workDirOption() string {
+	for cmd := inv.Command; cmd != nil; cmd = cmd.Parent {
+		for _, opt := range cmd.Options {
+			if opt.Annotations.IsSet(AnnotationWorkDir) && opt.Value != nil {
+				return opt.Value.String()
+			}
+		}
+	}
+	return ""
+}
+
+// configWorkDir returns the WorkDir of the invocation while options are
+// being parsed, before WorkDirMiddleware runs, taking the WorkDirOption into
+// account so that -C affects which config files are found.
+func (inv *Invocation) configWorkDir() string {
+	if inv.workDir == "" {
+		if dir := inv.workDirOption(); dir != "" {
+			return inv.ResolvePath(dir)
+		}
+	}
+	return inv.WorkDir()
+}
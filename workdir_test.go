@@ -0,0 +1,71 @@
+package serpent_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	serpent "github.com/bketelsen/serpent"
+)
+
+func TestWorkDir(t *testing.T) {
+	t.Parallel()
+
+	makeCmd := func(got *string) *serpent.Command {
+		return &serpent.Command{
+			Use:                  "root",
+			Options:              serpent.OptionSet{serpent.WorkDirOption()},
+			PersistentMiddleware: serpent.WorkDirMiddleware(),
+			Children: []*serpent.Command{{
+				Use: "sub",
+				Handler: func(inv *serpent.Invocation) error {
+					*got = inv.ResolvePath("file")
+					return nil
+				},
+			}},
+		}
+	}
+
+	t.Run("Set", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		var got string
+		require.NoError(t, makeCmd(&got).Invoke("-C", dir, "sub").Run())
+		require.Equal(t, filepath.Join(dir, "file"), got)
+
+		cwd, err := os.Getwd()
+		require.NoError(t, err)
+		require.NotEqual(t, dir, cwd, "the process directory is unchanged")
+	})
+
+	t.Run("Unset", func(t *testing.T) {
+		t.Parallel()
+
+		var got string
+		require.NoError(t, makeCmd(&got).Invoke("sub").Run())
+		cwd, err := os.Getwd()
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(cwd, "file"), got)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		t.Parallel()
+
+		var got string
+		err := makeCmd(&got).Invoke("--chdir", filepath.Join(t.TempDir(), "missing"), "sub").Run()
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("NotDirectory", func(t *testing.T) {
+		t.Parallel()
+
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o600))
+		var got string
+		err := makeCmd(&got).Invoke("--chdir", file, "sub").Run()
+		require.ErrorContains(t, err, "is not a directory")
+	})
+}
@@ -61,7 +61,7 @@ func (optSet *OptionSet) MarshalYAML() (any, error) {
 			continue
 		}
 
-		defValue := opt.Default
+		defValue := opt.defaultText()
 		if defValue == "" {
 			defValue = "<unset>"
 		}
@@ -82,6 +82,12 @@ func (optSet *OptionSet) MarshalYAML() (any, error) {
 				Kind:  yaml.ScalarNode,
 				Value: "null",
 			}
+		} else if opt.Sensitive && opt.Value.String() != "" {
+			var err error
+			valueNode, err = opt.encryptedYAMLNode()
+			if err != nil {
+				return nil, fmt.Errorf("marshal %q: %w", opt.Name, err)
+			}
 		} else if m, ok := opt.Value.(yaml.Marshaler); ok && !isValidator {
 			// Validators do a wrap, and should be handled by the else statement.
 			v, err := m.MarshalYAML()
@@ -199,8 +205,25 @@ func mapYAMLNodes(parent *yaml.Node) (map[string]*yaml.Node, error) {
 	return m, nil
 }
 
-func (o *Option) setFromYAMLNode(n *yaml.Node) error {
-	o.ValueSource = ValueSourceYAML
+func (o *Option) setFromYAMLNode(n *yaml.Node, origin ValueOrigin) error {
+	o.setOrigin(origin)
+	old := o.valueString()
+	if err := o.setYAMLValue(n); err != nil {
+		return err
+	}
+	o.changed(old)
+	return nil
+}
+
+// setYAMLValue sets the value of the option from n.
+func (o *Option) setYAMLValue(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode && n.Tag == encryptedTag {
+		v, err := decryptYAMLNode(n)
+		if err != nil {
+			return err
+		}
+		return o.Value.Set(v)
+	}
 	if um, ok := o.Value.(yaml.Unmarshaler); ok {
 		return um.UnmarshalYAML(n)
 	}
@@ -228,6 +251,11 @@ func (o *Option) setFromYAMLNode(n *yaml.Node) error {
 // UnmarshalYAML converts the given YAML node into the option set.
 // It is isomorphic with ToYAML.
 func (optSet *OptionSet) UnmarshalYAML(rootNode *yaml.Node) error {
+	return optSet.unmarshalYAML(rootNode, ValueOrigin{Source: ValueSourceYAML})
+}
+
+// unmarshalYAML is UnmarshalYAML, marking the options it sets with origin.
+func (optSet *OptionSet) unmarshalYAML(rootNode *yaml.Node, origin ValueOrigin) error {
 	// The rootNode will be a DocumentNode if it's read from a file. We do
 	// not support multiple documents in a single file.
 	if rootNode.Kind == yaml.DocumentNode {
@@ -270,10 +298,14 @@ func (optSet *OptionSet) UnmarshalYAML(rootNode *yaml.Node) error {
 		}
 
 		matchedNodes[key] = node
+		if origin.Source == ValueSourceProject && opt.Scope != ScopeProject {
+			merr = errors.Join(merr, fmt.Errorf("option %q can't be set in a project config file", opt.YAML))
+			continue
+		}
 		if opt.ValueSource != ValueSourceNone {
 			continue
 		}
-		if err := opt.setFromYAMLNode(node); err != nil {
+		if err := opt.setFromYAMLNode(node, origin); err != nil {
 			merr = errors.Join(merr, fmt.Errorf("setting %q: %w", opt.YAML, err))
 		}
 	}
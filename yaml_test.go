@@ -9,6 +9,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/bketelsen/serpent"
+	"github.com/bketelsen/serpent/secret"
 )
 
 func TestOptionSet_YAML(t *testing.T) {
@@ -200,3 +201,53 @@ func TestOptionSet_YAMLIsomorphism(t *testing.T) {
 		})
 	}
 }
+
+// TestOptionSet_YAMLSensitive isn't parallel, as it sets the global
+// ConfigCipher.
+func TestOptionSet_YAMLSensitive(t *testing.T) {
+	newSet := func(token *string) serpent.OptionSet {
+		return serpent.OptionSet{{
+			Name:      "token",
+			YAML:      "token",
+			Sensitive: true,
+			Value:     serpent.StringOf(token),
+		}}
+	}
+
+	var token string
+	set := newSet(&token)
+	require.NoError(t, set[0].Value.Set("hunter2"))
+
+	_, err := set.MarshalYAML()
+	require.ErrorContains(t, err, "no ConfigCipher")
+
+	serpent.ConfigCipher = &secret.Cipher{Service: "app", Keyring: secret.NewMemoryKeyring()}
+	t.Cleanup(func() { serpent.ConfigCipher = nil })
+
+	n, err := set.MarshalYAML()
+	require.NoError(t, err)
+	byt, err := yaml.Marshal(n)
+	require.NoError(t, err)
+	require.Contains(t, string(byt), "token: !encrypted ")
+	require.NotContains(t, string(byt), "hunter2")
+
+	var loaded string
+	loadedSet := newSet(&loaded)
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal(byt, &doc))
+	require.NoError(t, loadedSet.UnmarshalYAML(&doc))
+	require.Equal(t, "hunter2", loaded)
+
+	// Values written by hand are read as is.
+	var plain string
+	plainSet := newSet(&plain)
+	require.NoError(t, yaml.Unmarshal([]byte("token: hunter3\n"), &doc))
+	require.NoError(t, plainSet.UnmarshalYAML(&doc))
+	require.Equal(t, "hunter3", plain)
+
+	// Values encrypted with another key aren't.
+	serpent.ConfigCipher = &secret.Cipher{Service: "app", Keyring: secret.NewMemoryKeyring()}
+	otherSet := newSet(new(string))
+	require.NoError(t, yaml.Unmarshal(byt, &doc))
+	require.ErrorContains(t, otherSet.UnmarshalYAML(&doc), "decrypt")
+}